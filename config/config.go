@@ -0,0 +1,191 @@
+// config.go
+// Este arquivo centraliza a leitura das configurações da aplicação.
+// As configurações são lidas de variáveis de ambiente, com valores padrão adequados para desenvolvimento local.
+
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config reúne as configurações da aplicação.
+type Config struct {
+	// StripeBaseURL é a URL base da API do Stripe (ou de um servidor que a simule).
+	StripeBaseURL string
+	// StripeSecretKey é a chave secreta usada para autenticar na API do Stripe.
+	StripeSecretKey string
+	// StripeSettlementCurrencies são as moedas em que a conta Stripe liquida pagamentos; a primeira é a moeda padrão.
+	StripeSettlementCurrencies []string
+
+	// PayPalBaseURL é a URL base da API REST do PayPal (sandbox, produção ou um servidor que a simule).
+	PayPalBaseURL string
+	// PayPalClientID e PayPalClientSecret são as credenciais usadas no fluxo OAuth2 client credentials.
+	PayPalClientID     string
+	PayPalClientSecret string
+	// PayPalSettlementCurrencies são as moedas em que a conta PayPal liquida pagamentos; a primeira é a moeda padrão.
+	PayPalSettlementCurrencies []string
+
+	// PixPSPBaseURL é a URL base da API Pix do PSP (ou do servidor local que a simula).
+	PixPSPBaseURL string
+	// PixPSPToken é o token de acesso usado para autenticar na API Pix do PSP.
+	PixPSPToken string
+	// PixKey é a chave Pix do recebedor, para a qual as cobranças são criadas.
+	PixKey string
+	// PixMerchantName e PixMerchantCity identificam o recebedor no BR Code (até 25 e 15 caracteres, sem acentos).
+	PixMerchantName string
+	PixMerchantCity string
+	// PixExpiration é o tempo de validade das cobranças Pix; depois dele, a transação pendente falha.
+	PixExpiration time.Duration
+	// PixWebhookSecret é o segredo compartilhado com o PSP para assinar (HMAC-SHA256) as notificações do webhook.
+	// Vazio, as notificações são recusadas.
+	PixWebhookSecret string
+
+	// StorageDriver define onde os dados são armazenados: "memory" ou "sqlite".
+	StorageDriver string
+	// SQLitePath é o caminho do arquivo do banco SQLite, usado quando StorageDriver é "sqlite".
+	SQLitePath string
+
+	// IdempotencyRetention é por quanto tempo uma resposta associada a um Idempotency-Key é mantida.
+	IdempotencyRetention time.Duration
+
+	// FXQuoteTTL é o tempo de validade de uma cotação de câmbio com taxa travada.
+	FXQuoteTTL time.Duration
+	// FXRoundingMode é o modo de arredondamento dos valores convertidos entre moedas
+	// ("half_even", "half_up", "half_down", "up", "down", "ceiling" ou "floor").
+	FXRoundingMode string
+	// FXMarkupRulesFile é o caminho do arquivo JSON com as regras de markup das conversões de moeda; vazio, sem markup.
+	FXMarkupRulesFile string
+
+	// VaultKey é a chave AES-256 do cofre de cartões, em hexadecimal ou base64. Vazia, uma chave aleatória é gerada
+	// a cada inicialização e os tokens criados antes deixam de poder ser usados.
+	VaultKey string
+	// VaultCVVTTL é por quanto tempo o CVV informado na tokenização é mantido em memória para o primeiro pagamento.
+	VaultCVVTTL time.Duration
+
+	// RateProviders são os provedores de taxas de câmbio consultados em ordem de preferência, com failover
+	// ("erapi", "ecb" e "file").
+	RateProviders []string
+	// ERAPIBaseURL é a URL base da API open.er-api.com (ou de um servidor que a simule).
+	ERAPIBaseURL string
+	// ERAPITimeout é o tempo máximo de uma consulta à API open.er-api.com.
+	ERAPITimeout time.Duration
+	// ECBRatesURL é a URL do XML diário de taxas de referência do Banco Central Europeu.
+	ECBRatesURL string
+	// ECBTimeout é o tempo máximo de uma consulta ao XML do Banco Central Europeu.
+	ECBTimeout time.Duration
+	// RatesFile é o caminho de um arquivo de taxas em JSON ou CSV, usado pelo provedor "file".
+	RatesFile string
+
+	// RateCacheDriver é o armazenamento das tabelas de taxas em cache ("memory" ou "redis").
+	RateCacheDriver string
+	// RedisURL é a URL do Redis usado pelo cache de taxas "redis" (e.g. "redis://localhost:6379/0").
+	RedisURL string
+	// RateCacheTTL é por quanto tempo uma tabela de taxas é usada sem consultar os provedores.
+	RateCacheTTL time.Duration
+	// RateMaxStaleAge é até que idade uma tabela de taxas pode ser usada, marcada como desatualizada,
+	// enquanto os provedores não conseguem atualizá-la.
+	RateMaxStaleAge time.Duration
+	// RateBaseCurrency é a moeda base da tabela usada para calcular as taxas cruzadas entre quaisquer duas moedas.
+	RateBaseCurrency string
+	// RateRefreshBases são as moedas base atualizadas em segundo plano (padrão: RateBaseCurrency).
+	RateRefreshBases []string
+	// RateRefreshInterval é o intervalo entre as atualizações em segundo plano; zero desativa o atualizador.
+	RateRefreshInterval time.Duration
+}
+
+// Load lê as configurações das variáveis de ambiente.
+func Load() Config {
+	rateBaseCurrency := strings.ToUpper(getEnv("RATE_BASE_CURRENCY", "USD"))
+
+	return Config{
+		StripeBaseURL:              getEnv("STRIPE_BASE_URL", "https://api.stripe.com"),
+		StripeSecretKey:            getEnv("STRIPE_SECRET_KEY", ""),
+		StripeSettlementCurrencies: getEnvList("STRIPE_SETTLEMENT_CURRENCIES", []string{"USD", "EUR", "GBP", "BRL"}),
+
+		PayPalBaseURL:              getEnv("PAYPAL_BASE_URL", "https://api-m.sandbox.paypal.com"),
+		PayPalClientID:             getEnv("PAYPAL_CLIENT_ID", ""),
+		PayPalClientSecret:         getEnv("PAYPAL_CLIENT_SECRET", ""),
+		PayPalSettlementCurrencies: getEnvList("PAYPAL_SETTLEMENT_CURRENCIES", []string{"USD", "EUR", "GBP"}),
+
+		PixPSPBaseURL:    getEnv("PIX_PSP_BASE_URL", "http://localhost:8090"),
+		PixPSPToken:      getEnv("PIX_PSP_TOKEN", ""),
+		PixKey:           getEnv("PIX_KEY", ""),
+		PixMerchantName:  getEnv("PIX_MERCHANT_NAME", "Desafio Golang Payment"),
+		PixMerchantCity:  getEnv("PIX_MERCHANT_CITY", "Sao Paulo"),
+		PixExpiration:    getEnvDuration("PIX_EXPIRATION", 30*time.Minute),
+		PixWebhookSecret: getEnv("PIX_WEBHOOK_SECRET", ""),
+
+		StorageDriver: getEnv("STORAGE_DRIVER", "memory"),
+		SQLitePath:    getEnv("SQLITE_PATH", "payments.db"),
+
+		IdempotencyRetention: getEnvDuration("IDEMPOTENCY_RETENTION", 24*time.Hour),
+
+		FXQuoteTTL:        getEnvDuration("FX_QUOTE_TTL", 10*time.Minute),
+		FXRoundingMode:    getEnv("FX_ROUNDING_MODE", "half_even"),
+		FXMarkupRulesFile: getEnv("FX_MARKUP_RULES_FILE", ""),
+
+		VaultKey:    getEnv("VAULT_KEY", ""),
+		VaultCVVTTL: getEnvDuration("VAULT_CVV_TTL", 10*time.Minute),
+
+		RateProviders: getEnvList("RATE_PROVIDERS", []string{"erapi", "ecb"}),
+		ERAPIBaseURL:  getEnv("ERAPI_BASE_URL", "https://open.er-api.com"),
+		ERAPITimeout:  getEnvDuration("ERAPI_TIMEOUT", 5*time.Second),
+		ECBRatesURL:   getEnv("ECB_RATES_URL", "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"),
+		ECBTimeout:    getEnvDuration("ECB_TIMEOUT", 5*time.Second),
+		RatesFile:     getEnv("RATES_FILE", ""),
+
+		RateCacheDriver:     getEnv("RATE_CACHE_DRIVER", "memory"),
+		RedisURL:            getEnv("REDIS_URL", "redis://localhost:6379/0"),
+		RateCacheTTL:        getEnvDuration("RATE_CACHE_TTL", time.Hour),
+		RateMaxStaleAge:     getEnvDuration("RATE_MAX_STALE_AGE", 24*time.Hour),
+		RateBaseCurrency:    rateBaseCurrency,
+		RateRefreshBases:    getEnvList("RATE_REFRESH_BASES", []string{rateBaseCurrency}),
+		RateRefreshInterval: getEnvDuration("RATE_REFRESH_INTERVAL", 50*time.Minute),
+	}
+}
+
+// getEnv retorna o valor da variável de ambiente ou o valor padrão caso ela não esteja definida.
+func getEnv(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration lê uma duração (e.g. "24h", "30m") da variável de ambiente.
+// Valores inválidos são ignorados e o valor padrão é utilizado.
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using default %s\n", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getEnvList lê uma lista separada por vírgulas (e.g. "USD,EUR") da variável de ambiente.
+// Os itens são normalizados para letras maiúsculas e itens vazios são ignorados.
+func getEnvList(key string, defaultValue []string) []string {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToUpper(strings.TrimSpace(item)); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
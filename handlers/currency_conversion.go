@@ -3,16 +3,27 @@
 // Ele utiliza os pacotes services e models para realizar a conversão de moeda e validar dados de entrada.
 // As solicitações são recebidas como JSON, validadas e encaminhadas para o serviço apropriado para conversão.
 
-// O arquivo inclui uma função principal:
-// ConvertCurrency: Lida com solicitações de conversão de moeda, decodifica a solicitação JSON, valida os dados e encaminha para o serviço de conversão de moeda.
+// Códigos de moeda desconhecidos são rejeitados na validação (iso4217), antes de qualquer consulta aos provedores de taxas.
+
+// O arquivo inclui cinco funções principais:
+// 1. ConvertCurrency: Lida com solicitações de conversão de moeda, decodifica a solicitação JSON, valida os dados e encaminha para o serviço de conversão de moeda.
+// 2. ConvertCurrencyBatch: Converte um lote de valores, com o resultado ou o erro de cada item, em JSON ou em NDJSON (streaming).
+// 3. GetRateStatus: Retorna, por moeda base, a última atualização e o último erro das consultas aos provedores de taxas.
+// 4. ListCurrencies: Retorna o catálogo de moedas ISO 4217.
+// 5. GetRates: Retorna a tabela de taxas em cache de uma moeda base.
 
 package handlers
 
 import (
+	"bufio"
 	"desafiogolang-payment/models"
 	"desafiogolang-payment/services"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -21,22 +32,234 @@ import (
 func ConvertCurrency(w http.ResponseWriter, r *http.Request) {
 	var conversionRequest models.CurrencyConversionRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&conversionRequest); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&conversionRequest); err != nil {
+		writeDecodeError(w)
 		return
 	}
 
-	validate := validator.New()
 	if err := validate.Struct(conversionRequest); err != nil {
-		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		writeValidationError(w, err)
 		return
 	}
 
 	response, err := services.ConvertCurrency(conversionRequest)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, conversionProblem(err))
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+// conversionProblem traduz um erro da conversão de moeda para o status HTTP e o código de erro adequados.
+func conversionProblem(err error) models.Problem {
+	var precisionErr *models.AmountPrecisionError
+	switch {
+	case errors.As(err, &precisionErr):
+		return newProblem(http.StatusBadRequest, codeAmountPrecision, err.Error())
+	case errors.Is(err, models.ErrConvertedAmountOutOfRange):
+		// O valor informado é válido, mas o resultado não pode ser representado na moeda de destino
+		return newProblem(http.StatusUnprocessableEntity, codeAmountOutOfRange, err.Error())
+	case errors.Is(err, models.ErrAmountOutOfRange):
+		return newProblem(http.StatusBadRequest, codeAmountOutOfRange, err.Error())
+	case errors.Is(err, services.ErrInvalidRateDate):
+		return newProblem(http.StatusBadRequest, codeInvalidRateDate, err.Error())
+	case errors.Is(err, services.ErrAmountBelowFees):
+		return newProblem(http.StatusBadRequest, codeAmountBelowFees, err.Error())
+	case errors.Is(err, services.ErrRateHistoryNotFound):
+		return newProblem(http.StatusNotFound, codeRateHistoryNotFound, err.Error())
+	default:
+		return newProblem(http.StatusInternalServerError, codeConversionFailed, err.Error())
+	}
+}
+
+// ndjsonContentType é o tipo de conteúdo da saída em NDJSON: um objeto JSON por linha.
+const ndjsonContentType = "application/x-ndjson"
+
+// maxBatchItems é o número máximo de itens de um lote de conversões.
+const maxBatchItems = 1000
+
+// ConvertCurrencyBatch converte um lote de valores. O corpo é uma lista de solicitações de conversão
+// ou um único valor de origem com várias moedas de destino (models.BatchConversionSource).
+// Cada item tem seu próprio resultado ou erro (models.Problem); o lote só é rejeitado se o corpo não puder ser lido.
+// Com "Accept: application/x-ndjson", os itens são lidos e os resultados escritos um a um, à medida que são convertidos.
+// O corpo tem o mesmo limite de tamanho das demais solicitações, e lotes com mais de maxBatchItems itens retornam 413.
+func ConvertCurrencyBatch(w http.ResponseWriter, r *http.Request) {
+	body := bufio.NewReader(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder := json.NewDecoder(body)
+	next, err := batchItems(body, decoder)
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		writeValidationError(w, err)
+		return
+	}
+	if err != nil {
+		writeDecodeError(w)
+		return
+	}
+
+	stream := strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+	if stream {
+		w.Header().Set("Content-Type", ndjsonContentType)
+	}
+	flusher, _ := w.(http.Flusher)
+
+	converter := services.NewBatchConverter()
+	results := []models.BatchConversionResult{}
+	for index := 0; ; index++ {
+		request, ok, err := next()
+		if !ok {
+			break
+		}
+
+		if index == maxBatchItems {
+			problem := newProblem(http.StatusRequestEntityTooLarge, codeBatchTooLarge,
+				fmt.Sprintf("Batch exceeds %d items", maxBatchItems))
+			if !stream {
+				writeProblem(w, problem)
+				return
+			}
+			writeJSON(w, batchError(index, problem))
+			return
+		}
+
+		result := models.BatchConversionResult{Index: index, Status: http.StatusOK}
+		if unreadableBatch(err) {
+			// O restante do corpo não pode ser lido
+			if !stream {
+				writeDecodeError(w)
+				return
+			}
+			writeJSON(w, batchError(index, decodeProblem()))
+			return
+		}
+		if err != nil {
+			result = batchError(index, decodeProblem())
+		} else if err := validate.Struct(request); err != nil {
+			result = batchError(index, validationProblem(err))
+		} else if response, err := converter.Convert(request); err != nil {
+			result = batchError(index, conversionProblem(err))
+		} else {
+			result.CurrencyConversionResponse = &response
+		}
+
+		if !stream {
+			results = append(results, result)
+			continue
+		}
+		writeJSON(w, result)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if !stream {
+		writeJSON(w, models.BatchConversionResponse{Results: results})
+	}
+}
+
+// unreadableBatch informa se o erro impede a leitura do restante do lote: JSON malformado ou truncado,
+// ou um corpo maior que o limite.
+func unreadableBatch(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &maxBytesErr)
+}
+
+// batchError retorna o resultado de um item do lote que falhou.
+func batchError(index int, problem models.Problem) models.BatchConversionResult {
+	return models.BatchConversionResult{Index: index, Status: problem.Status, Error: &problem}
+}
+
+// errInvalidBatch indica que o corpo do lote não pode ser lido.
+var errInvalidBatch = errors.New("invalid batch body")
+
+// batchItems identifica o formato do corpo do lote e retorna uma função que lê o próximo item.
+// A função retorna ok = false quando não há mais itens. Um único valor de origem inválido resulta no erro de validação.
+func batchItems(body *bufio.Reader, decoder *json.Decoder) (func() (models.CurrencyConversionRequest, bool, error), error) {
+	first, err := firstNonSpace(body)
+	if err != nil {
+		return nil, errInvalidBatch
+	}
+
+	switch first {
+	case '{':
+		// Um único valor de origem com várias moedas de destino
+		var source models.BatchConversionSource
+		if err := decoder.Decode(&source); err != nil {
+			return nil, errInvalidBatch
+		}
+		if err := validate.Struct(source); err != nil {
+			return nil, err
+		}
+		requests := source.Requests()
+		return func() (models.CurrencyConversionRequest, bool, error) {
+			if len(requests) == 0 {
+				return models.CurrencyConversionRequest{}, false, nil
+			}
+			request := requests[0]
+			requests = requests[1:]
+			return request, true, nil
+		}, nil
+	case '[':
+		// Uma lista de solicitações, lida item a item
+		if _, err := decoder.Token(); err != nil {
+			return nil, errInvalidBatch
+		}
+		done := false
+		return func() (models.CurrencyConversionRequest, bool, error) {
+			if done || !decoder.More() {
+				return models.CurrencyConversionRequest{}, false, nil
+			}
+			var request models.CurrencyConversionRequest
+			err := decoder.Decode(&request)
+			if unreadableBatch(err) {
+				done = true
+			}
+			return request, true, err
+		}, nil
+	default:
+		return nil, errInvalidBatch
+	}
+}
+
+// firstNonSpace retorna o primeiro caractere do corpo que não é espaço em branco, sem consumi-lo.
+func firstNonSpace(body *bufio.Reader) (byte, error) {
+	for {
+		b, err := body.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, body.UnreadByte()
+		}
+	}
+}
+
+// GetRateStatus retorna o status das tabelas de taxas de câmbio de cada moeda base.
+func GetRateStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, services.RateStatuses())
+}
+
+// ListCurrencies retorna o catálogo de moedas aceitas em /convert-currency, com as casas decimais
+// e se cada moeda pode ser usada em pagamentos.
+func ListCurrencies(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, models.Currencies())
+}
+
+// GetRates retorna a tabela de taxas da moeda base informada no parâmetro "base" (padrão: a moeda base configurada).
+func GetRates(w http.ResponseWriter, r *http.Request) {
+	base := r.URL.Query().Get("base")
+	if base != "" && validate.Var(base, "iso4217") != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidCurrency, "Invalid currency code")
+		return
+	}
+
+	rates, err := services.LatestRates(base)
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, rates)
 }
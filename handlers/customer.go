@@ -0,0 +1,154 @@
+// customer.go
+// Este arquivo contém os handlers do cadastro de clientes e dos seus métodos de pagamento salvos.
+// Os cartões são salvos pelo token do cofre (POST /tokens) e os acordos de cobrança do PayPal pelo ID do acordo;
+// nos pagamentos, customer_id e payment_method_id substituem os dados do cartão.
+
+// O arquivo inclui:
+// 1. CreateCustomer / ListCustomers / GetCustomer / UpdateCustomer / DeleteCustomer: /customers e /customers/{id}.
+// 2. CreatePaymentMethod / ListPaymentMethods: /customers/{id}/payment-methods.
+// 3. GetPaymentMethod / DeletePaymentMethod: /customers/{id}/payment-methods/{payment_method_id}.
+
+package handlers
+
+import (
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateCustomer lida com solicitações de cadastro de clientes.
+func CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var customerRequest models.CustomerRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&customerRequest); err != nil {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(customerRequest); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	customer, err := services.CreateCustomer(customerRequest)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, customer)
+}
+
+// ListCustomers lida com solicitações de listagem dos clientes cadastrados.
+func ListCustomers(w http.ResponseWriter, r *http.Request) {
+	list, err := services.ListCustomers()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, list)
+}
+
+// GetCustomer lida com solicitações de consulta de um cliente.
+func GetCustomer(w http.ResponseWriter, r *http.Request) {
+	customer, err := services.GetCustomer(mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, customer)
+}
+
+// UpdateCustomer lida com solicitações de alteração de um cliente, incluindo a escolha do método de pagamento padrão.
+func UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	var customerRequest models.CustomerRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&customerRequest); err != nil {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(customerRequest); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	customer, err := services.UpdateCustomer(mux.Vars(r)["id"], customerRequest)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, customer)
+}
+
+// DeleteCustomer lida com solicitações de remoção de um cliente e dos seus métodos de pagamento salvos.
+func DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	if err := services.DeleteCustomer(mux.Vars(r)["id"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreatePaymentMethod lida com solicitações de cadastro de um método de pagamento do cliente.
+func CreatePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	var methodRequest models.PaymentMethodRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&methodRequest); err != nil {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(methodRequest); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	method, err := services.AddPaymentMethod(mux.Vars(r)["id"], methodRequest)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, method)
+}
+
+// ListPaymentMethods lida com solicitações de listagem dos métodos de pagamento salvos do cliente.
+func ListPaymentMethods(w http.ResponseWriter, r *http.Request) {
+	list, err := services.ListPaymentMethods(mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, list)
+}
+
+// GetPaymentMethod lida com solicitações de consulta de um método de pagamento salvo do cliente.
+func GetPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	method, err := services.GetPaymentMethod(vars["id"], vars["payment_method_id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, method)
+}
+
+// DeletePaymentMethod lida com solicitações de remoção de um método de pagamento salvo do cliente.
+func DeletePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := services.DeletePaymentMethod(vars["id"], vars["payment_method_id"]); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,53 @@
+// fx_quote.go
+// Este arquivo contém os handlers das cotações de câmbio com taxa travada.
+// A cotação retorna um quote_id, a taxa travada e a data de expiração; o quote_id pode ser informado
+// em /process-payment para que o pagamento use exatamente essa taxa.
+
+// O arquivo inclui:
+// 1. CreateFXQuote: POST /fx/quotes.
+// 2. GetFXQuote: GET /fx/quotes/{id}.
+
+package handlers
+
+import (
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateFXQuote lida com solicitações de cotação de câmbio.
+func CreateFXQuote(w http.ResponseWriter, r *http.Request) {
+	var quoteRequest models.FXQuoteRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&quoteRequest); err != nil {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(quoteRequest); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	quote, err := services.CreateFXQuote(quoteRequest)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, quote)
+}
+
+// GetFXQuote lida com solicitações de consulta de uma cotação de câmbio.
+func GetFXQuote(w http.ResponseWriter, r *http.Request) {
+	quote, err := services.GetFXQuote(mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, quote)
+}
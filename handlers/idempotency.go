@@ -0,0 +1,226 @@
+// idempotency.go
+// Este arquivo implementa o suporte ao cabeçalho Idempotency-Key, evitando cobranças duplicadas quando o cliente
+// repete uma requisição (e.g. após um timeout).
+
+// Funcionamento:
+// 1. A primeira requisição com uma chave é executada e sua resposta (status e corpo) é armazenada junto a um fingerprint do corpo.
+// 2. Repetições com a mesma chave e o mesmo corpo recebem a resposta armazenada, sem executar o handler novamente.
+// 3. Uma requisição com a mesma chave e um corpo diferente é rejeitada com 422.
+// 4. Requisições simultâneas com a mesma chave aguardam a conclusão da primeira em vez de executar em paralelo.
+// Respostas com erro de servidor (5xx) não são armazenadas, permitindo que o cliente tente novamente, exceto quando
+// o gateway já processou o pagamento e apenas o registro da transação falhou (retainResponse): nesse caso, repetir
+// a requisição cobraria o cliente novamente, então a resposta de erro é reenviada.
+// O corpo da requisição é limitado a maxRequestBodySize, como nos demais handlers.
+// As chaves expiram após o período de retenção configurado (IDEMPOTENCY_RETENTION).
+
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxIdempotencyKeyLength é o tamanho máximo aceito para o cabeçalho Idempotency-Key.
+const maxIdempotencyKeyLength = 255
+
+// idempotencyEntry guarda o resultado de uma requisição associada a uma chave.
+type idempotencyEntry struct {
+	fingerprint string
+	done        chan struct{}
+	completed   bool
+	status      int
+	header      http.Header
+	body        []byte
+	expiresAt   time.Time
+}
+
+// IdempotencyStore armazena em memória as respostas associadas às chaves de idempotência.
+type IdempotencyStore struct {
+	retention time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	lastSweep time.Time
+}
+
+// NewIdempotencyStore cria um armazenamento que mantém as respostas pelo período de retenção informado.
+func NewIdempotencyStore(retention time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		retention: retention,
+		entries:   make(map[string]*idempotencyEntry),
+	}
+}
+
+// Middleware aplica o controle de idempotência ao handler informado.
+// Requisições sem o cabeçalho Idempotency-Key são encaminhadas normalmente.
+func (s *IdempotencyStore) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeError(w, http.StatusBadRequest, codeIdempotencyKeyTooLong, "Idempotency-Key is too long")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+		if err != nil {
+			writeDecodeError(w)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// A chave é válida apenas para o mesmo método e caminho
+		scopedKey := r.Method + " " + r.URL.Path + " " + key
+		fingerprint := fingerprintOf(body)
+
+		for {
+			entry, owner := s.acquire(scopedKey, fingerprint)
+			if entry.fingerprint != fingerprint {
+				writeError(w, http.StatusUnprocessableEntity, codeIdempotencyKeyReused, "Idempotency-Key was already used with a different request body")
+				return
+			}
+
+			if owner {
+				s.execute(scopedKey, entry, next, w, r)
+				return
+			}
+
+			// Aguarda a requisição em andamento com a mesma chave
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
+			}
+
+			if entry.completed {
+				replay(w, entry)
+				return
+			}
+			// A requisição original falhou sem armazenar resposta; tenta novamente como dona da chave
+		}
+	}
+}
+
+// acquire retorna a entrada associada à chave, criando-a caso não exista.
+// O segundo valor indica se a requisição atual é a responsável por executar o handler.
+func (s *IdempotencyStore) acquire(key, fingerprint string) (*idempotencyEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if entry, exists := s.entries[key]; exists {
+		if !entry.completed || now.Before(entry.expiresAt) {
+			return entry, false
+		}
+		delete(s.entries, key)
+	}
+
+	entry := &idempotencyEntry{fingerprint: fingerprint, done: make(chan struct{})}
+	s.entries[key] = entry
+	return entry, true
+}
+
+// execute executa o handler, envia a resposta ao cliente e a armazena na entrada.
+func (s *IdempotencyStore) execute(key string, entry *idempotencyEntry, next http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	recorder := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+	finished := false
+
+	defer func() {
+		s.mu.Lock()
+		// Respostas 5xx e handlers interrompidos por panic não são armazenados, exceto as marcadas por retainResponse
+		if !finished || (recorder.status >= 500 && !recorder.retain) {
+			delete(s.entries, key)
+		} else {
+			entry.status = recorder.status
+			entry.header = recorder.header
+			entry.body = recorder.body.Bytes()
+			entry.expiresAt = time.Now().Add(s.retention)
+			entry.completed = true
+		}
+		s.mu.Unlock()
+		close(entry.done)
+	}()
+
+	next(recorder, r)
+	finished = true
+
+	for name, values := range recorder.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(recorder.status)
+	w.Write(recorder.body.Bytes())
+}
+
+// sweep remove as entradas expiradas, no máximo uma vez por minuto. Deve ser chamado com s.mu travado.
+func (s *IdempotencyStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, entry := range s.entries {
+		if entry.completed && now.After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// retainResponse indica que a resposta deve ser armazenada mesmo sendo um erro de servidor,
+// pois a operação já produziu efeitos no gateway e não pode ser executada novamente.
+// Não tem efeito fora do controle de idempotência.
+func retainResponse(w http.ResponseWriter) {
+	if recorder, ok := w.(*responseRecorder); ok {
+		recorder.retain = true
+	}
+}
+
+// replay envia ao cliente a resposta armazenada.
+func replay(w http.ResponseWriter, entry *idempotencyEntry) {
+	for name, values := range entry.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+}
+
+// fingerprintOf calcula o fingerprint do corpo da requisição.
+func fingerprintOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseRecorder captura a resposta do handler para que ela possa ser armazenada.
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	retain      bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(data)
+}
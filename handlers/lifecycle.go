@@ -0,0 +1,78 @@
+// lifecycle.go
+// Este arquivo contém os handlers do fluxo de autorização e captura em etapas.
+// Um pagamento autorizado apenas reserva o valor no meio de pagamento; ele é capturado ou cancelado (void) depois.
+// As transições de status são validadas pela máquina de estados: uma transição inválida retorna 409 Conflict.
+
+// O arquivo inclui:
+// 1. AuthorizePayment: POST /payments/authorize, recebe o mesmo corpo de /process-payment.
+// 2. CapturePayment: POST /payments/{id}/capture, com corpo opcional {"amount": ...} para captura parcial.
+// 3. VoidPayment: POST /payments/{id}/void.
+
+package handlers
+
+import (
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// AuthorizePayment lida com solicitações de autorização de pagamento.
+func AuthorizePayment(w http.ResponseWriter, r *http.Request) {
+	var paymentRequest models.PaymentRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&paymentRequest); err != nil {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(paymentRequest); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	response, err := services.AuthorizePayment(paymentRequest)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+// CapturePayment lida com solicitações de captura de um pagamento autorizado.
+// Sem corpo (ou sem amount), o valor total autorizado é capturado.
+func CapturePayment(w http.ResponseWriter, r *http.Request) {
+	var captureRequest models.CaptureRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&captureRequest); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(captureRequest); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	response, err := services.CapturePayment(mux.Vars(r)["id"], captureRequest.Amount)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+// VoidPayment lida com solicitações de cancelamento de um pagamento autorizado.
+func VoidPayment(w http.ResponseWriter, r *http.Request) {
+	response, err := services.VoidPayment(mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, response)
+}
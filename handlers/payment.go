@@ -6,23 +6,76 @@
 // O arquivo inclui duas funções principais:
 // 1. ProcessPayment: Lida com solicitações de pagamento, decodifica a solicitação JSON, valida os dados e encaminha para o gateway de pagamento especificado.
 // 2. GetPaymentStatus: Lida com solicitações para verificar o status de uma transação com base no ID da transação e no gateway de pagamento fornecido.
+// Os gateways são obtidos do registro do pacote services, portanto adicionar um gateway não exige alterações neste arquivo.
+// As transações são registradas e consultadas pelo repositório de transações configurado no pacote services.
 
 package handlers
 
 import (
 	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
 	"desafiogolang-payment/services" // Importando o pacote services
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// maxRequestBodySize é o tamanho máximo aceito para o corpo das requisições JSON.
+// O lote de conversões (ConvertCurrencyBatch), lido item a item, não é limitado.
+const maxRequestBodySize = 1 << 20
+
 // Inicializa uma instância do validador
 var validate *validator.Validate
 
 func init() {
 	validate = validator.New()
+	// Os campos inválidos são identificados pelo nome JSON nas respostas de erro
+	validate.RegisterTagNameFunc(jsonFieldName)
+	// Os valores decimais são validados pelo seu valor numérico (e.g. gt=0)
+	validate.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if amount, ok := field.Interface().(models.Decimal); ok {
+			return amount.Float64()
+		}
+		return nil
+	}, models.Decimal{})
+	// Moedas ISO 4217 que não representam moeda corrente (fundos, metais preciosos) não são aceitas em pagamentos
+	validate.RegisterValidation("payment_currency", func(fl validator.FieldLevel) bool {
+		currency, ok := models.LookupCurrency(fl.Field().String())
+		return ok && currency.SupportedForPayment
+	})
+	// Cartões: bandeira aceita, tamanho do número e do CVV da bandeira e validade (MM/YY) ainda não vencida
+	validate.RegisterValidation("card_brand", func(fl validator.FieldLevel) bool {
+		return models.DetectCardBrand(fl.Field().String()) != ""
+	})
+	validate.RegisterValidation("card_length", func(fl validator.FieldLevel) bool {
+		return models.ValidCardLength(fl.Field().String())
+	})
+	validate.RegisterValidation("card_expiry", func(fl validator.FieldLevel) bool {
+		_, _, err := models.ParseCardExpiry(fl.Field().String())
+		return err == nil
+	})
+	validate.RegisterValidation("not_expired", func(fl validator.FieldLevel) bool {
+		return !models.CardExpired(fl.Field().String(), time.Now())
+	})
+	validate.RegisterStructValidation(func(sl validator.StructLevel) {
+		card := sl.Current().Interface().(models.CardDetails)
+		if length := card.Brand().CVVLength(); length > 0 && card.CVV != "" && len(card.CVV) != length {
+			sl.ReportError(card.CVV, "cvv", "CVV", "len", strconv.Itoa(length))
+		}
+	}, models.CardDetails{})
+	// Exceto no Pix, o pagamento precisa do cartão: em card_details, no cofre (card_token) ou salvo pelo cliente
+	validate.RegisterStructValidation(func(sl validator.StructLevel) {
+		request := sl.Current().Interface().(models.PaymentRequest)
+		if request.PaymentMethod != models.PaymentMethodPix && request.CardDetails == nil && request.CardToken == "" && request.CustomerID == "" {
+			sl.ReportError(request.CardDetails, "card_details", "CardDetails", "required_without_all", "card_token customer_id")
+		}
+	}, models.PaymentRequest{})
 }
 
 // ProcessPayment lida com solicitações de pagamento, decodificando a solicitação JSON,
@@ -31,33 +84,26 @@ func ProcessPayment(w http.ResponseWriter, r *http.Request) {
 	var paymentRequest models.PaymentRequest
 
 	// Decodifica o corpo da solicitação JSON em uma estrutura PaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&paymentRequest); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&paymentRequest); err != nil {
+		writeDecodeError(w)
 		return
 	}
 
 	// Valida a estrutura paymentRequest
 	if err := validate.Struct(paymentRequest); err != nil {
-		http.Error(w, "Invalid request data", http.StatusBadRequest)
+		writeValidationError(w, err)
 		return
 	}
 
-	// Processa o pagamento com base no gateway especificado
-	switch paymentRequest.Gateway {
-	case "PayPal":
-		// Chama a função processPayPalPayment do pacote services para processar o pagamento
-		response := services.ProcessPayPalPayment(paymentRequest)
-		// Codifica a resposta em JSON e envia de volta ao cliente
-		json.NewEncoder(w).Encode(response)
-	case "Stripe":
-		// Chama a função processPayPalPayment do pacote services para processar o pagamento
-		response := services.ProcessStripePayment(paymentRequest)
-		// Codifica a resposta em JSON e envia de volta ao cliente
-		json.NewEncoder(w).Encode(response)
-	default:
-		// Retorna um erro se o gateway não for suportado
-		http.Error(w, "Unsupported gateway", http.StatusBadRequest)
+	// Processa o pagamento no gateway especificado e registra a transação
+	response, err := services.ProcessPayment(paymentRequest)
+	if err != nil {
+		writeServiceError(w, err)
+		return
 	}
+
+	// Codifica a resposta em JSON e envia de volta ao cliente
+	writeJSON(w, response)
 }
 
 // GetPaymentStatus lida com solicitações para verificar o status de uma transação
@@ -65,17 +111,112 @@ func GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
 	transactionID := r.URL.Query().Get("transaction_id")
 	payment_gateway := r.URL.Query().Get("gateway")
 	if transactionID == "" {
-		http.Error(w, "Transaction ID is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeTransactionIDRequired, "Transaction ID is required")
 		return
 	}
-	switch payment_gateway {
-	case "PayPal":
-		// Obtém o status da transação do serviço
-		response := services.GetPayPalPaymentStatus(transactionID)
-		// Codifica a resposta em JSON e envia de volta ao cliente
-		json.NewEncoder(w).Encode(response)
-	default:
+
+	// Obtém o status da transação registrada
+	response, err := services.GetPaymentStatus(payment_gateway, transactionID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	// Codifica a resposta em JSON e envia de volta ao cliente
+	writeJSON(w, response)
+}
+
+// writeServiceError escreve a resposta de erro correspondente a um erro retornado pelos serviços.
+func writeServiceError(w http.ResponseWriter, err error) {
+	var notRecordedErr *services.TransactionNotRecordedError
+	if errors.As(err, &notRecordedErr) {
+		// O gateway já processou o pagamento: repetir a requisição com o mesmo Idempotency-Key não pode cobrar novamente
+		retainResponse(w)
+	}
+	writeProblem(w, serviceProblem(err))
+}
+
+// serviceProblem traduz um erro retornado pelos serviços para o status HTTP e o código de erro adequados.
+func serviceProblem(err error) models.Problem {
+	var unsupportedErr *services.UnsupportedGatewayError
+	var unsupportedMethodErr *services.UnsupportedPaymentMethodError
+	var gatewayErr *models.GatewayError
+	var transitionErr *models.InvalidTransitionError
+	var quoteMismatchErr *services.FXQuoteMismatchError
+	var precisionErr *models.AmountPrecisionError
+	var notRecordedErr *services.TransactionNotRecordedError
+
+	switch {
+	case errors.As(err, &notRecordedErr):
+		// O erro original do registro não é exposto como erro de transição ou de repositório
+		return newProblem(http.StatusInternalServerError, codeTransactionNotRecorded, notRecordedErr.Error())
+	case errors.As(err, &unsupportedErr):
 		// Retorna um erro se o gateway não for suportado
-		http.Error(w, "Unsupported gateway", http.StatusBadRequest)
+		return newProblem(http.StatusBadRequest, codeUnsupportedGateway, unsupportedErr.Error())
+	case errors.As(err, &unsupportedMethodErr):
+		return newProblem(http.StatusBadRequest, codeUnsupportedPaymentMethod, unsupportedMethodErr.Error())
+	case errors.Is(err, services.ErrRefundNotFound):
+		return newProblem(http.StatusNotFound, codeRefundNotFound, "Refund ID not found")
+	case errors.Is(err, services.ErrFXQuoteNotFound):
+		return newProblem(http.StatusNotFound, codeQuoteNotFound, "Quote ID not found")
+	case errors.Is(err, services.ErrCardTokenNotFound):
+		return newProblem(http.StatusNotFound, codeCardTokenNotFound, "Card token not found")
+	case errors.Is(err, services.ErrCustomerNotFound):
+		return newProblem(http.StatusNotFound, codeCustomerNotFound, "Customer ID not found")
+	case errors.Is(err, services.ErrPaymentMethodNotFound):
+		return newProblem(http.StatusNotFound, codePaymentMethodNotFound, "Payment method ID not found")
+	case errors.Is(err, services.ErrCardExpired):
+		return newProblem(http.StatusUnprocessableEntity, codeCardExpired, err.Error())
+	case errors.Is(err, services.ErrNoDefaultPaymentMethod):
+		return newProblem(http.StatusUnprocessableEntity, codeNoDefaultPaymentMethod, err.Error())
+	case errors.Is(err, services.ErrPixAmountMismatch):
+		return newProblem(http.StatusUnprocessableEntity, codePixAmountMismatch, err.Error())
+	case errors.Is(err, services.ErrFXQuoteAlreadyUsed):
+		return newProblem(http.StatusConflict, codeQuoteAlreadyUsed, err.Error())
+	case errors.Is(err, services.ErrFXQuoteExpired):
+		return newProblem(http.StatusUnprocessableEntity, codeQuoteExpired, err.Error())
+	case errors.As(err, &quoteMismatchErr):
+		return newProblem(http.StatusUnprocessableEntity, codeQuoteMismatch, err.Error())
+	case errors.Is(err, repository.ErrNotFound):
+		return newProblem(http.StatusNotFound, codeTransactionNotFound, "Transaction ID not found")
+	case errors.As(err, &transitionErr):
+		// A operação não é permitida no status atual da transação
+		return newProblem(http.StatusConflict, codeInvalidTransition, transitionErr.Error())
+	case errors.Is(err, services.ErrOperationNotSupported):
+		return newProblem(http.StatusBadRequest, codeOperationNotSupported, err.Error())
+	case errors.Is(err, services.ErrCaptureAmountExceeded):
+		return newProblem(http.StatusBadRequest, codeCaptureAmountExceeded, err.Error())
+	case errors.Is(err, services.ErrRefundAmountExceeded):
+		return newProblem(http.StatusBadRequest, codeRefundAmountExceeded, err.Error())
+	case errors.As(err, &precisionErr):
+		return newProblem(http.StatusBadRequest, codeAmountPrecision, err.Error())
+	case errors.Is(err, models.ErrConvertedAmountOutOfRange):
+		// O valor informado é válido, mas o resultado não pode ser representado na moeda de liquidação
+		return newProblem(http.StatusUnprocessableEntity, codeAmountOutOfRange, err.Error())
+	case errors.Is(err, models.ErrAmountOutOfRange):
+		return newProblem(http.StatusBadRequest, codeAmountOutOfRange, err.Error())
+	case errors.As(err, &gatewayErr):
+		return gatewayProblem(gatewayErr)
+	case errors.Is(err, services.ErrExchangeRateUnavailable):
+		return newProblem(http.StatusBadGateway, codeExchangeRateUnavailable, err.Error())
+	default:
+		// O texto dos erros internos (repositório, banco de dados) fica apenas no log
+		slog.Error("internal error", "error", err)
+		return newProblem(http.StatusInternalServerError, codeInternalError, "Internal error")
+	}
+}
+
+// gatewayProblem traduz um erro retornado pelo gateway para o status HTTP adequado.
+// O código do erro é o tipo do erro de gateway (e.g. card_error), comum a todos os gateways.
+func gatewayProblem(gatewayErr *models.GatewayError) models.Problem {
+	switch gatewayErr.Type {
+	case models.GatewayErrorCard:
+		return newProblem(http.StatusPaymentRequired, gatewayErr.Type, gatewayErr.Error())
+	case models.GatewayErrorInvalidRequest:
+		return newProblem(http.StatusBadRequest, gatewayErr.Type, gatewayErr.Error())
+	case models.GatewayErrorNotFound:
+		return newProblem(http.StatusNotFound, gatewayErr.Type, gatewayErr.Error())
+	default:
+		return newProblem(http.StatusBadGateway, gatewayErr.Type, gatewayErr.Error())
 	}
 }
@@ -0,0 +1,58 @@
+// pix.go
+// Este arquivo contém o webhook que recebe do PSP as notificações de pagamentos Pix.
+// O PSP assina o corpo da notificação com HMAC-SHA256, usando o segredo compartilhado (PIX_WEBHOOK_SECRET),
+// e envia a assinatura em hexadecimal no cabeçalho X-Pix-Signature. Notificações sem assinatura válida são recusadas.
+// Cada Pix recebido conclui a transação pendente da cobrança (txid); respostas de erro fazem o PSP reenviar a notificação.
+// Por isso um Pix pago depois da expiração é confirmado com 200, levando a transação para requires_review.
+
+// O arquivo inclui:
+// 1. PixWebhook: POST /webhooks/pix.
+
+package handlers
+
+import (
+	"bytes"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// pixSignatureHeader é o cabeçalho com a assinatura da notificação.
+const pixSignatureHeader = "X-Pix-Signature"
+
+// PixWebhook lida com as notificações de pagamentos Pix recebidos, enviadas pelo PSP.
+func PixWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		writeDecodeError(w)
+		return
+	}
+	if err := services.VerifyPixWebhookSignature(body, r.Header.Get(pixSignatureHeader)); err != nil {
+		writeError(w, http.StatusUnauthorized, codeInvalidSignature, err.Error())
+		return
+	}
+
+	var notification models.PixNotification
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&notification); err != nil {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(notification); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	confirmed := make([]models.TransactionResponse, 0, len(notification.Pix))
+	for _, payment := range notification.Pix {
+		response, err := services.ConfirmPixPayment(payment)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		confirmed = append(confirmed, response)
+	}
+
+	writeJSON(w, confirmed)
+}
@@ -0,0 +1,143 @@
+// problem.go
+// Este arquivo contém as funções que escrevem as respostas de erro da API no formato RFC 7807 (application/problem+json).
+// Todos os handlers respondem erros com um models.Problem, com o status HTTP, uma descrição legível (detail)
+// e um código estável (code) que os clientes podem tratar sem depender do texto da descrição.
+
+// Nos erros de validação, cada campo inválido é informado pelo seu caminho JSON e pela regra que falhou
+// (e.g. card_details.cvv: len=3). Os nomes dos campos vêm da tag json, registrada no validador em payment.go.
+
+package handlers
+
+import (
+	"desafiogolang-payment/models"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// problemContentType é o tipo de conteúdo das respostas de erro.
+const problemContentType = "application/problem+json"
+
+// Códigos estáveis dos erros retornados no campo code.
+const (
+	codeInvalidBody              = "invalid_body"
+	codeValidationFailed         = "validation_failed"
+	codeTransactionIDRequired    = "transaction_id_required"
+	codeInvalidCurrency          = "invalid_currency"
+	codeUnsupportedGateway       = "unsupported_gateway"
+	codeUnsupportedPaymentMethod = "unsupported_payment_method"
+	codeTransactionNotFound      = "transaction_not_found"
+	codeRefundNotFound           = "refund_not_found"
+	codeQuoteNotFound            = "quote_not_found"
+	codeCardTokenNotFound        = "card_token_not_found"
+	codeCardExpired              = "card_expired"
+	codeCustomerNotFound         = "customer_not_found"
+	codePaymentMethodNotFound    = "payment_method_not_found"
+	codeNoDefaultPaymentMethod   = "no_default_payment_method"
+	codeInvalidSignature         = "invalid_signature"
+	codePixAmountMismatch        = "pix_amount_mismatch"
+	codeQuoteAlreadyUsed         = "quote_already_used"
+	codeQuoteExpired             = "quote_expired"
+	codeQuoteMismatch            = "quote_mismatch"
+	codeInvalidTransition        = "invalid_transition"
+	codeOperationNotSupported    = "operation_not_supported"
+	codeCaptureAmountExceeded    = "capture_amount_exceeded"
+	codeRefundAmountExceeded     = "refund_amount_exceeded"
+	codeAmountPrecision          = "amount_precision"
+	codeAmountOutOfRange         = "amount_out_of_range"
+	codeAmountBelowFees          = "amount_below_fees"
+	codeInvalidRateDate          = "invalid_rate_date"
+	codeRateHistoryNotFound      = "rate_history_not_found"
+	codeExchangeRateUnavailable  = "exchange_rate_unavailable"
+	codeTransactionNotRecorded   = "transaction_not_recorded"
+	codeConversionFailed         = "conversion_failed"
+	codeBatchTooLarge            = "batch_too_large"
+	codeIdempotencyKeyTooLong    = "idempotency_key_too_long"
+	codeIdempotencyKeyReused     = "idempotency_key_reused"
+	codeInternalError            = "internal_error"
+)
+
+// newProblem cria uma resposta de erro com o status, o código e a descrição informados.
+func newProblem(status int, code, detail string) models.Problem {
+	return models.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// writeProblem escreve a resposta de erro em application/problem+json.
+func writeProblem(w http.ResponseWriter, problem models.Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	writeJSON(w, problem)
+}
+
+// writeError escreve uma resposta de erro com o status, o código e a descrição informados.
+func writeError(w http.ResponseWriter, status int, code, detail string) {
+	writeProblem(w, newProblem(status, code, detail))
+}
+
+// writeDecodeError escreve a resposta de um corpo que não pôde ser lido como JSON.
+func writeDecodeError(w http.ResponseWriter) {
+	writeProblem(w, decodeProblem())
+}
+
+// writeValidationError escreve a resposta de uma requisição que falhou na validação.
+func writeValidationError(w http.ResponseWriter, err error) {
+	writeProblem(w, validationProblem(err))
+}
+
+// decodeProblem retorna o erro de um corpo que não pôde ser lido como JSON.
+func decodeProblem() models.Problem {
+	return newProblem(http.StatusBadRequest, codeInvalidBody, "Invalid request")
+}
+
+// validationProblem retorna o erro de validação com a lista de campos inválidos.
+// A descrição inclui os campos no formato "card_details.cvv: len=3".
+func validationProblem(err error) models.Problem {
+	problem := newProblem(http.StatusBadRequest, codeValidationFailed, "Invalid request data")
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return problem
+	}
+	fields := make([]string, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		field := models.FieldError{Field: fieldPath(fieldErr), Rule: fieldErr.Tag()}
+		if fieldErr.Param() != "" {
+			field.Rule += "=" + fieldErr.Param()
+		}
+		problem.Errors = append(problem.Errors, field)
+		fields = append(fields, field.String())
+	}
+	problem.Detail += ": " + strings.Join(fields, ", ")
+	return problem
+}
+
+// fieldPath retorna o caminho JSON do campo, sem o nome da estrutura validada (e.g. card_details.cvv).
+func fieldPath(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+// jsonFieldName retorna o nome do campo na tag json, usado pelo validador nos caminhos dos campos inválidos.
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,68 @@
+// refund.go
+// Este arquivo contém os handlers de reembolso das transações.
+// O reembolso é encaminhado ao gateway que processou a transação; cada reembolso recebe um ID próprio para consulta.
+
+// O arquivo inclui:
+// 1. RefundPayment: POST /payments/{id}/refunds, com corpo opcional {"amount": ..., "reason": ...} para reembolso parcial.
+// 2. ListRefunds: GET /payments/{id}/refunds.
+// 3. GetRefund: GET /payments/{id}/refunds/{refund_id}.
+
+package handlers
+
+import (
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// RefundPayment lida com solicitações de reembolso total ou parcial de uma transação.
+// Sem corpo (ou sem amount), todo o saldo ainda não reembolsado é reembolsado.
+func RefundPayment(w http.ResponseWriter, r *http.Request) {
+	var refundRequest models.RefundRequest
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&refundRequest); err != nil && !errors.Is(err, io.EOF) {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(refundRequest); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	response, err := services.RefundPayment(mux.Vars(r)["id"], refundRequest)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, response)
+}
+
+// ListRefunds lida com solicitações de listagem dos reembolsos de uma transação.
+func ListRefunds(w http.ResponseWriter, r *http.Request) {
+	response, err := services.ListRefunds(mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, response)
+}
+
+// GetRefund lida com solicitações de consulta de um reembolso.
+func GetRefund(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	response, err := services.GetRefund(vars["id"], vars["refund_id"])
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, response)
+}
@@ -0,0 +1,23 @@
+// response.go
+// Este arquivo contém a escrita das respostas JSON da API.
+// Todas as respostas passam por models.RedactJSON, de modo que um número de cartão completo ou um CVV nunca
+// é retornado, mesmo que chegue a uma resposta por uma mensagem de erro de um gateway ou por um campo de texto livre.
+
+package handlers
+
+import (
+	"desafiogolang-payment/models"
+	"encoding/json"
+	"net/http"
+)
+
+// writeJSON escreve o valor em JSON com os dados de cartão mascarados.
+// O status, quando diferente de 200, deve ser escrito antes pelo handler.
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternalError, err.Error())
+		return
+	}
+	w.Write(append(models.RedactJSON(data), '\n'))
+}
@@ -0,0 +1,40 @@
+// vault.go
+// Este arquivo contém o handler do cofre de cartões (vault).
+// O cliente troca os dados do cartão por um token opaco, que pode ser informado em card_token nos pagamentos
+// no lugar de card_details. A resposta nunca inclui o número do cartão nem o CVV.
+
+// O arquivo inclui:
+// 1. CreateCardToken: POST /tokens.
+
+package handlers
+
+import (
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+	"encoding/json"
+	"net/http"
+)
+
+// CreateCardToken lida com solicitações de tokenização de cartões.
+// Os dados do cartão passam pelas mesmas validações dos pagamentos com card_details.
+func CreateCardToken(w http.ResponseWriter, r *http.Request) {
+	var card models.CardDetails
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize)).Decode(&card); err != nil {
+		writeDecodeError(w)
+		return
+	}
+	if err := validate.Struct(card); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	token, err := services.TokenizeCard(card)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, token)
+}
@@ -0,0 +1,113 @@
+// redact.go
+// Este pacote configura os logs da aplicação (log/slog) com a remoção de dados de cartão.
+// RedactingHandler envolve outro slog.Handler e, antes de repassar cada registro, mascara os números de cartão
+// na mensagem e nos atributos e substitui os atributos de CVV (cvv, cvc, cvv2, security_code) por [REDACTED].
+
+// Setup define o logger padrão com esse handler. A partir do Go 1.21, o pacote log também escreve pelo logger
+// padrão do slog, portanto as mensagens de log.Printf passam pela mesma remoção.
+
+// O arquivo inclui:
+// 1. RedactingHandler / NewRedactingHandler: Handler do slog que remove os dados de cartão dos registros.
+// 2. Setup: Define o logger padrão da aplicação.
+
+package logging
+
+import (
+	"context"
+	"desafiogolang-payment/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// sensitiveKeys são os atributos cujo valor é sempre substituído por [REDACTED].
+var sensitiveKeys = map[string]bool{
+	"cvv":           true,
+	"cvv2":          true,
+	"cvc":           true,
+	"security_code": true,
+}
+
+// RedactingHandler remove os dados de cartão dos registros antes de repassá-los ao handler seguinte.
+type RedactingHandler struct {
+	next slog.Handler
+}
+
+// NewRedactingHandler cria um handler que remove os dados de cartão e repassa os registros para next.
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{next: next}
+}
+
+// Setup define como logger padrão um logger em texto que escreve em w e remove os dados de cartão.
+func Setup(w io.Writer, level slog.Level) *slog.Logger {
+	logger := slog.New(NewRedactingHandler(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})))
+	slog.SetDefault(logger)
+	return logger
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, models.RedactCardData(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name)}
+}
+
+// redactAttr remove os dados de cartão do atributo, inclusive dos atributos de grupos.
+// Valores que não são texto (erros, estruturas, mapas) são convertidos em texto antes da remoção.
+func redactAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, models.Redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, models.RedactCardData(attr.Value.String()))
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, member := range group {
+			redacted[i] = redactAttr(member)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		return slog.String(attr.Key, models.RedactCardData(anyText(attr.Value.Any())))
+	default:
+		return attr
+	}
+}
+
+// anyText converte um valor qualquer em texto: erros pela mensagem e os demais valores em JSON, para que
+// os campos de CVV sejam reconhecidos pelo nome. Valores que não podem ser serializados usam o formato %+v.
+func anyText(value any) string {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if data, err := json.Marshal(value); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%+v", value)
+}
@@ -1,22 +1,122 @@
 package main
 
 import (
+	"desafiogolang-payment/config"
 	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/logging"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 
 	"github.com/gorilla/mux"
 )
 
 func main() {
+	// Os logs (inclusive os do pacote log) passam pela remoção de números de cartão e CVVs
+	logging.Setup(os.Stderr, slog.LevelInfo)
+
+	cfg := config.Load()
+
+	// Abre os repositórios de acordo com o driver de armazenamento configurado
+	repos, err := repository.Open(cfg)
+	if err != nil {
+		log.Fatalf("Could not open storage: %s\n", err.Error())
+	}
+	defer repos.Close()
+	services.SetTransactionRepository(repos.Transactions)
+	services.SetRefundRepository(repos.Refunds)
+	services.SetFXQuoteRepository(repos.FXQuotes)
+	services.SetCustomerRepository(repos.Customers)
+	services.SetPaymentMethodRepository(repos.PaymentMethods)
+	services.SetRateHistoryRepository(repos.RateHistory)
+	services.SetFXQuoteTTL(cfg.FXQuoteTTL)
+
+	// As notificações de pagamentos Pix são assinadas pelo PSP com o segredo compartilhado
+	if cfg.PixWebhookSecret == "" {
+		log.Println("PIX_WEBHOOK_SECRET is not set: Pix payment notifications will be rejected")
+	}
+	services.SetPixWebhookSecret(cfg.PixWebhookSecret)
+
+	// O cofre de cartões cifra os números dos cartões com a chave configurada
+	vaultKey := services.NewVaultKey()
+	if cfg.VaultKey == "" {
+		log.Println("VAULT_KEY is not set, using a random key: card tokens will not survive a restart")
+	} else if vaultKey, err = services.ParseVaultKey(cfg.VaultKey); err != nil {
+		log.Fatalf("Invalid VAULT_KEY: %s\n", err.Error())
+	}
+	vault, err := services.NewVault(vaultKey, repos.CardTokens, cfg.VaultCVVTTL)
+	if err != nil {
+		log.Fatalf("Could not create card vault: %s\n", err.Error())
+	}
+	services.SetVault(vault)
+
+	roundingMode, err := models.ParseRoundingMode(cfg.FXRoundingMode)
+	if err != nil {
+		log.Fatalf("Invalid FX_ROUNDING_MODE: %s\n", err.Error())
+	}
+	services.SetRoundingMode(roundingMode)
+
+	markupRules, err := services.LoadMarkupRules(cfg.FXMarkupRulesFile)
+	if err == nil {
+		err = services.SetMarkupRules(markupRules)
+	}
+	if err != nil {
+		log.Fatalf("Invalid FX_MARKUP_RULES_FILE: %s\n", err.Error())
+	}
+
+	// As tabelas de taxas ficam em memória ou no Redis, compartilhadas entre as réplicas
+	rateCache, err := services.NewRateCache(cfg)
+	if err != nil {
+		log.Fatalf("Could not open rate cache: %s\n", err.Error())
+	}
+	if closer, ok := rateCache.(io.Closer); ok {
+		defer closer.Close()
+	}
+	services.SetRateCache(rateCache)
+
+	// Atualiza as taxas de câmbio em segundo plano, antes que o cache expire
+	services.SetRateCacheTTL(cfg.RateCacheTTL, cfg.RateMaxStaleAge)
+	services.SetRateBaseCurrency(cfg.RateBaseCurrency)
+	refresher := services.StartRateRefresher(cfg.RateRefreshBases, cfg.RateRefreshInterval)
+	defer refresher.Stop()
+
 	r := mux.NewRouter()
 
 	// Define os endpoints
-	r.HandleFunc("/process-payment", handlers.ProcessPayment).Methods("POST")
+	idempotency := handlers.NewIdempotencyStore(cfg.IdempotencyRetention)
+	r.HandleFunc("/process-payment", idempotency.Middleware(handlers.ProcessPayment)).Methods("POST")
+	r.HandleFunc("/tokens", handlers.CreateCardToken).Methods("POST")
+	r.HandleFunc("/customers", handlers.CreateCustomer).Methods("POST")
+	r.HandleFunc("/customers", handlers.ListCustomers).Methods("GET")
+	r.HandleFunc("/customers/{id}", handlers.GetCustomer).Methods("GET")
+	r.HandleFunc("/customers/{id}", handlers.UpdateCustomer).Methods("PUT")
+	r.HandleFunc("/customers/{id}", handlers.DeleteCustomer).Methods("DELETE")
+	r.HandleFunc("/customers/{id}/payment-methods", handlers.CreatePaymentMethod).Methods("POST")
+	r.HandleFunc("/customers/{id}/payment-methods", handlers.ListPaymentMethods).Methods("GET")
+	r.HandleFunc("/customers/{id}/payment-methods/{payment_method_id}", handlers.GetPaymentMethod).Methods("GET")
+	r.HandleFunc("/customers/{id}/payment-methods/{payment_method_id}", handlers.DeletePaymentMethod).Methods("DELETE")
+	r.HandleFunc("/payments/authorize", idempotency.Middleware(handlers.AuthorizePayment)).Methods("POST")
+	r.HandleFunc("/payments/{id}/capture", handlers.CapturePayment).Methods("POST")
+	r.HandleFunc("/payments/{id}/void", handlers.VoidPayment).Methods("POST")
+	r.HandleFunc("/payments/{id}/refunds", idempotency.Middleware(handlers.RefundPayment)).Methods("POST")
+	r.HandleFunc("/payments/{id}/refunds", handlers.ListRefunds).Methods("GET")
+	r.HandleFunc("/payments/{id}/refunds/{refund_id}", handlers.GetRefund).Methods("GET")
 	r.HandleFunc("/payment-status", handlers.GetPaymentStatus).Methods("GET")
+	r.HandleFunc("/webhooks/pix", handlers.PixWebhook).Methods("POST")
 	r.HandleFunc("/convert-currency", handlers.ConvertCurrency).Methods("POST")
+	r.HandleFunc("/convert-currency/batch", handlers.ConvertCurrencyBatch).Methods("POST")
+	r.HandleFunc("/fx/quotes", handlers.CreateFXQuote).Methods("POST")
+	r.HandleFunc("/fx/quotes/{id}", handlers.GetFXQuote).Methods("GET")
+	r.HandleFunc("/currencies", handlers.ListCurrencies).Methods("GET")
+	r.HandleFunc("/rates", handlers.GetRates).Methods("GET")
+	r.HandleFunc("/rates/status", handlers.GetRateStatus).Methods("GET")
 
-	log.Println("Server is running on port 8080")
+	log.Printf("Server is running on port 8080 (storage: %s, rate cache: %s)\n", cfg.StorageDriver, cfg.RateCacheDriver)
 	if err := http.ListenAndServe(":8080", r); err != nil {
 		log.Fatalf("Could not start server: %s\n", err.Error())
 	}
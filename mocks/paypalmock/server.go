@@ -0,0 +1,516 @@
+// server.go
+// Este pacote fornece um servidor local, em processo, que simula a API REST de pagamentos v1 do PayPal,
+// incluindo o endpoint de OAuth2 client credentials. Ele permite executar os testes sem acesso à internet.
+
+// Comportamento dos cartões de teste:
+// - 4000000000000002: cartão recusado (CREDIT_CARD_REFUSED)
+// - 4000000000000044: pagamento criado como pendente e aprovado após PendingLookups consultas
+// - Qualquer outro número: pagamento aprovado com a venda concluída
+// O type do cartão deve ser uma bandeira aceita pelo PayPal (visa, mastercard, amex ou discover); o último é informado por LastCardType.
+// Pagamentos com acordo de cobrança (billing agreement) são aprovados, exceto o acordo B-CANCELLED
+// (AGREEMENT_ALREADY_CANCELLED). O último acordo cobrado é informado por LastBillingAgreement.
+// Pagamentos com intent authorize geram uma autorização, que pode ser capturada ou cancelada (void).
+// O reembolso é feito sobre a venda (intent sale) ou sobre a captura (intent authorize).
+
+package paypalmock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Cartões de teste com comportamento especial.
+const (
+	CardRefused = "4000000000000002"
+	CardPending = "4000000000000044"
+)
+
+// BillingAgreementCancelled é o acordo de cobrança de teste que já foi cancelado pelo pagador.
+const BillingAgreementCancelled = "B-CANCELLED"
+
+// Amount representa um valor monetário no formato do PayPal.
+type Amount struct {
+	Total    string `json:"total"`
+	Currency string `json:"currency"`
+}
+
+// Sale representa uma venda associada a um pagamento.
+type Sale struct {
+	ID       string `json:"id"`
+	State    string `json:"state"`
+	Amount   Amount `json:"amount"`
+	refunded int64
+}
+
+// Authorization representa uma autorização associada a um pagamento com intent authorize.
+type Authorization struct {
+	ID     string `json:"id"`
+	State  string `json:"state"`
+	Amount Amount `json:"amount"`
+}
+
+// Capture representa a captura de uma autorização.
+type Capture struct {
+	ID       string `json:"id"`
+	State    string `json:"state"`
+	Amount   Amount `json:"amount"`
+	refunded int64
+}
+
+// Payment representa um pagamento armazenado pelo servidor.
+type Payment struct {
+	ID           string        `json:"id"`
+	Intent       string        `json:"intent"`
+	State        string        `json:"state"`
+	Transactions []Transaction `json:"transactions"`
+
+	lookupsUntilApproved int
+}
+
+// Transaction representa uma transação de um pagamento.
+type Transaction struct {
+	Amount           Amount            `json:"amount"`
+	RelatedResources []RelatedResource `json:"related_resources"`
+}
+
+// RelatedResource representa um recurso relacionado a uma transação.
+type RelatedResource struct {
+	Sale          *Sale          `json:"sale,omitempty"`
+	Authorization *Authorization `json:"authorization,omitempty"`
+	Capture       *Capture       `json:"capture,omitempty"`
+}
+
+// Server é o servidor que simula a API do PayPal.
+type Server struct {
+	*httptest.Server
+
+	ClientID     string
+	ClientSecret string
+
+	// TokenTTL é a validade, em segundos, dos tokens emitidos.
+	TokenTTL int
+	// PendingLookups é o número de consultas necessárias para um pagamento pendente ser aprovado.
+	PendingLookups int
+
+	mu             sync.Mutex
+	tokens         map[string]bool
+	tokenRequests  int
+	payments       map[string]*Payment
+	sales          map[string]*Sale
+	authorizations map[string]*Payment
+	captures       map[string]*Capture
+
+	lastBillingAgreement string
+	lastCardType         string
+}
+
+// NewServer inicia um novo servidor que aceita as credenciais informadas.
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{
+		ClientID:       clientID,
+		ClientSecret:   clientSecret,
+		TokenTTL:       32400,
+		PendingLookups: 1,
+		tokens:         make(map[string]bool),
+		payments:       make(map[string]*Payment),
+		sales:          make(map[string]*Sale),
+		authorizations: make(map[string]*Payment),
+		captures:       make(map[string]*Capture),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/oauth2/token", s.handleToken)
+	mux.HandleFunc("/v1/payments/payment", s.authenticate(s.handleCreatePayment))
+	mux.HandleFunc("/v1/payments/payment/", s.authenticate(s.handleGetPayment))
+	mux.HandleFunc("/v1/payments/sale/", s.authenticate(s.handleRefundSale))
+	mux.HandleFunc("/v1/payments/authorization/", s.authenticate(s.handleAuthorization))
+	mux.HandleFunc("/v1/payments/capture/", s.authenticate(s.handleRefundCapture))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// TokenRequests retorna quantos tokens de acesso foram emitidos.
+func (s *Server) TokenRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tokenRequests
+}
+
+// LastBillingAgreement retorna o ID do último acordo de cobrança usado em um pagamento.
+func (s *Server) LastBillingAgreement() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastBillingAgreement
+}
+
+// LastCardType retorna a bandeira (type) do último cartão usado em um pagamento.
+func (s *Server) LastCardType() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastCardType
+}
+
+// RevokeTokens invalida todos os tokens emitidos, simulando a expiração antecipada.
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = make(map[string]bool)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{
+			"error":             "invalid_client",
+			"error_description": "Client Authentication failed",
+		})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "unsupported_grant_type",
+			"error_description": "Grant Type is NULL",
+		})
+		return
+	}
+
+	s.mu.Lock()
+	token := newID("A21AA")
+	s.tokens[token] = true
+	s.tokenRequests++
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scope":        "https://uri.paypal.com/services/payments/payment",
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   s.TokenTTL,
+	})
+}
+
+func (s *Server) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		s.mu.Lock()
+		valid := s.tokens[token]
+		s.mu.Unlock()
+
+		if !valid {
+			writeError(w, http.StatusUnauthorized, "AUTHENTICATION_FAILURE", "Authentication failed due to invalid authentication credentials or a missing Authorization header.")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleCreatePayment cria um pagamento do tipo sale ou authorize com cartão de crédito.
+func (s *Server) handleCreatePayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_SUPPORTED", "The server does not implement the requested HTTP method.")
+		return
+	}
+
+	var request struct {
+		Intent string `json:"intent"`
+		Payer  struct {
+			PaymentMethod      string `json:"payment_method"`
+			FundingInstruments []struct {
+				CreditCard struct {
+					Number string `json:"number"`
+					Type   string `json:"type"`
+				} `json:"credit_card"`
+				Billing struct {
+					BillingAgreementID string `json:"billing_agreement_id"`
+				} `json:"billing"`
+			} `json:"funding_instruments"`
+		} `json:"payer"`
+		Transactions []struct {
+			Amount Amount `json:"amount"`
+		} `json:"transactions"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_REQUEST", "Incoming JSON request does not map to API request")
+		return
+	}
+	if len(request.Transactions) != 1 || len(request.Payer.FundingInstruments) != 1 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request - see details")
+		return
+	}
+	if total, err := strconv.ParseFloat(request.Transactions[0].Amount.Total, 64); err != nil || total <= 0 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request - see details")
+		return
+	}
+
+	card := request.Payer.FundingInstruments[0].CreditCard.Number
+	if card == CardRefused {
+		writeError(w, http.StatusBadRequest, "CREDIT_CARD_REFUSED", "Credit card was refused")
+		return
+	}
+	if card != "" {
+		cardType := request.Payer.FundingInstruments[0].CreditCard.Type
+		switch cardType {
+		case "visa", "mastercard", "amex", "discover":
+		default:
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Invalid request - see details")
+			return
+		}
+		s.mu.Lock()
+		s.lastCardType = cardType
+		s.mu.Unlock()
+	}
+	agreement := request.Payer.FundingInstruments[0].Billing.BillingAgreementID
+	if agreement == BillingAgreementCancelled {
+		writeError(w, http.StatusBadRequest, "AGREEMENT_ALREADY_CANCELLED", "The requested agreement is already canceled")
+		return
+	}
+	if agreement != "" {
+		s.mu.Lock()
+		s.lastBillingAgreement = agreement
+		s.mu.Unlock()
+	}
+
+	amount := request.Transactions[0].Amount
+	payment := &Payment{
+		ID:     newID("PAY-"),
+		Intent: request.Intent,
+		State:  "approved",
+	}
+
+	s.mu.Lock()
+	var resource RelatedResource
+	if request.Intent == "authorize" {
+		authorization := &Authorization{ID: newID("AUTH-"), State: "authorized", Amount: amount}
+		if card == CardPending {
+			authorization.State = "pending"
+		}
+		resource.Authorization = authorization
+		s.authorizations[authorization.ID] = payment
+	} else {
+		sale := &Sale{ID: newID("SALE-"), State: "completed", Amount: amount}
+		if card == CardPending {
+			sale.State = "pending"
+		}
+		resource.Sale = sale
+		s.sales[sale.ID] = sale
+	}
+	if card == CardPending {
+		payment.State = "created"
+		payment.lookupsUntilApproved = s.PendingLookups
+	}
+	payment.Transactions = []Transaction{{
+		Amount:           amount,
+		RelatedResources: []RelatedResource{resource},
+	}}
+
+	s.payments[payment.ID] = payment
+	body, _ := json.Marshal(payment)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+}
+
+// handleGetPayment retorna um pagamento existente.
+func (s *Server) handleGetPayment(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/payments/payment/")
+
+	s.mu.Lock()
+	payment, ok := s.payments[id]
+	var body []byte
+	if ok {
+		// Pagamentos pendentes são aprovados após o número configurado de consultas
+		if payment.State == "created" {
+			payment.lookupsUntilApproved--
+			if payment.lookupsUntilApproved <= 0 {
+				payment.State = "approved"
+				resource := payment.Transactions[0].RelatedResources[0]
+				if resource.Sale != nil {
+					resource.Sale.State = "completed"
+				}
+				if resource.Authorization != nil {
+					resource.Authorization.State = "authorized"
+				}
+			}
+		}
+		body, _ = json.Marshal(payment)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Requested resource ID was not found.")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// handleRefundSale reembolsa total ou parcialmente uma venda.
+func (s *Server) handleRefundSale(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/payments/sale/"), "/refund")
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/refund") {
+		writeError(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Requested resource ID was not found.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sale, ok := s.sales[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Requested resource ID was not found.")
+		return
+	}
+	s.refund(w, r, "sale_id", sale.ID, sale.Amount, &sale.State, &sale.refunded)
+}
+
+// handleRefundCapture reembolsa total ou parcialmente uma captura.
+func (s *Server) handleRefundCapture(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/payments/capture/"), "/refund")
+	if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/refund") {
+		writeError(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Requested resource ID was not found.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	capture, ok := s.captures[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Requested resource ID was not found.")
+		return
+	}
+	s.refund(w, r, "capture_id", capture.ID, capture.Amount, &capture.State, &capture.refunded)
+}
+
+// refund aplica o reembolso sobre uma venda ou captura. Deve ser chamado com s.mu bloqueado.
+func (s *Server) refund(w http.ResponseWriter, r *http.Request, parentField, parentID string, total Amount, state *string, refunded *int64) {
+	var request struct {
+		Amount *Amount `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_REQUEST", "Incoming JSON request does not map to API request")
+		return
+	}
+	if *state != "completed" && *state != "partially_refunded" {
+		writeError(w, http.StatusBadRequest, "TRANSACTION_REFUSED", "The request was refused")
+		return
+	}
+
+	cents := toCents(total.Total)
+	amount := cents - *refunded
+	if request.Amount != nil {
+		amount = toCents(request.Amount.Total)
+	}
+	if amount <= 0 || amount > cents-*refunded {
+		writeError(w, http.StatusBadRequest, "REFUND_EXCEEDED_TRANSACTION_AMOUNT", "Refund amount exceeded transaction amount")
+		return
+	}
+
+	*refunded += amount
+	*state = "partially_refunded"
+	if *refunded == cents {
+		*state = "refunded"
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":        newID("REF-"),
+		"state":     "completed",
+		parentField: parentID,
+		"amount": Amount{
+			Total:    fmt.Sprintf("%d.%02d", amount/100, amount%100),
+			Currency: total.Currency,
+		},
+	})
+}
+
+// handleAuthorization captura ou cancela uma autorização.
+func (s *Server) handleAuthorization(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/payments/authorization/"), "/")
+	if r.Method != http.MethodPost || (action != "capture" && action != "void") {
+		writeError(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Requested resource ID was not found.")
+		return
+	}
+
+	var request struct {
+		Amount *Amount `json:"amount"`
+	}
+	if action == "capture" {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeError(w, http.StatusBadRequest, "MALFORMED_REQUEST", "Incoming JSON request does not map to API request")
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payment, ok := s.authorizations[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "INVALID_RESOURCE_ID", "Requested resource ID was not found.")
+		return
+	}
+	authorization := payment.Transactions[0].RelatedResources[0].Authorization
+	if authorization.State != "authorized" {
+		writeError(w, http.StatusBadRequest, "AUTHORIZATION_ALREADY_COMPLETED", "Authorization has already been completed.")
+		return
+	}
+
+	if action == "void" {
+		authorization.State = "voided"
+		writeJSON(w, http.StatusOK, authorization)
+		return
+	}
+
+	amount := authorization.Amount
+	if request.Amount != nil {
+		if value := toCents(request.Amount.Total); value <= 0 || value > toCents(amount.Total) {
+			writeError(w, http.StatusBadRequest, "AMOUNT_EXCEEDED", "Capture amount exceeds the authorized amount")
+			return
+		}
+		amount.Total = request.Amount.Total
+	}
+
+	capture := &Capture{ID: newID("CAP-"), State: "completed", Amount: amount}
+	authorization.State = "captured"
+	payment.Transactions[0].RelatedResources = append(payment.Transactions[0].RelatedResources, RelatedResource{Capture: capture})
+	s.captures[capture.ID] = capture
+	writeJSON(w, http.StatusOK, capture)
+}
+
+func toCents(total string) int64 {
+	value, _ := strconv.ParseFloat(total, 64)
+	return int64(value*100 + 0.5)
+}
+
+func newID(prefix string) string {
+	buf := make([]byte, 10)
+	rand.Read(buf)
+	return prefix + strings.ToUpper(hex.EncodeToString(buf))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, name, message string) {
+	writeJSON(w, status, map[string]string{
+		"name":     name,
+		"message":  message,
+		"debug_id": newID(""),
+	})
+}
@@ -0,0 +1,285 @@
+// server.go
+// Este pacote fornece um servidor local, em processo, que simula o PSP (prestador de serviços de pagamento) do recebedor
+// na API Pix do Banco Central (cobranças imediatas, /v2/cob). Ele permite executar os testes sem acesso à internet.
+// https://bacen.github.io/pix-api/
+
+// Comportamento:
+// - PUT /v2/cob/{txid} cria uma cobrança ATIVA para a chave Pix do recebedor (PixKey); outras chaves são recusadas.
+// - GET /v2/cob/{txid} consulta a cobrança; depois da expiração, a cobrança não paga é REMOVIDA_PELO_PSP.
+// - Pay simula o pagamento da cobrança pelo pagador: a cobrança é CONCLUIDA e a notificação é enviada ao webhook
+//   (WebhookURL), assinada com HMAC-SHA256 do corpo no cabeçalho X-Pix-Signature.
+// - Expire simula o fim do prazo de pagamento da cobrança.
+
+package pixmock
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status das cobranças imediatas.
+const (
+	StatusActive    = "ATIVA"
+	StatusCompleted = "CONCLUIDA"
+	StatusRemoved   = "REMOVIDA_PELO_PSP"
+)
+
+// txidPattern é o formato do txid das cobranças imediatas criadas pelo recebedor.
+var txidPattern = regexp.MustCompile(`^[a-zA-Z0-9]{26,35}$`)
+
+// Calendar representa o campo calendario de uma cobrança; a expiração é contada em segundos a partir da criação.
+type Calendar struct {
+	CreatedAt  time.Time `json:"criacao"`
+	Expiration int       `json:"expiracao"`
+}
+
+// Value representa o campo valor de uma cobrança, com duas casas decimais.
+type Value struct {
+	Original string `json:"original"`
+}
+
+// Pix representa um pagamento recebido.
+type Pix struct {
+	EndToEndID string    `json:"endToEndId"`
+	TxID       string    `json:"txid"`
+	Amount     string    `json:"valor"`
+	PaidAt     time.Time `json:"horario"`
+}
+
+// Charge representa uma cobrança imediata armazenada pelo servidor.
+type Charge struct {
+	Calendar Calendar `json:"calendario"`
+	TxID     string   `json:"txid"`
+	Revision int      `json:"revisao"`
+	Location string   `json:"location"`
+	Status   string   `json:"status"`
+	Value    Value    `json:"valor"`
+	Key      string   `json:"chave"`
+	Pix      []Pix    `json:"pix,omitempty"`
+}
+
+// Notification é o corpo enviado ao webhook do recebedor.
+type Notification struct {
+	Pix []Pix `json:"pix"`
+}
+
+// Server é o servidor que simula o PSP.
+type Server struct {
+	*httptest.Server
+
+	// Token é o token aceito no cabeçalho Authorization.
+	Token string
+	// PixKey é a chave Pix do recebedor.
+	PixKey string
+	// WebhookURL e WebhookSecret definem para onde e com qual segredo as notificações de pagamento são enviadas.
+	WebhookURL    string
+	WebhookSecret string
+
+	mu      sync.Mutex
+	charges map[string]*Charge
+}
+
+// NewServer inicia um novo servidor que aceita o token informado e recebe pagamentos na chave Pix informada.
+func NewServer(token, pixKey string) *Server {
+	s := &Server{
+		Token:   token,
+		PixKey:  pixKey,
+		charges: make(map[string]*Charge),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/cob/", s.handleCharge)
+	s.Server = httptest.NewServer(s.authenticate(mux))
+	return s
+}
+
+// Pay simula o pagamento da cobrança: a cobrança é concluída e a notificação é enviada ao webhook.
+// Retorna um erro se a cobrança não estiver ativa ou se o webhook não responder com sucesso.
+func (s *Server) Pay(txid string) (Pix, error) {
+	s.mu.Lock()
+	charge, ok := s.charges[txid]
+	if !ok || s.expire(charge) != StatusActive {
+		s.mu.Unlock()
+		return Pix{}, fmt.Errorf("charge %s is not active", txid)
+	}
+	pix := Pix{
+		EndToEndID: newEndToEndID(),
+		TxID:       txid,
+		Amount:     charge.Value.Original,
+		PaidAt:     time.Now().UTC(),
+	}
+	charge.Status = StatusCompleted
+	charge.Pix = append(charge.Pix, pix)
+	webhookURL, secret := s.WebhookURL, s.WebhookSecret
+	s.mu.Unlock()
+
+	return pix, Notify(webhookURL, secret, Notification{Pix: []Pix{pix}})
+}
+
+// Expire simula o fim do prazo de pagamento da cobrança.
+func (s *Server) Expire(txid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if charge, ok := s.charges[txid]; ok {
+		charge.Calendar.CreatedAt = charge.Calendar.CreatedAt.Add(-time.Duration(charge.Calendar.Expiration+1) * time.Second)
+	}
+}
+
+// Notify envia a notificação ao webhook, assinada com o segredo informado.
+func Notify(webhookURL, secret string, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Pix-Signature", Sign(secret, body))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign retorna a assinatura HMAC-SHA256, em hexadecimal, do corpo da notificação.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.Token {
+			writeError(w, http.StatusUnauthorized, "AcessoNegado", "Acesso negado.", "Token de acesso inválido.")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleCharge cria (PUT) ou consulta (GET) uma cobrança imediata.
+func (s *Server) handleCharge(w http.ResponseWriter, r *http.Request) {
+	txid := strings.TrimPrefix(r.URL.Path, "/v2/cob/")
+
+	switch r.Method {
+	case http.MethodPut:
+		s.createCharge(w, r, txid)
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		charge, ok := s.charges[txid]
+		if !ok {
+			writeError(w, http.StatusNotFound, "CobNaoEncontrado", "Cobrança não encontrada.", "Cobrança "+txid+" não encontrada.")
+			return
+		}
+		s.expire(charge)
+		writeJSON(w, http.StatusOK, charge)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "OperacaoInvalida", "Operação inválida.", "Method not allowed")
+	}
+}
+
+func (s *Server) createCharge(w http.ResponseWriter, r *http.Request, txid string) {
+	var body struct {
+		Calendar struct {
+			Expiration int `json:"expiracao"`
+		} `json:"calendario"`
+		Value Value  `json:"valor"`
+		Key   string `json:"chave"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "CobOperacaoInvalida", "Cobrança inválida.", "Invalid JSON body")
+		return
+	}
+	switch {
+	case !txidPattern.MatchString(txid):
+		writeError(w, http.StatusBadRequest, "CobOperacaoInvalida", "Cobrança inválida.", "O campo txid não respeita o schema.")
+		return
+	case body.Key != s.PixKey:
+		writeError(w, http.StatusBadRequest, "CobOperacaoInvalida", "Cobrança inválida.", "A chave Pix não pertence ao recebedor.")
+		return
+	case body.Value.Original == "" || body.Calendar.Expiration <= 0:
+		writeError(w, http.StatusBadRequest, "CobOperacaoInvalida", "Cobrança inválida.", "Os campos valor.original e calendario.expiracao são obrigatórios.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.charges[txid]; exists {
+		writeError(w, http.StatusBadRequest, "CobOperacaoInvalida", "Cobrança inválida.", "O txid "+txid+" já foi utilizado.")
+		return
+	}
+	charge := &Charge{
+		Calendar: Calendar{CreatedAt: time.Now().UTC(), Expiration: body.Calendar.Expiration},
+		TxID:     txid,
+		Location: strings.TrimPrefix(s.URL, "http://") + "/qr/v2/" + newID(),
+		Status:   StatusActive,
+		Value:    body.Value,
+		Key:      body.Key,
+	}
+	s.charges[txid] = charge
+	writeJSON(w, http.StatusCreated, charge)
+}
+
+// expire remove a cobrança ativa cujo prazo de pagamento terminou e retorna o status atual.
+func (s *Server) expire(charge *Charge) string {
+	expiresAt := charge.Calendar.CreatedAt.Add(time.Duration(charge.Calendar.Expiration) * time.Second)
+	if charge.Status == StatusActive && !time.Now().Before(expiresAt) {
+		charge.Status = StatusRemoved
+	}
+	return charge.Status
+}
+
+func newID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// newEndToEndID gera um endToEndId: "E", ISPB do pagador, data e hora (AAAAMMDDHHMM) e 11 caracteres aleatórios.
+func newEndToEndID() string {
+	return "E12345678" + time.Now().UTC().Format("200601021504") + newID()[:11]
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError escreve um erro no formato da API Pix (RFC 7807).
+func writeError(w http.ResponseWriter, status int, errorType, title, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type":   "https://pix.bcb.gov.br/api/v2/error/" + errorType,
+		"title":  title,
+		"status": status,
+		"detail": detail,
+	})
+}
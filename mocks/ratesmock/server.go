@@ -0,0 +1,170 @@
+// server.go
+// Este pacote fornece um servidor local, em processo, que simula os provedores de taxas de câmbio:
+// a API open.er-api.com (/v6/latest/{base}) e o XML diário do Banco Central Europeu (/stats/eurofxref/eurofxref-daily.xml).
+// Ele permite executar os testes sem acesso à internet.
+
+// As duas rotas usam a mesma tabela de taxas com base EUR; as demais bases da open.er-api.com são calculadas por taxas cruzadas.
+// O servidor também pode simular falhas (status 500) e lentidão, para testar o failover e os timeouts dos provedores.
+
+package ratesmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ECBPath é o caminho do XML diário do Banco Central Europeu.
+const ECBPath = "/stats/eurofxref/eurofxref-daily.xml"
+
+// DefaultRates são as taxas com base EUR usadas quando nenhuma outra é definida.
+var DefaultRates = map[string]float64{
+	"USD": 1.0845,
+	"JPY": 161.25,
+	"GBP": 0.8571,
+	"BRL": 5.3712,
+	"KWD": 0.3334,
+}
+
+// Server é o servidor que simula os provedores de taxas de câmbio.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	rates    map[string]float64
+	date     time.Time
+	delay    time.Duration
+	failing  bool
+	requests int
+}
+
+// NewServer inicia um novo servidor com as taxas DefaultRates.
+func NewServer() *Server {
+	s := &Server{
+		rates: DefaultRates,
+		date:  time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v6/latest/", s.handleERAPI)
+	mux.HandleFunc(ECBPath, s.handleECB)
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// SetRates substitui as taxas com base EUR e a data de referência.
+func (s *Server) SetRates(rates map[string]float64, date time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rates = rates
+	s.date = date
+}
+
+// SetDelay faz o servidor aguardar o tempo informado antes de responder.
+func (s *Server) SetDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.delay = delay
+}
+
+// SetFailing faz o servidor responder com status 500 a todas as requisições.
+func (s *Server) SetFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failing = failing
+}
+
+// Requests retorna a quantidade de requisições recebidas.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		delay, failing := s.delay, s.failing
+		s.mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if failing {
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleERAPI responde no formato da API open.er-api.com.
+func (s *Server) handleERAPI(w http.ResponseWriter, r *http.Request) {
+	base := strings.TrimPrefix(r.URL.Path, "/v6/latest/")
+
+	s.mu.Lock()
+	rates, date := s.rates, s.date
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	baseRate, ok := rates[base]
+	if base == "EUR" {
+		baseRate, ok = 1, true
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"result": "error", "error-type": "unsupported-code"})
+		return
+	}
+
+	converted := map[string]float64{"EUR": 1 / baseRate}
+	for currency, rate := range rates {
+		converted[currency] = rate / baseRate
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"result":                "success",
+		"base_code":             base,
+		"time_last_update_unix": date.Unix(),
+		"rates":                 converted,
+	})
+}
+
+// handleECB responde no formato do XML diário do Banco Central Europeu.
+func (s *Server) handleECB(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	rates, date := s.rates, s.date
+	s.mu.Unlock()
+
+	currencies := make([]string, 0, len(rates))
+	for currency := range rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<gesmes:Sender><gesmes:name>European Central Bank</gesmes:name></gesmes:Sender>
+	<Cube>
+`)
+	fmt.Fprintf(w, "\t\t<Cube time='%s'>\n", date.Format("2006-01-02"))
+	for _, currency := range currencies {
+		fmt.Fprintf(w, "\t\t\t<Cube currency='%s' rate='%v'/>\n", currency, rates[currency])
+	}
+	fmt.Fprint(w, "\t\t</Cube>\n\t</Cube>\n</gesmes:Envelope>\n")
+}
@@ -0,0 +1,310 @@
+// server.go
+// Este pacote fornece um servidor local, em processo, que simula a API de PaymentIntents do Stripe.
+// Ele permite executar os testes sem acesso à internet e sem credenciais reais.
+
+// O comportamento segue os cartões de teste documentados pelo Stripe (https://docs.stripe.com/testing):
+// - 4000000000000002: cartão recusado (card_declined)
+// - 4000000000009995: saldo insuficiente (insufficient_funds)
+// - 4000002500003155: exige autenticação (requires_action)
+// - Qualquer outro número: pagamento aprovado (succeeded)
+
+package stripemock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Cartões de teste com comportamento especial.
+const (
+	CardDeclined          = "4000000000000002"
+	CardInsufficientFunds = "4000000000009995"
+	CardRequiresAction    = "4000002500003155"
+)
+
+// PaymentIntent representa um PaymentIntent armazenado pelo servidor.
+type PaymentIntent struct {
+	ID               string            `json:"id"`
+	Object           string            `json:"object"`
+	Amount           int64             `json:"amount"`
+	AmountReceived   int64             `json:"amount_received"`
+	AmountRefunded   int64             `json:"amount_refunded"`
+	CaptureMethod    string            `json:"capture_method"`
+	Currency         string            `json:"currency"`
+	Status           string            `json:"status"`
+	LastPaymentError *PaymentError     `json:"last_payment_error"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// PaymentError representa o campo last_payment_error de um PaymentIntent.
+type PaymentError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Refund representa um reembolso armazenado pelo servidor.
+type Refund struct {
+	ID            string `json:"id"`
+	Object        string `json:"object"`
+	Amount        int64  `json:"amount"`
+	PaymentIntent string `json:"payment_intent"`
+	Status        string `json:"status"`
+}
+
+// Server é o servidor que simula a API do Stripe.
+type Server struct {
+	*httptest.Server
+
+	// SecretKey é a chave aceita no cabeçalho Authorization.
+	SecretKey string
+
+	mu      sync.Mutex
+	intents map[string]*PaymentIntent
+	refunds map[string]*Refund
+}
+
+// NewServer inicia um novo servidor que aceita a chave secreta informada.
+func NewServer(secretKey string) *Server {
+	s := &Server{
+		SecretKey: secretKey,
+		intents:   make(map[string]*PaymentIntent),
+		refunds:   make(map[string]*Refund),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/payment_intents", s.handlePaymentIntents)
+	mux.HandleFunc("/v1/payment_intents/", s.handlePaymentIntent)
+	mux.HandleFunc("/v1/refunds", s.handleRefunds)
+	s.Server = httptest.NewServer(s.authenticate(mux))
+	return s
+}
+
+// SetStatus altera o status de um PaymentIntent, simulando eventos assíncronos (e.g. autenticação 3DS concluída).
+func (s *Server) SetStatus(id, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if intent, ok := s.intents[id]; ok {
+		intent.Status = status
+		if status == "succeeded" {
+			intent.AmountReceived = intent.Amount
+		}
+	}
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.SecretKey {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "", "", "Invalid API Key provided")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handlePaymentIntents cria e confirma um PaymentIntent.
+func (s *Server) handlePaymentIntents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "", "", "Method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "", "Invalid form body")
+		return
+	}
+
+	amount, err := strconv.ParseInt(r.PostForm.Get("amount"), 10, 64)
+	if err != nil || amount <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "parameter_invalid_integer", "", "Invalid positive integer: amount")
+		return
+	}
+	currency := r.PostForm.Get("currency")
+	if len(currency) != 3 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "parameter_missing", "", "Missing required param: currency")
+		return
+	}
+
+	intent := &PaymentIntent{
+		ID:            newID("pi_"),
+		Object:        "payment_intent",
+		Amount:        amount,
+		Currency:      strings.ToLower(currency),
+		Status:        "requires_payment_method",
+		CaptureMethod: "automatic",
+		Metadata:      map[string]string{},
+	}
+	if r.PostForm.Get("capture_method") == "manual" {
+		intent.CaptureMethod = "manual"
+	}
+
+	if r.PostForm.Get("confirm") == "true" {
+		switch r.PostForm.Get("payment_method_data[card][number]") {
+		case CardDeclined:
+			s.store(intent)
+			writeError(w, http.StatusPaymentRequired, "card_error", "card_declined", "generic_decline", "Your card was declined.")
+			return
+		case CardInsufficientFunds:
+			s.store(intent)
+			writeError(w, http.StatusPaymentRequired, "card_error", "card_declined", "insufficient_funds", "Your card has insufficient funds.")
+			return
+		case CardRequiresAction:
+			intent.Status = "requires_action"
+		default:
+			if intent.CaptureMethod == "manual" {
+				intent.Status = "requires_capture"
+			} else {
+				intent.Status = "succeeded"
+				intent.AmountReceived = amount
+			}
+		}
+	}
+
+	s.store(intent)
+	writeJSON(w, http.StatusOK, intent)
+}
+
+// handlePaymentIntent retorna, captura ou cancela um PaymentIntent existente.
+func (s *Server) handlePaymentIntent(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1/payment_intents/")
+	id, action, _ := strings.Cut(path, "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	intent, ok := s.intents[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", "", "No such payment_intent: '"+id+"'")
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+	case action == "capture" && r.Method == http.MethodPost:
+		if intent.Status != "requires_capture" {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "payment_intent_unexpected_state",
+				"", "This PaymentIntent could not be captured because it has a status of "+intent.Status+".")
+			return
+		}
+		r.ParseForm()
+		amount := intent.Amount
+		if value := r.PostForm.Get("amount_to_capture"); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || parsed <= 0 || parsed > intent.Amount {
+				writeError(w, http.StatusBadRequest, "invalid_request_error", "amount_too_large", "", "The amount_to_capture must be less than or equal to the amount.")
+				return
+			}
+			amount = parsed
+		}
+		intent.Status = "succeeded"
+		intent.AmountReceived = amount
+	case action == "cancel" && r.Method == http.MethodPost:
+		if intent.Status == "succeeded" || intent.Status == "canceled" {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "payment_intent_unexpected_state",
+				"", "You cannot cancel this PaymentIntent because it has a status of "+intent.Status+".")
+			return
+		}
+		intent.Status = "canceled"
+	default:
+		writeError(w, http.StatusNotFound, "invalid_request_error", "", "", "Unrecognized request URL")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, intent)
+}
+
+// handleRefunds cria um reembolso total ou parcial de um PaymentIntent.
+func (s *Server) handleRefunds(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "", "", "Method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "", "Invalid form body")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.PostForm.Get("payment_intent")
+	intent, ok := s.intents[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "resource_missing", "", "No such payment_intent: '"+id+"'")
+		return
+	}
+	if intent.Status != "succeeded" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "charge_not_refundable", "", "This PaymentIntent does not have a successful charge to refund.")
+		return
+	}
+
+	remaining := intent.AmountReceived - intent.AmountRefunded
+	amount := remaining
+	if value := r.PostForm.Get("amount"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "parameter_invalid_integer", "", "Invalid positive integer: amount")
+			return
+		}
+		amount = parsed
+	}
+	if remaining == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "charge_already_refunded", "", "Charge has already been refunded.")
+		return
+	}
+	if amount > remaining {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "amount_too_large", "", "Refund amount is greater than unrefunded amount on charge.")
+		return
+	}
+
+	intent.AmountRefunded += amount
+	refund := &Refund{
+		ID:            newID("re_"),
+		Object:        "refund",
+		Amount:        amount,
+		PaymentIntent: id,
+		Status:        "succeeded",
+	}
+	s.refunds[refund.ID] = refund
+	writeJSON(w, http.StatusOK, refund)
+}
+
+func (s *Server) store(intent *PaymentIntent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.intents[intent.ID] = intent
+}
+
+func newID(prefix string) string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return prefix + hex.EncodeToString(buf)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, errorType, code, declineCode, message string) {
+	body := map[string]map[string]string{
+		"error": {
+			"type":    errorType,
+			"message": message,
+		},
+	}
+	if code != "" {
+		body["error"]["code"] = code
+	}
+	if declineCode != "" {
+		body["error"]["decline_code"] = declineCode
+	}
+	writeJSON(w, status, body)
+}
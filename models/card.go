@@ -0,0 +1,183 @@
+// card.go
+// Este arquivo contém as regras de validação dos cartões: identificação da bandeira pelas faixas de IIN
+// (os primeiros dígitos do número), o tamanho do número e do CVV de cada bandeira e a validade no formato MM/YY.
+// O dígito verificador (Luhn) é validado pela regra luhn_checksum do validador.
+
+// As faixas de Elo e Hipercard se sobrepõem às de Visa, Mastercard e Discover, por isso são verificadas primeiro.
+
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// CardBrand é a bandeira de um cartão.
+type CardBrand string
+
+// Bandeiras de cartão aceitas.
+const (
+	CardBrandVisa       CardBrand = "visa"
+	CardBrandMastercard CardBrand = "mastercard"
+	CardBrandAmex       CardBrand = "amex"
+	CardBrandElo        CardBrand = "elo"
+	CardBrandHipercard  CardBrand = "hipercard"
+	CardBrandDiscover   CardBrand = "discover"
+)
+
+// iinRange é uma faixa de prefixos do número do cartão, com o mesmo número de dígitos em From e To.
+type iinRange struct {
+	From, To string
+}
+
+// cardBrandRule define as faixas de IIN e os tamanhos do número e do CVV de uma bandeira.
+type cardBrandRule struct {
+	brand     CardBrand
+	ranges    []iinRange
+	lengths   []int
+	cvvLength int
+}
+
+// prefixes cria faixas de um único prefixo cada.
+func prefixes(values ...string) []iinRange {
+	ranges := make([]iinRange, len(values))
+	for i, value := range values {
+		ranges[i] = iinRange{value, value}
+	}
+	return ranges
+}
+
+// cardBrandRules lista as bandeiras na ordem em que são verificadas.
+var cardBrandRules = []cardBrandRule{
+	{
+		brand: CardBrandElo,
+		ranges: append(prefixes("401178", "401179", "431274", "438935", "451416", "457393", "457631", "457632",
+			"504175", "627780", "636297", "636368"),
+			iinRange{"506699", "506778"}, iinRange{"509000", "509999"}, iinRange{"650031", "650033"},
+			iinRange{"650035", "650051"}, iinRange{"650405", "650439"}, iinRange{"650485", "650538"},
+			iinRange{"650541", "650598"}, iinRange{"650700", "650718"}, iinRange{"650720", "650727"},
+			iinRange{"650901", "650978"}, iinRange{"651652", "651679"}, iinRange{"655000", "655019"},
+			iinRange{"655021", "655058"}),
+		lengths:   []int{16},
+		cvvLength: 3,
+	},
+	{
+		brand:     CardBrandHipercard,
+		ranges:    prefixes("384100", "384140", "384160", "606282", "637095", "637568", "637599", "637609", "637612"),
+		lengths:   []int{16, 19},
+		cvvLength: 3,
+	},
+	{
+		brand:     CardBrandAmex,
+		ranges:    prefixes("34", "37"),
+		lengths:   []int{15},
+		cvvLength: 4,
+	},
+	{
+		brand:     CardBrandDiscover,
+		ranges:    append(prefixes("6011", "65"), iinRange{"622126", "622925"}, iinRange{"644", "649"}),
+		lengths:   []int{16, 17, 18, 19},
+		cvvLength: 3,
+	},
+	{
+		brand:     CardBrandMastercard,
+		ranges:    []iinRange{{"51", "55"}, {"2221", "2720"}},
+		lengths:   []int{16},
+		cvvLength: 3,
+	},
+	{
+		brand:     CardBrandVisa,
+		ranges:    prefixes("4"),
+		lengths:   []int{13, 16, 19},
+		cvvLength: 3,
+	},
+}
+
+// DetectCardBrand identifica a bandeira pelo número do cartão. Retorna "" se nenhuma bandeira aceita corresponder.
+func DetectCardBrand(number string) CardBrand {
+	if rule, ok := cardBrandRuleOf(number); ok {
+		return rule.brand
+	}
+	return ""
+}
+
+// cardBrandRuleOf retorna a regra da bandeira cujo prefixo corresponde ao número do cartão.
+func cardBrandRuleOf(number string) (cardBrandRule, bool) {
+	for _, rule := range cardBrandRules {
+		for _, r := range rule.ranges {
+			if len(number) < len(r.From) {
+				continue
+			}
+			// Prefixos com o mesmo número de dígitos podem ser comparados como texto
+			if prefix := number[:len(r.From)]; prefix >= r.From && prefix <= r.To {
+				return rule, true
+			}
+		}
+	}
+	return cardBrandRule{}, false
+}
+
+// ValidCardLength informa se o tamanho do número é aceito pela bandeira do cartão.
+func ValidCardLength(number string) bool {
+	rule, ok := cardBrandRuleOf(number)
+	if !ok {
+		return false
+	}
+	for _, length := range rule.lengths {
+		if len(number) == length {
+			return true
+		}
+	}
+	return false
+}
+
+// CVVLength retorna o tamanho do código de segurança da bandeira (4 para Amex, 3 para as demais).
+// Retorna 0 para uma bandeira desconhecida.
+func (b CardBrand) CVVLength() int {
+	for _, rule := range cardBrandRules {
+		if rule.brand == b {
+			return rule.cvvLength
+		}
+	}
+	return 0
+}
+
+// Brand retorna a bandeira do cartão.
+func (c CardDetails) Brand() CardBrand {
+	return DetectCardBrand(c.Number)
+}
+
+// ParseCardExpiry converte a validade do cartão no formato MM/YY para mês e ano com quatro dígitos.
+func ParseCardExpiry(expiry string) (int, int, error) {
+	if len(expiry) != 5 || expiry[2] != '/' || !isDigits(expiry[:2]) || !isDigits(expiry[3:]) {
+		return 0, 0, fmt.Errorf("invalid card expiry %q", expiry)
+	}
+	month, _ := strconv.Atoi(expiry[:2])
+	if month < 1 || month > 12 {
+		return 0, 0, fmt.Errorf("invalid card expiry %q", expiry)
+	}
+	year, _ := strconv.Atoi(expiry[3:])
+	return month, 2000 + year, nil
+}
+
+// CardExpired informa se a validade MM/YY já passou. O cartão é válido até o último dia do mês da validade.
+func CardExpired(expiry string, now time.Time) bool {
+	month, year, err := ParseCardExpiry(expiry)
+	if err != nil {
+		return true
+	}
+	// Primeiro instante do mês seguinte ao da validade
+	expiresAt := time.Date(year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+	return !now.UTC().Before(expiresAt)
+}
+
+// isDigits informa se o texto contém apenas dígitos.
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+// card_token.go
+// Este arquivo define os modelos do cofre de cartões (vault).
+// O cofre troca os dados do cartão por um token opaco, que pode ser informado em card_token nos pagamentos.
+// O número do cartão é guardado cifrado e nunca volta nas respostas; o CVV não é gravado, apenas mantido em memória
+// por um curto período para o primeiro pagamento com o token.
+
+package models
+
+import "time"
+
+// CardToken representa um cartão guardado no cofre.
+// Além do token, são expostos apenas a bandeira, os quatro últimos dígitos e a validade.
+type CardToken struct {
+	Token     string    `json:"token"`
+	Brand     CardBrand `json:"brand"`
+	Last4     string    `json:"last4"`
+	Expiry    string    `json:"expiry"`
+	CreatedAt time.Time `json:"created_at"`
+	// EncryptedNumber é o número do cartão cifrado com AES-GCM (nonce seguido do texto cifrado); nunca é serializado.
+	EncryptedNumber []byte `json:"-"`
+}
+
+// CardTokenResponse representa a resposta da criação de um token.
+// CVVExpiresAt informa até quando o CVV informado pode ser usado: ele é descartado no primeiro pagamento ou na expiração.
+type CardTokenResponse struct {
+	CardToken
+	CVVExpiresAt *time.Time `json:"cvv_expires_at,omitempty"`
+}
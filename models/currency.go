@@ -0,0 +1,120 @@
+// currency.go
+// Este arquivo define o catálogo de moedas ISO 4217: nome, quantidade de casas decimais (unidade menor)
+// e se a moeda pode ser usada em pagamentos.
+// O catálogo contém os mesmos códigos aceitos pela validação iso4217 das requisições.
+// A maioria das moedas usa duas casas decimais; a tabela de casas decimais lista apenas as exceções.
+
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// Currency representa uma moeda do catálogo ISO 4217.
+type Currency struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	MinorUnits int    `json:"minor_units"`
+	// SupportedForPayment indica se a moeda pode ser usada em pagamentos. Fundos, metais preciosos,
+	// unidades de conta e códigos de teste podem ser convertidos, mas não são aceitos em /process-payment.
+	SupportedForPayment bool `json:"supported_for_payment"`
+}
+
+// currencyNames são os nomes das moedas ISO 4217.
+var currencyNames = map[string]string{
+	"AED": "UAE Dirham", "AFN": "Afghani", "ALL": "Lek", "AMD": "Armenian Dram",
+	"ANG": "Netherlands Antillean Guilder", "AOA": "Kwanza", "ARS": "Argentine Peso", "AUD": "Australian Dollar",
+	"AWG": "Aruban Florin", "AZN": "Azerbaijan Manat", "BAM": "Convertible Mark", "BBD": "Barbados Dollar",
+	"BDT": "Taka", "BGN": "Bulgarian Lev", "BHD": "Bahraini Dinar", "BIF": "Burundi Franc",
+	"BMD": "Bermudian Dollar", "BND": "Brunei Dollar", "BOB": "Boliviano", "BOV": "Mvdol",
+	"BRL": "Brazilian Real", "BSD": "Bahamian Dollar", "BTN": "Ngultrum", "BWP": "Pula",
+	"BYN": "Belarusian Ruble", "BZD": "Belize Dollar", "CAD": "Canadian Dollar", "CDF": "Congolese Franc",
+	"CHE": "WIR Euro", "CHF": "Swiss Franc", "CHW": "WIR Franc", "CLF": "Unidad de Fomento",
+	"CLP": "Chilean Peso", "CNY": "Yuan Renminbi", "COP": "Colombian Peso", "COU": "Unidad de Valor Real",
+	"CRC": "Costa Rican Colon", "CUC": "Peso Convertible", "CUP": "Cuban Peso", "CVE": "Cabo Verde Escudo",
+	"CZK": "Czech Koruna", "DJF": "Djibouti Franc", "DKK": "Danish Krone", "DOP": "Dominican Peso",
+	"DZD": "Algerian Dinar", "EGP": "Egyptian Pound", "ERN": "Nakfa", "ETB": "Ethiopian Birr",
+	"EUR": "Euro", "FJD": "Fiji Dollar", "FKP": "Falkland Islands Pound", "GBP": "Pound Sterling",
+	"GEL": "Lari", "GHS": "Ghana Cedi", "GIP": "Gibraltar Pound", "GMD": "Dalasi",
+	"GNF": "Guinean Franc", "GTQ": "Quetzal", "GYD": "Guyana Dollar", "HKD": "Hong Kong Dollar",
+	"HNL": "Lempira", "HRK": "Kuna", "HTG": "Gourde", "HUF": "Forint",
+	"IDR": "Rupiah", "ILS": "New Israeli Sheqel", "INR": "Indian Rupee", "IQD": "Iraqi Dinar",
+	"IRR": "Iranian Rial", "ISK": "Iceland Krona", "JMD": "Jamaican Dollar", "JOD": "Jordanian Dinar",
+	"JPY": "Yen", "KES": "Kenyan Shilling", "KGS": "Som", "KHR": "Riel",
+	"KMF": "Comorian Franc", "KPW": "North Korean Won", "KRW": "Won", "KWD": "Kuwaiti Dinar",
+	"KYD": "Cayman Islands Dollar", "KZT": "Tenge", "LAK": "Lao Kip", "LBP": "Lebanese Pound",
+	"LKR": "Sri Lanka Rupee", "LRD": "Liberian Dollar", "LSL": "Loti", "LYD": "Libyan Dinar",
+	"MAD": "Moroccan Dirham", "MDL": "Moldovan Leu", "MGA": "Malagasy Ariary", "MKD": "Denar",
+	"MMK": "Kyat", "MNT": "Tugrik", "MOP": "Pataca", "MRU": "Ouguiya",
+	"MUR": "Mauritius Rupee", "MVR": "Rufiyaa", "MWK": "Malawi Kwacha", "MXN": "Mexican Peso",
+	"MXV": "Mexican Unidad de Inversion (UDI)", "MYR": "Malaysian Ringgit", "MZN": "Mozambique Metical", "NAD": "Namibia Dollar",
+	"NGN": "Naira", "NIO": "Cordoba Oro", "NOK": "Norwegian Krone", "NPR": "Nepalese Rupee",
+	"NZD": "New Zealand Dollar", "OMR": "Rial Omani", "PAB": "Balboa", "PEN": "Sol",
+	"PGK": "Kina", "PHP": "Philippine Peso", "PKR": "Pakistan Rupee", "PLN": "Zloty",
+	"PYG": "Guarani", "QAR": "Qatari Rial", "RON": "Romanian Leu", "RSD": "Serbian Dinar",
+	"RUB": "Russian Ruble", "RWF": "Rwanda Franc", "SAR": "Saudi Riyal", "SBD": "Solomon Islands Dollar",
+	"SCR": "Seychelles Rupee", "SDG": "Sudanese Pound", "SEK": "Swedish Krona", "SGD": "Singapore Dollar",
+	"SHP": "Saint Helena Pound", "SLL": "Leone", "SOS": "Somali Shilling", "SRD": "Surinam Dollar",
+	"SSP": "South Sudanese Pound", "STN": "Dobra", "SVC": "El Salvador Colon", "SYP": "Syrian Pound",
+	"SZL": "Lilangeni", "THB": "Baht", "TJS": "Somoni", "TMT": "Turkmenistan New Manat",
+	"TND": "Tunisian Dinar", "TOP": "Pa'anga", "TRY": "Turkish Lira", "TTD": "Trinidad and Tobago Dollar",
+	"TWD": "New Taiwan Dollar", "TZS": "Tanzanian Shilling", "UAH": "Hryvnia", "UGX": "Uganda Shilling",
+	"USD": "US Dollar", "USN": "US Dollar (Next day)", "UYI": "Uruguay Peso en Unidades Indexadas (UI)", "UYU": "Peso Uruguayo",
+	"UYW": "Unidad Previsional", "UZS": "Uzbekistan Sum", "VES": "Bolívar Soberano", "VND": "Dong",
+	"VUV": "Vatu", "WST": "Tala", "XAF": "CFA Franc BEAC", "XAG": "Silver",
+	"XAU": "Gold", "XBA": "Bond Markets Unit European Composite Unit (EURCO)", "XBB": "Bond Markets Unit European Monetary Unit (E.M.U.-6)", "XBC": "Bond Markets Unit European Unit of Account 9 (E.U.A.-9)",
+	"XBD": "Bond Markets Unit European Unit of Account 17 (E.U.A.-17)", "XCD": "East Caribbean Dollar", "XDR": "SDR (Special Drawing Right)", "XOF": "CFA Franc BCEAO",
+	"XPD": "Palladium", "XPF": "CFP Franc", "XPT": "Platinum", "XSU": "Sucre",
+	"XTS": "Codes specifically reserved for testing purposes", "XUA": "ADB Unit of Account", "XXX": "The codes assigned for transactions where no currency is involved", "YER": "Yemeni Rial",
+	"ZAR": "Rand", "ZMW": "Zambian Kwacha", "ZWL": "Zimbabwe Dollar",
+}
+
+// nonPaymentCurrencies são os códigos ISO 4217 que não representam moeda corrente:
+// fundos, metais preciosos, unidades de conta e códigos de teste.
+var nonPaymentCurrencies = map[string]bool{
+	"BOV": true, "CHE": true, "CHW": true, "CLF": true, "COU": true, "MXV": true, "USN": true, "UYI": true, "UYW": true,
+	"XAG": true, "XAU": true, "XPD": true, "XPT": true,
+	"XBA": true, "XBB": true, "XBC": true, "XBD": true, "XDR": true, "XSU": true, "XUA": true,
+	"XTS": true, "XXX": true,
+}
+
+// currencyMinorUnits são as moedas ISO 4217 cuja unidade menor não tem duas casas decimais.
+var currencyMinorUnits = map[string]int{
+	// Sem casas decimais
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	// Três casas decimais
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	// Quatro casas decimais
+	"CLF": 4, "UYW": 4,
+}
+
+// MinorUnits retorna a quantidade de casas decimais da moeda (e.g. JPY 0, USD 2, KWD 3).
+func MinorUnits(currency string) int {
+	if units, ok := currencyMinorUnits[strings.ToUpper(currency)]; ok {
+		return units
+	}
+	return 2
+}
+
+// LookupCurrency retorna a moeda do catálogo com o código informado.
+func LookupCurrency(code string) (Currency, bool) {
+	code = strings.ToUpper(code)
+	name, ok := currencyNames[code]
+	if !ok {
+		return Currency{}, false
+	}
+	return Currency{Code: code, Name: name, MinorUnits: MinorUnits(code), SupportedForPayment: !nonPaymentCurrencies[code]}, true
+}
+
+// Currencies retorna o catálogo de moedas, ordenado pelo código.
+func Currencies() []Currency {
+	currencies := make([]Currency, 0, len(currencyNames))
+	for code := range currencyNames {
+		currency, _ := LookupCurrency(code)
+		currencies = append(currencies, currency)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies
+}
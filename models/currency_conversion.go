@@ -6,15 +6,61 @@ package models
 
 // CurrencyConversionRequest representa uma solicitação de conversão de moeda.
 type CurrencyConversionRequest struct {
-	Amount       float64 `json:"amount" validate:"required,gt=0"`
-	FromCurrency string  `json:"from_currency" validate:"required,len=3"`
-	ToCurrency   string  `json:"to_currency" validate:"required,len=3"`
+	Amount       Decimal `json:"amount" validate:"required,gt=0"`
+	FromCurrency string  `json:"from_currency" validate:"required,iso4217"`
+	ToCurrency   string  `json:"to_currency" validate:"required,iso4217"`
+	// MerchantID identifica o merchant, cujas regras de markup prevalecem sobre as demais.
+	MerchantID string `json:"merchant_id,omitempty"`
+	// Date é a data (AAAA-MM-DD) da taxa a ser usada; se omitida, é usada a taxa atual.
+	Date string `json:"date,omitempty" validate:"omitempty,datetime=2006-01-02"`
 }
 
 // CurrencyConversionResponse representa a resposta de uma conversão de moeda.
 type CurrencyConversionResponse struct {
-	ConvertedAmount float64 `json:"converted_amount"`
+	ConvertedAmount Decimal `json:"converted_amount"`
 	FromCurrency    string  `json:"from_currency"`
 	ToCurrency      string  `json:"to_currency"`
-	Rate            float64 `json:"rate"`
+	// Rate é a taxa do cliente, com o markup; MidMarketRate é a taxa de mercado, sem markup.
+	Rate          float64 `json:"rate"`
+	MidMarketRate float64 `json:"mid_market_rate"`
+	// Fees é o detalhamento das tarifas de markup, descontadas do valor antes da conversão; nulo se não houver markup.
+	Fees *ConversionFees `json:"fees,omitempty"`
+	// Stale indica que a taxa passou do TTL do cache e foi usada porque não pôde ser atualizada a tempo.
+	Stale bool `json:"stale"`
+	// RateDate é a data efetiva da taxa usada em uma conversão com data, que pode ser o dia útil anterior à data solicitada.
+	RateDate string `json:"rate_date,omitempty"`
+}
+
+// BatchConversionSource representa um lote com um único valor de origem convertido para várias moedas.
+type BatchConversionSource struct {
+	Amount       Decimal  `json:"amount" validate:"required,gt=0"`
+	FromCurrency string   `json:"from_currency" validate:"required,iso4217"`
+	ToCurrencies []string `json:"to_currencies" validate:"required,min=1,dive,iso4217"`
+	MerchantID   string   `json:"merchant_id,omitempty"`
+	Date         string   `json:"date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// Requests retorna um item de conversão para cada moeda de destino.
+func (s BatchConversionSource) Requests() []CurrencyConversionRequest {
+	requests := make([]CurrencyConversionRequest, len(s.ToCurrencies))
+	for i, to := range s.ToCurrencies {
+		requests[i] = CurrencyConversionRequest{Amount: s.Amount, FromCurrency: s.FromCurrency, ToCurrency: to, MerchantID: s.MerchantID, Date: s.Date}
+	}
+	return requests
+}
+
+// BatchConversionResult representa o resultado de um item de um lote de conversões.
+// Em caso de sucesso, os campos da conversão são incluídos; em caso de erro, apenas Error, no mesmo formato das respostas de erro da API.
+type BatchConversionResult struct {
+	// Index é a posição do item no lote, começando em zero.
+	Index int `json:"index"`
+	// Status é o código HTTP que a conversão do item teria em /convert-currency.
+	Status int `json:"status"`
+	*CurrencyConversionResponse
+	Error *Problem `json:"error,omitempty"`
+}
+
+// BatchConversionResponse representa a resposta de um lote de conversões no formato JSON.
+type BatchConversionResponse struct {
+	Results []BatchConversionResult `json:"results"`
 }
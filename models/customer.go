@@ -0,0 +1,61 @@
+// customer.go
+// Este arquivo define os modelos de clientes e dos seus métodos de pagamento salvos.
+// Um cliente pode salvar cartões já guardados no cofre (card_token) e acordos de cobrança do PayPal (billing agreements)
+// e escolher um deles como padrão. Nos pagamentos, customer_id e payment_method_id substituem os dados do cartão;
+// sem payment_method_id, é usado o método de pagamento padrão do cliente.
+
+package models
+
+import "time"
+
+// Tipos de métodos de pagamento salvos.
+const (
+	PaymentMethodCard                   = "card"
+	PaymentMethodPayPalBillingAgreement = "paypal_billing_agreement"
+)
+
+// CustomerRequest representa a criação ou a alteração de um cliente.
+// DefaultPaymentMethodID altera o método de pagamento padrão, que deve pertencer ao cliente.
+type CustomerRequest struct {
+	Name                   string `json:"name" validate:"required,max=200"`
+	Email                  string `json:"email" validate:"required,email,max=254"`
+	DefaultPaymentMethodID string `json:"default_payment_method_id,omitempty"`
+}
+
+// Customer representa um cliente cadastrado.
+type Customer struct {
+	ID                     string    `json:"id"`
+	Name                   string    `json:"name"`
+	Email                  string    `json:"email"`
+	DefaultPaymentMethodID string    `json:"default_payment_method_id,omitempty"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+}
+
+// PaymentMethodRequest representa o cadastro de um método de pagamento de um cliente.
+// Cartões são informados pelo token do cofre (POST /tokens); acordos de cobrança do PayPal, pelo ID do acordo (B-...).
+// O primeiro método de pagamento do cliente se torna o padrão; Default torna o novo método o padrão.
+type PaymentMethodRequest struct {
+	Type               string `json:"type" validate:"required,oneof=card paypal_billing_agreement"`
+	CardToken          string `json:"card_token" validate:"required_if=Type card,excluded_unless=Type card"`
+	BillingAgreementID string `json:"billing_agreement_id" validate:"required_if=Type paypal_billing_agreement,excluded_unless=Type paypal_billing_agreement,max=64"`
+	PayerEmail         string `json:"payer_email" validate:"excluded_unless=Type paypal_billing_agreement,max=254"`
+	Default            bool   `json:"default"`
+}
+
+// PaymentMethod representa um método de pagamento salvo de um cliente.
+// Dos cartões são expostos apenas o token do cofre, a bandeira, os quatro últimos dígitos e a validade.
+type PaymentMethod struct {
+	ID                 string    `json:"id"`
+	CustomerID         string    `json:"customer_id"`
+	Type               string    `json:"type"`
+	CardToken          string    `json:"card_token,omitempty"`
+	CardBrand          CardBrand `json:"card_brand,omitempty"`
+	CardLast4          string    `json:"card_last4,omitempty"`
+	CardExpiry         string    `json:"card_expiry,omitempty"`
+	BillingAgreementID string    `json:"billing_agreement_id,omitempty"`
+	PayerEmail         string    `json:"payer_email,omitempty"`
+	// Default indica se é o método de pagamento padrão do cliente; não é armazenado, e sim calculado a partir do cliente.
+	Default   bool      `json:"default"`
+	CreatedAt time.Time `json:"created_at"`
+}
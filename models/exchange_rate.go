@@ -0,0 +1,103 @@
+// exchange_rate.go
+// Este arquivo define a tabela de taxas de câmbio retornada pelos provedores de taxas (services.RateProvider).
+// Uma tabela contém as taxas de uma única moeda base; as demais bases são obtidas por taxas cruzadas (Rebase).
+
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// RateTable é a tabela de taxas de câmbio de uma moeda base.
+type RateTable struct {
+	// Base é a moeda base da tabela (e.g. "EUR").
+	Base string
+	// Rates contém quantas unidades de cada moeda equivalem a uma unidade da moeda base.
+	Rates map[string]float64
+	// Date é a data de referência das taxas informada pelo provedor.
+	Date time.Time
+	// Provider é o nome do provedor que forneceu as taxas.
+	Provider string
+}
+
+// Rate retorna a taxa de conversão da moeda base para a moeda informada.
+func (t RateTable) Rate(currency string) (float64, bool) {
+	if currency == t.Base {
+		return 1, true
+	}
+	rate, ok := t.Rates[currency]
+	return rate, ok
+}
+
+// Rebase calcula, por taxas cruzadas, a tabela de outra moeda base presente na tabela.
+// e.g. com base EUR, USD/BRL = (EUR/BRL) / (EUR/USD).
+func (t RateTable) Rebase(base string) (RateTable, error) {
+	if base == t.Base {
+		return t, nil
+	}
+	baseRate, ok := t.Rates[base]
+	if !ok {
+		return RateTable{}, fmt.Errorf("currency %s not found in %s rates", base, t.Base)
+	}
+
+	rates := make(map[string]float64, len(t.Rates))
+	rates[t.Base] = 1 / baseRate
+	for currency, rate := range t.Rates {
+		if currency != base {
+			rates[currency] = rate / baseRate
+		}
+	}
+	return RateTable{Base: base, Rates: rates, Date: t.Date, Provider: t.Provider}, nil
+}
+
+// Validate verifica se a tabela tem uma moeda base e apenas taxas positivas e finitas.
+func (t RateTable) Validate() error {
+	if len(t.Base) != 3 {
+		return fmt.Errorf("invalid base currency %q", t.Base)
+	}
+	if len(t.Rates) == 0 {
+		return fmt.Errorf("no rates for %s", t.Base)
+	}
+	for currency, rate := range t.Rates {
+		if len(currency) != 3 {
+			return fmt.Errorf("invalid currency code %q", currency)
+		}
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return fmt.Errorf("invalid rate %v for %s", rate, currency)
+		}
+	}
+	return nil
+}
+
+// RateStatus descreve a última consulta às taxas de uma moeda base.
+type RateStatus struct {
+	Base string `json:"base"`
+	// Provider é o provedor que forneceu a última tabela obtida.
+	Provider string `json:"provider,omitempty"`
+	// RateDate é a data de referência da última tabela obtida.
+	RateDate string `json:"rate_date,omitempty"`
+	// LastRefresh é quando a última tabela foi obtida; LastAttempt é quando o provedor foi consultado pela última vez.
+	LastRefresh *time.Time `json:"last_refresh,omitempty"`
+	LastAttempt *time.Time `json:"last_attempt,omitempty"`
+	// LastError é o erro da última consulta, vazio se ela foi bem-sucedida.
+	LastError string `json:"last_error,omitempty"`
+	// Stale indica que a tabela em cache passou do TTL.
+	Stale bool `json:"stale"`
+}
+
+// RatesResponse representa a tabela de taxas em cache de uma moeda base (GET /rates).
+type RatesResponse struct {
+	Base string `json:"base"`
+	// Provider é o provedor que forneceu a tabela.
+	Provider string `json:"provider"`
+	// RateDate é a data de referência das taxas informada pelo provedor.
+	RateDate string `json:"rate_date"`
+	// FetchedAt é quando a tabela foi obtida do provedor.
+	FetchedAt time.Time `json:"fetched_at"`
+	// Stale indica que a tabela passou do TTL do cache e está sendo atualizada em segundo plano.
+	Stale bool `json:"stale"`
+	// Rates contém quantas unidades de cada moeda equivalem a uma unidade da moeda base.
+	Rates map[string]float64 `json:"rates"`
+}
@@ -0,0 +1,72 @@
+// fx_markup.go
+// Este arquivo define as regras de markup (margem) cobradas nas conversões de moeda e o detalhamento das tarifas.
+// Uma regra pode ser restrita a um merchant, a uma moeda de origem e/ou a uma moeda de destino; campos vazios
+// aceitam qualquer valor. A tarifa é composta por um percentual sobre o valor e/ou uma tarifa fixa, ambos na
+// moeda de origem, e pode variar por faixa de valor (tiers).
+
+package models
+
+// MarkupTier é a tarifa aplicada a partir de um valor mínimo, na moeda de origem.
+type MarkupTier struct {
+	MinAmount Decimal `json:"min_amount"`
+	// Percent é o percentual cobrado sobre o valor (e.g. 1.5 para 1,5%).
+	Percent Decimal `json:"percent"`
+	// FixedFee é a tarifa fixa, na moeda de origem.
+	FixedFee Decimal `json:"fixed_fee"`
+}
+
+// MarkupRule é uma regra de markup das conversões de moeda.
+type MarkupRule struct {
+	MerchantID   string `json:"merchant_id,omitempty"`
+	FromCurrency string `json:"from_currency,omitempty"`
+	ToCurrency   string `json:"to_currency,omitempty"`
+	// Percent e FixedFee são a tarifa de valores abaixo da primeira faixa.
+	Percent  Decimal `json:"percent"`
+	FixedFee Decimal `json:"fixed_fee"`
+	// Tiers substituem a tarifa a partir de cada valor mínimo.
+	Tiers []MarkupTier `json:"tiers,omitempty"`
+}
+
+// Matches informa se a regra se aplica à conversão do merchant entre as moedas informadas.
+func (r MarkupRule) Matches(merchantID, fromCurrency, toCurrency string) bool {
+	return (r.MerchantID == "" || r.MerchantID == merchantID) &&
+		(r.FromCurrency == "" || r.FromCurrency == fromCurrency) &&
+		(r.ToCurrency == "" || r.ToCurrency == toCurrency)
+}
+
+// Specificity indica quão específica é a regra: o merchant prevalece sobre o par de moedas,
+// e a moeda de origem sobre a de destino.
+func (r MarkupRule) Specificity() int {
+	specificity := 0
+	if r.MerchantID != "" {
+		specificity += 4
+	}
+	if r.FromCurrency != "" {
+		specificity += 2
+	}
+	if r.ToCurrency != "" {
+		specificity++
+	}
+	return specificity
+}
+
+// Tier retorna a tarifa da regra para o valor informado: a da faixa com o maior valor mínimo não superior ao valor.
+func (r MarkupRule) Tier(amount Decimal) MarkupTier {
+	tier := MarkupTier{Percent: r.Percent, FixedFee: r.FixedFee}
+	for _, candidate := range r.Tiers {
+		if candidate.MinAmount.Cmp(amount) <= 0 && candidate.MinAmount.Cmp(tier.MinAmount) >= 0 {
+			tier = candidate
+		}
+	}
+	return tier
+}
+
+// ConversionFees é o detalhamento das tarifas de uma conversão, na moeda de origem.
+type ConversionFees struct {
+	Currency string `json:"currency"`
+	// Percent é o percentual aplicado (e.g. 1.5 para 1,5%) e PercentageFee o valor correspondente.
+	Percent       Decimal `json:"percent"`
+	PercentageFee Decimal `json:"percentage_fee"`
+	FixedFee      Decimal `json:"fixed_fee"`
+	Total         Decimal `json:"total"`
+}
@@ -0,0 +1,35 @@
+// fx_quote.go
+// Este arquivo define os modelos das cotações de câmbio com taxa travada.
+// Uma cotação garante ao cliente a taxa apresentada até a sua expiração e pode ser usada em um único pagamento,
+// informando o quote_id na solicitação de pagamento.
+
+package models
+
+import "time"
+
+// FXQuoteRequest representa uma solicitação de cotação de câmbio.
+// O valor é opcional; quando informado, o pagamento que usar a cotação deve ter exatamente esse valor.
+type FXQuoteRequest struct {
+	Amount       Decimal `json:"amount" validate:"omitempty,gt=0"`
+	FromCurrency string  `json:"from_currency" validate:"required,iso4217"`
+	ToCurrency   string  `json:"to_currency" validate:"required,iso4217,nefield=FromCurrency"`
+}
+
+// FXQuote representa uma cotação de câmbio registrada.
+type FXQuote struct {
+	ID              string     `json:"quote_id"`
+	FromCurrency    string     `json:"from_currency"`
+	ToCurrency      string     `json:"to_currency"`
+	Rate            float64    `json:"rate"`
+	Amount          *Decimal   `json:"amount,omitempty"`
+	ConvertedAmount *Decimal   `json:"converted_amount,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	UsedAt          *time.Time `json:"used_at,omitempty"`
+	TransactionID   string     `json:"transaction_id,omitempty"`
+}
+
+// Expired informa se a cotação já expirou no instante informado.
+func (q FXQuote) Expired(at time.Time) bool {
+	return !at.Before(q.ExpiresAt)
+}
@@ -0,0 +1,114 @@
+// mask.go
+// Este arquivo contém o mascaramento dos números de cartão (PAN) e a remoção de dados do cartão de textos livres.
+// O número do cartão nunca é exibido por completo: apenas o BIN (os seis primeiros dígitos) e os quatro últimos,
+// o máximo permitido pelo PCI DSS (e.g. 411111******1111). O CVV nunca é exibido.
+
+// CardDetails implementa fmt.Stringer, fmt.GoStringer e slog.LogValuer com os dados mascarados, de modo que
+// o cartão não aparece por completo ao ser impresso ou registrado em log. A serialização em JSON continua completa,
+// pois é usada pelos clientes da API; as respostas da API passam por RedactJSON.
+// Textos livres (mensagens de log, mensagens de erro dos gateways) passam por RedactCardData, que mascara qualquer
+// sequência de 13 a 19 dígitos, separados ou não por espaços ou hífens, e remove os valores de campos de CVV.
+
+package models
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted substitui os valores que não podem ser exibidos, como o CVV.
+const Redacted = "[REDACTED]"
+
+// cardBINLength é o número de dígitos iniciais do cartão que podem ser exibidos.
+const cardBINLength = 6
+
+var (
+	// panPattern reconhece sequências de 13 a 19 dígitos, que podem estar separados por um espaço ou hífen.
+	panPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	// cvvPattern reconhece o valor de campos de CVV em texto ou JSON (e.g. cvv=123, "cvc": "1234").
+	cvvPattern = regexp.MustCompile(`(?i)\b(cvv2?|cvc|security_code)("?\s*[:=]\s*"?)\d{3,4}\b`)
+	// jsonStringPattern reconhece os textos de um documento JSON.
+	jsonStringPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	// jsonCVVPattern reconhece os campos de CVV de um documento JSON, com qualquer valor em texto.
+	jsonCVVPattern = regexp.MustCompile(`(?i)"(cvv2?|cvc|security_code)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+// MaskPAN retorna o número do cartão com apenas o BIN e os quatro últimos dígitos (e.g. 411111******1111).
+// Números com menos de 13 dígitos, que não são cartões válidos, são totalmente mascarados.
+func MaskPAN(number string) string {
+	if len(number) < 13 {
+		return strings.Repeat("*", len(number))
+	}
+	return number[:cardBINLength] + strings.Repeat("*", len(number)-cardBINLength-4) + number[len(number)-4:]
+}
+
+// RedactCardData mascara no texto todas as sequências de dígitos que podem ser números de cartão
+// e substitui os valores de campos de CVV por Redacted.
+func RedactCardData(text string) string {
+	text = panPattern.ReplaceAllStringFunc(text, func(match string) string {
+		return MaskPAN(strings.NewReplacer(" ", "", "-", "").Replace(match))
+	})
+	return cvvPattern.ReplaceAllString(text, "${1}${2}"+Redacted)
+}
+
+// RedactJSON remove os dados de cartão de um documento JSON: os números de cartão são mascarados apenas
+// dentro dos textos, para não alterar valores numéricos, e os campos de CVV têm o valor substituído por Redacted.
+func RedactJSON(data []byte) []byte {
+	data = jsonStringPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		return []byte(RedactCardData(string(match)))
+	})
+	return jsonCVVPattern.ReplaceAll(data, []byte(`"${1}"${2}"`+Redacted+`"`))
+}
+
+// BIN retorna os seis primeiros dígitos do cartão, que identificam o emissor.
+func (c CardDetails) BIN() string {
+	if len(c.Number) < cardBINLength {
+		return ""
+	}
+	return c.Number[:cardBINLength]
+}
+
+// Last4 retorna os quatro últimos dígitos do cartão.
+func (c CardDetails) Last4() string {
+	if len(c.Number) < 4 {
+		return ""
+	}
+	return c.Number[len(c.Number)-4:]
+}
+
+// maskedCard é a representação mascarada de CardDetails.
+type maskedCard struct {
+	Number, Expiry, CVV string
+}
+
+// masked retorna o cartão com o número mascarado e o CVV substituído por Redacted.
+func (c CardDetails) masked() maskedCard {
+	card := maskedCard{Number: MaskPAN(c.Number), Expiry: c.Expiry}
+	if c.CVV != "" {
+		card.CVV = Redacted
+	}
+	return card
+}
+
+// String retorna o cartão mascarado, usado por fmt (%v, %+v e %s).
+func (c CardDetails) String() string {
+	card := c.masked()
+	return fmt.Sprintf("{Number:%s Expiry:%s CVV:%s}", card.Number, card.Expiry, card.CVV)
+}
+
+// GoString retorna o cartão mascarado, usado por fmt (%#v).
+func (c CardDetails) GoString() string {
+	return "models.CardDetails" + c.String()
+}
+
+// LogValue registra o cartão mascarado nos logs (log/slog).
+func (c CardDetails) LogValue() slog.Value {
+	card := c.masked()
+	return slog.GroupValue(
+		slog.String("number", card.Number),
+		slog.String("expiry", card.Expiry),
+		slog.String("cvv", card.CVV),
+	)
+}
@@ -0,0 +1,385 @@
+// money.go
+// Este arquivo define os tipos usados para representar valores monetários sem os erros de arredondamento de float64
+// (e.g. 18.330000000000002).
+
+// Decimal é um número decimal exato, usado nos campos de valor do JSON e do banco de dados.
+// Money é um valor inteiro na unidade menor da moeda (e.g. centavos) junto com o código ISO 4217, usado nos cálculos.
+// As conversões entre moedas são arredondadas às casas decimais da moeda de destino (currency.go)
+// pelo modo de arredondamento informado; o padrão é RoundHalfEven (arredondamento bancário).
+
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDecimalScale é a maior quantidade de casas decimais aceita em um Decimal.
+const maxDecimalScale = 18
+
+// Erros de leitura de valores decimais.
+var (
+	ErrInvalidDecimal   = errors.New("invalid decimal")
+	ErrAmountOutOfRange = errors.New("amount out of range")
+	// ErrConvertedAmountOutOfRange é retornado quando o resultado de uma conversão ou de um arredondamento
+	// (e.g. 10^15 USD em IDR) não cabe na unidade menor da moeda. Também satisfaz errors.Is(err, ErrAmountOutOfRange).
+	ErrConvertedAmountOutOfRange = fmt.Errorf("converted %w", ErrAmountOutOfRange)
+)
+
+// decimalPattern aceita números no formato JSON (e.g. "18.33", "-5", "1e3"), limitando o tamanho do expoente.
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]{1,3})?$`)
+
+// Decimal é um número decimal exato, representado pelos dígitos sem o separador e pela quantidade de casas decimais.
+// O valor zero de Decimal é o número 0.
+type Decimal struct {
+	value int64
+	scale int
+}
+
+// NewDecimal cria um Decimal a partir dos dígitos e da quantidade de casas decimais (e.g. 1833, 2 para 18.33).
+func NewDecimal(value int64, scale int) Decimal {
+	return Decimal{value: value, scale: scale}
+}
+
+// ParseDecimal lê um número decimal no formato JSON, mantendo as casas decimais escritas (e.g. "10.50" tem duas).
+func ParseDecimal(s string) (Decimal, error) {
+	if !decimalPattern.MatchString(s) {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+
+	// As casas decimais são as da mantissa, descontado o expoente (e.g. "1.25e1" tem uma casa decimal)
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		exponent, _ = strconv.Atoi(s[i+1:])
+	}
+	scale := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		scale = len(mantissa) - i - 1
+	}
+	if scale -= exponent; scale < 0 {
+		scale = 0
+	}
+	if scale > maxDecimalScale {
+		return Decimal{}, fmt.Errorf("%w: %s has more than %d decimal places", ErrInvalidDecimal, s, maxDecimalScale)
+	}
+
+	value := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(scale)))
+	if !value.Num().IsInt64() {
+		return Decimal{}, fmt.Errorf("%w: %s", ErrAmountOutOfRange, s)
+	}
+	return Decimal{value: value.Num().Int64(), scale: scale}, nil
+}
+
+// MustParseDecimal é como ParseDecimal, mas entra em pânico se o número for inválido.
+// Indicada para valores constantes.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// DecimalFromFloat converte um float64 para Decimal usando a menor representação decimal que o identifica
+// (e.g. 18.33, e não 18.329999999999998). Usada apenas na leitura de colunas REAL do banco de dados.
+func DecimalFromFloat(f float64) (Decimal, error) {
+	return ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Sign retorna -1, 0 ou 1 conforme o número seja negativo, zero ou positivo.
+func (d Decimal) Sign() int {
+	switch {
+	case d.value < 0:
+		return -1
+	case d.value > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero informa se o número é zero.
+func (d Decimal) IsZero() bool {
+	return d.value == 0
+}
+
+// Cmp compara dois números, retornando -1, 0 ou 1.
+func (d Decimal) Cmp(other Decimal) int {
+	return d.rat().Cmp(other.rat())
+}
+
+// Float64 retorna o número como float64, possivelmente com perda de precisão.
+// Usado apenas nas validações (e.g. gt=0).
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+// String retorna o número com todas as suas casas decimais (e.g. "18.33").
+func (d Decimal) String() string {
+	return d.rat().FloatString(d.scale)
+}
+
+// MarshalJSON escreve o número sem aspas, com todas as suas casas decimais.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON lê um número JSON sem passar por float64. Números entre aspas também são aceitos.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+
+	parsed, err := ParseDecimal(strings.TrimSpace(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value grava o número no banco de dados como texto decimal, que o SQLite converte para a afinidade da coluna.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan lê o número de uma coluna do banco de dados (REAL, INTEGER ou TEXT).
+func (d *Decimal) Scan(src interface{}) error {
+	var (
+		parsed Decimal
+		err    error
+	)
+	switch v := src.(type) {
+	case nil:
+		parsed = Decimal{}
+	case float64:
+		parsed, err = DecimalFromFloat(v)
+	case int64:
+		parsed = Decimal{value: v}
+	case string:
+		parsed, err = ParseDecimal(v)
+	case []byte:
+		parsed, err = ParseDecimal(string(v))
+	default:
+		err = fmt.Errorf("%w: unsupported type %T", ErrInvalidDecimal, src)
+	}
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Money converte o número para um valor na moeda informada, arredondando-o (RoundHalfEven) às casas decimais
+// da moeda quando necessário. Para valores informados pelo cliente, use NewMoney, que rejeita casas decimais a mais.
+// Retorna ErrConvertedAmountOutOfRange se o valor não couber na unidade menor da moeda.
+func (d Decimal) Money(currency string) (Money, error) {
+	return newRoundedMoney(d.rat(), currency, RoundHalfEven)
+}
+
+// rat retorna o número como um racional exato.
+func (d Decimal) rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(d.value), pow10(d.scale))
+}
+
+// AmountPrecisionError é retornado quando um valor tem mais casas decimais do que a moeda permite (e.g. 10.001 USD).
+type AmountPrecisionError struct {
+	Amount   Decimal
+	Currency string
+}
+
+func (e *AmountPrecisionError) Error() string {
+	return fmt.Sprintf("amount %s has more decimal places than %s allows (%d)", e.Amount, e.Currency, MinorUnits(e.Currency))
+}
+
+// Money é um valor monetário na unidade menor da moeda (e.g. 1833 centavos de USD para 18.33 USD).
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// NewMoney cria um valor na moeda informada, rejeitando valores com mais casas decimais do que a moeda permite.
+// Zeros à direita não contam (e.g. 10.500 USD é aceito).
+func NewMoney(amount Decimal, currency string) (Money, error) {
+	units := MinorUnits(currency)
+	value, scale := amount.value, amount.scale
+	for scale > units && value%10 == 0 {
+		value, scale = value/10, scale-1
+	}
+	if scale > units {
+		return Money{}, &AmountPrecisionError{Amount: amount, Currency: currency}
+	}
+
+	minor := new(big.Int).Mul(big.NewInt(value), pow10(units-scale))
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s %s", ErrAmountOutOfRange, amount, currency)
+	}
+	return Money{Minor: minor.Int64(), Currency: currency}, nil
+}
+
+// Amount retorna o valor como Decimal, com as casas decimais da moeda (e.g. "18.30", "1500", "1.250").
+func (m Money) Amount() Decimal {
+	return Decimal{value: m.Minor, scale: MinorUnits(m.Currency)}
+}
+
+// IsZero informa se o valor é zero.
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+// Add e Sub somam e subtraem valores da mesma moeda.
+func (m Money) Add(other Money) Money {
+	m.Minor += other.Minor
+	return m
+}
+
+func (m Money) Sub(other Money) Money {
+	m.Minor -= other.Minor
+	return m
+}
+
+// Convert converte o valor para outra moeda pela taxa informada, arredondando o resultado às casas decimais
+// da moeda de destino pelo modo de arredondamento informado.
+// A taxa é tratada pela sua menor representação decimal (e.g. 1.1, e não 1.100000000000000088817...).
+// Retorna ErrConvertedAmountOutOfRange se o valor convertido não couber na unidade menor da moeda de destino.
+func (m Money) Convert(rate float64, currency string, mode RoundingMode) (Money, error) {
+	converted := new(big.Rat).Mul(m.Amount().rat(), rateRat(rate))
+	return newRoundedMoney(converted, currency, mode)
+}
+
+// Percentage retorna o percentual informado do valor (e.g. 2.5 para 2,5%), arredondado às casas decimais
+// da moeda pelo modo de arredondamento informado. Retorna ErrConvertedAmountOutOfRange se o resultado não couber na unidade menor.
+func (m Money) Percentage(percent Decimal, mode RoundingMode) (Money, error) {
+	value := new(big.Rat).Mul(m.Amount().rat(), percent.rat())
+	value.Quo(value, big.NewRat(100, 1))
+	return newRoundedMoney(value, m.Currency, mode)
+}
+
+// newRoundedMoney arredonda o valor às casas decimais da moeda, verificando se ele cabe na unidade menor (int64).
+func newRoundedMoney(value *big.Rat, currency string, mode RoundingMode) (Money, error) {
+	minor := roundRat(value, MinorUnits(currency), mode)
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s %s", ErrConvertedAmountOutOfRange, value.FloatString(MinorUnits(currency)), currency)
+	}
+	return Money{Minor: minor.Int64(), Currency: currency}, nil
+}
+
+// String retorna o valor com a moeda (e.g. "18.33 USD").
+func (m Money) String() string {
+	return m.Amount().String() + " " + m.Currency
+}
+
+// rateRat converte uma taxa de câmbio para um racional exato a partir da sua menor representação decimal.
+func rateRat(rate float64) *big.Rat {
+	if r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'g', -1, 64)); ok {
+		return r
+	}
+	return new(big.Rat)
+}
+
+// RoundingMode define como um valor é arredondado às casas decimais da moeda.
+type RoundingMode int
+
+// Modos de arredondamento suportados. O valor zero é RoundHalfEven.
+const (
+	// RoundHalfEven arredonda para o vizinho mais próximo e, no empate, para o dígito par (arredondamento bancário).
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp arredonda para o vizinho mais próximo e, no empate, para longe do zero.
+	RoundHalfUp
+	// RoundHalfDown arredonda para o vizinho mais próximo e, no empate, em direção ao zero.
+	RoundHalfDown
+	// RoundUp arredonda para longe do zero.
+	RoundUp
+	// RoundDown arredonda em direção ao zero (trunca).
+	RoundDown
+	// RoundCeiling arredonda em direção ao infinito positivo.
+	RoundCeiling
+	// RoundFloor arredonda em direção ao infinito negativo.
+	RoundFloor
+)
+
+var roundingModeNames = map[RoundingMode]string{
+	RoundHalfEven: "half_even",
+	RoundHalfUp:   "half_up",
+	RoundHalfDown: "half_down",
+	RoundUp:       "up",
+	RoundDown:     "down",
+	RoundCeiling:  "ceiling",
+	RoundFloor:    "floor",
+}
+
+// ParseRoundingMode lê um modo de arredondamento pelo nome (e.g. "half_even", "half_up", "floor").
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	for mode, modeName := range roundingModeNames {
+		if strings.EqualFold(name, modeName) {
+			return mode, nil
+		}
+	}
+	return RoundHalfEven, fmt.Errorf("unknown rounding mode %q", name)
+}
+
+func (m RoundingMode) String() string {
+	return roundingModeNames[m]
+}
+
+// roundRat arredonda o número para a quantidade de casas decimais informada e retorna os dígitos resultantes
+// (e.g. 18.335 com 2 casas em RoundHalfEven retorna 1834).
+func roundRat(r *big.Rat, scale int, mode RoundingMode) *big.Int {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(scale)))
+	quotient, remainder := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// Compara a parte descartada com a metade: 2*|resto| em relação ao denominador
+	half := new(big.Int).Abs(remainder)
+	half.Lsh(half, 1)
+	cmp := half.Cmp(scaled.Denom())
+	negative := scaled.Sign() < 0
+
+	var away bool
+	switch mode {
+	case RoundHalfUp:
+		away = cmp >= 0
+	case RoundHalfDown:
+		away = cmp > 0
+	case RoundUp:
+		away = true
+	case RoundDown:
+		away = false
+	case RoundCeiling:
+		away = !negative
+	case RoundFloor:
+		away = negative
+	default:
+		away = cmp > 0 || (cmp == 0 && quotient.Bit(0) == 1)
+	}
+
+	if away {
+		if negative {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+	return quotient
+}
+
+// pow10 retorna 10 elevado à potência informada.
+func pow10(exp int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil)
+}
@@ -4,36 +4,149 @@
 
 package models
 
+import "time"
+
 // PaymentRequest representa uma solicitação de pagamento.
-// Inclui detalhes do gateway, valor, moeda (somente USD é aceito), método de pagamento e informações do cartão.
+// Inclui detalhes do gateway, valor, moeda (qualquer código ISO 4217 aceito em pagamentos, veja Currency.SupportedForPayment),
+// método de pagamento e informações do cartão, enviadas diretamente em card_details ou guardadas no cofre e referenciadas por card_token.
+// Clientes cadastrados podem informar customer_id e payment_method_id no lugar dos dados do cartão (veja Customer).
+// Pagamentos Pix (payment_method "pix") não têm dados de cartão: o pagador paga o QR Code retornado em pix (veja PixCharge).
+// A exigência dos dados do cartão nos demais métodos é verificada por uma validação da estrutura (handlers/payment.go).
+// O valor não pode ter mais casas decimais do que a moeda permite (e.g. 10.5 JPY é inválido).
 type PaymentRequest struct {
-	Gateway       string      `json:"gateway" validate:"required"`
-	Amount        float64     `json:"amount" validate:"required,gt=0"`
-	Currency      string      `json:"currency" validate:"required,oneof=USD"`
-	PaymentMethod string      `json:"payment_method" validate:"required"`
-	CardDetails   CardDetails `json:"card_details" validate:"required"`
+	Gateway       string       `json:"gateway" validate:"required"`
+	Amount        Decimal      `json:"amount" validate:"required,gt=0"`
+	Currency      string       `json:"currency" validate:"required,iso4217,payment_currency"`
+	PaymentMethod string       `json:"payment_method" validate:"required,oneof=credit_card paypal pix"`
+	CardDetails   *CardDetails `json:"card_details,omitempty" validate:"excluded_with=CardToken CustomerID,excluded_if=PaymentMethod pix"`
+	// CardToken é o token de um cartão guardado no cofre (POST /tokens), usado no lugar de card_details.
+	CardToken string `json:"card_token,omitempty" validate:"excluded_with=CustomerID,excluded_if=PaymentMethod pix"`
+	// CustomerID e PaymentMethodID identificam um método de pagamento salvo do cliente, usado no lugar de card_details.
+	// Sem PaymentMethodID, é usado o método de pagamento padrão do cliente.
+	CustomerID      string `json:"customer_id,omitempty" validate:"excluded_if=PaymentMethod pix"`
+	PaymentMethodID string `json:"payment_method_id,omitempty" validate:"excluded_without=CustomerID"`
+	// BillingAgreementID é o acordo de cobrança do PayPal do método de pagamento salvo; é preenchido pelo serviço.
+	BillingAgreementID string `json:"-"`
+	// QuoteID é o ID de uma cotação de câmbio (POST /fx/quotes) cuja taxa deve ser usada na conversão.
+	QuoteID string `json:"quote_id,omitempty"`
 }
 
 // CardDetails representa os detalhes do cartão de crédito.
+// O número deve pertencer a uma bandeira aceita (veja CardBrand), ter o tamanho da bandeira e um dígito verificador (Luhn) válido;
+// a validade (MM/YY) não pode ter passado, e o tamanho do CVV depende da bandeira (4 dígitos para Amex).
 type CardDetails struct {
-	Number string `json:"number" validate:"required,len=16"`
-	Expiry string `json:"expiry" validate:"required,len=5"`
-	CVV    string `json:"cvv" validate:"required,len=3"`
+	Number string `json:"number" validate:"required,numeric,card_brand,card_length,luhn_checksum"`
+	Expiry string `json:"expiry" validate:"required,card_expiry,not_expired"`
+	CVV    string `json:"cvv" validate:"required,numeric"`
 }
 
 // PaymentResponse representa a resposta de uma transação de pagamento.
+// Os campos de liquidação informam o valor original, o valor liquidado pelo gateway e a taxa de câmbio utilizada
+// (1 quando o gateway liquida na própria moeda do pagamento).
 type PaymentResponse struct {
-	Message        string `json:"message"`
-	Transaction_ID string `json:"transaction_id"`
+	Message          string  `json:"message"`
+	Transaction_ID   string  `json:"transaction_id"`
+	Status           string  `json:"status,omitempty"`
+	OriginalAmount   Decimal `json:"original_amount"`
+	OriginalCurrency string  `json:"original_currency,omitempty"`
+	SettledAmount    Decimal `json:"settled_amount"`
+	SettledCurrency  string  `json:"settled_currency,omitempty"`
+	ExchangeRate     float64 `json:"exchange_rate,omitempty"`
+	QuoteID          string  `json:"quote_id,omitempty"`
+	CardBrand        string  `json:"card_brand,omitempty"`
+	CardLast4        string  `json:"card_last4,omitempty"`
+	CustomerID       string  `json:"customer_id,omitempty"`
+	PaymentMethodID  string  `json:"payment_method_id,omitempty"`
+	// Pix traz o QR Code de uma cobrança Pix, que fica pendente até o pagamento ou a expiração.
+	Pix *PixCharge `json:"pix,omitempty"`
 }
 
+// TransactionResponse representa a resposta da consulta de status de uma transação,
+// incluindo o histórico de mudanças de status.
 type TransactionResponse struct {
-	Message string `json:"message"`
-	Status  string `json:"status"`
+	Message       string             `json:"message"`
+	Status        string             `json:"status"`
+	TransactionID string             `json:"transaction_id,omitempty"`
+	Gateway       string             `json:"gateway,omitempty"`
+	History       []StatusTransition `json:"history,omitempty"`
+}
+
+// CaptureRequest representa uma solicitação de captura de um pagamento autorizado.
+// Se o valor não for informado, o valor total autorizado é capturado.
+type CaptureRequest struct {
+	Amount Decimal `json:"amount" validate:"omitempty,gt=0"`
 }
 
-// Transaction representa a estrutura de dados de uma transação interna
+// Transaction representa a estrutura de dados de uma transação interna.
+// Amount e Currency são os valores liquidados no gateway (base para captura e reembolso);
+// OriginalAmount e OriginalCurrency são os valores apresentados ao cliente, convertidos pela taxa ExchangeRate.
+// Do cartão são guardados apenas a bandeira, o BIN (seis primeiros dígitos) e os quatro últimos dígitos.
+// ExpiresAt é a expiração das cobranças Pix, que ficam pendentes até a confirmação do pagamento pelo PSP;
+// PixEndToEndID identifica o Pix recebido (endToEndId), informado na notificação do PSP, e PixDuplicates,
+// os endToEndIds de outros Pix recebidos para a cobrança já paga, que precisam ser devolvidos.
 type Transaction struct {
-	Status         string `json:"status"`
-	Transaction_ID string `json:"message"`
+	Status           string             `json:"status"`
+	Transaction_ID   string             `json:"transaction_id"`
+	Gateway          string             `json:"gateway"`
+	Amount           Decimal            `json:"amount"`
+	CapturedAmount   Decimal            `json:"captured_amount"`
+	RefundedAmount   Decimal            `json:"refunded_amount"`
+	Currency         string             `json:"currency"`
+	OriginalAmount   Decimal            `json:"original_amount"`
+	OriginalCurrency string             `json:"original_currency"`
+	ExchangeRate     float64            `json:"exchange_rate"`
+	QuoteID          string             `json:"quote_id,omitempty"`
+	CardBrand        string             `json:"card_brand,omitempty"`
+	CardBIN          string             `json:"card_bin,omitempty"`
+	CardLast4        string             `json:"card_last4,omitempty"`
+	CustomerID       string             `json:"customer_id,omitempty"`
+	PaymentMethodID  string             `json:"payment_method_id,omitempty"`
+	PaymentMethod    string             `json:"payment_method,omitempty"`
+	ExpiresAt        *time.Time         `json:"expires_at,omitempty"`
+	PixEndToEndID    string             `json:"pix_end_to_end_id,omitempty"`
+	PixDuplicates    []string           `json:"pix_duplicate_end_to_end_ids,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	History          []StatusTransition `json:"history"`
+}
+
+// RefundResponse representa a resposta de um reembolso.
+// Os gateways preenchem GatewayRefundID; RefundID e TransactionStatus são preenchidos pelo serviço de reembolsos.
+type RefundResponse struct {
+	Message           string  `json:"message"`
+	RefundID          string  `json:"refund_id,omitempty"`
+	GatewayRefundID   string  `json:"gateway_refund_id,omitempty"`
+	TransactionID     string  `json:"transaction_id"`
+	Amount            Decimal `json:"amount"`
+	Currency          string  `json:"currency,omitempty"`
+	Status            string  `json:"status"`
+	Reason            string  `json:"reason,omitempty"`
+	TransactionStatus string  `json:"transaction_status,omitempty"`
+}
+
+// Tipos de erro de gateway, comuns a todos os gateways.
+const (
+	GatewayErrorCard           = "card_error"
+	GatewayErrorInvalidRequest = "invalid_request"
+	GatewayErrorNotFound       = "not_found"
+	GatewayErrorAuthentication = "authentication_error"
+	GatewayErrorUnavailable    = "gateway_unavailable"
+)
+
+// GatewayError representa um erro retornado por um gateway de pagamento externo,
+// traduzido para um formato comum independente do gateway.
+type GatewayError struct {
+	Gateway     string `json:"gateway"`
+	StatusCode  int    `json:"-"`
+	Type        string `json:"type"`
+	Code        string `json:"code,omitempty"`
+	DeclineCode string `json:"decline_code,omitempty"`
+	Message     string `json:"message"`
+}
+
+func (e *GatewayError) Error() string {
+	if e.Code != "" {
+		return e.Gateway + ": " + e.Message + " (" + e.Code + ")"
+	}
+	return e.Gateway + ": " + e.Message
 }
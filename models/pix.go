@@ -0,0 +1,229 @@
+// pix.go
+// Este arquivo define os modelos dos pagamentos Pix e a geração do BR Code, o "Pix copia e cola".
+// O BR Code segue o padrão EMV QRCPS-MPM (Merchant Presented Mode) adotado pelo Banco Central do Brasil:
+// uma sequência de campos TLV (ID de dois dígitos, tamanho de dois dígitos e valor) terminada pelo CRC16 do payload.
+// https://www.bcb.gov.br/estabilidadefinanceira/pix (Manual de Padrões para Iniciação do Pix)
+
+// Cobranças imediatas usam o QR Code dinâmico: a URL de payload (location) informada pelo PSP substitui a chave Pix
+// e o txid é informado pela URL, de modo que o campo 62-05 leva "***". QR Codes estáticos levam a chave Pix
+// e, opcionalmente, um txid de até 25 caracteres.
+
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Métodos de pagamento aceitos em payment_method.
+const (
+	PaymentMethodCreditCard = "credit_card"
+	PaymentMethodPayPal     = "paypal"
+	PaymentMethodPix        = "pix"
+)
+
+// PixCharge representa uma cobrança Pix aguardando pagamento.
+// QRCode é o payload do BR Code ("Pix copia e cola") e QRCodeImage é a imagem PNG do QR Code em base64.
+type PixCharge struct {
+	TxID        string    `json:"txid"`
+	QRCode      string    `json:"qr_code"`
+	QRCodeImage string    `json:"qr_code_image"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// PixNotification representa a notificação de pagamentos recebidos enviada pelo PSP ao webhook.
+// Segue o formato da API Pix do Banco Central, em que o valor é um texto com duas casas decimais.
+type PixNotification struct {
+	Pix []PixPayment `json:"pix" validate:"required,min=1,dive"`
+}
+
+// PixPayment representa um Pix recebido: o endToEndId identifica a transferência e o txid, a cobrança paga.
+type PixPayment struct {
+	EndToEndID string    `json:"endToEndId" validate:"required,alphanum,max=32"`
+	TxID       string    `json:"txid" validate:"required,alphanum,max=35"`
+	Amount     string    `json:"valor" validate:"required"`
+	PaidAt     time.Time `json:"horario" validate:"required"`
+}
+
+// Tamanhos máximos dos campos do BR Code.
+const (
+	brCodeMaxMerchantName = 25
+	brCodeMaxMerchantCity = 15
+	brCodeMaxTxID         = 25
+	brCodeMaxFieldLength  = 99
+)
+
+// IDs dos campos do BR Code utilizados.
+const (
+	brCodePayloadFormat    = "00"
+	brCodeInitiationMethod = "01"
+	brCodeMerchantAccount  = "26"
+	brCodeCategoryCode     = "52"
+	brCodeCurrency         = "53"
+	brCodeAmount           = "54"
+	brCodeCountryCode      = "58"
+	brCodeMerchantName     = "59"
+	brCodeMerchantCity     = "60"
+	brCodeAdditionalData   = "62"
+	brCodeCRC              = "63"
+
+	// Subcampos do campo 26 (Merchant Account Information) e do campo 62 (Additional Data Field).
+	brCodePixGUI      = "00"
+	brCodePixKey      = "01"
+	brCodePixLocation = "25"
+	brCodeTxID        = "05"
+)
+
+// BRCode reúne os dados de um BR Code do Pix.
+// Informe Location para um QR Code dinâmico ou PixKey para um QR Code estático; Amount vazio deixa o valor para o pagador.
+type BRCode struct {
+	PixKey       string
+	Location     string
+	MerchantName string
+	MerchantCity string
+	Amount       Decimal
+	TxID         string
+}
+
+// Payload monta o BR Code ("Pix copia e cola"), incluindo o CRC16 no campo 63.
+// Nome e cidade do recebedor são convertidos para ASCII e truncados nos tamanhos máximos do padrão.
+func (c BRCode) Payload() (string, error) {
+	if (c.PixKey == "") == (c.Location == "") {
+		return "", fmt.Errorf("BR Code requires either a Pix key or a location")
+	}
+	if c.MerchantName == "" || c.MerchantCity == "" {
+		return "", fmt.Errorf("BR Code requires the merchant name and city")
+	}
+
+	txID := c.TxID
+	if c.Location != "" || txID == "" {
+		// Nos QR Codes dinâmicos o txid é obtido pela URL de payload
+		txID = "***"
+	}
+	if len(txID) > brCodeMaxTxID {
+		return "", fmt.Errorf("BR Code txid %q exceeds %d characters", txID, brCodeMaxTxID)
+	}
+
+	account := tlv(brCodePixGUI, "br.gov.bcb.pix")
+	if c.Location != "" {
+		account += tlv(brCodePixLocation, c.Location)
+	} else {
+		account += tlv(brCodePixKey, c.PixKey)
+	}
+	if len(account) > brCodeMaxFieldLength {
+		return "", fmt.Errorf("BR Code merchant account information exceeds %d characters", brCodeMaxFieldLength)
+	}
+
+	var b strings.Builder
+	b.WriteString(tlv(brCodePayloadFormat, "01"))
+	if c.Location != "" {
+		// 12: o QR Code dinâmico não pode ser pago mais de uma vez
+		b.WriteString(tlv(brCodeInitiationMethod, "12"))
+	}
+	b.WriteString(tlv(brCodeMerchantAccount, account))
+	b.WriteString(tlv(brCodeCategoryCode, "0000"))
+	b.WriteString(tlv(brCodeCurrency, "986"))
+	if !c.Amount.IsZero() {
+		amount, err := c.Amount.Money("BRL")
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(tlv(brCodeAmount, amount.Amount().String()))
+	}
+	b.WriteString(tlv(brCodeCountryCode, "BR"))
+	b.WriteString(tlv(brCodeMerchantName, brCodeText(c.MerchantName, brCodeMaxMerchantName)))
+	b.WriteString(tlv(brCodeMerchantCity, brCodeText(c.MerchantCity, brCodeMaxMerchantCity)))
+	b.WriteString(tlv(brCodeAdditionalData, tlv(brCodeTxID, txID)))
+
+	// O CRC é calculado sobre todo o payload, incluindo o ID e o tamanho do próprio campo 63
+	b.WriteString(brCodeCRC + "04")
+	b.WriteString(CRC16(b.String()))
+	return b.String(), nil
+}
+
+// ParseBRCode decodifica os campos de primeiro nível de um BR Code, verificando o CRC16.
+// Os campos compostos (26 e 62) podem ser decodificados com ParseTLV.
+func ParseBRCode(payload string) (map[string]string, error) {
+	fields, err := ParseTLV(payload)
+	if err != nil {
+		return nil, err
+	}
+	crc, ok := fields[brCodeCRC]
+	if !ok || !strings.HasSuffix(payload, brCodeCRC+"04"+crc) {
+		return nil, fmt.Errorf("BR Code must end with the CRC16 field")
+	}
+	if expected := CRC16(payload[:len(payload)-4]); crc != expected {
+		return nil, fmt.Errorf("BR Code CRC16 %s does not match %s", crc, expected)
+	}
+	return fields, nil
+}
+
+// ParseTLV decodifica uma sequência de campos TLV do padrão EMV (ID e tamanho com dois dígitos).
+func ParseTLV(data string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("truncated TLV field %q", data)
+		}
+		id := data[:2]
+		length, err := strconv.Atoi(data[2:4])
+		if err != nil || len(data) < 4+length {
+			return nil, fmt.Errorf("invalid length for TLV field %s", id)
+		}
+		fields[id] = data[4 : 4+length]
+		data = data[4+length:]
+	}
+	return fields, nil
+}
+
+// CRC16 calcula o CRC16-CCITT (polinômio 0x1021, valor inicial 0xFFFF) exigido pelo BR Code,
+// em quatro dígitos hexadecimais maiúsculos.
+func CRC16(data string) string {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(data); i++ {
+		crc ^= uint16(data[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%04X", crc)
+}
+
+// tlv monta um campo TLV. O tamanho é contado em bytes, como no cálculo do CRC.
+func tlv(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// accentReplacer troca as letras acentuadas do português pelas letras sem acento.
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i", "ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u", "ç", "c", "ñ", "n",
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A", "É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I", "Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U", "Ç", "C", "Ñ", "N",
+)
+
+// brCodeText converte o nome ou a cidade do recebedor para ASCII, exigido pela maioria dos PSPs, e limita o texto
+// ao tamanho máximo do campo. Os tamanhos do BR Code são contados em bytes: com o texto em ASCII, cada caractere
+// ocupa um byte. Os acentos são removidos e os demais caracteres fora do ASCII são descartados.
+func brCodeText(value string, max int) string {
+	value = accentReplacer.Replace(value)
+	var b strings.Builder
+	for _, r := range value {
+		if r >= ' ' && r <= '~' {
+			b.WriteRune(r)
+		}
+	}
+	value = b.String()
+	if len(value) > max {
+		value = value[:max]
+	}
+	return value
+}
@@ -0,0 +1,32 @@
+// problem.go
+// Este arquivo define o corpo das respostas de erro da API, no formato RFC 7807 (application/problem+json).
+// Além dos membros padrão (type, title, status, detail), cada erro tem um código estável (code) para tratamento
+// pelos clientes e, nos erros de validação, a lista de campos inválidos.
+
+package models
+
+// Problem representa uma resposta de erro no formato RFC 7807.
+type Problem struct {
+	// Type é "about:blank": o tipo do erro é identificado por Code, e Title é a descrição do status HTTP.
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Code é o código estável do erro (e.g. validation_failed, transaction_not_found).
+	Code string `json:"code"`
+	// Errors lista os campos que falharam na validação.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError representa um campo que falhou na validação.
+type FieldError struct {
+	// Field é o caminho JSON do campo (e.g. card_details.cvv, to_currencies[1]).
+	Field string `json:"field"`
+	// Rule é a regra de validação que falhou, com o seu parâmetro (e.g. len=3, gt=0).
+	Rule string `json:"rule"`
+}
+
+// String retorna o campo e a regra no formato "card_details.cvv: len=3".
+func (e FieldError) String() string {
+	return e.Field + ": " + e.Rule
+}
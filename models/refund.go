@@ -0,0 +1,36 @@
+// refund.go
+// Este arquivo define os modelos de reembolso.
+// Cada reembolso possui um ID próprio e é registrado separadamente da transação; a transação acumula o valor reembolsado
+// e passa para partially_refunded ou refunded conforme o total reembolsado se aproxima do valor capturado.
+
+package models
+
+import "time"
+
+// Status possíveis de um reembolso.
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+// RefundRequest representa uma solicitação de reembolso.
+// Se o valor não for informado, o saldo ainda não reembolsado do valor capturado é reembolsado.
+type RefundRequest struct {
+	Amount Decimal `json:"amount" validate:"omitempty,gt=0"`
+	Reason string  `json:"reason" validate:"max=255"`
+}
+
+// Refund representa um reembolso registrado.
+type Refund struct {
+	ID              string    `json:"id"`
+	TransactionID   string    `json:"transaction_id"`
+	Gateway         string    `json:"gateway"`
+	GatewayRefundID string    `json:"gateway_refund_id"`
+	Amount          Decimal   `json:"amount"`
+	Currency        string    `json:"currency"`
+	Status          string    `json:"status"`
+	Reason          string    `json:"reason"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
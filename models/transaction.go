@@ -0,0 +1,183 @@
+// transaction.go
+// Este arquivo define a máquina de estados do ciclo de vida de um pagamento.
+// Todo status de uma transação pertence ao vocabulário abaixo e só pode ser alterado por uma transição permitida,
+// que é registrada no histórico da transação com data e motivo.
+
+// Fluxo principal: created → authorized → captured → settled.
+// Estados alternativos: pending (aguardando confirmação do gateway), failed, voided, refunded e partially_refunded.
+// requires_review indica um valor recebido que precisa ser devolvido ou conciliado manualmente
+// (e.g. um Pix pago depois da expiração da cobrança, inclusive quando a transação já havia falhado,
+// ou um segundo Pix para uma cobrança já paga).
+
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status possíveis de uma transação.
+const (
+	StatusCreated           = "created"
+	StatusPending           = "pending"
+	StatusAuthorized        = "authorized"
+	StatusCaptured          = "captured"
+	StatusSettled           = "settled"
+	StatusFailed            = "failed"
+	StatusVoided            = "voided"
+	StatusRefunded          = "refunded"
+	StatusPartiallyRefunded = "partially_refunded"
+	StatusRequiresReview    = "requires_review"
+)
+
+// statusTransitions define, para cada status, os status para os quais a transação pode avançar.
+var statusTransitions = map[string][]string{
+	StatusCreated:           {StatusPending, StatusAuthorized, StatusFailed},
+	StatusPending:           {StatusAuthorized, StatusFailed, StatusRequiresReview},
+	StatusAuthorized:        {StatusCaptured, StatusVoided, StatusFailed},
+	StatusCaptured:          {StatusSettled, StatusRefunded, StatusPartiallyRefunded, StatusRequiresReview},
+	StatusSettled:           {StatusRefunded, StatusPartiallyRefunded},
+	StatusPartiallyRefunded: {StatusPartiallyRefunded, StatusRefunded},
+	StatusFailed:            {StatusRequiresReview},
+	StatusVoided:            {},
+	StatusRefunded:          {},
+	StatusRequiresReview:    {StatusRequiresReview, StatusCaptured, StatusRefunded, StatusPartiallyRefunded},
+}
+
+// StatusTransition representa uma mudança de status registrada no histórico da transação.
+type StatusTransition struct {
+	From   string    `json:"from,omitempty"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// InvalidTransitionError é retornado quando uma transição de status não é permitida.
+type InvalidTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid status transition from %s to %s", e.From, e.To)
+}
+
+// IsValidStatus informa se o status pertence ao vocabulário da máquina de estados.
+func IsValidStatus(status string) bool {
+	_, exists := statusTransitions[status]
+	return exists
+}
+
+// CanTransition informa se a transação pode passar diretamente do status from para o status to.
+func CanTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionPath retorna a menor sequência de status que leva de from até to, sem incluir from.
+// É utilizada quando o gateway informa um status mais avançado (e.g. pending → captured passa por authorized).
+// Retorna nil se to não puder ser alcançado a partir de from.
+func TransitionPath(from, to string) []string {
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, next := range statusTransitions[current] {
+			if _, visited := previous[next]; visited {
+				continue
+			}
+			previous[next] = current
+
+			if next == to {
+				path := []string{}
+				for status := to; status != from; status = previous[status] {
+					path = append([]string{status}, path...)
+				}
+				return path
+			}
+			queue = append(queue, next)
+		}
+	}
+	return nil
+}
+
+// NewTransaction cria uma transação no status created, registrando o início do histórico.
+// O valor original é inicialmente igual ao valor liquidado (sem conversão de moeda).
+func NewTransaction(transactionID, gateway string, amount Decimal, currency string, at time.Time) Transaction {
+	return Transaction{
+		Transaction_ID:   transactionID,
+		Gateway:          gateway,
+		Status:           StatusCreated,
+		Amount:           amount,
+		Currency:         currency,
+		OriginalAmount:   amount,
+		OriginalCurrency: currency,
+		ExchangeRate:     1,
+		CreatedAt:        at,
+		UpdatedAt:        at,
+		History: []StatusTransition{{
+			To:     StatusCreated,
+			At:     at,
+			Reason: "transaction created",
+		}},
+	}
+}
+
+// Transition altera o status da transação, registrando a mudança no histórico.
+// Retorna *InvalidTransitionError se a transição não for permitida.
+func (t *Transaction) Transition(to, reason string, at time.Time) error {
+	if !CanTransition(t.Status, to) {
+		return &InvalidTransitionError{From: t.Status, To: to}
+	}
+
+	t.History = append(t.History, StatusTransition{
+		From:   t.Status,
+		To:     to,
+		At:     at,
+		Reason: reason,
+	})
+	t.Status = to
+	t.UpdatedAt = at
+	return nil
+}
+
+// ReceivedAmount retorna o valor efetivamente recebido, base dos reembolsos: o valor capturado ou, em uma transação
+// que passou por requires_review sem ter sido capturada (e.g. um Pix pago depois da expiração), o valor da transação.
+// Cada Pix recebido em duplicidade (PixDuplicates) soma mais uma vez o valor da transação.
+func (t Transaction) ReceivedAmount() (Money, error) {
+	received := t.CapturedAmount
+	if received.IsZero() && t.reviewed() {
+		received = t.Amount
+	}
+	money, err := received.Money(t.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if len(t.PixDuplicates) > 0 {
+		amount, err := t.Amount.Money(t.Currency)
+		if err != nil {
+			return Money{}, err
+		}
+		for range t.PixDuplicates {
+			money = money.Add(amount)
+		}
+	}
+	return money, nil
+}
+
+// reviewed informa se a transação passou pelo status requires_review.
+func (t Transaction) reviewed() bool {
+	for _, transition := range t.History {
+		if transition.To == StatusRequiresReview {
+			return true
+		}
+	}
+	return false
+}
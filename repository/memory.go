@@ -0,0 +1,463 @@
+// memory.go
+// Este arquivo contém a implementação em memória dos repositórios.
+// Os dados são perdidos ao reiniciar a aplicação, portanto ela é indicada apenas para testes e desenvolvimento.
+
+package repository
+
+import (
+	"desafiogolang-payment/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryTransactionRepository armazena as transações em um mapa protegido por mutex.
+type MemoryTransactionRepository struct {
+	mu           sync.RWMutex
+	transactions map[string]models.Transaction
+}
+
+// NewMemoryTransactionRepository cria um repositório de transações em memória vazio.
+func NewMemoryTransactionRepository() *MemoryTransactionRepository {
+	return &MemoryTransactionRepository{
+		transactions: make(map[string]models.Transaction),
+	}
+}
+
+func (r *MemoryTransactionRepository) Create(transaction models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.transactions[transaction.Transaction_ID]; exists {
+		return ErrAlreadyExists
+	}
+
+	now := time.Now().UTC()
+	if transaction.CreatedAt.IsZero() {
+		transaction.CreatedAt = now
+	}
+	transaction.UpdatedAt = now
+	r.transactions[transaction.Transaction_ID] = copyTransaction(transaction)
+	return nil
+}
+
+func (r *MemoryTransactionRepository) Get(transactionID string) (models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transaction, exists := r.transactions[transactionID]
+	if !exists {
+		return models.Transaction{}, ErrNotFound
+	}
+	return copyTransaction(transaction), nil
+}
+
+func (r *MemoryTransactionRepository) Update(transactionID string, apply func(transaction *models.Transaction) error) (models.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.transactions[transactionID]
+	if !exists {
+		return models.Transaction{}, ErrNotFound
+	}
+
+	transaction := copyTransaction(stored)
+	if err := apply(&transaction); err != nil {
+		return models.Transaction{}, err
+	}
+	transaction.UpdatedAt = time.Now().UTC()
+	r.transactions[transactionID] = copyTransaction(transaction)
+	return transaction, nil
+}
+
+func (r *MemoryTransactionRepository) List() ([]models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]models.Transaction, 0, len(r.transactions))
+	for _, transaction := range r.transactions {
+		list = append(list, copyTransaction(transaction))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+// copyTransaction copia a transação, incluindo o histórico, para que o chamador não altere o mapa interno.
+func copyTransaction(transaction models.Transaction) models.Transaction {
+	transaction.History = append([]models.StatusTransition(nil), transaction.History...)
+	transaction.PixDuplicates = append([]string(nil), transaction.PixDuplicates...)
+	return transaction
+}
+
+// MemoryRefundRepository armazena os reembolsos em um mapa protegido por mutex.
+type MemoryRefundRepository struct {
+	mu      sync.RWMutex
+	refunds map[string]models.Refund
+}
+
+// NewMemoryRefundRepository cria um repositório de reembolsos em memória vazio.
+func NewMemoryRefundRepository() *MemoryRefundRepository {
+	return &MemoryRefundRepository{
+		refunds: make(map[string]models.Refund),
+	}
+}
+
+func (r *MemoryRefundRepository) Create(refund models.Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.refunds[refund.ID]; exists {
+		return ErrAlreadyExists
+	}
+
+	now := time.Now().UTC()
+	if refund.CreatedAt.IsZero() {
+		refund.CreatedAt = now
+	}
+	refund.UpdatedAt = now
+	r.refunds[refund.ID] = refund
+	return nil
+}
+
+func (r *MemoryRefundRepository) Get(refundID string) (models.Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	refund, exists := r.refunds[refundID]
+	if !exists {
+		return models.Refund{}, ErrNotFound
+	}
+	return refund, nil
+}
+
+func (r *MemoryRefundRepository) ListByTransaction(transactionID string) ([]models.Refund, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []models.Refund{}
+	for _, refund := range r.refunds {
+		if refund.TransactionID == transactionID {
+			list = append(list, refund)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+// MemoryFXQuoteRepository armazena as cotações de câmbio em um mapa protegido por mutex.
+type MemoryFXQuoteRepository struct {
+	mu     sync.Mutex
+	quotes map[string]models.FXQuote
+}
+
+// NewMemoryFXQuoteRepository cria um repositório de cotações em memória vazio.
+func NewMemoryFXQuoteRepository() *MemoryFXQuoteRepository {
+	return &MemoryFXQuoteRepository{
+		quotes: make(map[string]models.FXQuote),
+	}
+}
+
+func (r *MemoryFXQuoteRepository) Create(quote models.FXQuote) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.quotes[quote.ID]; exists {
+		return ErrAlreadyExists
+	}
+	if quote.CreatedAt.IsZero() {
+		quote.CreatedAt = time.Now().UTC()
+	}
+	r.quotes[quote.ID] = copyFXQuote(quote)
+	return nil
+}
+
+func (r *MemoryFXQuoteRepository) Get(quoteID string) (models.FXQuote, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quote, exists := r.quotes[quoteID]
+	if !exists {
+		return models.FXQuote{}, ErrNotFound
+	}
+	return copyFXQuote(quote), nil
+}
+
+func (r *MemoryFXQuoteRepository) Update(quoteID string, apply func(quote *models.FXQuote) error) (models.FXQuote, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.quotes[quoteID]
+	if !exists {
+		return models.FXQuote{}, ErrNotFound
+	}
+
+	quote := copyFXQuote(stored)
+	if err := apply(&quote); err != nil {
+		return models.FXQuote{}, err
+	}
+	r.quotes[quoteID] = copyFXQuote(quote)
+	return quote, nil
+}
+
+// copyFXQuote copia a cotação, incluindo a data de uso, para que o chamador não altere o mapa interno.
+func copyFXQuote(quote models.FXQuote) models.FXQuote {
+	if quote.UsedAt != nil {
+		usedAt := *quote.UsedAt
+		quote.UsedAt = &usedAt
+	}
+	return quote
+}
+
+// MemoryCardTokenRepository armazena os cartões do cofre em um mapa protegido por mutex.
+type MemoryCardTokenRepository struct {
+	mu    sync.RWMutex
+	cards map[string]models.CardToken
+}
+
+// NewMemoryCardTokenRepository cria um repositório de cartões do cofre em memória vazio.
+func NewMemoryCardTokenRepository() *MemoryCardTokenRepository {
+	return &MemoryCardTokenRepository{
+		cards: make(map[string]models.CardToken),
+	}
+}
+
+func (r *MemoryCardTokenRepository) Create(card models.CardToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.cards[card.Token]; exists {
+		return ErrAlreadyExists
+	}
+	if card.CreatedAt.IsZero() {
+		card.CreatedAt = time.Now().UTC()
+	}
+	r.cards[card.Token] = copyCardToken(card)
+	return nil
+}
+
+func (r *MemoryCardTokenRepository) Get(token string) (models.CardToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	card, exists := r.cards[token]
+	if !exists {
+		return models.CardToken{}, ErrNotFound
+	}
+	return copyCardToken(card), nil
+}
+
+// copyCardToken copia o cartão, incluindo o número cifrado, para que o chamador não altere o mapa interno.
+func copyCardToken(card models.CardToken) models.CardToken {
+	card.EncryptedNumber = append([]byte(nil), card.EncryptedNumber...)
+	return card
+}
+
+// MemoryCustomerRepository armazena os clientes em um mapa protegido por mutex.
+type MemoryCustomerRepository struct {
+	mu        sync.RWMutex
+	customers map[string]models.Customer
+}
+
+// NewMemoryCustomerRepository cria um repositório de clientes em memória vazio.
+func NewMemoryCustomerRepository() *MemoryCustomerRepository {
+	return &MemoryCustomerRepository{
+		customers: make(map[string]models.Customer),
+	}
+}
+
+func (r *MemoryCustomerRepository) Create(customer models.Customer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.customers[customer.ID]; exists {
+		return ErrAlreadyExists
+	}
+
+	now := time.Now().UTC()
+	if customer.CreatedAt.IsZero() {
+		customer.CreatedAt = now
+	}
+	customer.UpdatedAt = now
+	r.customers[customer.ID] = customer
+	return nil
+}
+
+func (r *MemoryCustomerRepository) Get(customerID string) (models.Customer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	customer, exists := r.customers[customerID]
+	if !exists {
+		return models.Customer{}, ErrNotFound
+	}
+	return customer, nil
+}
+
+func (r *MemoryCustomerRepository) Update(customerID string, apply func(customer *models.Customer) error) (models.Customer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	customer, exists := r.customers[customerID]
+	if !exists {
+		return models.Customer{}, ErrNotFound
+	}
+	if err := apply(&customer); err != nil {
+		return models.Customer{}, err
+	}
+	customer.UpdatedAt = time.Now().UTC()
+	r.customers[customerID] = customer
+	return customer, nil
+}
+
+func (r *MemoryCustomerRepository) Delete(customerID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.customers[customerID]; !exists {
+		return ErrNotFound
+	}
+	delete(r.customers, customerID)
+	return nil
+}
+
+func (r *MemoryCustomerRepository) List() ([]models.Customer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]models.Customer, 0, len(r.customers))
+	for _, customer := range r.customers {
+		list = append(list, customer)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+// MemoryPaymentMethodRepository armazena os métodos de pagamento salvos em um mapa protegido por mutex.
+type MemoryPaymentMethodRepository struct {
+	mu      sync.RWMutex
+	methods map[string]models.PaymentMethod
+}
+
+// NewMemoryPaymentMethodRepository cria um repositório de métodos de pagamento em memória vazio.
+func NewMemoryPaymentMethodRepository() *MemoryPaymentMethodRepository {
+	return &MemoryPaymentMethodRepository{
+		methods: make(map[string]models.PaymentMethod),
+	}
+}
+
+func (r *MemoryPaymentMethodRepository) Create(method models.PaymentMethod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.methods[method.ID]; exists {
+		return ErrAlreadyExists
+	}
+	if method.CreatedAt.IsZero() {
+		method.CreatedAt = time.Now().UTC()
+	}
+	r.methods[method.ID] = method
+	return nil
+}
+
+func (r *MemoryPaymentMethodRepository) Get(paymentMethodID string) (models.PaymentMethod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	method, exists := r.methods[paymentMethodID]
+	if !exists {
+		return models.PaymentMethod{}, ErrNotFound
+	}
+	return method, nil
+}
+
+func (r *MemoryPaymentMethodRepository) ListByCustomer(customerID string) ([]models.PaymentMethod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := []models.PaymentMethod{}
+	for _, method := range r.methods {
+		if method.CustomerID == customerID {
+			list = append(list, method)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+func (r *MemoryPaymentMethodRepository) Delete(paymentMethodID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.methods[paymentMethodID]; !exists {
+		return ErrNotFound
+	}
+	delete(r.methods, paymentMethodID)
+	return nil
+}
+
+// MemoryRateHistoryRepository armazena o histórico de taxas em um mapa por moeda base e dia, protegido por mutex.
+type MemoryRateHistoryRepository struct {
+	mu     sync.Mutex
+	tables map[string]map[time.Time]models.RateTable
+}
+
+// NewMemoryRateHistoryRepository cria um repositório de histórico de taxas em memória vazio.
+func NewMemoryRateHistoryRepository() *MemoryRateHistoryRepository {
+	return &MemoryRateHistoryRepository{
+		tables: make(map[string]map[time.Time]models.RateTable),
+	}
+}
+
+func (r *MemoryRateHistoryRepository) Save(table models.RateTable) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	table = copyRateTable(table)
+	table.Date = RateDay(table.Date)
+	if r.tables[table.Base] == nil {
+		r.tables[table.Base] = make(map[time.Time]models.RateTable)
+	}
+	r.tables[table.Base][table.Date] = table
+	return nil
+}
+
+func (r *MemoryRateHistoryRepository) Latest(base string, date time.Time) (models.RateTable, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	date = RateDay(date)
+	var latest models.RateTable
+	for day, table := range r.tables[base] {
+		if !day.After(date) && day.After(latest.Date) {
+			latest = table
+		}
+	}
+	if latest.Base == "" {
+		return models.RateTable{}, ErrNotFound
+	}
+	return copyRateTable(latest), nil
+}
+
+// copyRateTable copia a tabela, incluindo o mapa de taxas, para que o chamador não altere o mapa interno.
+func copyRateTable(table models.RateTable) models.RateTable {
+	rates := make(map[string]float64, len(table.Rates))
+	for currency, rate := range table.Rates {
+		rates[currency] = rate
+	}
+	table.Rates = rates
+	return table
+}
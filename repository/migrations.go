@@ -0,0 +1,274 @@
+// migrations.go
+// Este arquivo contém as migrações do esquema do banco SQLite.
+// Cada migração é aplicada uma única vez, em ordem, e registrada na tabela schema_migrations.
+// Para alterar o esquema, adicione uma nova migração ao final da lista; nunca altere uma migração já publicada.
+
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migration representa uma alteração versionada do esquema.
+type migration struct {
+	version    int
+	statements []string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		statements: []string{
+			`CREATE TABLE transactions (
+				id         TEXT PRIMARY KEY,
+				gateway    TEXT NOT NULL,
+				status     TEXT NOT NULL,
+				amount     REAL NOT NULL,
+				currency   TEXT NOT NULL,
+				created_at TEXT NOT NULL,
+				updated_at TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_transactions_created_at ON transactions (created_at)`,
+		},
+	},
+	{
+		// Máquina de estados do pagamento: valor capturado e histórico de status.
+		// Transações antigas com status "completed" passam a ser "captured" no novo vocabulário.
+		version: 2,
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN captured_amount REAL NOT NULL DEFAULT 0`,
+			`CREATE TABLE transaction_history (
+				id             INTEGER PRIMARY KEY AUTOINCREMENT,
+				transaction_id TEXT NOT NULL REFERENCES transactions (id),
+				from_status    TEXT NOT NULL,
+				to_status      TEXT NOT NULL,
+				reason         TEXT NOT NULL,
+				created_at     TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_transaction_history_transaction_id ON transaction_history (transaction_id)`,
+			`UPDATE transactions SET status = 'captured', captured_amount = amount WHERE status = 'completed'`,
+			`INSERT INTO transaction_history (transaction_id, from_status, to_status, reason, created_at)
+				SELECT id, '', status, 'migrated', created_at FROM transactions`,
+		},
+	},
+	{
+		// Reembolsos: valor reembolsado acumulado na transação e registro de cada reembolso com ID próprio.
+		version: 3,
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN refunded_amount REAL NOT NULL DEFAULT 0`,
+			`UPDATE transactions SET refunded_amount = captured_amount WHERE status = 'refunded'`,
+			`CREATE TABLE refunds (
+				id                TEXT PRIMARY KEY,
+				transaction_id    TEXT NOT NULL REFERENCES transactions (id),
+				gateway           TEXT NOT NULL,
+				gateway_refund_id TEXT NOT NULL,
+				amount            REAL NOT NULL,
+				currency          TEXT NOT NULL,
+				status            TEXT NOT NULL,
+				reason            TEXT NOT NULL,
+				created_at        TEXT NOT NULL,
+				updated_at        TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_refunds_transaction_id ON refunds (transaction_id)`,
+		},
+	},
+	{
+		// Pagamentos multimoeda: valor e moeda originais e taxa de câmbio usada na liquidação.
+		version: 4,
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN original_amount REAL NOT NULL DEFAULT 0`,
+			`ALTER TABLE transactions ADD COLUMN original_currency TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE transactions ADD COLUMN exchange_rate REAL NOT NULL DEFAULT 1`,
+			`UPDATE transactions SET original_amount = amount, original_currency = currency`,
+		},
+	},
+	{
+		// Cotações de câmbio com taxa travada, de uso único, e a cotação usada por cada transação.
+		version: 5,
+		statements: []string{
+			`CREATE TABLE fx_quotes (
+				id               TEXT PRIMARY KEY,
+				from_currency    TEXT NOT NULL,
+				to_currency      TEXT NOT NULL,
+				rate             REAL NOT NULL,
+				amount           REAL NOT NULL,
+				converted_amount REAL NOT NULL,
+				created_at       TEXT NOT NULL,
+				expires_at       TEXT NOT NULL,
+				used_at          TEXT,
+				transaction_id   TEXT NOT NULL DEFAULT ''
+			)`,
+			`ALTER TABLE transactions ADD COLUMN quote_id TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// Histórico diário de taxas de câmbio: uma tabela (JSON) por moeda base e dia.
+		version: 6,
+		statements: []string{
+			`CREATE TABLE rate_history (
+				base       TEXT NOT NULL,
+				rate_date  TEXT NOT NULL,
+				provider   TEXT NOT NULL,
+				rates      TEXT NOT NULL,
+				updated_at TEXT NOT NULL,
+				PRIMARY KEY (base, rate_date)
+			)`,
+		},
+	},
+	{
+		// Bandeira do cartão usado no pagamento.
+		version: 7,
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN card_brand TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// Cartões do cofre: apenas o número cifrado é gravado, nunca o número em claro ou o CVV.
+		version: 8,
+		statements: []string{
+			`CREATE TABLE card_tokens (
+				token            TEXT PRIMARY KEY,
+				encrypted_number BLOB NOT NULL,
+				brand            TEXT NOT NULL,
+				last4            TEXT NOT NULL,
+				expiry           TEXT NOT NULL,
+				created_at       TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		// BIN e quatro últimos dígitos do cartão usado no pagamento; o número completo nunca é gravado.
+		version: 9,
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN card_bin TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE transactions ADD COLUMN card_last4 TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// Clientes e métodos de pagamento salvos; os cartões são referenciados pelo token do cofre.
+		// As transações registram o cliente e o método de pagamento salvo utilizados.
+		version: 10,
+		statements: []string{
+			`CREATE TABLE customers (
+				id                        TEXT PRIMARY KEY,
+				name                      TEXT NOT NULL,
+				email                     TEXT NOT NULL,
+				default_payment_method_id TEXT NOT NULL DEFAULT '',
+				created_at                TEXT NOT NULL,
+				updated_at                TEXT NOT NULL
+			)`,
+			`CREATE TABLE payment_methods (
+				id                   TEXT PRIMARY KEY,
+				customer_id          TEXT NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+				type                 TEXT NOT NULL,
+				card_token           TEXT NOT NULL DEFAULT '',
+				card_brand           TEXT NOT NULL DEFAULT '',
+				card_last4           TEXT NOT NULL DEFAULT '',
+				card_expiry          TEXT NOT NULL DEFAULT '',
+				billing_agreement_id TEXT NOT NULL DEFAULT '',
+				payer_email          TEXT NOT NULL DEFAULT '',
+				created_at           TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_payment_methods_customer_id ON payment_methods (customer_id)`,
+			`ALTER TABLE transactions ADD COLUMN customer_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE transactions ADD COLUMN payment_method_id TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// Método de pagamento das transações e expiração das cobranças Pix, que ficam pendentes até serem pagas.
+		version: 11,
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN payment_method TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE transactions ADD COLUMN expires_at TEXT`,
+		},
+	},
+	{
+		// Valores monetários gravados como texto decimal, sem passar por float64; os valores REAL já gravados são convertidos.
+		version: 12,
+		statements: concat(
+			decimalColumns("transactions", "amount", "captured_amount", "refunded_amount", "original_amount"),
+			decimalColumns("refunds", "amount"),
+			decimalColumns("fx_quotes", "amount", "converted_amount"),
+		),
+	},
+	{
+		// endToEndId do Pix recebido, registrado inclusive quando o pagamento chega depois da expiração da cobrança.
+		version: 13,
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN pix_end_to_end_id TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		// endToEndIds dos Pix recebidos para uma cobrança já paga, separados por vírgula.
+		version: 14,
+		statements: []string{
+			`ALTER TABLE transactions ADD COLUMN pix_duplicate_end_to_end_ids TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+}
+
+// decimalColumns retorna as instruções que recriam as colunas REAL informadas como TEXT, preservando os valores.
+// O SQLite não altera o tipo de uma coluna: o valor é copiado para uma nova coluna, que substitui a original.
+func decimalColumns(table string, columns ...string) []string {
+	var statements []string
+	for _, column := range columns {
+		statements = append(statements,
+			fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s_decimal TEXT NOT NULL DEFAULT '0'`, table, column),
+			fmt.Sprintf(`UPDATE %s SET %s_decimal = CAST(%s AS TEXT)`, table, column, column),
+			fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s`, table, column),
+			fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN %s_decimal TO %s`, table, column, column),
+		)
+	}
+	return statements
+}
+
+func concat(lists ...[]string) []string {
+	var all []string
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return all
+}
+
+// migrate aplica as migrações ainda não registradas na tabela schema_migrations.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, statement := range m.statements {
+			if _, err := tx.Exec(statement); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("apply migration %d: %w", m.version, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+			m.version, time.Now().UTC().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("record migration %d: %w", m.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit migration %d: %w", m.version, err)
+		}
+	}
+	return nil
+}
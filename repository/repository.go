@@ -0,0 +1,170 @@
+// repository.go
+// Este arquivo define a camada de repositório da aplicação, responsável pelo armazenamento das transações,
+// dos reembolsos, das cotações de câmbio, do histórico diário de taxas de câmbio, dos cartões do cofre
+// e dos clientes com seus métodos de pagamento salvos.
+// Existem duas implementações: em memória (memory.go), usada nos testes e em desenvolvimento,
+// e SQLite embarcado (sqlite.go), que mantém os dados entre reinicializações.
+// A implementação utilizada é escolhida pela configuração STORAGE_DRIVER.
+
+package repository
+
+import (
+	"database/sql"
+	"desafiogolang-payment/config"
+	"desafiogolang-payment/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound é retornado quando o registro solicitado não existe.
+var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists é retornado ao criar um registro com um ID já existente.
+var ErrAlreadyExists = errors.New("already exists")
+
+// TransactionRepository define as operações de armazenamento de transações.
+type TransactionRepository interface {
+	// Create armazena uma nova transação.
+	Create(transaction models.Transaction) error
+	// Get retorna a transação com o ID informado, incluindo o histórico de status.
+	Get(transactionID string) (models.Transaction, error)
+	// Update aplica a função informada à transação de forma atômica e grava o resultado.
+	// Se a função retornar erro, nada é gravado. Novas entradas do histórico são acrescentadas ao armazenamento.
+	Update(transactionID string, apply func(transaction *models.Transaction) error) (models.Transaction, error)
+	// List retorna todas as transações, da mais antiga para a mais recente.
+	List() ([]models.Transaction, error)
+}
+
+// RefundRepository define as operações de armazenamento de reembolsos.
+type RefundRepository interface {
+	// Create armazena um novo reembolso.
+	Create(refund models.Refund) error
+	// Get retorna o reembolso com o ID informado.
+	Get(refundID string) (models.Refund, error)
+	// ListByTransaction retorna os reembolsos da transação informada, do mais antigo para o mais recente.
+	ListByTransaction(transactionID string) ([]models.Refund, error)
+}
+
+// FXQuoteRepository define as operações de armazenamento de cotações de câmbio.
+type FXQuoteRepository interface {
+	// Create armazena uma nova cotação.
+	Create(quote models.FXQuote) error
+	// Get retorna a cotação com o ID informado.
+	Get(quoteID string) (models.FXQuote, error)
+	// Update aplica a função informada à cotação de forma atômica e grava o resultado.
+	// Se a função retornar erro, nada é gravado.
+	Update(quoteID string, apply func(quote *models.FXQuote) error) (models.FXQuote, error)
+}
+
+// CardTokenRepository define as operações de armazenamento dos cartões do cofre.
+// O número do cartão chega ao repositório já cifrado; o CVV nunca é armazenado.
+type CardTokenRepository interface {
+	// Create armazena um novo cartão.
+	Create(card models.CardToken) error
+	// Get retorna o cartão com o token informado.
+	Get(token string) (models.CardToken, error)
+}
+
+// CustomerRepository define as operações de armazenamento de clientes.
+type CustomerRepository interface {
+	// Create armazena um novo cliente.
+	Create(customer models.Customer) error
+	// Get retorna o cliente com o ID informado.
+	Get(customerID string) (models.Customer, error)
+	// Update aplica a função informada ao cliente de forma atômica e grava o resultado.
+	// Se a função retornar erro, nada é gravado.
+	Update(customerID string, apply func(customer *models.Customer) error) (models.Customer, error)
+	// Delete remove o cliente com o ID informado.
+	Delete(customerID string) error
+	// List retorna todos os clientes, do mais antigo para o mais recente.
+	List() ([]models.Customer, error)
+}
+
+// PaymentMethodRepository define as operações de armazenamento dos métodos de pagamento salvos dos clientes.
+type PaymentMethodRepository interface {
+	// Create armazena um novo método de pagamento.
+	Create(method models.PaymentMethod) error
+	// Get retorna o método de pagamento com o ID informado.
+	Get(paymentMethodID string) (models.PaymentMethod, error)
+	// ListByCustomer retorna os métodos de pagamento do cliente informado, do mais antigo para o mais recente.
+	ListByCustomer(customerID string) ([]models.PaymentMethod, error)
+	// Delete remove o método de pagamento com o ID informado.
+	Delete(paymentMethodID string) error
+}
+
+// RateHistoryRepository define as operações de armazenamento do histórico diário de taxas de câmbio.
+// Cada moeda base tem no máximo uma tabela por dia (a data de referência da tabela, em UTC).
+type RateHistoryRepository interface {
+	// Save grava a tabela como a tabela do dia para a moeda base, substituindo a tabela já gravada nesse dia.
+	Save(table models.RateTable) error
+	// Latest retorna a tabela mais recente da moeda base com data igual ou anterior à data informada.
+	Latest(base string, date time.Time) (models.RateTable, error)
+}
+
+// RateDay retorna o dia (meia-noite UTC) a que uma data de referência de taxas pertence.
+func RateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// UpdateStatus altera o status de uma transação existente, validando a transição pela máquina de estados.
+func UpdateStatus(repo TransactionRepository, transactionID, status, reason string) (models.Transaction, error) {
+	return repo.Update(transactionID, func(transaction *models.Transaction) error {
+		return transaction.Transition(status, reason, time.Now().UTC())
+	})
+}
+
+// Repositories agrupa os repositórios da aplicação criados a partir da configuração.
+type Repositories struct {
+	Transactions   TransactionRepository
+	Refunds        RefundRepository
+	FXQuotes       FXQuoteRepository
+	RateHistory    RateHistoryRepository
+	CardTokens     CardTokenRepository
+	Customers      CustomerRepository
+	PaymentMethods PaymentMethodRepository
+
+	db *sql.DB
+}
+
+// Open cria os repositórios de acordo com o driver configurado.
+func Open(cfg config.Config) (*Repositories, error) {
+	switch cfg.StorageDriver {
+	case "memory":
+		return &Repositories{
+			Transactions:   NewMemoryTransactionRepository(),
+			Refunds:        NewMemoryRefundRepository(),
+			FXQuotes:       NewMemoryFXQuoteRepository(),
+			RateHistory:    NewMemoryRateHistoryRepository(),
+			CardTokens:     NewMemoryCardTokenRepository(),
+			Customers:      NewMemoryCustomerRepository(),
+			PaymentMethods: NewMemoryPaymentMethodRepository(),
+		}, nil
+	case "sqlite":
+		db, err := OpenSQLite(cfg.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		return &Repositories{
+			Transactions:   NewSQLiteTransactionRepository(db),
+			Refunds:        NewSQLiteRefundRepository(db),
+			FXQuotes:       NewSQLiteFXQuoteRepository(db),
+			RateHistory:    NewSQLiteRateHistoryRepository(db),
+			CardTokens:     NewSQLiteCardTokenRepository(db),
+			Customers:      NewSQLiteCustomerRepository(db),
+			PaymentMethods: NewSQLitePaymentMethodRepository(db),
+			db:             db,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %q", cfg.StorageDriver)
+	}
+}
+
+// Close libera os recursos utilizados pelos repositórios.
+func (r *Repositories) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
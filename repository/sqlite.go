@@ -0,0 +1,731 @@
+// sqlite.go
+// Este arquivo contém a implementação dos repositórios sobre um banco SQLite embarcado.
+// É utilizado o driver modernc.org/sqlite, escrito em Go puro, que dispensa CGO e bibliotecas do sistema.
+// As migrações do esquema (migrations.go) são aplicadas automaticamente ao abrir o banco.
+
+package repository
+
+import (
+	"database/sql"
+	"desafiogolang-payment/models"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// OpenSQLite abre (ou cria) o banco SQLite no caminho informado e aplica as migrações pendentes.
+func OpenSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, err
+	}
+
+	// O SQLite aceita apenas um escritor por vez; uma única conexão evita erros de banco bloqueado
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// SQLiteTransactionRepository armazena as transações na tabela transactions.
+type SQLiteTransactionRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteTransactionRepository cria um repositório de transações sobre o banco informado.
+func NewSQLiteTransactionRepository(db *sql.DB) *SQLiteTransactionRepository {
+	return &SQLiteTransactionRepository{db: db}
+}
+
+func (r *SQLiteTransactionRepository) Create(transaction models.Transaction) error {
+	now := time.Now().UTC()
+	if transaction.CreatedAt.IsZero() {
+		transaction.CreatedAt = now
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO transactions (id, gateway, status, amount, captured_amount, refunded_amount, currency,
+			original_amount, original_currency, exchange_rate, quote_id, card_brand, card_bin, card_last4, customer_id, payment_method_id,
+			payment_method, expires_at, pix_end_to_end_id, pix_duplicate_end_to_end_ids, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		transaction.Transaction_ID, transaction.Gateway, transaction.Status, transaction.Amount, transaction.CapturedAmount,
+		transaction.RefundedAmount, transaction.Currency, transaction.OriginalAmount, transaction.OriginalCurrency,
+		transaction.ExchangeRate, transaction.QuoteID, transaction.CardBrand, transaction.CardBIN, transaction.CardLast4,
+		transaction.CustomerID, transaction.PaymentMethodID, transaction.PaymentMethod, formatNullTime(transaction.ExpiresAt), transaction.PixEndToEndID,
+		strings.Join(transaction.PixDuplicates, ","), formatTime(transaction.CreatedAt), formatTime(now))
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := insertHistory(tx, transaction.Transaction_ID, transaction.History); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteTransactionRepository) Get(transactionID string) (models.Transaction, error) {
+	return getTransaction(r.db, transactionID)
+}
+
+func (r *SQLiteTransactionRepository) Update(transactionID string, apply func(transaction *models.Transaction) error) (models.Transaction, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	defer tx.Rollback()
+
+	transaction, err := getTransaction(tx, transactionID)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	recorded := len(transaction.History)
+
+	if err := apply(&transaction); err != nil {
+		return models.Transaction{}, err
+	}
+	transaction.UpdatedAt = time.Now().UTC()
+
+	_, err = tx.Exec(`UPDATE transactions SET status = ?, amount = ?, captured_amount = ?, refunded_amount = ?, currency = ?,
+			pix_end_to_end_id = ?, pix_duplicate_end_to_end_ids = ?, updated_at = ?
+		WHERE id = ?`,
+		transaction.Status, transaction.Amount, transaction.CapturedAmount, transaction.RefundedAmount, transaction.Currency,
+		transaction.PixEndToEndID, strings.Join(transaction.PixDuplicates, ","), formatTime(transaction.UpdatedAt), transactionID)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	// Apenas as entradas acrescentadas pela função são gravadas; o histórico existente é imutável
+	if len(transaction.History) > recorded {
+		if err := insertHistory(tx, transactionID, transaction.History[recorded:]); err != nil {
+			return models.Transaction{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Transaction{}, err
+	}
+	return transaction, nil
+}
+
+func (r *SQLiteTransactionRepository) List() ([]models.Transaction, error) {
+	rows, err := r.db.Query(`SELECT id, gateway, status, amount, captured_amount, refunded_amount, currency,
+			original_amount, original_currency, exchange_rate, quote_id, card_brand, card_bin, card_last4, customer_id, payment_method_id,
+			payment_method, expires_at, pix_end_to_end_id, pix_duplicate_end_to_end_ids, created_at, updated_at
+		FROM transactions ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []models.Transaction{}
+	for rows.Next() {
+		transaction, err := scanTransaction(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, transaction)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// O histórico é carregado após fechar o cursor, pois o banco utiliza uma única conexão
+	for i := range list {
+		if list[i].History, err = getHistory(r.db, list[i].Transaction_ID); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// querier é implementado por *sql.DB e *sql.Tx.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanner é implementado por *sql.Row e *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func getTransaction(q querier, transactionID string) (models.Transaction, error) {
+	row := q.QueryRow(`SELECT id, gateway, status, amount, captured_amount, refunded_amount, currency,
+			original_amount, original_currency, exchange_rate, quote_id, card_brand, card_bin, card_last4, customer_id, payment_method_id,
+			payment_method, expires_at, pix_end_to_end_id, pix_duplicate_end_to_end_ids, created_at, updated_at
+		FROM transactions WHERE id = ?`, transactionID)
+
+	transaction, err := scanTransaction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Transaction{}, ErrNotFound
+	}
+	if err != nil {
+		return models.Transaction{}, err
+	}
+
+	transaction.History, err = getHistory(q, transactionID)
+	return transaction, err
+}
+
+func scanTransaction(row scanner) (models.Transaction, error) {
+	var transaction models.Transaction
+	var createdAt, updatedAt string
+	var expiresAt sql.NullString
+	var duplicateEndToEndIDs string
+
+	err := row.Scan(&transaction.Transaction_ID, &transaction.Gateway, &transaction.Status,
+		&transaction.Amount, &transaction.CapturedAmount, &transaction.RefundedAmount, &transaction.Currency,
+		&transaction.OriginalAmount, &transaction.OriginalCurrency, &transaction.ExchangeRate, &transaction.QuoteID,
+		&transaction.CardBrand, &transaction.CardBIN, &transaction.CardLast4, &transaction.CustomerID, &transaction.PaymentMethodID,
+		&transaction.PaymentMethod, &expiresAt, &transaction.PixEndToEndID, &duplicateEndToEndIDs, &createdAt, &updatedAt)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	transaction.Amount = inCurrency(transaction.Amount, transaction.Currency)
+	transaction.CapturedAmount = inCurrency(transaction.CapturedAmount, transaction.Currency)
+	transaction.RefundedAmount = inCurrency(transaction.RefundedAmount, transaction.Currency)
+	transaction.OriginalAmount = inCurrency(transaction.OriginalAmount, transaction.OriginalCurrency)
+	transaction.CreatedAt = parseTime(createdAt)
+	transaction.UpdatedAt = parseTime(updatedAt)
+	if expiresAt.Valid {
+		t := parseTime(expiresAt.String)
+		transaction.ExpiresAt = &t
+	}
+	if duplicateEndToEndIDs != "" {
+		transaction.PixDuplicates = strings.Split(duplicateEndToEndIDs, ",")
+	}
+	return transaction, nil
+}
+
+func getHistory(q querier, transactionID string) ([]models.StatusTransition, error) {
+	rows, err := q.Query(`SELECT from_status, to_status, reason, created_at
+		FROM transaction_history WHERE transaction_id = ? ORDER BY id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []models.StatusTransition{}
+	for rows.Next() {
+		var transition models.StatusTransition
+		var at string
+		if err := rows.Scan(&transition.From, &transition.To, &transition.Reason, &at); err != nil {
+			return nil, err
+		}
+		transition.At = parseTime(at)
+		history = append(history, transition)
+	}
+	return history, rows.Err()
+}
+
+func insertHistory(q querier, transactionID string, history []models.StatusTransition) error {
+	for _, transition := range history {
+		_, err := q.Exec(`INSERT INTO transaction_history (transaction_id, from_status, to_status, reason, created_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			transactionID, transition.From, transition.To, transition.Reason, formatTime(transition.At))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SQLiteRefundRepository armazena os reembolsos na tabela refunds.
+type SQLiteRefundRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRefundRepository cria um repositório de reembolsos sobre o banco informado.
+func NewSQLiteRefundRepository(db *sql.DB) *SQLiteRefundRepository {
+	return &SQLiteRefundRepository{db: db}
+}
+
+func (r *SQLiteRefundRepository) Create(refund models.Refund) error {
+	now := time.Now().UTC()
+	if refund.CreatedAt.IsZero() {
+		refund.CreatedAt = now
+	}
+
+	_, err := r.db.Exec(`INSERT INTO refunds (id, transaction_id, gateway, gateway_refund_id, amount, currency, status, reason, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		refund.ID, refund.TransactionID, refund.Gateway, refund.GatewayRefundID, refund.Amount, refund.Currency,
+		refund.Status, refund.Reason, formatTime(refund.CreatedAt), formatTime(now))
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *SQLiteRefundRepository) Get(refundID string) (models.Refund, error) {
+	row := r.db.QueryRow(`SELECT id, transaction_id, gateway, gateway_refund_id, amount, currency, status, reason, created_at, updated_at
+		FROM refunds WHERE id = ?`, refundID)
+
+	refund, err := scanRefund(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Refund{}, ErrNotFound
+	}
+	return refund, err
+}
+
+func (r *SQLiteRefundRepository) ListByTransaction(transactionID string) ([]models.Refund, error) {
+	rows, err := r.db.Query(`SELECT id, transaction_id, gateway, gateway_refund_id, amount, currency, status, reason, created_at, updated_at
+		FROM refunds WHERE transaction_id = ? ORDER BY created_at, id`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Refund{}
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, refund)
+	}
+	return list, rows.Err()
+}
+
+func scanRefund(row scanner) (models.Refund, error) {
+	var refund models.Refund
+	var createdAt, updatedAt string
+
+	err := row.Scan(&refund.ID, &refund.TransactionID, &refund.Gateway, &refund.GatewayRefundID, &refund.Amount,
+		&refund.Currency, &refund.Status, &refund.Reason, &createdAt, &updatedAt)
+	if err != nil {
+		return models.Refund{}, err
+	}
+	refund.Amount = inCurrency(refund.Amount, refund.Currency)
+	refund.CreatedAt = parseTime(createdAt)
+	refund.UpdatedAt = parseTime(updatedAt)
+	return refund, nil
+}
+
+// SQLiteFXQuoteRepository armazena as cotações de câmbio na tabela fx_quotes.
+type SQLiteFXQuoteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteFXQuoteRepository cria um repositório de cotações sobre o banco informado.
+func NewSQLiteFXQuoteRepository(db *sql.DB) *SQLiteFXQuoteRepository {
+	return &SQLiteFXQuoteRepository{db: db}
+}
+
+func (r *SQLiteFXQuoteRepository) Create(quote models.FXQuote) error {
+	if quote.CreatedAt.IsZero() {
+		quote.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := r.db.Exec(`INSERT INTO fx_quotes (id, from_currency, to_currency, rate, amount, converted_amount,
+			created_at, expires_at, used_at, transaction_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		quote.ID, quote.FromCurrency, quote.ToCurrency, quote.Rate, decimalOrZero(quote.Amount), decimalOrZero(quote.ConvertedAmount),
+		formatTime(quote.CreatedAt), formatTime(quote.ExpiresAt), formatNullTime(quote.UsedAt), quote.TransactionID)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *SQLiteFXQuoteRepository) Get(quoteID string) (models.FXQuote, error) {
+	return getFXQuote(r.db, quoteID)
+}
+
+func (r *SQLiteFXQuoteRepository) Update(quoteID string, apply func(quote *models.FXQuote) error) (models.FXQuote, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.FXQuote{}, err
+	}
+	defer tx.Rollback()
+
+	quote, err := getFXQuote(tx, quoteID)
+	if err != nil {
+		return models.FXQuote{}, err
+	}
+	if err := apply(&quote); err != nil {
+		return models.FXQuote{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE fx_quotes SET rate = ?, amount = ?, converted_amount = ?, expires_at = ?, used_at = ?, transaction_id = ?
+		WHERE id = ?`,
+		quote.Rate, decimalOrZero(quote.Amount), decimalOrZero(quote.ConvertedAmount), formatTime(quote.ExpiresAt), formatNullTime(quote.UsedAt),
+		quote.TransactionID, quoteID)
+	if err != nil {
+		return models.FXQuote{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.FXQuote{}, err
+	}
+	return quote, nil
+}
+
+func getFXQuote(q querier, quoteID string) (models.FXQuote, error) {
+	var quote models.FXQuote
+	var amount, convertedAmount models.Decimal
+	var createdAt, expiresAt string
+	var usedAt sql.NullString
+
+	err := q.QueryRow(`SELECT id, from_currency, to_currency, rate, amount, converted_amount, created_at, expires_at, used_at, transaction_id
+		FROM fx_quotes WHERE id = ?`, quoteID).Scan(&quote.ID, &quote.FromCurrency, &quote.ToCurrency, &quote.Rate,
+		&amount, &convertedAmount, &createdAt, &expiresAt, &usedAt, &quote.TransactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.FXQuote{}, ErrNotFound
+	}
+	if err != nil {
+		return models.FXQuote{}, err
+	}
+
+	// Cotações sem valor são gravadas com valor zero
+	if !amount.IsZero() {
+		amount = inCurrency(amount, quote.FromCurrency)
+		convertedAmount = inCurrency(convertedAmount, quote.ToCurrency)
+		quote.Amount, quote.ConvertedAmount = &amount, &convertedAmount
+	}
+	quote.CreatedAt = parseTime(createdAt)
+	quote.ExpiresAt = parseTime(expiresAt)
+	if usedAt.Valid {
+		t := parseTime(usedAt.String)
+		quote.UsedAt = &t
+	}
+	return quote, nil
+}
+
+// SQLiteCardTokenRepository armazena os cartões do cofre na tabela card_tokens.
+type SQLiteCardTokenRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCardTokenRepository cria um repositório de cartões do cofre sobre o banco informado.
+func NewSQLiteCardTokenRepository(db *sql.DB) *SQLiteCardTokenRepository {
+	return &SQLiteCardTokenRepository{db: db}
+}
+
+func (r *SQLiteCardTokenRepository) Create(card models.CardToken) error {
+	if card.CreatedAt.IsZero() {
+		card.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := r.db.Exec(`INSERT INTO card_tokens (token, encrypted_number, brand, last4, expiry, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		card.Token, card.EncryptedNumber, string(card.Brand), card.Last4, card.Expiry, formatTime(card.CreatedAt))
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *SQLiteCardTokenRepository) Get(token string) (models.CardToken, error) {
+	var card models.CardToken
+	var brand, createdAt string
+
+	err := r.db.QueryRow(`SELECT token, encrypted_number, brand, last4, expiry, created_at FROM card_tokens WHERE token = ?`, token).
+		Scan(&card.Token, &card.EncryptedNumber, &brand, &card.Last4, &card.Expiry, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.CardToken{}, ErrNotFound
+	}
+	if err != nil {
+		return models.CardToken{}, err
+	}
+	card.Brand = models.CardBrand(brand)
+	card.CreatedAt = parseTime(createdAt)
+	return card, nil
+}
+
+// SQLiteCustomerRepository armazena os clientes na tabela customers.
+type SQLiteCustomerRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteCustomerRepository cria um repositório de clientes sobre o banco informado.
+func NewSQLiteCustomerRepository(db *sql.DB) *SQLiteCustomerRepository {
+	return &SQLiteCustomerRepository{db: db}
+}
+
+func (r *SQLiteCustomerRepository) Create(customer models.Customer) error {
+	now := time.Now().UTC()
+	if customer.CreatedAt.IsZero() {
+		customer.CreatedAt = now
+	}
+
+	_, err := r.db.Exec(`INSERT INTO customers (id, name, email, default_payment_method_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		customer.ID, customer.Name, customer.Email, customer.DefaultPaymentMethodID, formatTime(customer.CreatedAt), formatTime(now))
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *SQLiteCustomerRepository) Get(customerID string) (models.Customer, error) {
+	return getCustomer(r.db, customerID)
+}
+
+func (r *SQLiteCustomerRepository) Update(customerID string, apply func(customer *models.Customer) error) (models.Customer, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Customer{}, err
+	}
+	defer tx.Rollback()
+
+	customer, err := getCustomer(tx, customerID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	if err := apply(&customer); err != nil {
+		return models.Customer{}, err
+	}
+	customer.UpdatedAt = time.Now().UTC()
+
+	_, err = tx.Exec(`UPDATE customers SET name = ?, email = ?, default_payment_method_id = ?, updated_at = ? WHERE id = ?`,
+		customer.Name, customer.Email, customer.DefaultPaymentMethodID, formatTime(customer.UpdatedAt), customerID)
+	if err != nil {
+		return models.Customer{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Customer{}, err
+	}
+	return customer, nil
+}
+
+func (r *SQLiteCustomerRepository) Delete(customerID string) error {
+	result, err := r.db.Exec(`DELETE FROM customers WHERE id = ?`, customerID)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *SQLiteCustomerRepository) List() ([]models.Customer, error) {
+	rows, err := r.db.Query(`SELECT id, name, email, default_payment_method_id, created_at, updated_at
+		FROM customers ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Customer{}
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, customer)
+	}
+	return list, rows.Err()
+}
+
+func getCustomer(q querier, customerID string) (models.Customer, error) {
+	row := q.QueryRow(`SELECT id, name, email, default_payment_method_id, created_at, updated_at
+		FROM customers WHERE id = ?`, customerID)
+
+	customer, err := scanCustomer(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Customer{}, ErrNotFound
+	}
+	return customer, err
+}
+
+func scanCustomer(row scanner) (models.Customer, error) {
+	var customer models.Customer
+	var createdAt, updatedAt string
+
+	err := row.Scan(&customer.ID, &customer.Name, &customer.Email, &customer.DefaultPaymentMethodID, &createdAt, &updatedAt)
+	if err != nil {
+		return models.Customer{}, err
+	}
+	customer.CreatedAt = parseTime(createdAt)
+	customer.UpdatedAt = parseTime(updatedAt)
+	return customer, nil
+}
+
+// SQLitePaymentMethodRepository armazena os métodos de pagamento salvos na tabela payment_methods.
+type SQLitePaymentMethodRepository struct {
+	db *sql.DB
+}
+
+// NewSQLitePaymentMethodRepository cria um repositório de métodos de pagamento sobre o banco informado.
+func NewSQLitePaymentMethodRepository(db *sql.DB) *SQLitePaymentMethodRepository {
+	return &SQLitePaymentMethodRepository{db: db}
+}
+
+func (r *SQLitePaymentMethodRepository) Create(method models.PaymentMethod) error {
+	if method.CreatedAt.IsZero() {
+		method.CreatedAt = time.Now().UTC()
+	}
+
+	_, err := r.db.Exec(`INSERT INTO payment_methods (id, customer_id, type, card_token, card_brand, card_last4, card_expiry,
+			billing_agreement_id, payer_email, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		method.ID, method.CustomerID, method.Type, method.CardToken, string(method.CardBrand), method.CardLast4, method.CardExpiry,
+		method.BillingAgreementID, method.PayerEmail, formatTime(method.CreatedAt))
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *SQLitePaymentMethodRepository) Get(paymentMethodID string) (models.PaymentMethod, error) {
+	row := r.db.QueryRow(`SELECT id, customer_id, type, card_token, card_brand, card_last4, card_expiry,
+			billing_agreement_id, payer_email, created_at
+		FROM payment_methods WHERE id = ?`, paymentMethodID)
+
+	method, err := scanPaymentMethod(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PaymentMethod{}, ErrNotFound
+	}
+	return method, err
+}
+
+func (r *SQLitePaymentMethodRepository) ListByCustomer(customerID string) ([]models.PaymentMethod, error) {
+	rows, err := r.db.Query(`SELECT id, customer_id, type, card_token, card_brand, card_last4, card_expiry,
+			billing_agreement_id, payer_email, created_at
+		FROM payment_methods WHERE customer_id = ? ORDER BY created_at, id`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.PaymentMethod{}
+	for rows.Next() {
+		method, err := scanPaymentMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, method)
+	}
+	return list, rows.Err()
+}
+
+func (r *SQLitePaymentMethodRepository) Delete(paymentMethodID string) error {
+	result, err := r.db.Exec(`DELETE FROM payment_methods WHERE id = ?`, paymentMethodID)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func scanPaymentMethod(row scanner) (models.PaymentMethod, error) {
+	var method models.PaymentMethod
+	var brand, createdAt string
+
+	err := row.Scan(&method.ID, &method.CustomerID, &method.Type, &method.CardToken, &brand, &method.CardLast4, &method.CardExpiry,
+		&method.BillingAgreementID, &method.PayerEmail, &createdAt)
+	if err != nil {
+		return models.PaymentMethod{}, err
+	}
+	method.CardBrand = models.CardBrand(brand)
+	method.CreatedAt = parseTime(createdAt)
+	return method, nil
+}
+
+// SQLiteRateHistoryRepository armazena o histórico de taxas na tabela rate_history, com as taxas em JSON.
+type SQLiteRateHistoryRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRateHistoryRepository cria um repositório de histórico de taxas sobre o banco informado.
+func NewSQLiteRateHistoryRepository(db *sql.DB) *SQLiteRateHistoryRepository {
+	return &SQLiteRateHistoryRepository{db: db}
+}
+
+// rateDayLayout é o formato dos dias do histórico de taxas; a ordenação textual é igual à cronológica.
+const rateDayLayout = "2006-01-02"
+
+func (r *SQLiteRateHistoryRepository) Save(table models.RateTable) error {
+	rates, err := json.Marshal(table.Rates)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`INSERT INTO rate_history (base, rate_date, provider, rates, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (base, rate_date) DO UPDATE SET provider = excluded.provider, rates = excluded.rates, updated_at = excluded.updated_at`,
+		table.Base, RateDay(table.Date).Format(rateDayLayout), table.Provider, string(rates), formatTime(time.Now()))
+	return err
+}
+
+func (r *SQLiteRateHistoryRepository) Latest(base string, date time.Time) (models.RateTable, error) {
+	var day, rates string
+	table := models.RateTable{Base: base}
+
+	err := r.db.QueryRow(`SELECT rate_date, provider, rates FROM rate_history
+		WHERE base = ? AND rate_date <= ? ORDER BY rate_date DESC LIMIT 1`,
+		base, RateDay(date).Format(rateDayLayout)).Scan(&day, &table.Provider, &rates)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.RateTable{}, ErrNotFound
+	}
+	if err != nil {
+		return models.RateTable{}, err
+	}
+
+	if err := json.Unmarshal([]byte(rates), &table.Rates); err != nil {
+		return models.RateTable{}, err
+	}
+	if table.Date, err = time.Parse(rateDayLayout, day); err != nil {
+		return models.RateTable{}, err
+	}
+	return table, nil
+}
+
+// timeLayout é o formato das datas armazenadas no banco.
+// A largura fixa dos nanossegundos mantém a ordenação textual igual à ordenação cronológica.
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// formatTime e parseTime convertem datas para o formato texto armazenado no banco.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+// formatNullTime converte uma data opcional, gravando NULL quando ela não está definida.
+func formatNullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// inCurrency devolve ao valor lido as casas decimais da moeda (e.g. 100.5 → 100.50 USD),
+// ausentes nos valores gravados como REAL antes da migração 12.
+func inCurrency(amount models.Decimal, currency string) models.Decimal {
+	money, err := amount.Money(currency)
+	if err != nil {
+		return amount
+	}
+	return money.Amount()
+}
+
+// decimalOrZero grava um valor opcional, usando zero quando ele não está definido.
+func decimalOrZero(d *models.Decimal) models.Decimal {
+	if d == nil {
+		return models.Decimal{}
+	}
+	return *d
+}
+
+func parseTime(value string) time.Time {
+	t, _ := time.Parse(timeLayout, value)
+	return t
+}
@@ -1,97 +1,135 @@
 // currency_conversion.go
 // Este arquivo contém funções para realizar a conversão de moeda e obter taxas de câmbio atualizadas.
-// Utiliza uma estrutura de cache para armazenar temporariamente as taxas de câmbio e evitar consultas excessivas à API externa.
+// As taxas são obtidas dos provedores configurados (rate_provider.go), com failover entre eles.
+// Utiliza uma estrutura de cache (rate_cache.go) para armazenar temporariamente as taxas de câmbio e evitar consultas excessivas aos provedores.
+// Quando os provedores falham, taxas desatualizadas são usadas até uma idade máxima, e a conversão é marcada como stale.
 
-// O arquivo inclui duas funções principais e uma variável de função mockável:
+// O arquivo inclui duas funções principais e duas variáveis de função mockáveis:
 // 1. GetExchangeRate: Obtém a taxa de câmbio atual entre duas moedas, utilizando cache para armazenar as taxas mais recentes.
-// 2. convertCurrency: Realiza a conversão de moeda usando a taxa de câmbio atual.
+// 2. convertCurrency: Realiza a conversão de moeda usando a taxa de câmbio atual, descontadas as tarifas de markup (fx_markup.go).
 // 3. ConvertCurrencyFunc: Variável de função mockável que permite substituir a implementação da função convertCurrency durante os testes.
+// 4. GetExchangeRateFunc: Variável de função mockável que permite substituir a consulta da taxa de câmbio durante os testes
+//    (utilizada também na liquidação de pagamentos em outra moeda).
+// 5. SetRoundingMode: Define como os valores convertidos são arredondados às casas decimais da moeda de destino
+//    (RoundHalfEven por padrão).
 
 package services
 
 import (
 	"desafiogolang-payment/models"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"sync"
 	"time"
 )
 
-type exchangeRateCache struct {
-	rates      map[string]map[string]float64
-	timestamps map[string]time.Time
-	mu         sync.Mutex
+var (
+	conversionRoundingMode     = models.RoundHalfEven
+	conversionRoundingModeLock sync.RWMutex
+)
+
+// SetRoundingMode define o modo de arredondamento usado nas conversões de moeda.
+func SetRoundingMode(mode models.RoundingMode) {
+	conversionRoundingModeLock.Lock()
+	defer conversionRoundingModeLock.Unlock()
+
+	conversionRoundingMode = mode
 }
 
-var cache = exchangeRateCache{
-	rates:      make(map[string]map[string]float64),
-	timestamps: make(map[string]time.Time),
+// roundingMode retorna o modo de arredondamento em uso.
+func roundingMode() models.RoundingMode {
+	conversionRoundingModeLock.RLock()
+	defer conversionRoundingModeLock.RUnlock()
+
+	return conversionRoundingMode
 }
 
+// Mockable function variable
+var GetExchangeRateFunc = getExchangeRate
+
 // GetExchangeRate obtém a taxa de câmbio atual entre duas moedas.
 func GetExchangeRate(fromCurrency, toCurrency string) (float64, error) {
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
-
-	// Verifica se a taxa está no cache e se é da última hora
-	if rates, ok := cache.rates[fromCurrency]; ok {
-		if rate, exists := rates[toCurrency]; exists {
-			if time.Since(cache.timestamps[fromCurrency]) < time.Hour {
-				return rate, nil
-			}
-		}
-	}
+	return GetExchangeRateFunc(fromCurrency, toCurrency)
+}
 
-	// Se não estiver no cache ou estiver desatualizada, consulta a API externa
-	url := fmt.Sprintf("https://open.er-api.com/v6/latest/%s", fromCurrency)
-	resp, err := http.Get(url)
+// getExchangeRate consulta a taxa de câmbio nos provedores configurados (rate_provider.go), utilizando o cache (rate_cache.go).
+func getExchangeRate(fromCurrency, toCurrency string) (float64, error) {
+	// Se não estiver no cache ou estiver desatualizada, consulta os provedores de taxas
+	rate, _, err := cache.rate(fromCurrency, toCurrency, baseCurrency(), getRateProvider())
+	return rate, err
+}
+
+// Mockable function variable
+var ConvertCurrencyFunc = convertCurrency
+
+// convertCurrency realiza a conversão de moeda usando a taxa de câmbio atual ou, se uma data for informada, a taxa vigente nessa data.
+// O valor convertido é arredondado às casas decimais da moeda de destino.
+func convertCurrency(request models.CurrencyConversionRequest) (models.CurrencyConversionResponse, error) {
+	amount, err := models.NewMoney(request.Amount, request.FromCurrency)
 	if err != nil {
-		return 0, err
+		return models.CurrencyConversionResponse{}, err
 	}
-	defer resp.Body.Close()
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return 0, err
+	quote, err := conversionRate(request)
+	if err != nil {
+		return models.CurrencyConversionResponse{}, err
 	}
+	return quote.convert(amount, request)
+}
 
-	if result["result"] != "success" {
-		return 0, fmt.Errorf("failed to get exchange rate")
-	}
+// conversionQuote é a taxa usada em uma conversão.
+type conversionQuote struct {
+	rate     float64
+	rateDate string
+	stale    bool
+}
 
-	rates := result["rates"].(map[string]interface{})
-	toRate, exists := rates[toCurrency]
-	if !exists {
-		return 0, fmt.Errorf("currency not found")
+// conversionRate obtém a taxa da conversão: a atual ou, com uma data passada, a taxa do histórico vigente nessa data.
+func conversionRate(request models.CurrencyConversionRequest) (conversionQuote, error) {
+	if request.Date != "" && request.Date != time.Now().UTC().Format("2006-01-02") {
+		date, err := time.Parse("2006-01-02", request.Date)
+		if err != nil {
+			return conversionQuote{}, fmt.Errorf("%w: %q", ErrInvalidRateDate, request.Date)
+		}
+		rate, rateDate, err := HistoricalExchangeRate(request.FromCurrency, request.ToCurrency, date)
+		if err != nil {
+			return conversionQuote{}, err
+		}
+		return conversionQuote{rate: rate, rateDate: rateDate.Format("2006-01-02")}, nil
 	}
 
-	// Atualiza o cache
-	if cache.rates[fromCurrency] == nil {
-		cache.rates[fromCurrency] = make(map[string]float64)
+	rate, err := GetExchangeRate(request.FromCurrency, request.ToCurrency)
+	if err != nil {
+		return conversionQuote{}, err
 	}
-	cache.rates[fromCurrency][toCurrency] = toRate.(float64)
-	cache.timestamps[fromCurrency] = time.Now()
-
-	return toRate.(float64), nil
+	return conversionQuote{rate: rate, stale: cache.isStale(request.FromCurrency, request.ToCurrency, baseCurrency())}, nil
 }
 
-// Mockable function variable
-var ConvertCurrencyFunc = convertCurrency
-
-// convertCurrency realiza a conversão de moeda usando a taxa de câmbio atual.
-func convertCurrency(request models.CurrencyConversionRequest) (models.CurrencyConversionResponse, error) {
-	rate, err := GetExchangeRate(request.FromCurrency, request.ToCurrency)
+// convert desconta as tarifas de markup, converte o valor líquido pela taxa de mercado e monta a resposta da conversão.
+func (q conversionQuote) convert(amount models.Money, request models.CurrencyConversionRequest) (models.CurrencyConversionResponse, error) {
+	net, fees, err := applyMarkup(amount, request.MerchantID, request.ToCurrency)
 	if err != nil {
 		return models.CurrencyConversionResponse{}, err
 	}
 
-	convertedAmount := request.Amount * rate
+	// A taxa do cliente é a taxa de mercado descontado o percentual de markup
+	rate := q.rate
+	if fees != nil {
+		rate = q.rate * (1 - fees.Percent.Float64()/100)
+	}
+
+	converted, err := net.Convert(q.rate, request.ToCurrency, roundingMode())
+	if err != nil {
+		return models.CurrencyConversionResponse{}, err
+	}
 	return models.CurrencyConversionResponse{
-		ConvertedAmount: convertedAmount,
+		ConvertedAmount: converted.Amount(),
 		FromCurrency:    request.FromCurrency,
 		ToCurrency:      request.ToCurrency,
 		Rate:            rate,
+		MidMarketRate:   q.rate,
+		Fees:            fees,
+		Stale:           q.stale,
+		RateDate:        q.rateDate,
 	}, nil
 }
 
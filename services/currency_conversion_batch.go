@@ -0,0 +1,54 @@
+// currency_conversion_batch.go
+// Este arquivo contém a conversão de moeda em lote.
+// Em um lote, cada taxa distinta (moedas de origem e destino e data) é obtida uma única vez e reutilizada pelos demais itens,
+// inclusive quando a consulta falha, de modo que um lote grande não multiplique as consultas às taxas.
+// Cada item é convertido de forma independente: o erro de um item não interrompe os demais.
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"time"
+)
+
+// BatchConverter converte os itens de um lote, consultando cada taxa distinta uma única vez.
+// Não é seguro para uso concorrente; cada lote deve usar o seu.
+type BatchConverter struct {
+	quotes map[string]batchQuote
+}
+
+// batchQuote é o resultado da consulta de uma taxa, reutilizado pelos itens do lote.
+type batchQuote struct {
+	quote conversionQuote
+	err   error
+}
+
+// NewBatchConverter cria um conversor para um novo lote.
+func NewBatchConverter() *BatchConverter {
+	return &BatchConverter{quotes: make(map[string]batchQuote)}
+}
+
+// Convert converte um item do lote.
+func (b *BatchConverter) Convert(request models.CurrencyConversionRequest) (models.CurrencyConversionResponse, error) {
+	amount, err := models.NewMoney(request.Amount, request.FromCurrency)
+	if err != nil {
+		return models.CurrencyConversionResponse{}, err
+	}
+
+	// A data de hoje usa a taxa atual, assim como uma data omitida
+	date := request.Date
+	if date == time.Now().UTC().Format("2006-01-02") {
+		date = ""
+	}
+	key := request.FromCurrency + "/" + request.ToCurrency + "/" + date
+
+	cached, ok := b.quotes[key]
+	if !ok {
+		cached.quote, cached.err = conversionRate(request)
+		b.quotes[key] = cached
+	}
+	if cached.err != nil {
+		return models.CurrencyConversionResponse{}, cached.err
+	}
+	return cached.quote.convert(amount, request)
+}
@@ -0,0 +1,307 @@
+// customer.go
+// Este arquivo contém o cadastro de clientes e dos seus métodos de pagamento salvos.
+// Os cartões salvos são referências a tokens do cofre (vault.go), de modo que o número do cartão continua guardado
+// apenas cifrado; os acordos de cobrança do PayPal (billing agreements) são guardados pelo ID do acordo.
+
+// Nos pagamentos, customer_id e payment_method_id são trocados pelo card_token ou pelo acordo de cobrança do método
+// salvo (withPaymentMethod) antes da conversão de moeda e da chamada ao gateway. Sem payment_method_id, é usado o
+// método de pagamento padrão do cliente. O primeiro método salvo se torna o padrão; ao remover o método padrão,
+// o cliente fica sem método padrão até que outro seja escolhido.
+
+// O arquivo inclui:
+// 1. SetCustomerRepository / SetPaymentMethodRepository: Definem os repositórios utilizados (em memória por padrão).
+// 2. CreateCustomer / GetCustomer / ListCustomers / UpdateCustomer / DeleteCustomer: Cadastro de clientes.
+// 3. AddPaymentMethod / GetPaymentMethod / ListPaymentMethods / DeletePaymentMethod: Métodos de pagamento salvos.
+// 4. withPaymentMethod: Substitui customer_id e payment_method_id de um pagamento pelo método de pagamento salvo.
+
+package services
+
+import (
+	"crypto/rand"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Erros do cadastro de clientes.
+var (
+	ErrCustomerNotFound       = fmt.Errorf("customer %w", repository.ErrNotFound)
+	ErrPaymentMethodNotFound  = fmt.Errorf("payment method %w", repository.ErrNotFound)
+	ErrNoDefaultPaymentMethod = errors.New("customer has no default payment method")
+)
+
+var (
+	customerRepository          repository.CustomerRepository      = repository.NewMemoryCustomerRepository()
+	paymentMethodRepository     repository.PaymentMethodRepository = repository.NewMemoryPaymentMethodRepository()
+	customerRepositoryLock      sync.RWMutex
+	paymentMethodRepositoryLock sync.RWMutex
+)
+
+// SetCustomerRepository define o repositório de clientes utilizado pelos serviços.
+func SetCustomerRepository(repo repository.CustomerRepository) {
+	customerRepositoryLock.Lock()
+	defer customerRepositoryLock.Unlock()
+
+	customerRepository = repo
+}
+
+// customers retorna o repositório de clientes em uso.
+func customers() repository.CustomerRepository {
+	customerRepositoryLock.RLock()
+	defer customerRepositoryLock.RUnlock()
+
+	return customerRepository
+}
+
+// SetPaymentMethodRepository define o repositório de métodos de pagamento salvos utilizado pelos serviços.
+func SetPaymentMethodRepository(repo repository.PaymentMethodRepository) {
+	paymentMethodRepositoryLock.Lock()
+	defer paymentMethodRepositoryLock.Unlock()
+
+	paymentMethodRepository = repo
+}
+
+// paymentMethods retorna o repositório de métodos de pagamento salvos em uso.
+func paymentMethods() repository.PaymentMethodRepository {
+	paymentMethodRepositoryLock.RLock()
+	defer paymentMethodRepositoryLock.RUnlock()
+
+	return paymentMethodRepository
+}
+
+// CreateCustomer cadastra um cliente. O método de pagamento padrão só pode ser escolhido depois de salvo um método.
+func CreateCustomer(request models.CustomerRequest) (models.Customer, error) {
+	if request.DefaultPaymentMethodID != "" {
+		return models.Customer{}, ErrPaymentMethodNotFound
+	}
+
+	customer := models.Customer{
+		ID:    newCustomerID(),
+		Name:  request.Name,
+		Email: request.Email,
+	}
+	if err := customers().Create(customer); err != nil {
+		return models.Customer{}, fmt.Errorf("store customer %s: %w", customer.ID, err)
+	}
+	return GetCustomer(customer.ID)
+}
+
+// GetCustomer retorna o cliente com o ID informado.
+func GetCustomer(customerID string) (models.Customer, error) {
+	customer, err := customers().Get(customerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Customer{}, ErrCustomerNotFound
+	}
+	return customer, err
+}
+
+// ListCustomers retorna os clientes cadastrados.
+func ListCustomers() ([]models.Customer, error) {
+	return customers().List()
+}
+
+// UpdateCustomer altera o nome, o e-mail e, quando informado, o método de pagamento padrão do cliente.
+func UpdateCustomer(customerID string, request models.CustomerRequest) (models.Customer, error) {
+	if request.DefaultPaymentMethodID != "" {
+		if _, err := GetPaymentMethod(customerID, request.DefaultPaymentMethodID); err != nil {
+			return models.Customer{}, err
+		}
+	}
+
+	customer, err := customers().Update(customerID, func(customer *models.Customer) error {
+		customer.Name = request.Name
+		customer.Email = request.Email
+		if request.DefaultPaymentMethodID != "" {
+			customer.DefaultPaymentMethodID = request.DefaultPaymentMethodID
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Customer{}, ErrCustomerNotFound
+	}
+	return customer, err
+}
+
+// DeleteCustomer remove o cliente e os seus métodos de pagamento salvos.
+// Os tokens do cofre referenciados pelos cartões não são removidos.
+func DeleteCustomer(customerID string) error {
+	methods, err := ListPaymentMethods(customerID)
+	if err != nil {
+		return err
+	}
+	for _, method := range methods {
+		if err := paymentMethods().Delete(method.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("delete payment method %s: %w", method.ID, err)
+		}
+	}
+
+	err = customers().Delete(customerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrCustomerNotFound
+	}
+	return err
+}
+
+// AddPaymentMethod salva um método de pagamento do cliente. Cartões devem estar guardados no cofre;
+// a bandeira, os últimos dígitos e a validade são copiados do token.
+func AddPaymentMethod(customerID string, request models.PaymentMethodRequest) (models.PaymentMethod, error) {
+	customer, err := GetCustomer(customerID)
+	if err != nil {
+		return models.PaymentMethod{}, err
+	}
+
+	method := models.PaymentMethod{
+		ID:         newPaymentMethodID(),
+		CustomerID: customerID,
+		Type:       request.Type,
+	}
+	switch request.Type {
+	case models.PaymentMethodCard:
+		card, err := cardVault().lookup(request.CardToken)
+		if err != nil {
+			return models.PaymentMethod{}, err
+		}
+		method.CardToken = card.Token
+		method.CardBrand = card.Brand
+		method.CardLast4 = card.Last4
+		method.CardExpiry = card.Expiry
+	case models.PaymentMethodPayPalBillingAgreement:
+		method.BillingAgreementID = request.BillingAgreementID
+		method.PayerEmail = request.PayerEmail
+	}
+
+	if err := paymentMethods().Create(method); err != nil {
+		return models.PaymentMethod{}, fmt.Errorf("store payment method %s: %w", method.ID, err)
+	}
+
+	// O primeiro método de pagamento do cliente se torna o padrão
+	if request.Default || customer.DefaultPaymentMethodID == "" {
+		customer, err = customers().Update(customerID, func(customer *models.Customer) error {
+			if request.Default || customer.DefaultPaymentMethodID == "" {
+				customer.DefaultPaymentMethodID = method.ID
+			}
+			return nil
+		})
+		if err != nil {
+			return models.PaymentMethod{}, fmt.Errorf("set default payment method %s: %w", method.ID, err)
+		}
+	}
+	return GetPaymentMethod(customerID, method.ID)
+}
+
+// GetPaymentMethod retorna o método de pagamento salvo do cliente.
+func GetPaymentMethod(customerID, paymentMethodID string) (models.PaymentMethod, error) {
+	customer, err := GetCustomer(customerID)
+	if err != nil {
+		return models.PaymentMethod{}, err
+	}
+
+	method, err := paymentMethods().Get(paymentMethodID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && method.CustomerID != customerID) {
+		return models.PaymentMethod{}, ErrPaymentMethodNotFound
+	}
+	if err != nil {
+		return models.PaymentMethod{}, err
+	}
+	method.Default = method.ID == customer.DefaultPaymentMethodID
+	return method, nil
+}
+
+// ListPaymentMethods retorna os métodos de pagamento salvos do cliente.
+func ListPaymentMethods(customerID string) ([]models.PaymentMethod, error) {
+	customer, err := GetCustomer(customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := paymentMethods().ListByCustomer(customerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range methods {
+		methods[i].Default = methods[i].ID == customer.DefaultPaymentMethodID
+	}
+	return methods, nil
+}
+
+// DeletePaymentMethod remove o método de pagamento salvo do cliente.
+// Se for o método padrão, o cliente fica sem método de pagamento padrão.
+func DeletePaymentMethod(customerID, paymentMethodID string) error {
+	method, err := GetPaymentMethod(customerID, paymentMethodID)
+	if err != nil {
+		return err
+	}
+
+	if err := paymentMethods().Delete(method.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrPaymentMethodNotFound
+		}
+		return err
+	}
+
+	_, err = customers().Update(customerID, func(customer *models.Customer) error {
+		if customer.DefaultPaymentMethodID == method.ID {
+			customer.DefaultPaymentMethodID = ""
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("clear default payment method %s: %w", method.ID, err)
+	}
+	return nil
+}
+
+// withPaymentMethod substitui customer_id e payment_method_id da requisição pelo card_token ou pelo acordo de cobrança
+// do método de pagamento salvo. Sem payment_method_id, é usado o método padrão do cliente.
+// Acordos de cobrança só são aceitos pelos gateways que os suportam.
+func withPaymentMethod(gateway PaymentGateway, request models.PaymentRequest) (models.PaymentRequest, error) {
+	if request.CustomerID == "" {
+		return request, nil
+	}
+
+	paymentMethodID := request.PaymentMethodID
+	if paymentMethodID == "" {
+		customer, err := GetCustomer(request.CustomerID)
+		if err != nil {
+			return models.PaymentRequest{}, err
+		}
+		if customer.DefaultPaymentMethodID == "" {
+			return models.PaymentRequest{}, ErrNoDefaultPaymentMethod
+		}
+		paymentMethodID = customer.DefaultPaymentMethodID
+	}
+
+	method, err := GetPaymentMethod(request.CustomerID, paymentMethodID)
+	if err != nil {
+		return models.PaymentRequest{}, err
+	}
+
+	request.PaymentMethodID = method.ID
+	switch method.Type {
+	case models.PaymentMethodCard:
+		request.CardToken = method.CardToken
+	case models.PaymentMethodPayPalBillingAgreement:
+		if !gateway.Capabilities().BillingAgreements {
+			return models.PaymentRequest{}, ErrOperationNotSupported
+		}
+		request.BillingAgreementID = method.BillingAgreementID
+	}
+	return request, nil
+}
+
+// newCustomerID gera um ID único para um cliente.
+func newCustomerID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "cus_" + hex.EncodeToString(buf)
+}
+
+// newPaymentMethodID gera um ID único para um método de pagamento salvo.
+func newPaymentMethodID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "pm_" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,146 @@
+// fx_markup.go
+// Este arquivo contém o motor de regras de markup (margem) aplicado às conversões de moeda.
+// Entre as regras que se aplicam à conversão, é usada a mais específica (merchant, depois moeda de origem,
+// depois moeda de destino); em caso de empate, a primeira da lista. A tarifa da regra é escolhida pela faixa do valor.
+//
+// A tarifa fixa é descontada do valor, e o percentual é cobrado sobre o restante; o valor líquido é convertido
+// pela taxa de mercado (mid-market). A taxa do cliente é a taxa de mercado descontado o percentual.
+// Sem regras aplicáveis, a conversão usa a taxa de mercado sem tarifas.
+//
+// As regras são lidas de um arquivo JSON (FX_MARKUP_RULES_FILE), uma lista de models.MarkupRule:
+//
+//	[
+//	  {"percent": 1},
+//	  {"from_currency": "USD", "to_currency": "BRL", "percent": 2, "fixed_fee": 0.30,
+//	   "tiers": [{"min_amount": 1000, "percent": 1.5}]},
+//	  {"merchant_id": "merchant-123", "percent": 0.5}
+//	]
+
+// O arquivo inclui:
+// 1. LoadMarkupRules: Lê as regras de markup de um arquivo JSON.
+// 2. SetMarkupRules: Valida e define as regras de markup usadas nas conversões.
+// 3. applyMarkup: Calcula as tarifas de uma conversão e o valor líquido a ser convertido.
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Erros das regras de markup.
+var (
+	ErrInvalidMarkupRule = errors.New("invalid markup rule")
+	ErrAmountBelowFees   = errors.New("amount does not cover the conversion fees")
+)
+
+var (
+	markupRules     []models.MarkupRule
+	markupRulesLock sync.RWMutex
+)
+
+// LoadMarkupRules lê as regras de markup do arquivo JSON informado. Sem arquivo, não há regras.
+func LoadMarkupRules(path string) ([]models.MarkupRule, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []models.MarkupRule
+	if err := json.NewDecoder(file).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("invalid markup rules file %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// SetMarkupRules define as regras de markup usadas nas conversões de moeda, rejeitando regras inválidas.
+func SetMarkupRules(rules []models.MarkupRule) error {
+	for i, rule := range rules {
+		if err := validateMarkupRule(rule); err != nil {
+			return fmt.Errorf("%w (rule %d): %s", ErrInvalidMarkupRule, i, err.Error())
+		}
+	}
+
+	markupRulesLock.Lock()
+	defer markupRulesLock.Unlock()
+
+	markupRules = rules
+	return nil
+}
+
+// validateMarkupRule verifica as moedas, os percentuais (de 0 a 100, exclusive) e as tarifas da regra.
+func validateMarkupRule(rule models.MarkupRule) error {
+	for _, currency := range []string{rule.FromCurrency, rule.ToCurrency} {
+		if _, ok := models.LookupCurrency(currency); currency != "" && !ok {
+			return fmt.Errorf("unknown currency %q", currency)
+		}
+	}
+
+	tiers := append([]models.MarkupTier{{Percent: rule.Percent, FixedFee: rule.FixedFee}}, rule.Tiers...)
+	for _, tier := range tiers {
+		switch {
+		case tier.MinAmount.Sign() < 0:
+			return fmt.Errorf("negative min_amount %s", tier.MinAmount)
+		case tier.Percent.Sign() < 0 || tier.Percent.Cmp(models.NewDecimal(100, 0)) >= 0:
+			return fmt.Errorf("percent %s out of range [0, 100)", tier.Percent)
+		case tier.FixedFee.Sign() < 0:
+			return fmt.Errorf("negative fixed_fee %s", tier.FixedFee)
+		}
+	}
+	return nil
+}
+
+// markupRule retorna a regra mais específica que se aplica à conversão.
+func markupRule(merchantID, fromCurrency, toCurrency string) (models.MarkupRule, bool) {
+	markupRulesLock.RLock()
+	defer markupRulesLock.RUnlock()
+
+	var best models.MarkupRule
+	found := false
+	for _, rule := range markupRules {
+		if rule.Matches(merchantID, fromCurrency, toCurrency) && (!found || rule.Specificity() > best.Specificity()) {
+			best, found = rule, true
+		}
+	}
+	return best, found
+}
+
+// applyMarkup calcula as tarifas da conversão do valor e retorna o valor líquido a ser convertido.
+// Sem regra aplicável, retorna o próprio valor e nenhuma tarifa.
+func applyMarkup(amount models.Money, merchantID, toCurrency string) (models.Money, *models.ConversionFees, error) {
+	rule, ok := markupRule(merchantID, amount.Currency, toCurrency)
+	if !ok {
+		return amount, nil, nil
+	}
+
+	tier := rule.Tier(amount.Amount())
+	fixedFee, err := tier.FixedFee.Money(amount.Currency)
+	if err != nil {
+		return models.Money{}, nil, err
+	}
+	percentageFee, err := amount.Sub(fixedFee).Percentage(tier.Percent, roundingMode())
+	if err != nil {
+		return models.Money{}, nil, err
+	}
+	total := fixedFee.Add(percentageFee)
+	if total.Minor >= amount.Minor {
+		return models.Money{}, nil, fmt.Errorf("%w: %s %s (fees: %s)", ErrAmountBelowFees, amount.Amount(), amount.Currency, total)
+	}
+
+	return amount.Sub(total), &models.ConversionFees{
+		Currency:      amount.Currency,
+		Percent:       tier.Percent,
+		PercentageFee: percentageFee.Amount(),
+		FixedFee:      fixedFee.Amount(),
+		Total:         total.Amount(),
+	}, nil
+}
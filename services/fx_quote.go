@@ -0,0 +1,196 @@
+// fx_quote.go
+// Este arquivo contém as cotações de câmbio com taxa travada.
+// Uma cotação registra a taxa obtida por GetExchangeRate e garante essa taxa até a expiração.
+// O pagamento que informa o quote_id usa exatamente a taxa da cotação; cada cotação pode ser usada por um único pagamento.
+
+// A cotação é reservada antes de o pagamento ser enviado ao gateway, de modo que dois pagamentos simultâneos
+// não usem a mesma cotação. Se o gateway recusar o pagamento, a reserva é desfeita e a cotação pode ser usada novamente.
+
+// O arquivo inclui:
+// 1. SetFXQuoteRepository / SetFXQuoteTTL: Definem o repositório e a validade das cotações.
+// 2. CreateFXQuote / GetFXQuote: Criam e consultam cotações com taxa travada.
+// 3. useFXQuote / releaseFXQuote / completeFXQuote: Reserva, liberação e vínculo da cotação com a transação.
+
+package services
+
+import (
+	"crypto/rand"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Erros de validação das cotações utilizadas em pagamentos.
+var (
+	ErrFXQuoteNotFound    = fmt.Errorf("fx quote %w", repository.ErrNotFound)
+	ErrFXQuoteExpired     = errors.New("fx quote expired")
+	ErrFXQuoteAlreadyUsed = errors.New("fx quote already used")
+)
+
+// FXQuoteMismatchError é retornado quando a cotação não corresponde ao pagamento (moedas ou valor).
+type FXQuoteMismatchError struct {
+	QuoteID string
+	Reason  string
+}
+
+func (e *FXQuoteMismatchError) Error() string {
+	return fmt.Sprintf("fx quote %s does not match payment: %s", e.QuoteID, e.Reason)
+}
+
+var (
+	fxQuoteRepository     repository.FXQuoteRepository = repository.NewMemoryFXQuoteRepository()
+	fxQuoteTTL                                         = 10 * time.Minute
+	fxQuoteRepositoryLock sync.RWMutex
+)
+
+// SetFXQuoteRepository define o repositório de cotações utilizado pelos serviços.
+func SetFXQuoteRepository(repo repository.FXQuoteRepository) {
+	fxQuoteRepositoryLock.Lock()
+	defer fxQuoteRepositoryLock.Unlock()
+
+	fxQuoteRepository = repo
+}
+
+// SetFXQuoteTTL define a validade das novas cotações.
+func SetFXQuoteTTL(ttl time.Duration) {
+	fxQuoteRepositoryLock.Lock()
+	defer fxQuoteRepositoryLock.Unlock()
+
+	fxQuoteTTL = ttl
+}
+
+// fxQuotes retorna o repositório de cotações em uso e a validade configurada.
+func fxQuotes() (repository.FXQuoteRepository, time.Duration) {
+	fxQuoteRepositoryLock.RLock()
+	defer fxQuoteRepositoryLock.RUnlock()
+
+	return fxQuoteRepository, fxQuoteTTL
+}
+
+// CreateFXQuote obtém a taxa de câmbio atual e a registra como uma cotação válida até a expiração.
+func CreateFXQuote(request models.FXQuoteRequest) (models.FXQuote, error) {
+	amount, err := models.NewMoney(request.Amount, request.FromCurrency)
+	if err != nil {
+		return models.FXQuote{}, err
+	}
+
+	rate, err := GetExchangeRate(request.FromCurrency, request.ToCurrency)
+	if err != nil {
+		return models.FXQuote{}, fmt.Errorf("%w: %s to %s: %v", ErrExchangeRateUnavailable, request.FromCurrency, request.ToCurrency, err)
+	}
+
+	repo, ttl := fxQuotes()
+	now := time.Now().UTC()
+	quote := models.FXQuote{
+		ID:           newFXQuoteID(),
+		FromCurrency: request.FromCurrency,
+		ToCurrency:   request.ToCurrency,
+		Rate:         rate,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+	}
+	if !amount.IsZero() {
+		converted, err := amount.Convert(rate, request.ToCurrency, roundingMode())
+		if err != nil {
+			return models.FXQuote{}, err
+		}
+		quoted, convertedAmount := amount.Amount(), converted.Amount()
+		quote.Amount, quote.ConvertedAmount = &quoted, &convertedAmount
+	}
+
+	if err := repo.Create(quote); err != nil {
+		return models.FXQuote{}, fmt.Errorf("store fx quote %s: %w", quote.ID, err)
+	}
+	return quote, nil
+}
+
+// GetFXQuote retorna uma cotação registrada.
+func GetFXQuote(quoteID string) (models.FXQuote, error) {
+	repo, _ := fxQuotes()
+	quote, err := repo.Get(quoteID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.FXQuote{}, ErrFXQuoteNotFound
+	}
+	return quote, err
+}
+
+// useFXQuote reserva a cotação para o pagamento, verificando se ela existe, não expirou, não foi usada
+// e corresponde à moeda e ao valor do pagamento. A moeda de destino deve ser uma das moedas de liquidação do gateway.
+func useFXQuote(request models.PaymentRequest, settlementCurrencies []string) (models.FXQuote, error) {
+	repo, _ := fxQuotes()
+	now := time.Now().UTC()
+
+	quote, err := repo.Update(request.QuoteID, func(quote *models.FXQuote) error {
+		if quote.UsedAt != nil {
+			return ErrFXQuoteAlreadyUsed
+		}
+		if quote.Expired(now) {
+			return ErrFXQuoteExpired
+		}
+		if quote.FromCurrency != request.Currency {
+			return &FXQuoteMismatchError{QuoteID: quote.ID, Reason: fmt.Sprintf("quote is from %s, payment is in %s", quote.FromCurrency, request.Currency)}
+		}
+		if !containsCurrency(settlementCurrencies, quote.ToCurrency) {
+			return &FXQuoteMismatchError{QuoteID: quote.ID, Reason: fmt.Sprintf("gateway does not settle in %s", quote.ToCurrency)}
+		}
+		if quote.Amount != nil && quote.Amount.Cmp(request.Amount) != 0 {
+			return &FXQuoteMismatchError{QuoteID: quote.ID, Reason: fmt.Sprintf("quote is for %s %s", quote.Amount, quote.FromCurrency)}
+		}
+
+		quote.UsedAt = &now
+		return nil
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.FXQuote{}, ErrFXQuoteNotFound
+	}
+	return quote, err
+}
+
+// releaseFXQuote desfaz a reserva de uma cotação cujo pagamento não foi concluído.
+func releaseFXQuote(quoteID string) {
+	repo, _ := fxQuotes()
+	_, err := repo.Update(quoteID, func(quote *models.FXQuote) error {
+		quote.UsedAt = nil
+		return nil
+	})
+	if err != nil {
+		log.Printf("could not release fx quote %s: %v", quoteID, err)
+	}
+}
+
+// completeFXQuote vincula a cotação utilizada à transação criada.
+func completeFXQuote(quoteID, transactionID string) {
+	repo, _ := fxQuotes()
+	_, err := repo.Update(quoteID, func(quote *models.FXQuote) error {
+		quote.TransactionID = transactionID
+		return nil
+	})
+	if err != nil {
+		log.Printf("could not link fx quote %s to transaction %s: %v", quoteID, transactionID, err)
+	}
+}
+
+// containsCurrency informa se a moeda está na lista. Uma lista vazia aceita qualquer moeda.
+func containsCurrency(currencies []string, currency string) bool {
+	if len(currencies) == 0 {
+		return true
+	}
+	for _, c := range currencies {
+		if c == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// newFXQuoteID gera um ID único para uma cotação.
+func newFXQuoteID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "qt_" + hex.EncodeToString(buf)
+}
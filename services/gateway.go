@@ -0,0 +1,138 @@
+// gateway.go
+// Este arquivo define a interface comum dos gateways de pagamento e o registro onde eles são cadastrados.
+// Cada gateway se registra na inicialização do pacote (init), de modo que adicionar um novo gateway
+// não exige nenhuma alteração nos handlers: eles apenas consultam o registro pelo nome informado na requisição.
+
+// O arquivo inclui:
+// 1. PaymentGateway: Interface implementada por todos os gateways (processamento, status, reembolso e capacidades).
+//    Authorizer: Interface opcional para gateways que suportam autorização, captura e cancelamento em etapas.
+// 2. RegisterGateway / GetGateway / RegisteredGateways: Funções de acesso ao registro de gateways.
+// 3. UnsupportedGatewayError: Erro uniforme retornado quando o gateway solicitado não está registrado.
+//    UnsupportedPaymentMethodError: Erro retornado quando o gateway não aceita o método de pagamento informado.
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrOperationNotSupported é retornado quando o gateway não implementa a operação solicitada.
+var ErrOperationNotSupported = errors.New("operation not supported by gateway")
+
+// GatewayCapabilities descreve quais operações um gateway suporta.
+type GatewayCapabilities struct {
+	StatusLookup   bool
+	Refunds        bool
+	PartialRefunds bool
+	// Authorization indica que o gateway implementa Authorizer (autorização e captura em etapas separadas).
+	Authorization bool
+	// BillingAgreements indica que o gateway aceita pagamentos com acordos de cobrança do PayPal salvos pelos clientes.
+	BillingAgreements bool
+	// PaymentMethods são os métodos de pagamento (payment_method) aceitos pelo gateway.
+	PaymentMethods []string
+	// SettlementCurrencies são as moedas em que o gateway liquida pagamentos; a primeira é a moeda padrão.
+	// Pagamentos em outras moedas são convertidos antes de serem enviados ao gateway. Uma lista vazia aceita qualquer moeda.
+	SettlementCurrencies []string
+}
+
+// PaymentGateway é a interface implementada por todos os gateways de pagamento.
+type PaymentGateway interface {
+	// Name retorna o nome usado no campo "gateway" das requisições.
+	Name() string
+	// ProcessPayment processa um pagamento e retorna o ID da transação no gateway.
+	ProcessPayment(request models.PaymentRequest) (models.PaymentResponse, error)
+	// GetPaymentStatus consulta o status de uma transação.
+	GetPaymentStatus(transactionID string) (models.TransactionResponse, error)
+	// RefundPayment reembolsa total ou parcialmente uma transação.
+	RefundPayment(transactionID string, amount models.Money) (models.RefundResponse, error)
+	// Capabilities informa quais operações o gateway suporta.
+	Capabilities() GatewayCapabilities
+}
+
+// Authorizer é implementado pelos gateways que suportam autorizar um pagamento e capturá-lo depois.
+// Os status retornados pertencem ao vocabulário da máquina de estados (models.Status*).
+type Authorizer interface {
+	// AuthorizePayment reserva o valor no meio de pagamento sem capturá-lo.
+	AuthorizePayment(request models.PaymentRequest) (models.PaymentResponse, error)
+	// CapturePayment captura um pagamento autorizado. Um valor igual a zero captura o valor total autorizado.
+	CapturePayment(transactionID string, amount models.Money) (models.TransactionResponse, error)
+	// VoidPayment cancela um pagamento autorizado que ainda não foi capturado.
+	VoidPayment(transactionID string) (models.TransactionResponse, error)
+}
+
+// UnsupportedGatewayError é retornado quando o gateway solicitado não está registrado.
+type UnsupportedGatewayError struct {
+	Gateway   string
+	Supported []string
+}
+
+func (e *UnsupportedGatewayError) Error() string {
+	return fmt.Sprintf("Unsupported gateway %q, supported gateways: %s", e.Gateway, strings.Join(e.Supported, ", "))
+}
+
+// UnsupportedPaymentMethodError é retornado quando o gateway não aceita o método de pagamento (payment_method) informado.
+type UnsupportedPaymentMethodError struct {
+	Gateway       string
+	PaymentMethod string
+	Supported     []string
+}
+
+func (e *UnsupportedPaymentMethodError) Error() string {
+	return fmt.Sprintf("Gateway %s does not support payment method %q, supported payment methods: %s",
+		e.Gateway, e.PaymentMethod, strings.Join(e.Supported, ", "))
+}
+
+// checkPaymentMethod verifica se o gateway aceita o método de pagamento da requisição.
+func checkPaymentMethod(gateway PaymentGateway, paymentMethod string) error {
+	supported := gateway.Capabilities().PaymentMethods
+	for _, method := range supported {
+		if method == paymentMethod {
+			return nil
+		}
+	}
+	return &UnsupportedPaymentMethodError{Gateway: gateway.Name(), PaymentMethod: paymentMethod, Supported: supported}
+}
+
+var (
+	gateways     = make(map[string]PaymentGateway)
+	gatewaysLock sync.RWMutex
+)
+
+// RegisterGateway adiciona um gateway ao registro.
+// Registrar um gateway com um nome já existente substitui o anterior, o que permite trocar a implementação nos testes.
+func RegisterGateway(gateway PaymentGateway) {
+	gatewaysLock.Lock()
+	defer gatewaysLock.Unlock()
+
+	gateways[gateway.Name()] = gateway
+}
+
+// GetGateway retorna o gateway registrado com o nome informado.
+func GetGateway(name string) (PaymentGateway, error) {
+	gatewaysLock.RLock()
+	gateway, exists := gateways[name]
+	gatewaysLock.RUnlock()
+
+	if !exists {
+		return nil, &UnsupportedGatewayError{Gateway: name, Supported: RegisteredGateways()}
+	}
+	return gateway, nil
+}
+
+// RegisteredGateways retorna os nomes de todos os gateways registrados, em ordem alfabética.
+func RegisteredGateways() []string {
+	gatewaysLock.RLock()
+	defer gatewaysLock.RUnlock()
+
+	names := make([]string, 0, len(gateways))
+	for name := range gateways {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
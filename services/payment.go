@@ -0,0 +1,426 @@
+// payment.go
+// Este arquivo coordena o processamento de pagamentos entre os gateways e o repositório de transações.
+// Os gateways apenas se comunicam com o provedor externo; o registro das transações e a consulta de status
+// são feitos aqui, de modo que todos os gateways compartilham o mesmo armazenamento.
+// Toda mudança de status passa pela máquina de estados (models/transaction.go) e fica registrada no histórico.
+
+// O arquivo inclui:
+// 1. SetTransactionRepository: Define o repositório de transações utilizado (em memória por padrão).
+// 2. ProcessPayment: Processa o pagamento no gateway informado e registra a transação.
+//    Pagamentos em uma moeda que o gateway não liquida são convertidos pela taxa de GetExchangeRate
+//    ou, quando informado o quote_id, pela taxa travada na cotação (fx_quote.go).
+//    Com card_token, os dados do cartão são obtidos do cofre (vault.go) imediatamente antes da chamada ao gateway.
+//    Com customer_id, é usado o método de pagamento salvo do cliente (customer.go).
+//    O gateway precisa aceitar o método de pagamento informado; pagamentos Pix ficam pendentes até a confirmação (pix.go).
+// 3. AuthorizePayment / CapturePayment / VoidPayment: Fluxo de autorização e captura em etapas.
+// 4. GetPaymentStatus: Retorna o status de uma transação registrada, atualizando-o no gateway quando suportado.
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrCaptureAmountExceeded é retornado quando o valor da captura é maior que o valor autorizado.
+var ErrCaptureAmountExceeded = errors.New("capture amount exceeds authorized amount")
+
+// ErrExchangeRateUnavailable é retornado quando não é possível obter a taxa de câmbio para liquidar o pagamento.
+var ErrExchangeRateUnavailable = errors.New("exchange rate unavailable")
+
+// TransactionNotRecordedError é retornado quando o gateway processou o pagamento, mas a transação não pôde ser registrada.
+// O pagamento não deve ser repetido: a transação precisa ser conciliada com o gateway pelo ID informado.
+type TransactionNotRecordedError struct {
+	Gateway       string
+	TransactionID string
+	Err           error
+}
+
+func (e *TransactionNotRecordedError) Error() string {
+	return fmt.Sprintf("Payment %s was processed by gateway %s but could not be recorded: %s", e.TransactionID, e.Gateway, e.Err)
+}
+
+func (e *TransactionNotRecordedError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	transactionRepository     repository.TransactionRepository = repository.NewMemoryTransactionRepository()
+	transactionRepositoryLock sync.RWMutex
+)
+
+// SetTransactionRepository define o repositório de transações utilizado pelos serviços.
+func SetTransactionRepository(repo repository.TransactionRepository) {
+	transactionRepositoryLock.Lock()
+	defer transactionRepositoryLock.Unlock()
+
+	transactionRepository = repo
+}
+
+// transactions retorna o repositório de transações em uso.
+func transactions() repository.TransactionRepository {
+	transactionRepositoryLock.RLock()
+	defer transactionRepositoryLock.RUnlock()
+
+	return transactionRepository
+}
+
+// ProcessPayment processa o pagamento no gateway informado na requisição e registra a transação.
+func ProcessPayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	gateway, err := GetGateway(request.Gateway)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+	if err := checkPaymentMethod(gateway, request.PaymentMethod); err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	// O método de pagamento salvo é resolvido antes de reservar a cotação de câmbio
+	request, err = withPaymentMethod(gateway, request)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+	if err := checkCardExpiry(request); err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	original, settled, rate, err := settlePayment(gateway, request)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	// Os dados do cartão são obtidos do cofre somente para a chamada ao gateway
+	settled, err = withCardDetails(settled)
+	if err != nil {
+		if request.QuoteID != "" {
+			releaseFXQuote(request.QuoteID)
+		}
+		return models.PaymentResponse{}, err
+	}
+
+	response, err := gateway.ProcessPayment(settled)
+	if err != nil {
+		if request.QuoteID != "" {
+			releaseFXQuote(request.QuoteID)
+		}
+		return models.PaymentResponse{}, err
+	}
+
+	if err := storeTransaction(gateway, original, settled, rate, response, "payment processed"); err != nil {
+		return models.PaymentResponse{}, err
+	}
+	return withSettlement(response, original, settled, rate), nil
+}
+
+// AuthorizePayment autoriza o pagamento no gateway informado, sem capturá-lo, e registra a transação.
+func AuthorizePayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	gateway, err := GetGateway(request.Gateway)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+	authorizer, ok := gateway.(Authorizer)
+	if !ok || !gateway.Capabilities().Authorization {
+		return models.PaymentResponse{}, ErrOperationNotSupported
+	}
+	if err := checkPaymentMethod(gateway, request.PaymentMethod); err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	// O método de pagamento salvo é resolvido antes de reservar a cotação de câmbio
+	request, err = withPaymentMethod(gateway, request)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+	if err := checkCardExpiry(request); err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	original, settled, rate, err := settlePayment(gateway, request)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	// Os dados do cartão são obtidos do cofre somente para a chamada ao gateway
+	settled, err = withCardDetails(settled)
+	if err != nil {
+		if request.QuoteID != "" {
+			releaseFXQuote(request.QuoteID)
+		}
+		return models.PaymentResponse{}, err
+	}
+
+	response, err := authorizer.AuthorizePayment(settled)
+	if err != nil {
+		if request.QuoteID != "" {
+			releaseFXQuote(request.QuoteID)
+		}
+		return models.PaymentResponse{}, err
+	}
+
+	if err := storeTransaction(gateway, original, settled, rate, response, "payment authorized"); err != nil {
+		return models.PaymentResponse{}, err
+	}
+	return withSettlement(response, original, settled, rate), nil
+}
+
+// CapturePayment captura um pagamento autorizado. Um valor igual a zero captura o valor total autorizado.
+// O valor está na moeda de liquidação da transação.
+func CapturePayment(transactionID string, amount models.Decimal) (models.TransactionResponse, error) {
+	transaction, authorizer, err := authorizedTransaction(transactionID, models.StatusCaptured)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+	capture, err := models.NewMoney(amount, transaction.Currency)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+	authorized, err := transaction.Amount.Money(transaction.Currency)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+	if capture.Minor > authorized.Minor {
+		return models.TransactionResponse{}, ErrCaptureAmountExceeded
+	}
+
+	response, err := authorizer.CapturePayment(transactionID, capture)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	transaction, err = transactions().Update(transactionID, func(transaction *models.Transaction) error {
+		if err := advance(transaction, response.Status, "payment captured", time.Now().UTC()); err != nil {
+			return err
+		}
+		if !capture.IsZero() && transaction.Status == models.StatusCaptured {
+			transaction.CapturedAmount = capture.Amount()
+		}
+		return nil
+	})
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+	return transactionResponse(response.Message, transaction), nil
+}
+
+// VoidPayment cancela um pagamento autorizado que ainda não foi capturado.
+func VoidPayment(transactionID string) (models.TransactionResponse, error) {
+	_, authorizer, err := authorizedTransaction(transactionID, models.StatusVoided)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	response, err := authorizer.VoidPayment(transactionID)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	transaction, err := transactions().Update(transactionID, func(transaction *models.Transaction) error {
+		return advance(transaction, response.Status, "payment voided", time.Now().UTC())
+	})
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+	return transactionResponse(response.Message, transaction), nil
+}
+
+// GetPaymentStatus retorna o status e o histórico de uma transação registrada.
+// Quando o gateway suporta consulta de status, o status é atualizado no gateway e gravado no repositório.
+func GetPaymentStatus(gatewayName, transactionID string) (models.TransactionResponse, error) {
+	gateway, err := GetGateway(gatewayName)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	transaction, err := transactions().Get(transactionID)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+	if transaction.Gateway != gateway.Name() {
+		return models.TransactionResponse{}, repository.ErrNotFound
+	}
+
+	if gateway.Capabilities().StatusLookup {
+		response, err := gateway.GetPaymentStatus(transactionID)
+		if err != nil {
+			return models.TransactionResponse{}, err
+		}
+		if response.Status != transaction.Status {
+			updated, err := transactions().Update(transactionID, func(transaction *models.Transaction) error {
+				return advance(transaction, response.Status, "gateway status update", time.Now().UTC())
+			})
+			var invalid *models.InvalidTransitionError
+			switch {
+			case errors.As(err, &invalid):
+				// O status informado pelo gateway não é alcançável a partir do status registrado: mantém o registro
+				log.Printf("ignoring %s status update for transaction %s: %v", gateway.Name(), transactionID, err)
+			case err != nil:
+				return models.TransactionResponse{}, err
+			default:
+				transaction = updated
+			}
+		}
+	}
+
+	return transactionResponse(fmt.Sprintf("Transaction ID: %s found", transactionID), transaction), nil
+}
+
+// settlePayment retorna a requisição original, com o valor nas casas decimais da moeda (e.g. 100 → 100.00),
+// a requisição na moeda de liquidação do gateway e a taxa de câmbio utilizada.
+// Com quote_id, a cotação é reservada e sua taxa e moeda de destino são usadas. Sem cotação, se o gateway liquida
+// na moeda do pagamento, a requisição é mantida e a taxa é 1; caso contrário, o valor é convertido para a moeda
+// de liquidação padrão do gateway (a primeira da lista).
+func settlePayment(gateway PaymentGateway, request models.PaymentRequest) (models.PaymentRequest, models.PaymentRequest, float64, error) {
+	amount, err := models.NewMoney(request.Amount, request.Currency)
+	if err != nil {
+		return models.PaymentRequest{}, models.PaymentRequest{}, 0, err
+	}
+	request.Amount = amount.Amount()
+
+	currencies := gateway.Capabilities().SettlementCurrencies
+	if request.QuoteID != "" {
+		quote, err := useFXQuote(request, currencies)
+		if err != nil {
+			return models.PaymentRequest{}, models.PaymentRequest{}, 0, err
+		}
+
+		converted, err := amount.Convert(quote.Rate, quote.ToCurrency, roundingMode())
+		if err != nil {
+			releaseFXQuote(request.QuoteID)
+			return models.PaymentRequest{}, models.PaymentRequest{}, 0, err
+		}
+
+		settled := request
+		settled.Currency = quote.ToCurrency
+		settled.Amount = converted.Amount()
+		return request, settled, quote.Rate, nil
+	}
+
+	if containsCurrency(currencies, request.Currency) {
+		return request, request, 1, nil
+	}
+
+	rate, err := GetExchangeRate(request.Currency, currencies[0])
+	if err != nil {
+		return models.PaymentRequest{}, models.PaymentRequest{}, 0, fmt.Errorf("%w: %s to %s: %v", ErrExchangeRateUnavailable, request.Currency, currencies[0], err)
+	}
+
+	converted, err := amount.Convert(rate, currencies[0], roundingMode())
+	if err != nil {
+		return models.PaymentRequest{}, models.PaymentRequest{}, 0, err
+	}
+
+	settled := request
+	settled.Currency = currencies[0]
+	settled.Amount = converted.Amount()
+	return request, settled, rate, nil
+}
+
+// withSettlement acrescenta à resposta do gateway os valores original e liquidado e a taxa de câmbio.
+func withSettlement(response models.PaymentResponse, request, settled models.PaymentRequest, rate float64) models.PaymentResponse {
+	response.OriginalAmount = request.Amount
+	response.OriginalCurrency = request.Currency
+	response.SettledAmount = settled.Amount
+	response.SettledCurrency = settled.Currency
+	response.ExchangeRate = rate
+	response.QuoteID = request.QuoteID
+	response.CustomerID = request.CustomerID
+	response.PaymentMethodID = request.PaymentMethodID
+	if card := settled.CardDetails; card != nil {
+		response.CardBrand, response.CardLast4 = string(card.Brand()), card.Last4()
+	}
+	return response
+}
+
+// storeTransaction registra a transação criada no gateway, levando-a do status created até o status retornado.
+// A transação guarda o valor liquidado e, quando houve conversão, o valor original e a taxa de câmbio.
+// Como o gateway já processou o pagamento, uma falha no registro é retornada como TransactionNotRecordedError.
+func storeTransaction(gateway PaymentGateway, request, settled models.PaymentRequest, rate float64, response models.PaymentResponse, reason string) error {
+	now := time.Now().UTC()
+	transaction := models.NewTransaction(response.Transaction_ID, gateway.Name(), settled.Amount, settled.Currency, now)
+	transaction.OriginalAmount = request.Amount
+	transaction.OriginalCurrency = request.Currency
+	transaction.ExchangeRate = rate
+	transaction.QuoteID = request.QuoteID
+	transaction.CustomerID = request.CustomerID
+	transaction.PaymentMethodID = request.PaymentMethodID
+	transaction.PaymentMethod = request.PaymentMethod
+	if response.Pix != nil {
+		expiresAt := response.Pix.ExpiresAt
+		transaction.ExpiresAt = &expiresAt
+	}
+	// Do cartão são guardados apenas a bandeira, o BIN e os quatro últimos dígitos
+	if card := settled.CardDetails; card != nil {
+		transaction.CardBrand, transaction.CardBIN, transaction.CardLast4 = string(card.Brand()), card.BIN(), card.Last4()
+	}
+	err := advance(&transaction, response.Status, reason, now)
+	if err == nil {
+		err = transactions().Create(transaction)
+	}
+	if err != nil {
+		log.Printf("Payment %s was processed by gateway %s but could not be recorded: %v\n", response.Transaction_ID, gateway.Name(), err)
+		return &TransactionNotRecordedError{Gateway: gateway.Name(), TransactionID: response.Transaction_ID, Err: err}
+	}
+	if request.QuoteID != "" {
+		completeFXQuote(request.QuoteID, response.Transaction_ID)
+	}
+	return nil
+}
+
+// authorizedTransaction carrega a transação e o gateway que a processou, verificando se o gateway
+// suporta autorização e se a transação pode passar para o status desejado antes de acionar o gateway.
+func authorizedTransaction(transactionID, target string) (models.Transaction, Authorizer, error) {
+	transaction, err := transactions().Get(transactionID)
+	if err != nil {
+		return models.Transaction{}, nil, err
+	}
+	gateway, err := GetGateway(transaction.Gateway)
+	if err != nil {
+		return models.Transaction{}, nil, err
+	}
+	authorizer, ok := gateway.(Authorizer)
+	if !ok || !gateway.Capabilities().Authorization {
+		return models.Transaction{}, nil, ErrOperationNotSupported
+	}
+	if !models.CanTransition(transaction.Status, target) {
+		return models.Transaction{}, nil, &models.InvalidTransitionError{From: transaction.Status, To: target}
+	}
+	return transaction, authorizer, nil
+}
+
+// advance leva a transação até o status informado, passando pelos status intermediários necessários
+// (e.g. created → captured passa por authorized). Ao chegar em captured, registra o valor capturado.
+func advance(transaction *models.Transaction, status, reason string, at time.Time) error {
+	if status == transaction.Status {
+		return nil
+	}
+
+	path := models.TransitionPath(transaction.Status, status)
+	if path == nil {
+		return &models.InvalidTransitionError{From: transaction.Status, To: status}
+	}
+	for _, step := range path {
+		if err := transaction.Transition(step, reason, at); err != nil {
+			return err
+		}
+		if step == models.StatusCaptured {
+			transaction.CapturedAmount = transaction.Amount
+		}
+	}
+	return nil
+}
+
+// transactionResponse monta a resposta com o status atual e o histórico da transação.
+func transactionResponse(message string, transaction models.Transaction) models.TransactionResponse {
+	return models.TransactionResponse{
+		Message:       message,
+		Status:        transaction.Status,
+		TransactionID: transaction.Transaction_ID,
+		Gateway:       transaction.Gateway,
+		History:       transaction.History,
+	}
+}
@@ -1,79 +1,584 @@
 // paypal.go
-// Este módulo simula de maneira simplificada uma transação para a API do PayPal.
-// Ele gera um ID de transação e um status de pagamento de maneira aleatória e guarda essas informações em memória.
-// Outra função checa e retorna o status baseado nesse ID.
-
-// Idealmente, a implementação deveria utilizar a API de sandbox do PayPal e os endpoints comentados abaixo,
-// levando em consideração todas as variáveis descritas, tratamento de erros da API externa e por fim criado uma camada de repositório para acesso ao banco de dados.
-// https://developer.paypal.com/docs/api/payments/v1/#payment_get
+// Este arquivo implementa o adaptador do gateway PayPal utilizando a API REST de pagamentos v1.
+// A autenticação é feita pelo fluxo OAuth2 client credentials: o token de acesso é obtido uma vez
+// e reutilizado até próximo da sua expiração, sendo renovado automaticamente.
+// https://developer.paypal.com/api/rest/authentication/
 // https://developer.paypal.com/docs/api/payments/v1/#payment_create
+// https://developer.paypal.com/docs/api/payments/v1/#payment_get
+// https://developer.paypal.com/docs/api/payments/v1/#sale_refund
+
+// A URL base é configurável, permitindo apontar para o sandbox do PayPal ou para o servidor local que o simula (mocks/paypalmock).
+// Os valores de state do PayPal são traduzidos para o vocabulário da máquina de estados (models.Status*).
+// Pagamentos com intent authorize são capturados ou cancelados posteriormente pelos recursos de autorização.
+// https://developer.paypal.com/docs/api/payments/v1/#authorization_capture
+// https://developer.paypal.com/docs/api/payments/v1/#authorization_void
 
 package services
 
 import (
+	"bytes"
+	"desafiogolang-payment/config"
 	"desafiogolang-payment/models"
+	"encoding/json"
 	"fmt"
-	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 )
 
-var (
-	transactions     = make(map[string]models.Transaction)
-	transactionsLock sync.Mutex
-	rng              = rand.New(rand.NewSource(time.Now().UnixNano()))
-)
+func init() {
+	cfg := config.Load()
+	gateway := NewPayPalGateway(cfg.PayPalBaseURL, cfg.PayPalClientID, cfg.PayPalClientSecret)
+	gateway.SettlementCurrencies = cfg.PayPalSettlementCurrencies
+	RegisterGateway(gateway)
+}
+
+// PayPalGateway é o adaptador para a API REST de pagamentos do PayPal.
+type PayPalGateway struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	Client       *http.Client
+
+	// PollAttempts e PollInterval controlam quantas vezes um pagamento ainda pendente
+	// é consultado após a criação antes de retornar o status pendente ao cliente.
+	PollAttempts int
+	PollInterval time.Duration
 
-// Mockable function variable
-var GetPayPalPaymentStatusFunc = getPayPalPaymentStatus
+	// SettlementCurrencies são as moedas em que a conta liquida pagamentos (padrão: USD).
+	SettlementCurrencies []string
 
-// generateTransactionID gera um ID de transação único
-func generateTransactionID() string {
-	return fmt.Sprintf("PAY-%d", rng.Intn(1000000000))
+	tokenLock   sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
 }
 
-// ProcessPayPalPayment simula o processamento de um pagamento no PayPal
-func ProcessPayPalPayment(request models.PaymentRequest) models.PaymentResponse {
-	transactionID := generateTransactionID()
+// NewPayPalGateway cria um adaptador do PayPal que se comunica com a URL base informada.
+func NewPayPalGateway(baseURL, clientID, clientSecret string) *PayPalGateway {
+	return &PayPalGateway{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Client:       &http.Client{Timeout: 30 * time.Second},
+		PollAttempts: 3,
+		PollInterval: 500 * time.Millisecond,
 
-	// Simulando diferentes resultados com base em valores aleatórios
-	statuses := []string{"completed", "pending", "failed"}
-	status := statuses[rng.Intn(len(statuses))]
+		SettlementCurrencies: []string{"USD"},
+	}
+}
 
-	transactionsLock.Lock()
-	transactions[transactionID] = models.Transaction{
-		Status:         status,
-		Transaction_ID: transactionID,
+// payPalAmount representa um valor monetário no formato do PayPal.
+type payPalAmount struct {
+	Total    string `json:"total"`
+	Currency string `json:"currency"`
+}
+
+// payPalPayment representa os campos utilizados de um recurso payment do PayPal.
+type payPalPayment struct {
+	ID           string `json:"id"`
+	Intent       string `json:"intent"`
+	State        string `json:"state"`
+	Transactions []struct {
+		Amount           payPalAmount `json:"amount"`
+		RelatedResources []struct {
+			Sale          *payPalResource `json:"sale,omitempty"`
+			Authorization *payPalResource `json:"authorization,omitempty"`
+			Capture       *payPalResource `json:"capture,omitempty"`
+		} `json:"related_resources"`
+	} `json:"transactions"`
+}
+
+// payPalResource representa os campos utilizados dos recursos sale, authorization e capture do PayPal.
+type payPalResource struct {
+	ID     string       `json:"id"`
+	State  string       `json:"state"`
+	Amount payPalAmount `json:"amount"`
+}
+
+// payPalRefund representa os campos utilizados de um recurso refund do PayPal.
+type payPalRefund struct {
+	ID     string       `json:"id"`
+	State  string       `json:"state"`
+	Amount payPalAmount `json:"amount"`
+}
+
+// payPalErrorResponse representa o corpo de erro retornado pela API do PayPal,
+// incluindo o formato de erro do endpoint de OAuth2.
+type payPalErrorResponse struct {
+	Name             string `json:"name"`
+	Message          string `json:"message"`
+	DebugID          string `json:"debug_id"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (g *PayPalGateway) Name() string {
+	return "PayPal"
+}
+
+func (g *PayPalGateway) Capabilities() GatewayCapabilities {
+	return GatewayCapabilities{
+		StatusLookup:         true,
+		Refunds:              true,
+		PartialRefunds:       true,
+		Authorization:        true,
+		BillingAgreements:    true,
+		PaymentMethods:       []string{models.PaymentMethodCreditCard, models.PaymentMethodPayPal},
+		SettlementCurrencies: g.SettlementCurrencies,
 	}
-	transactionsLock.Unlock()
+}
 
-	return models.PaymentResponse{
-		Message:        "Payment processed with success",
-		Transaction_ID: transactionID,
+// ProcessPayment cria um pagamento do tipo sale com os dados do cartão.
+// Caso o pagamento ainda esteja pendente, ele é consultado novamente até PollAttempts vezes.
+func (g *PayPalGateway) ProcessPayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	return g.createPayment(request, "sale")
+}
+
+// AuthorizePayment cria um pagamento do tipo authorize, que apenas reserva o valor no cartão.
+func (g *PayPalGateway) AuthorizePayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	return g.createPayment(request, "authorize")
+}
+
+// CapturePayment captura a autorização associada ao pagamento. Um valor igual a zero captura o valor total autorizado.
+func (g *PayPalGateway) CapturePayment(transactionID string, amount models.Money) (models.TransactionResponse, error) {
+	payment, err := g.getPayment(transactionID)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	authorization := payPalResourceOf(payment, "authorization")
+	if authorization == nil {
+		return models.TransactionResponse{}, &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorInvalidRequest, Message: "Payment has no authorization to capture"}
+	}
+
+	total := authorization.Amount.Total
+	if !amount.IsZero() {
+		total = amount.Amount().String()
+	}
+	body := map[string]interface{}{
+		"amount":           payPalAmount{Total: total, Currency: authorization.Amount.Currency},
+		"is_final_capture": true,
+	}
+
+	var capture payPalResource
+	if err := g.do(http.MethodPost, "/v1/payments/authorization/"+url.PathEscape(authorization.ID)+"/capture", body, &capture); err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	status := models.StatusPending
+	if capture.State == "completed" {
+		status = models.StatusCaptured
 	}
+	return models.TransactionResponse{
+		Message: "Payment captured with success",
+		Status:  status,
+	}, nil
 }
 
-// getPayPalPaymentStatus simula a verificação do status de uma transação no PayPal
-func getPayPalPaymentStatus(transactionID string) models.TransactionResponse {
-	transactionsLock.Lock()
-	defer transactionsLock.Unlock()
+// VoidPayment cancela a autorização associada ao pagamento.
+func (g *PayPalGateway) VoidPayment(transactionID string) (models.TransactionResponse, error) {
+	payment, err := g.getPayment(transactionID)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	authorization := payPalResourceOf(payment, "authorization")
+	if authorization == nil {
+		return models.TransactionResponse{}, &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorInvalidRequest, Message: "Payment has no authorization to void"}
+	}
+
+	var voided payPalResource
+	if err := g.do(http.MethodPost, "/v1/payments/authorization/"+url.PathEscape(authorization.ID)+"/void", nil, &voided); err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	return models.TransactionResponse{
+		Message: "Payment voided with success",
+		Status:  models.StatusVoided,
+	}, nil
+}
 
-	if transaction, exists := transactions[transactionID]; exists {
-		return models.TransactionResponse{
-			Message: fmt.Sprintf("Transaction ID: %s found", transactionID),
-			Status:  transaction.Status,
+// createPayment cria um pagamento com o intent informado (sale ou authorize), com cartão de crédito
+// ou, para os métodos de pagamento salvos dos clientes, com um acordo de cobrança (billing agreement).
+func (g *PayPalGateway) createPayment(request models.PaymentRequest, intent string) (models.PaymentResponse, error) {
+	payer, err := g.payer(request)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+	total, err := request.Amount.Money(request.Currency)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	body := map[string]interface{}{
+		"intent": intent,
+		"payer":  payer,
+		"transactions": []interface{}{
+			map[string]interface{}{
+				"amount": payPalAmount{
+					Total:    total.Amount().String(),
+					Currency: request.Currency,
+				},
+			},
+		},
+	}
+
+	var payment payPalPayment
+	if err := g.do(http.MethodPost, "/v1/payments/payment", body, &payment); err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	// Consulta novamente pagamentos que ainda não chegaram a um estado final
+	for attempt := 0; attempt < g.PollAttempts && payPalStatus(payment) == models.StatusPending; attempt++ {
+		time.Sleep(g.PollInterval)
+		if payment, err = g.getPayment(payment.ID); err != nil {
+			return models.PaymentResponse{}, err
 		}
 	}
+
+	status := payPalStatus(payment)
+	message := "Payment processed with success"
+	switch status {
+	case models.StatusAuthorized:
+		message = "Payment authorized with success"
+	case models.StatusPending:
+		message = "Payment is pending confirmation"
+	case models.StatusFailed:
+		message = "Payment failed"
+	}
+
+	return models.PaymentResponse{
+		Message:        message,
+		Transaction_ID: payment.ID,
+		Status:         status,
+	}, nil
+}
+
+// payer monta o pagador do pagamento: um acordo de cobrança, quando informado, ou o cartão de crédito.
+func (g *PayPalGateway) payer(request models.PaymentRequest) (map[string]interface{}, error) {
+	if request.BillingAgreementID != "" {
+		return map[string]interface{}{
+			"payment_method": "paypal",
+			"funding_instruments": []interface{}{
+				map[string]interface{}{
+					"billing": map[string]interface{}{"billing_agreement_id": request.BillingAgreementID},
+				},
+			},
+		}, nil
+	}
+
+	cardType, err := payPalCardType(*request.CardDetails)
+	if err != nil {
+		return nil, err
+	}
+	expMonth, expYear, err := models.ParseCardExpiry(request.CardDetails.Expiry)
+	if err != nil {
+		return nil, &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorInvalidRequest, Message: err.Error()}
+	}
+
+	creditCard := map[string]interface{}{
+		"number":       request.CardDetails.Number,
+		"type":         cardType,
+		"expire_month": expMonth,
+		"expire_year":  expYear,
+	}
+	// Pagamentos com card_token não têm CVV depois que o CVV guardado no cofre é usado ou expira
+	if request.CardDetails.CVV != "" {
+		creditCard["cvv2"] = request.CardDetails.CVV
+	}
+
+	return map[string]interface{}{
+		"payment_method": "credit_card",
+		"funding_instruments": []interface{}{
+			map[string]interface{}{
+				"credit_card": creditCard,
+			},
+		},
+	}, nil
+}
+
+// getPayment consulta um pagamento no PayPal.
+func (g *PayPalGateway) getPayment(transactionID string) (payPalPayment, error) {
+	var payment payPalPayment
+	err := g.do(http.MethodGet, "/v1/payments/payment/"+url.PathEscape(transactionID), nil, &payment)
+	return payment, err
+}
+
+// GetPaymentStatus consulta o pagamento no PayPal.
+func (g *PayPalGateway) GetPaymentStatus(transactionID string) (models.TransactionResponse, error) {
+	payment, err := g.getPayment(transactionID)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+
 	return models.TransactionResponse{
-		Message: "Transaction ID not found",
-		Status:  "unknown",
+		Message: fmt.Sprintf("Transaction ID: %s found", transactionID),
+		Status:  payPalStatus(payment),
+	}, nil
+}
+
+// RefundPayment reembolsa a venda (ou a captura, para pagamentos autorizados) associada ao pagamento.
+// Um valor igual a zero reembolsa o valor total.
+func (g *PayPalGateway) RefundPayment(transactionID string, amount models.Money) (models.RefundResponse, error) {
+	payment, err := g.getPayment(transactionID)
+	if err != nil {
+		return models.RefundResponse{}, err
+	}
+
+	path := ""
+	var target *payPalResource
+	if target = payPalResourceOf(payment, "capture"); target != nil {
+		path = "/v1/payments/capture/" + url.PathEscape(target.ID) + "/refund"
+	} else if target = payPalResourceOf(payment, "sale"); target != nil {
+		path = "/v1/payments/sale/" + url.PathEscape(target.ID) + "/refund"
+	} else {
+		return models.RefundResponse{}, &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorInvalidRequest, Message: "Payment has no sale or capture to refund"}
+	}
+
+	body := map[string]interface{}{}
+	if !amount.IsZero() {
+		body["amount"] = payPalAmount{
+			Total:    amount.Amount().String(),
+			Currency: target.Amount.Currency,
+		}
+	}
+
+	var refund payPalRefund
+	if err := g.do(http.MethodPost, path, body, &refund); err != nil {
+		return models.RefundResponse{}, err
 	}
+
+	status := models.RefundStatusPending
+	switch refund.State {
+	case "completed":
+		status = models.RefundStatusSucceeded
+	case "failed", "cancelled":
+		status = models.RefundStatusFailed
+	}
+	refunded, _ := models.ParseDecimal(refund.Amount.Total)
+	if amount, err := refunded.Money(refund.Amount.Currency); err == nil {
+		refunded = amount.Amount()
+	}
+
+	return models.RefundResponse{
+		Message:         "Refund " + refund.ID + " created",
+		GatewayRefundID: refund.ID,
+		TransactionID:   transactionID,
+		Amount:          refunded,
+		Currency:        refund.Amount.Currency,
+		Status:          status,
+	}, nil
 }
 
-// GetPayPalPaymentStatus é feito para efeitos de testes.
-// Como os dados são armazenados em memória, foi necessário atribuir a uma variável a função,
-// permitindo modificar e mockar o seu comportamento durante os testes.
-// Idealmente, utilizando um banco de dados para armazenamento dos dados, isso não seria necessário.
-func GetPayPalPaymentStatus(transactionID string) models.TransactionResponse {
-	return GetPayPalPaymentStatusFunc(transactionID)
+// token retorna o token de acesso em cache ou obtém um novo pelo fluxo client credentials.
+func (g *PayPalGateway) token() (string, error) {
+	g.tokenLock.Lock()
+	defer g.tokenLock.Unlock()
+
+	if g.accessToken != "" && time.Now().Before(g.tokenExpiry) {
+		return g.accessToken, nil
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.BaseURL+"/v1/oauth2/token", strings.NewReader("grant_type=client_credentials"))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(g.ClientID, g.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return "", &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorUnavailable, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", payPalError(resp)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	// Renova o token um minuto antes da expiração para evitar usá-lo já expirado
+	g.accessToken = result.AccessToken
+	g.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return g.accessToken, nil
+}
+
+// invalidateToken descarta o token em cache, forçando a obtenção de um novo.
+func (g *PayPalGateway) invalidateToken() {
+	g.tokenLock.Lock()
+	defer g.tokenLock.Unlock()
+
+	g.accessToken = ""
+}
+
+// do executa uma requisição autenticada na API do PayPal e decodifica a resposta em out.
+// Caso o token seja rejeitado, ele é renovado e a requisição é repetida uma única vez.
+func (g *PayPalGateway) do(method, path string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = encoded
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, err := g.token()
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(method, g.BaseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := g.Client.Do(req)
+		if err != nil {
+			return &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorUnavailable, Message: err.Error()}
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			g.invalidateToken()
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return payPalError(resp)
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+}
+
+// payPalError traduz uma resposta de erro do PayPal para models.GatewayError.
+func payPalError(resp *http.Response) error {
+	var body payPalErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	gatewayErr := &models.GatewayError{
+		Gateway:    "PayPal",
+		StatusCode: resp.StatusCode,
+		Code:       body.Name,
+		Message:    body.Message,
+	}
+	if body.Error != "" {
+		gatewayErr.Code = body.Error
+		gatewayErr.Message = body.ErrorDescription
+	}
+	if gatewayErr.Message == "" {
+		gatewayErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	switch {
+	case body.Name == "CREDIT_CARD_REFUSED" || body.Name == "INSTRUMENT_DECLINED" ||
+		body.Name == "CREDIT_CARD_CVV_CHECK_FAILED" || body.Name == "EXPIRED_CREDIT_CARD" ||
+		body.Name == "AGREEMENT_ALREADY_CANCELLED":
+		gatewayErr.Type = models.GatewayErrorCard
+	case resp.StatusCode == http.StatusUnauthorized:
+		gatewayErr.Type = models.GatewayErrorAuthentication
+	case resp.StatusCode == http.StatusNotFound || body.Name == "INVALID_RESOURCE_ID":
+		gatewayErr.Type = models.GatewayErrorNotFound
+	case resp.StatusCode >= 500:
+		gatewayErr.Type = models.GatewayErrorUnavailable
+	default:
+		gatewayErr.Type = models.GatewayErrorInvalidRequest
+	}
+	return gatewayErr
+}
+
+// payPalResourceOf retorna o recurso do tipo informado (sale, authorization ou capture) associado ao pagamento.
+func payPalResourceOf(payment payPalPayment, kind string) *payPalResource {
+	for _, transaction := range payment.Transactions {
+		for _, resource := range transaction.RelatedResources {
+			switch {
+			case kind == "sale" && resource.Sale != nil:
+				return resource.Sale
+			case kind == "authorization" && resource.Authorization != nil:
+				return resource.Authorization
+			case kind == "capture" && resource.Capture != nil:
+				return resource.Capture
+			}
+		}
+	}
+	return nil
+}
+
+// payPalStatus traduz o state do pagamento (e dos recursos associados) para o vocabulário da máquina de estados.
+func payPalStatus(payment payPalPayment) string {
+	switch payment.State {
+	case "failed", "canceled", "expired":
+		return models.StatusFailed
+	case "created", "pending", "in_progress":
+		return models.StatusPending
+	}
+
+	// Pagamento aprovado: o resultado final depende dos recursos associados
+	if capture := payPalResourceOf(payment, "capture"); capture != nil {
+		return payPalCompletedResourceStatus(capture.State)
+	}
+	if sale := payPalResourceOf(payment, "sale"); sale != nil {
+		return payPalCompletedResourceStatus(sale.State)
+	}
+	if authorization := payPalResourceOf(payment, "authorization"); authorization != nil {
+		switch authorization.State {
+		case "voided", "expired":
+			return models.StatusVoided
+		case "pending":
+			return models.StatusPending
+		case "captured", "partially_captured":
+			return models.StatusCaptured
+		}
+		return models.StatusAuthorized
+	}
+	return models.StatusCaptured
+}
+
+// payPalCompletedResourceStatus traduz o state de uma venda ou captura.
+func payPalCompletedResourceStatus(state string) string {
+	switch state {
+	case "pending":
+		return models.StatusPending
+	case "denied":
+		return models.StatusFailed
+	case "refunded":
+		return models.StatusRefunded
+	case "partially_refunded":
+		return models.StatusPartiallyRefunded
+	}
+	return models.StatusCaptured
+}
+
+// payPalCardTypes são as bandeiras aceitas pelo PayPal, no formato do campo type do cartão.
+var payPalCardTypes = map[models.CardBrand]string{
+	models.CardBrandVisa:       "visa",
+	models.CardBrandMastercard: "mastercard",
+	models.CardBrandAmex:       "amex",
+	models.CardBrandDiscover:   "discover",
+}
+
+// payPalCardType retorna a bandeira do cartão no formato esperado pelo PayPal, a mesma registrada na transação.
+// Bandeiras que o PayPal não aceita (e.g. Elo e Hipercard) resultam em ErrOperationNotSupported.
+func payPalCardType(card models.CardDetails) (string, error) {
+	brand := card.Brand()
+	cardType, ok := payPalCardTypes[brand]
+	if !ok {
+		return "", fmt.Errorf("%w: PayPal does not accept %s cards", ErrOperationNotSupported, brand)
+	}
+	return cardType, nil
 }
@@ -0,0 +1,364 @@
+// pix.go
+// Este arquivo implementa o gateway Pix, que cria cobranças imediatas na API Pix do PSP do recebedor (/v2/cob),
+// em uma URL base configurável, permitindo apontar para o PSP real ou para o servidor local que o simula (mocks/pixmock).
+// https://bacen.github.io/pix-api/
+
+// Cada pagamento Pix cria uma cobrança com um txid gerado aqui e devolve o BR Code dinâmico ("Pix copia e cola",
+// models.BRCode) e a imagem PNG do QR Code. A transação fica pendente até que o PSP notifique o pagamento pelo webhook
+// (ConfirmPixPayment), quando passa para captured, ou até a expiração da cobrança, quando a consulta de status a leva
+// para failed. Um Pix recebido depois da expiração leva a transação para requires_review, para ser devolvido.
+// As notificações são assinadas pelo PSP com HMAC-SHA256 do corpo, usando o segredo compartilhado.
+// Os pagamentos são sempre liquidados em BRL; as devoluções de Pix não são suportadas.
+
+// O arquivo inclui:
+// 1. PixGateway / NewPixGateway: Adaptador da API de cobranças imediatas do PSP.
+// 2. SetPixWebhookSecret / VerifyPixWebhookSignature: Verificação da assinatura das notificações do webhook.
+// 3. ConfirmPixPayment: Conclui a transação pendente ao receber a notificação de pagamento do PSP.
+
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"desafiogolang-payment/config"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Erros dos pagamentos Pix.
+var (
+	ErrInvalidPixWebhookSignature = errors.New("invalid Pix webhook signature")
+	ErrPixAmountMismatch          = errors.New("Pix amount does not match the charge amount")
+)
+
+// pixQRCodeSize é o tamanho, em pixels, da imagem PNG do QR Code.
+const pixQRCodeSize = 256
+
+func init() {
+	cfg := config.Load()
+	gateway := NewPixGateway(cfg.PixPSPBaseURL, cfg.PixPSPToken, cfg.PixKey)
+	gateway.MerchantName = cfg.PixMerchantName
+	gateway.MerchantCity = cfg.PixMerchantCity
+	gateway.Expiration = cfg.PixExpiration
+	RegisterGateway(gateway)
+}
+
+// PixGateway é o adaptador para a API de cobranças imediatas do PSP do recebedor.
+type PixGateway struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+
+	// PixKey é a chave Pix do recebedor, para a qual as cobranças são criadas.
+	PixKey string
+	// MerchantName e MerchantCity identificam o recebedor no BR Code.
+	MerchantName string
+	MerchantCity string
+	// Expiration é o tempo de validade das cobranças (padrão: 30 minutos).
+	Expiration time.Duration
+}
+
+// NewPixGateway cria um adaptador do Pix que se comunica com a URL base do PSP informada.
+func NewPixGateway(baseURL, token, pixKey string) *PixGateway {
+	return &PixGateway{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		Client:  &http.Client{Timeout: 30 * time.Second},
+
+		PixKey:       pixKey,
+		MerchantName: "Desafio Golang Payment",
+		MerchantCity: "Sao Paulo",
+		Expiration:   30 * time.Minute,
+	}
+}
+
+// pixCharge representa os campos utilizados de uma cobrança imediata.
+type pixCharge struct {
+	Calendar struct {
+		CreatedAt  time.Time `json:"criacao"`
+		Expiration int       `json:"expiracao"`
+	} `json:"calendario"`
+	TxID     string `json:"txid"`
+	Location string `json:"location"`
+	Status   string `json:"status"`
+}
+
+// expiresAt retorna o fim do prazo de pagamento da cobrança.
+func (c pixCharge) expiresAt() time.Time {
+	return c.Calendar.CreatedAt.Add(time.Duration(c.Calendar.Expiration) * time.Second)
+}
+
+// pixErrorResponse representa o corpo de erro (RFC 7807) retornado pela API Pix.
+type pixErrorResponse struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Detail string `json:"detail"`
+}
+
+func (g *PixGateway) Name() string {
+	return "Pix"
+}
+
+func (g *PixGateway) Capabilities() GatewayCapabilities {
+	return GatewayCapabilities{
+		StatusLookup:         true,
+		PaymentMethods:       []string{models.PaymentMethodPix},
+		SettlementCurrencies: []string{"BRL"},
+	}
+}
+
+// ProcessPayment cria a cobrança imediata no PSP e retorna o BR Code e o QR Code, com a transação pendente.
+func (g *PixGateway) ProcessPayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	amount, err := request.Amount.Money(request.Currency)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+	body := map[string]interface{}{
+		"calendario": map[string]int{"expiracao": int(g.Expiration / time.Second)},
+		"valor":      map[string]string{"original": amount.Amount().String()},
+		"chave":      g.PixKey,
+	}
+
+	var charge pixCharge
+	if err := g.do(http.MethodPut, "/v2/cob/"+newPixTxID(), body, &charge); err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	payload, err := models.BRCode{
+		Location:     charge.Location,
+		MerchantName: g.MerchantName,
+		MerchantCity: g.MerchantCity,
+		Amount:       request.Amount,
+	}.Payload()
+	if err != nil {
+		return models.PaymentResponse{}, fmt.Errorf("Pix charge %s: %w", charge.TxID, err)
+	}
+	image, err := qrcode.Encode(payload, qrcode.Medium, pixQRCodeSize)
+	if err != nil {
+		return models.PaymentResponse{}, fmt.Errorf("Pix charge %s: QR code: %w", charge.TxID, err)
+	}
+
+	return models.PaymentResponse{
+		Message:        "Pix charge created, waiting for payment",
+		Transaction_ID: charge.TxID,
+		Status:         pixStatus(charge, time.Now()),
+		Pix: &models.PixCharge{
+			TxID:        charge.TxID,
+			QRCode:      payload,
+			QRCodeImage: base64.StdEncoding.EncodeToString(image),
+			ExpiresAt:   charge.expiresAt().UTC(),
+		},
+	}, nil
+}
+
+// GetPaymentStatus consulta a cobrança no PSP. Cobranças não pagas até a expiração resultam em failed.
+func (g *PixGateway) GetPaymentStatus(transactionID string) (models.TransactionResponse, error) {
+	var charge pixCharge
+	if err := g.do(http.MethodGet, "/v2/cob/"+url.PathEscape(transactionID), nil, &charge); err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	return models.TransactionResponse{
+		Message: fmt.Sprintf("Transaction ID: %s found", transactionID),
+		Status:  pixStatus(charge, time.Now()),
+	}, nil
+}
+
+// RefundPayment não é suportado: as devoluções de Pix não são implementadas.
+func (g *PixGateway) RefundPayment(transactionID string, amount models.Money) (models.RefundResponse, error) {
+	return models.RefundResponse{}, ErrOperationNotSupported
+}
+
+// do executa uma requisição na API Pix e decodifica a resposta em out.
+// Respostas de erro são traduzidas para *models.GatewayError.
+func (g *PixGateway) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, g.BaseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorUnavailable, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return pixError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// pixError traduz uma resposta de erro da API Pix para models.GatewayError.
+// O código é o último segmento do tipo do erro (e.g. CobOperacaoInvalida).
+func pixError(resp *http.Response) error {
+	var body pixErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	gatewayErr := &models.GatewayError{
+		Gateway:    "Pix",
+		StatusCode: resp.StatusCode,
+		Message:    body.Detail,
+	}
+	if body.Type != "" {
+		gatewayErr.Code = path.Base(body.Type)
+	}
+	if gatewayErr.Message == "" {
+		gatewayErr.Message = body.Title
+	}
+	if gatewayErr.Message == "" {
+		gatewayErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		gatewayErr.Type = models.GatewayErrorAuthentication
+	case resp.StatusCode == http.StatusNotFound:
+		gatewayErr.Type = models.GatewayErrorNotFound
+	case resp.StatusCode >= 500:
+		gatewayErr.Type = models.GatewayErrorUnavailable
+	default:
+		gatewayErr.Type = models.GatewayErrorInvalidRequest
+	}
+	return gatewayErr
+}
+
+// pixStatus traduz o status de uma cobrança para o vocabulário da máquina de estados.
+func pixStatus(charge pixCharge, now time.Time) string {
+	switch charge.Status {
+	case "CONCLUIDA":
+		return models.StatusCaptured
+	case "ATIVA":
+		// O PSP pode demorar a remover a cobrança expirada
+		if !now.Before(charge.expiresAt()) {
+			return models.StatusFailed
+		}
+		return models.StatusPending
+	default:
+		// REMOVIDA_PELO_USUARIO_RECEBEDOR e REMOVIDA_PELO_PSP
+		return models.StatusFailed
+	}
+}
+
+// newPixTxID gera o txid de uma cobrança imediata (26 a 35 caracteres alfanuméricos).
+func newPixTxID() string {
+	buf := make([]byte, 14)
+	rand.Read(buf)
+	return "pix" + hex.EncodeToString(buf)
+}
+
+var (
+	pixWebhookSecret     string
+	pixWebhookSecretLock sync.RWMutex
+)
+
+// SetPixWebhookSecret define o segredo compartilhado com o PSP para assinar as notificações do webhook.
+// Sem segredo, todas as notificações são recusadas.
+func SetPixWebhookSecret(secret string) {
+	pixWebhookSecretLock.Lock()
+	defer pixWebhookSecretLock.Unlock()
+
+	pixWebhookSecret = secret
+}
+
+// VerifyPixWebhookSignature verifica a assinatura HMAC-SHA256, em hexadecimal, do corpo da notificação.
+func VerifyPixWebhookSignature(body []byte, signature string) error {
+	pixWebhookSecretLock.RLock()
+	secret := pixWebhookSecret
+	pixWebhookSecretLock.RUnlock()
+
+	expected, err := hex.DecodeString(signature)
+	if secret == "" || err != nil {
+		return ErrInvalidPixWebhookSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrInvalidPixWebhookSignature
+	}
+	return nil
+}
+
+// ConfirmPixPayment conclui a transação pendente da cobrança paga, levando-a para captured, e registra o endToEndId do Pix.
+// O valor recebido deve ser igual ao valor da cobrança. Um pagamento feito depois da expiração também é registrado,
+// pois o valor foi recebido: a transação passa para requires_review, para que ele seja devolvido ao pagador.
+// Da mesma forma, outro Pix para uma cobrança já paga é registrado em PixDuplicates e leva a transação para requires_review.
+// Notificações repetidas de um Pix já registrado são ignoradas.
+func ConfirmPixPayment(payment models.PixPayment) (models.TransactionResponse, error) {
+	transaction, err := transactions().Get(payment.TxID)
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+	if transaction.PaymentMethod != models.PaymentMethodPix {
+		return models.TransactionResponse{}, repository.ErrNotFound
+	}
+
+	amount, err := models.ParseDecimal(payment.Amount)
+	if err != nil {
+		return models.TransactionResponse{}, fmt.Errorf("%w: %q", ErrPixAmountMismatch, payment.Amount)
+	}
+	if amount.Cmp(transaction.Amount) != 0 {
+		return models.TransactionResponse{}, fmt.Errorf("%w: received %s, expected %s", ErrPixAmountMismatch, amount, transaction.Amount)
+	}
+
+	expired := transaction.ExpiresAt != nil && payment.PaidAt.After(*transaction.ExpiresAt)
+	message := "Pix payment received"
+	transaction, err = transactions().Update(payment.TxID, func(transaction *models.Transaction) error {
+		if transaction.PixEndToEndID == payment.EndToEndID || slices.Contains(transaction.PixDuplicates, payment.EndToEndID) {
+			// Notificação repetida
+			if transaction.Status == models.StatusRequiresReview {
+				message = "Pix payment received, refund required"
+			}
+			return nil
+		}
+
+		now := time.Now().UTC()
+		switch {
+		case transaction.PixEndToEndID != "" || transaction.Status == models.StatusCaptured:
+			// A cobrança já foi paga: o valor recebido de novo precisa ser devolvido
+			transaction.PixDuplicates = append(transaction.PixDuplicates, payment.EndToEndID)
+			message = "Pix payment received for a charge already paid, refund required"
+			// Transição direta, registrada no histórico mesmo se a transação já estiver em requires_review
+			return transaction.Transition(models.StatusRequiresReview, "duplicate Pix payment received ("+payment.EndToEndID+"), refund required", now)
+		case expired || transaction.Status == models.StatusFailed:
+			// A cobrança pode já ter sido levada para failed pela consulta de status após a expiração
+			transaction.PixEndToEndID = payment.EndToEndID
+			message = "Pix payment received after the charge expired, refund required"
+			return advance(transaction, models.StatusRequiresReview, "Pix payment received after the charge expired ("+payment.EndToEndID+"), refund required", now)
+		default:
+			transaction.PixEndToEndID = payment.EndToEndID
+			return advance(transaction, models.StatusCaptured, "Pix payment received ("+payment.EndToEndID+")", now)
+		}
+	})
+	if err != nil {
+		return models.TransactionResponse{}, err
+	}
+	return transactionResponse(message, transaction), nil
+}
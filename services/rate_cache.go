@@ -0,0 +1,408 @@
+// rate_cache.go
+// Este arquivo contém o cache das tabelas de taxas de câmbio, uma tabela por moeda base.
+// As tabelas são gravadas no armazenamento configurado (RateCache, em memória ou no Redis compartilhado entre as réplicas);
+// o status das consultas e as consultas em andamento são mantidos por instância.
+// A trava do cache nunca é mantida durante a consulta ao provedor nem durante a leitura ou a gravação no armazenamento,
+// que no Redis são chamadas de rede; ela protege apenas as consultas em andamento e o status.
+// Enquanto a tabela de uma moeda base está sendo consultada, as demais chamadas para a mesma base
+// aguardam essa consulta (inflight) em vez de consultar o provedor novamente.
+
+// As tabelas seguem a semântica stale-while-revalidate:
+// - Até o TTL (1h por padrão), a tabela é usada sem consultar o provedor.
+// - Após o TTL e até a idade máxima (24h por padrão), a tabela é usada marcada como desatualizada (stale),
+//   enquanto uma nova consulta é feita em segundo plano. Se o provedor estiver falhando, a tabela continua sendo usada.
+// - Após a idade máxima, a tabela é descartada e a chamada aguarda a consulta ao provedor.
+// Cada tabela obtida também é gravada no histórico diário de taxas (rate_history.go).
+
+// As taxas entre duas moedas são calculadas por taxas cruzadas (taxa = destino/origem) a partir de qualquer tabela em cache
+// que contenha as duas moedas: as tabelas da moeda base e da moeda de origem e as demais obtidas por esta instância.
+// Quando nenhuma contém, é consultada a tabela da moeda base configurada (USD por padrão),
+// de modo que uma única consulta ao provedor atenda às conversões entre quaisquer moedas da tabela.
+
+// O arquivo inclui:
+// 1. SetRateCacheTTL: Define o TTL e a idade máxima das tabelas em cache.
+// 2. SetRateBaseCurrency: Define a moeda base da tabela usada para calcular as taxas cruzadas.
+// 3. RateStatuses: Retorna, por moeda base, a última atualização e o último erro das consultas aos provedores.
+// 4. LatestRates: Retorna a tabela de taxas de uma moeda base, do cache ou consultada ao provedor.
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// exchangeRateCache mantém a última tabela de taxas de cada moeda base no armazenamento e o status das consultas.
+// writes ordena as gravações no armazenamento em relação ao descarte do cache (reset), sem bloquear as leituras.
+type exchangeRateCache struct {
+	mu          sync.RWMutex
+	writes      sync.RWMutex
+	ttl         time.Duration
+	maxStaleAge time.Duration
+	store       RateCache
+	inflight    map[string]*rateFetch
+	status      map[string]models.RateStatus
+}
+
+// rateFetch é uma consulta ao provedor em andamento; done é fechado quando table e err estão disponíveis.
+type rateFetch struct {
+	done  chan struct{}
+	table models.RateTable
+	err   error
+}
+
+var cache = exchangeRateCache{
+	ttl:         time.Hour,
+	maxStaleAge: 24 * time.Hour,
+	store:       NewMemoryRateCache(),
+	inflight:    make(map[string]*rateFetch),
+	status:      make(map[string]models.RateStatus),
+}
+
+// SetRateCacheTTL define por quanto tempo uma tabela é usada sem consultar o provedor (ttl)
+// e até que idade ela ainda pode ser usada, marcada como desatualizada, enquanto é atualizada (maxStaleAge).
+func SetRateCacheTTL(ttl, maxStaleAge time.Duration) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.ttl = ttl
+	cache.maxStaleAge = maxStaleAge
+}
+
+var (
+	rateBaseCurrency     = "USD"
+	rateBaseCurrencyLock sync.RWMutex
+)
+
+// SetRateBaseCurrency define a moeda base da tabela consultada para calcular as taxas cruzadas.
+func SetRateBaseCurrency(base string) {
+	rateBaseCurrencyLock.Lock()
+	defer rateBaseCurrencyLock.Unlock()
+
+	rateBaseCurrency = base
+}
+
+// baseCurrency retorna a moeda base em uso.
+func baseCurrency() string {
+	rateBaseCurrencyLock.RLock()
+	defer rateBaseCurrencyLock.RUnlock()
+
+	return rateBaseCurrency
+}
+
+// crossRate calcula a taxa entre duas moedas presentes na tabela.
+func crossRate(table models.RateTable, from, to string) (float64, bool) {
+	fromRate, ok := table.Rate(from)
+	if !ok {
+		return 0, false
+	}
+	toRate, ok := table.Rate(to)
+	if !ok {
+		return 0, false
+	}
+	if from == table.Base {
+		return toRate, true
+	}
+	return toRate / fromRate, true
+}
+
+// rate retorna a taxa entre duas moedas e se ela foi calculada a partir de uma tabela desatualizada.
+// A tabela da moeda base é consultada quando nenhuma tabela em cache contém as duas moedas; se ela também
+// não contiver, é consultada a tabela da própria moeda de origem.
+func (c *exchangeRateCache) rate(from, to, base string, provider RateProvider) (float64, bool, error) {
+	if rate, ok := c.cachedRate(from, to, base); ok {
+		return rate, false, nil
+	}
+
+	table, stale, err := c.lookup(base, provider)
+	if err != nil {
+		return 0, false, err
+	}
+	if rate, ok := crossRate(table, from, to); ok {
+		return rate, stale, nil
+	}
+	if from == base {
+		return 0, false, fmt.Errorf("currency not found")
+	}
+
+	if table, stale, err = c.lookup(from, provider); err != nil {
+		return 0, false, err
+	}
+	rate, ok := table.Rate(to)
+	if !ok {
+		return 0, false, fmt.Errorf("currency not found")
+	}
+	return rate, stale, nil
+}
+
+// cachedRate procura, nas tabelas em cache dentro do TTL, uma que contenha as duas moedas, começando pela tabela
+// da moeda base e pela tabela da moeda de origem. As demais tabelas são lidas apenas para as moedas base já obtidas
+// por esta instância, sem listar todo o armazenamento.
+func (c *exchangeRateCache) cachedRate(from, to, base string) (float64, bool) {
+	ttl, _ := c.settings()
+	fresh := func(cached CachedRateTable) (float64, bool) {
+		if time.Since(cached.FetchedAt) >= ttl {
+			return 0, false
+		}
+		return crossRate(cached.Table, from, to)
+	}
+
+	for _, currency := range []string{base, from} {
+		if cached, ok := c.cached(currency); ok {
+			if rate, ok := fresh(cached); ok {
+				return rate, true
+			}
+		}
+	}
+
+	for _, currency := range c.refreshedBases() {
+		if currency == base || currency == from {
+			continue
+		}
+		if cached, ok := c.cached(currency); ok {
+			if rate, ok := fresh(cached); ok {
+				return rate, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// refreshedBases retorna as moedas base cujas tabelas foram obtidas por esta instância, em ordem alfabética.
+func (c *exchangeRateCache) refreshedBases() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	bases := make([]string, 0, len(c.status))
+	for base, status := range c.status {
+		if status.LastRefresh != nil {
+			bases = append(bases, base)
+		}
+	}
+	sort.Strings(bases)
+	return bases
+}
+
+// isStale informa se a taxa entre as duas moedas seria calculada a partir de uma tabela desatualizada.
+func (c *exchangeRateCache) isStale(from, to, base string) bool {
+	if _, ok := c.cachedRate(from, to, base); ok {
+		return false
+	}
+
+	ttl, _ := c.settings()
+	for _, currency := range []string{base, from} {
+		if cached, ok := c.cached(currency); ok {
+			if _, ok := crossRate(cached.Table, from, to); ok {
+				return time.Since(cached.FetchedAt) >= ttl
+			}
+		}
+	}
+	return false
+}
+
+// settings retorna o TTL e a idade máxima das tabelas.
+func (c *exchangeRateCache) settings() (time.Duration, time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.ttl, c.maxStaleAge
+}
+
+// rateStore retorna o armazenamento das tabelas em uso.
+func (c *exchangeRateCache) rateStore() RateCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.store
+}
+
+// cached retorna a tabela da moeda base no armazenamento. Erros do armazenamento (e.g. Redis fora do ar) são
+// registrados em log e tratados como ausência da tabela, de modo que as taxas sejam consultadas no provedor.
+func (c *exchangeRateCache) cached(base string) (CachedRateTable, bool) {
+	return cachedIn(c.rateStore(), base)
+}
+
+// cachedIn retorna a tabela da moeda base no armazenamento informado, registrando os erros em log.
+func cachedIn(store RateCache, base string) (CachedRateTable, bool) {
+	cached, ok, err := store.Get(base)
+	if err != nil {
+		log.Printf("Could not read cached %s exchange rates: %s\n", base, err.Error())
+		return CachedRateTable{}, false
+	}
+	return cached, ok
+}
+
+// RateStatuses retorna o status das tabelas de taxas de cada moeda base consultada, ordenado pela moeda base.
+func RateStatuses() []models.RateStatus {
+	cache.mu.RLock()
+	statuses := make([]models.RateStatus, 0, len(cache.status))
+	for _, status := range cache.status {
+		statuses = append(statuses, status)
+	}
+	cache.mu.RUnlock()
+
+	ttl, _ := cache.settings()
+	for i, status := range statuses {
+		if cached, ok := cache.cached(status.Base); ok {
+			statuses[i].Stale = time.Since(cached.FetchedAt) >= ttl
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Base < statuses[j].Base })
+	return statuses
+}
+
+// LatestRates retorna a tabela de taxas em cache da moeda base, com o provedor e o momento em que foi obtida.
+// Sem moeda base, é usada a moeda base configurada. Se a tabela não estiver em cache, o provedor é consultado.
+func LatestRates(base string) (models.RatesResponse, error) {
+	if base == "" {
+		base = baseCurrency()
+	}
+
+	table, stale, err := cache.lookup(base, getRateProvider())
+	if err != nil {
+		return models.RatesResponse{}, fmt.Errorf("%w: %s: %v", ErrExchangeRateUnavailable, base, err)
+	}
+
+	rates := make(map[string]float64, len(table.Rates))
+	for currency, rate := range table.Rates {
+		rates[currency] = rate
+	}
+	return models.RatesResponse{
+		Base:      base,
+		Provider:  table.Provider,
+		RateDate:  table.Date.Format("2006-01-02"),
+		FetchedAt: cache.fetchedAt(base),
+		Stale:     stale,
+		Rates:     rates,
+	}, nil
+}
+
+// fetchedAt retorna quando a tabela em cache da moeda base foi obtida.
+func (c *exchangeRateCache) fetchedAt(base string) time.Time {
+	cached, _ := c.cached(base)
+	return cached.FetchedAt
+}
+
+// lookup retorna a tabela da moeda base e se ela está desatualizada.
+// Tabelas desatualizadas, mas dentro da idade máxima, são retornadas imediatamente e atualizadas em segundo plano.
+func (c *exchangeRateCache) lookup(base string, provider RateProvider) (models.RateTable, bool, error) {
+	cached, ok := c.cached(base)
+	ttl, maxStaleAge := c.settings()
+
+	if ok {
+		age := time.Since(cached.FetchedAt)
+		if age < ttl {
+			return cached.Table, false, nil
+		}
+		if age < maxStaleAge {
+			go c.load(base, provider, false)
+			return cached.Table, true, nil
+		}
+	}
+
+	table, err := c.load(base, provider, false)
+	return table, false, err
+}
+
+// load consulta a tabela da moeda base no provedor, compartilhando a consulta em andamento, se houver.
+// Sem force, uma tabela obtida há menos do TTL (inclusive por outra réplica, no Redis) é retornada sem consultar o provedor.
+func (c *exchangeRateCache) load(base string, provider RateProvider, force bool) (models.RateTable, error) {
+	c.mu.Lock()
+	if fetch, ok := c.inflight[base]; ok {
+		c.mu.Unlock()
+		<-fetch.done
+		return fetch.table, fetch.err
+	}
+	fetch := &rateFetch{done: make(chan struct{})}
+	c.inflight[base] = fetch
+	store, ttl, maxStaleAge := c.store, c.ttl, c.maxStaleAge
+	c.mu.Unlock()
+
+	// Outra chamada ou réplica pode ter atualizado o cache antes desta consulta ser registrada
+	if cached, ok := cachedIn(store, base); ok && !force && time.Since(cached.FetchedAt) < ttl {
+		fetch.table = cached.Table
+		c.finish(base, fetch)
+		return fetch.table, nil
+	}
+
+	fetch.table, fetch.err = provider.Rates(base)
+	now := time.Now()
+	if fetch.err != nil {
+		log.Printf("Could not refresh %s exchange rates: %s\n", base, fetch.err.Error())
+	}
+
+	// O cache pode ter sido descartado (reset) durante a consulta; nesse caso o resultado não é armazenado.
+	// A gravação é feita fora da trava do cache: writes apenas impede que o descarte ocorra durante ela.
+	c.writes.RLock()
+	c.mu.RLock()
+	current := c.inflight[base] == fetch
+	c.mu.RUnlock()
+	stored := current && fetch.err == nil
+	if stored {
+		// A tabela expira no armazenamento quando deixa de poder ser usada (idade máxima)
+		expiration := maxStaleAge
+		if expiration < ttl {
+			expiration = ttl
+		}
+		if err := store.Set(base, CachedRateTable{Table: fetch.table, FetchedAt: now}, expiration); err != nil {
+			log.Printf("Could not cache %s exchange rates: %s\n", base, err.Error())
+		}
+	}
+	c.writes.RUnlock()
+
+	c.mu.Lock()
+	if current && c.inflight[base] == fetch {
+		status := c.status[base]
+		status.Base = base
+		status.LastAttempt = &now
+		if fetch.err == nil {
+			status.LastRefresh = &now
+			status.LastError = ""
+			status.Provider = fetch.table.Provider
+			status.RateDate = fetch.table.Date.Format("2006-01-02")
+		} else {
+			status.LastError = fetch.err.Error()
+		}
+		c.status[base] = status
+	}
+	c.mu.Unlock()
+	c.finish(base, fetch)
+
+	if stored {
+		recordRateHistory(fetch.table)
+	}
+
+	return fetch.table, fetch.err
+}
+
+// finish remove a consulta das consultas em andamento e libera as chamadas que a aguardam.
+func (c *exchangeRateCache) finish(base string, fetch *rateFetch) {
+	c.mu.Lock()
+	if c.inflight[base] == fetch {
+		delete(c.inflight, base)
+	}
+	c.mu.Unlock()
+	close(fetch.done)
+}
+
+// reset descarta o estado da instância: as consultas em andamento, o status das consultas e as tabelas
+// do armazenamento em memória. Consultas em andamento terminam, mas seus resultados não são armazenados.
+// O armazenamento compartilhado (Redis) não é limpo, pois é usado pelas demais réplicas; as suas tabelas expiram normalmente.
+func (c *exchangeRateCache) reset() {
+	c.writes.Lock()
+	defer c.writes.Unlock()
+
+	c.mu.Lock()
+	store := c.store
+	c.inflight = make(map[string]*rateFetch)
+	c.status = make(map[string]models.RateStatus)
+	c.mu.Unlock()
+
+	if local, ok := store.(*MemoryRateCache); ok {
+		local.Clear()
+	}
+}
@@ -0,0 +1,96 @@
+// rate_cache_redis.go
+// Este arquivo implementa o armazenamento das tabelas de taxas em cache no Redis (ou em qualquer servidor compatível
+// com o protocolo Redis), compartilhado entre as réplicas da aplicação.
+// Cada tabela é gravada em JSON na chave "rates:<moeda base>", que expira junto com a tabela (idade máxima do cache).
+
+package services
+
+import (
+	"context"
+	"desafiogolang-payment/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisRateKeyPrefix é o prefixo das chaves das tabelas de taxas no Redis.
+const redisRateKeyPrefix = "rates:"
+
+// RedisRateCache armazena as tabelas de taxas no Redis.
+type RedisRateCache struct {
+	Client *redis.Client
+}
+
+// redisRateTable representa uma tabela de taxas gravada no Redis.
+type redisRateTable struct {
+	Base      string             `json:"base"`
+	Rates     map[string]float64 `json:"rates"`
+	Date      time.Time          `json:"date"`
+	Provider  string             `json:"provider"`
+	FetchedAt time.Time          `json:"fetched_at"`
+}
+
+// NewRedisRateCache conecta ao Redis pela URL informada (e.g. "redis://:senha@localhost:6379/0").
+func NewRedisRateCache(url string) (*RedisRateCache, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+
+	client := redis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("could not connect to redis: %w", err)
+	}
+	return &RedisRateCache{Client: client}, nil
+}
+
+func (c *RedisRateCache) Get(base string) (CachedRateTable, bool, error) {
+	data, err := c.Client.Get(context.Background(), redisRateKeyPrefix+base).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return CachedRateTable{}, false, nil
+	}
+	if err != nil {
+		return CachedRateTable{}, false, err
+	}
+
+	table, err := decodeRedisRateTable(data)
+	if err != nil {
+		return CachedRateTable{}, false, fmt.Errorf("invalid cached %s rates: %w", base, err)
+	}
+	return table, true, nil
+}
+
+func (c *RedisRateCache) Set(base string, table CachedRateTable, expiration time.Duration) error {
+	data, err := json.Marshal(redisRateTable{
+		Base:      table.Table.Base,
+		Rates:     table.Table.Rates,
+		Date:      table.Table.Date,
+		Provider:  table.Table.Provider,
+		FetchedAt: table.FetchedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return c.Client.Set(context.Background(), redisRateKeyPrefix+base, data, expiration).Err()
+}
+
+// Close encerra a conexão com o Redis.
+func (c *RedisRateCache) Close() error {
+	return c.Client.Close()
+}
+
+// decodeRedisRateTable lê uma tabela de taxas gravada no Redis.
+func decodeRedisRateTable(data []byte) (CachedRateTable, error) {
+	var stored redisRateTable
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return CachedRateTable{}, err
+	}
+	return CachedRateTable{
+		Table:     models.RateTable{Base: stored.Base, Rates: stored.Rates, Date: stored.Date, Provider: stored.Provider},
+		FetchedAt: stored.FetchedAt,
+	}, nil
+}
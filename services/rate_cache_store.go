@@ -0,0 +1,103 @@
+// rate_cache_store.go
+// Este arquivo define o armazenamento das tabelas de taxas em cache (RateCache), separado das regras do cache
+// (TTL, stale-while-revalidate e consultas em andamento), que ficam em rate_cache.go.
+// Existem duas implementações: em memória, de cada instância da aplicação, e Redis (rate_cache_redis.go),
+// compartilhada entre as réplicas, de modo que uma tabela obtida por uma réplica seja usada pelas demais.
+// A implementação utilizada é escolhida pela configuração RATE_CACHE_DRIVER.
+
+// O arquivo inclui:
+// 1. RateCache: Interface do armazenamento das tabelas em cache.
+// 2. NewRateCache: Cria o armazenamento de acordo com a configuração.
+// 3. MemoryRateCache: Armazenamento em memória.
+// 4. SetRateCache: Define o armazenamento usado pelo cache de taxas.
+
+package services
+
+import (
+	"desafiogolang-payment/config"
+	"desafiogolang-payment/models"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedRateTable é uma tabela de taxas e o momento em que foi obtida do provedor.
+type CachedRateTable struct {
+	Table     models.RateTable
+	FetchedAt time.Time
+}
+
+// RateCache define as operações de armazenamento das tabelas de taxas em cache, uma por moeda base.
+type RateCache interface {
+	// Get retorna a tabela da moeda base; ok é false se ela não estiver em cache ou tiver expirado.
+	Get(base string) (table CachedRateTable, ok bool, err error)
+	// Set armazena a tabela da moeda base, que expira após o tempo informado.
+	Set(base string, table CachedRateTable, expiration time.Duration) error
+}
+
+// NewRateCache cria o armazenamento das tabelas de taxas de acordo com o driver configurado ("memory" ou "redis").
+func NewRateCache(cfg config.Config) (RateCache, error) {
+	switch strings.ToLower(cfg.RateCacheDriver) {
+	case "", "memory":
+		return NewMemoryRateCache(), nil
+	case "redis":
+		return NewRedisRateCache(cfg.RedisURL)
+	default:
+		return nil, fmt.Errorf("unsupported rate cache driver %q", cfg.RateCacheDriver)
+	}
+}
+
+// SetRateCache define o armazenamento das tabelas de taxas e descarta o status das consultas anteriores.
+func SetRateCache(store RateCache) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.store = store
+	cache.inflight = make(map[string]*rateFetch)
+	cache.status = make(map[string]models.RateStatus)
+}
+
+// MemoryRateCache armazena as tabelas de taxas em memória.
+type MemoryRateCache struct {
+	mu     sync.RWMutex
+	tables map[string]memoryRateEntry
+}
+
+// memoryRateEntry é uma tabela em memória e o momento em que expira.
+type memoryRateEntry struct {
+	table     CachedRateTable
+	expiresAt time.Time
+}
+
+// NewMemoryRateCache cria um armazenamento de tabelas de taxas em memória.
+func NewMemoryRateCache() *MemoryRateCache {
+	return &MemoryRateCache{tables: make(map[string]memoryRateEntry)}
+}
+
+func (c *MemoryRateCache) Get(base string) (CachedRateTable, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.tables[base]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return CachedRateTable{}, false, nil
+	}
+	return entry.table, true, nil
+}
+
+func (c *MemoryRateCache) Set(base string, table CachedRateTable, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tables[base] = memoryRateEntry{table: table, expiresAt: time.Now().Add(expiration)}
+	return nil
+}
+
+// Clear descarta todas as tabelas. É usado quando o provedor de taxas é substituído (veja exchangeRateCache.reset).
+func (c *MemoryRateCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tables = make(map[string]memoryRateEntry)
+}
@@ -0,0 +1,87 @@
+// rate_history.go
+// Este arquivo contém o histórico diário de taxas de câmbio e a consulta de taxas em uma data passada.
+// Cada tabela obtida dos provedores (pelo atualizador em segundo plano ou sob demanda) é gravada como a tabela do seu dia
+// de referência. A consulta de uma data usa a tabela do dia ou, se não houver (fins de semana, feriados),
+// a do dia útil anterior mais próximo, até maxRateHistoryLookback dias antes.
+
+// O arquivo inclui:
+// 1. SetRateHistoryRepository: Define o repositório do histórico de taxas.
+// 2. HistoricalExchangeRate: Retorna a taxa entre duas moedas em uma data passada e a data efetiva da taxa.
+// 3. recordRateHistory: Grava no histórico uma tabela obtida dos provedores.
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxRateHistoryLookback é até quantos dias antes da data solicitada uma tabela do histórico é aceita.
+const maxRateHistoryLookback = 7 * 24 * time.Hour
+
+// Erros da consulta de taxas em uma data passada.
+var (
+	ErrRateHistoryNotFound = fmt.Errorf("exchange rate history %w", repository.ErrNotFound)
+	ErrInvalidRateDate     = errors.New("invalid rate date")
+)
+
+var (
+	rateHistoryRepository     repository.RateHistoryRepository = repository.NewMemoryRateHistoryRepository()
+	rateHistoryRepositoryLock sync.RWMutex
+)
+
+// SetRateHistoryRepository define o repositório do histórico de taxas utilizado pelos serviços.
+func SetRateHistoryRepository(repo repository.RateHistoryRepository) {
+	rateHistoryRepositoryLock.Lock()
+	defer rateHistoryRepositoryLock.Unlock()
+
+	rateHistoryRepository = repo
+}
+
+// rateHistory retorna o repositório do histórico de taxas em uso.
+func rateHistory() repository.RateHistoryRepository {
+	rateHistoryRepositoryLock.RLock()
+	defer rateHistoryRepositoryLock.RUnlock()
+
+	return rateHistoryRepository
+}
+
+// recordRateHistory grava a tabela no histórico. Tabelas sem data de referência são gravadas no dia atual.
+// Erros são apenas registrados em log, pois não impedem o uso da tabela.
+func recordRateHistory(table models.RateTable) {
+	if table.Date.IsZero() {
+		table.Date = time.Now()
+	}
+	if err := rateHistory().Save(table); err != nil {
+		log.Printf("Could not record %s exchange rate history: %s\n", table.Base, err.Error())
+	}
+}
+
+// HistoricalExchangeRate retorna a taxa entre duas moedas vigente na data informada e a data efetiva da taxa,
+// que pode ser anterior à data informada quando não há taxas registradas nesse dia.
+func HistoricalExchangeRate(fromCurrency, toCurrency string, date time.Time) (float64, time.Time, error) {
+	date = repository.RateDay(date)
+	if date.After(time.Now().UTC()) {
+		return 0, time.Time{}, fmt.Errorf("%w: %s is in the future", ErrInvalidRateDate, date.Format("2006-01-02"))
+	}
+
+	// A tabela da moeda base atende a qualquer par; a tabela da moeda de origem é usada se a base não contiver as duas moedas
+	for _, base := range []string{baseCurrency(), fromCurrency} {
+		table, err := rateHistory().Latest(base, date)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && date.Sub(table.Date) > maxRateHistoryLookback) {
+			continue
+		}
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		if rate, ok := crossRate(table, fromCurrency, toCurrency); ok {
+			return rate, table.Date, nil
+		}
+	}
+	return 0, time.Time{}, fmt.Errorf("%w: %s to %s on %s", ErrRateHistoryNotFound, fromCurrency, toCurrency, date.Format("2006-01-02"))
+}
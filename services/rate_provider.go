@@ -0,0 +1,146 @@
+// rate_provider.go
+// Este arquivo define a interface comum dos provedores de taxas de câmbio e o encadeamento com failover.
+// Os provedores configurados em RATE_PROVIDERS são consultados em ordem: quando um provedor falha
+// (erro de rede, timeout, status HTTP inesperado ou resposta malformada), o próximo é consultado.
+// Cada provedor HTTP tem seu próprio timeout, de modo que um provedor lento não consome o tempo dos demais.
+
+// O arquivo inclui:
+// 1. RateProvider: Interface implementada pelos provedores (open.er-api, BCE e arquivo estático).
+// 2. FailoverRateProvider: Provedor que consulta uma lista de provedores em ordem até que um deles responda.
+// 3. NewRateProvider: Monta o encadeamento de provedores a partir da configuração.
+// 4. SetRateProvider: Substitui o provedor utilizado por GetExchangeRate (e.g. por um servidor local nos testes).
+
+package services
+
+import (
+	"desafiogolang-payment/config"
+	"desafiogolang-payment/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	SetRateProvider(NewRateProvider(config.Load()))
+}
+
+// defaultRateProviderTimeout é o timeout usado pelos provedores HTTP quando nenhum timeout positivo é configurado,
+// de modo que uma consulta nunca aguarda indefinidamente por um provedor que não responde.
+const defaultRateProviderTimeout = 5 * time.Second
+
+// rateProviderTimeout retorna o timeout informado ou defaultRateProviderTimeout, se ele não for positivo.
+func rateProviderTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultRateProviderTimeout
+	}
+	return timeout
+}
+
+// ErrRatesUnavailable é retornado quando nenhum provedor conseguiu fornecer as taxas de câmbio.
+var ErrRatesUnavailable = errors.New("exchange rates unavailable")
+
+// RateProvider é a interface implementada pelos provedores de taxas de câmbio.
+type RateProvider interface {
+	// Name retorna o nome do provedor, usado em logs e em RateTable.Provider.
+	Name() string
+	// Rates retorna a tabela de taxas da moeda base informada.
+	Rates(base string) (models.RateTable, error)
+}
+
+// RateProviderError identifica o provedor que originou um erro.
+type RateProviderError struct {
+	Provider string
+	Err      error
+}
+
+func (e *RateProviderError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+}
+
+func (e *RateProviderError) Unwrap() error {
+	return e.Err
+}
+
+// FailoverRateProvider consulta os provedores em ordem e retorna a primeira tabela válida.
+type FailoverRateProvider struct {
+	Providers []RateProvider
+}
+
+// NewFailoverRateProvider cria um encadeamento com os provedores informados, na ordem de preferência.
+func NewFailoverRateProvider(providers ...RateProvider) *FailoverRateProvider {
+	return &FailoverRateProvider{Providers: providers}
+}
+
+func (p *FailoverRateProvider) Name() string {
+	names := make([]string, len(p.Providers))
+	for i, provider := range p.Providers {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Rates consulta cada provedor em ordem. Se todos falharem, os erros de cada um são retornados juntos.
+func (p *FailoverRateProvider) Rates(base string) (models.RateTable, error) {
+	errs := []error{ErrRatesUnavailable}
+	for _, provider := range p.Providers {
+		table, err := provider.Rates(base)
+		if err == nil {
+			err = table.Validate()
+		}
+		if err == nil {
+			return table, nil
+		}
+		log.Printf("Rate provider %s failed for %s: %v\n", provider.Name(), base, err)
+		errs = append(errs, &RateProviderError{Provider: provider.Name(), Err: err})
+	}
+	return models.RateTable{}, errors.Join(errs...)
+}
+
+// NewRateProvider monta o encadeamento dos provedores listados em cfg.RateProviders ("erapi", "ecb" e "file").
+// Nomes desconhecidos e o provedor "file" sem RATES_FILE definido são ignorados.
+func NewRateProvider(cfg config.Config) *FailoverRateProvider {
+	chain := NewFailoverRateProvider()
+	for _, name := range cfg.RateProviders {
+		switch strings.ToLower(name) {
+		case "erapi":
+			chain.Providers = append(chain.Providers, NewERAPIRateProvider(cfg.ERAPIBaseURL, cfg.ERAPITimeout))
+		case "ecb":
+			chain.Providers = append(chain.Providers, NewECBRateProvider(cfg.ECBRatesURL, cfg.ECBTimeout))
+		case "file":
+			if cfg.RatesFile == "" {
+				log.Println("Rate provider file ignored: RATES_FILE is not set")
+				continue
+			}
+			chain.Providers = append(chain.Providers, NewFileRateProvider(cfg.RatesFile))
+		default:
+			log.Printf("Unknown rate provider %q ignored\n", name)
+		}
+	}
+	return chain
+}
+
+var (
+	rateProvider     RateProvider
+	rateProviderLock sync.RWMutex
+)
+
+// SetRateProvider define o provedor de taxas de câmbio e descarta as taxas em cache do provedor anterior nesta instância
+// (veja exchangeRateCache.reset).
+func SetRateProvider(provider RateProvider) {
+	rateProviderLock.Lock()
+	rateProvider = provider
+	rateProviderLock.Unlock()
+
+	cache.reset()
+}
+
+// getRateProvider retorna o provedor de taxas de câmbio em uso.
+func getRateProvider() RateProvider {
+	rateProviderLock.RLock()
+	defer rateProviderLock.RUnlock()
+
+	return rateProvider
+}
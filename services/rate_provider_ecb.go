@@ -0,0 +1,85 @@
+// rate_provider_ecb.go
+// Este arquivo implementa o provedor de taxas de câmbio de referência diárias do Banco Central Europeu (BCE).
+// O BCE publica apenas taxas com base EUR; as demais bases são calculadas por taxas cruzadas (models.RateTable.Rebase).
+// https://www.ecb.europa.eu/stats/policy_and_exchange_rates/euro_reference_exchange_rates/html/index.en.html
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ECBRateProvider consulta o arquivo XML diário de taxas de referência do BCE (ou um servidor que o simule).
+type ECBRateProvider struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewECBRateProvider cria um provedor que consulta a URL do XML diário com o timeout informado (5s, se não for positivo).
+func NewECBRateProvider(url string, timeout time.Duration) *ECBRateProvider {
+	return &ECBRateProvider{
+		URL:    url,
+		Client: &http.Client{Timeout: rateProviderTimeout(timeout)},
+	}
+}
+
+// ecbEnvelope representa os campos utilizados de eurofxref-daily.xml.
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+func (p *ECBRateProvider) Name() string {
+	return "ecb"
+}
+
+// Rates consulta as taxas com base EUR e as converte para a moeda base informada.
+func (p *ECBRateProvider) Rates(base string) (models.RateTable, error) {
+	resp, err := p.Client.Get(p.URL)
+	if err != nil {
+		return models.RateTable{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return models.RateTable{}, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return models.RateTable{}, fmt.Errorf("invalid response: %w", err)
+	}
+	if len(envelope.Days) == 0 {
+		return models.RateTable{}, fmt.Errorf("no reference rates in response")
+	}
+
+	// O arquivo diário contém um único dia; o histórico contém vários, do mais recente ao mais antigo
+	day := envelope.Days[0]
+	date, err := time.Parse("2006-01-02", day.Time)
+	if err != nil {
+		return models.RateTable{}, fmt.Errorf("invalid reference date %q", day.Time)
+	}
+
+	table := models.RateTable{Base: "EUR", Rates: map[string]float64{}, Date: date, Provider: p.Name()}
+	for _, rate := range day.Rates {
+		value, err := strconv.ParseFloat(rate.Rate, 64)
+		if err != nil {
+			return models.RateTable{}, fmt.Errorf("invalid rate %q for %s", rate.Rate, rate.Currency)
+		}
+		table.Rates[rate.Currency] = value
+	}
+	if err := table.Validate(); err != nil {
+		return models.RateTable{}, err
+	}
+	return table.Rebase(base)
+}
@@ -0,0 +1,76 @@
+// rate_provider_erapi.go
+// Este arquivo implementa o provedor de taxas de câmbio da API aberta do ExchangeRate-API (open.er-api.com).
+// A resposta é lida em uma estrutura tipada: campos ausentes ou com tipos inesperados resultam em erro, nunca em pânico.
+// https://www.exchangerate-api.com/docs/free
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ERAPIRateProvider consulta as taxas de câmbio em open.er-api.com (ou em um servidor que a simule).
+type ERAPIRateProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewERAPIRateProvider cria um provedor que consulta a URL base informada com o timeout informado (5s, se não for positivo).
+func NewERAPIRateProvider(baseURL string, timeout time.Duration) *ERAPIRateProvider {
+	return &ERAPIRateProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: rateProviderTimeout(timeout)},
+	}
+}
+
+// erapiResponse representa os campos utilizados da resposta de /v6/latest/{base}.
+type erapiResponse struct {
+	Result             string             `json:"result"`
+	ErrorType          string             `json:"error-type"`
+	BaseCode           string             `json:"base_code"`
+	TimeLastUpdateUnix int64              `json:"time_last_update_unix"`
+	Rates              map[string]float64 `json:"rates"`
+}
+
+func (p *ERAPIRateProvider) Name() string {
+	return "erapi"
+}
+
+// Rates consulta a tabela de taxas da moeda base informada.
+func (p *ERAPIRateProvider) Rates(base string) (models.RateTable, error) {
+	resp, err := p.Client.Get(p.BaseURL + "/v6/latest/" + url.PathEscape(base))
+	if err != nil {
+		return models.RateTable{}, err
+	}
+	defer resp.Body.Close()
+
+	var result erapiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return models.RateTable{}, fmt.Errorf("invalid response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if result.Result != "success" {
+		if result.ErrorType == "" {
+			result.ErrorType = "unknown error"
+		}
+		return models.RateTable{}, fmt.Errorf("failed to get %s rates (HTTP %d): %s", base, resp.StatusCode, result.ErrorType)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return models.RateTable{}, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+	if result.BaseCode != base {
+		return models.RateTable{}, fmt.Errorf("requested %s rates but received %s", base, result.BaseCode)
+	}
+
+	return models.RateTable{
+		Base:     result.BaseCode,
+		Rates:    result.Rates,
+		Date:     time.Unix(result.TimeLastUpdateUnix, 0).UTC(),
+		Provider: p.Name(),
+	}, nil
+}
@@ -0,0 +1,121 @@
+// rate_provider_file.go
+// Este arquivo implementa o provedor de taxas de câmbio lidas de um arquivo estático, em JSON ou CSV.
+// É útil como último recurso do failover (taxas de contingência) ou em ambientes sem acesso à internet.
+// O formato é definido pela extensão do arquivo:
+//
+// JSON (.json): {"base": "USD", "date": "2024-01-02", "rates": {"EUR": 0.92, "BRL": 4.95}}
+//
+// CSV (.csv), com cabeçalho e uma taxa por linha, todas com a mesma moeda base:
+//
+//	base,currency,rate
+//	USD,EUR,0.92
+//	USD,BRL,4.95
+//
+// O arquivo é lido a cada consulta, portanto pode ser atualizado sem reiniciar a aplicação.
+// As demais bases são calculadas por taxas cruzadas (models.RateTable.Rebase).
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileRateProvider lê as taxas de câmbio de um arquivo JSON ou CSV.
+type FileRateProvider struct {
+	Path string
+}
+
+// NewFileRateProvider cria um provedor que lê as taxas do arquivo informado.
+func NewFileRateProvider(path string) *FileRateProvider {
+	return &FileRateProvider{Path: path}
+}
+
+// rateFile representa o conteúdo de um arquivo de taxas em JSON.
+type rateFile struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (p *FileRateProvider) Name() string {
+	return "file"
+}
+
+// Rates lê o arquivo e converte as taxas para a moeda base informada.
+func (p *FileRateProvider) Rates(base string) (models.RateTable, error) {
+	file, err := os.Open(p.Path)
+	if err != nil {
+		return models.RateTable{}, err
+	}
+	defer file.Close()
+
+	var table models.RateTable
+	switch strings.ToLower(filepath.Ext(p.Path)) {
+	case ".json":
+		var content rateFile
+		if err := json.NewDecoder(file).Decode(&content); err != nil {
+			return models.RateTable{}, fmt.Errorf("invalid rates file %s: %w", p.Path, err)
+		}
+		table = models.RateTable{Base: content.Base, Rates: content.Rates}
+		if content.Date != "" {
+			if table.Date, err = time.Parse("2006-01-02", content.Date); err != nil {
+				return models.RateTable{}, fmt.Errorf("invalid rates file %s: invalid date %q", p.Path, content.Date)
+			}
+		}
+	case ".csv":
+		if table, err = readRatesCSV(file); err != nil {
+			return models.RateTable{}, fmt.Errorf("invalid rates file %s: %w", p.Path, err)
+		}
+	default:
+		return models.RateTable{}, fmt.Errorf("unsupported rates file format: %s", p.Path)
+	}
+
+	if table.Date.IsZero() {
+		if info, err := file.Stat(); err == nil {
+			table.Date = info.ModTime().UTC()
+		}
+	}
+	table.Provider = p.Name()
+	if err := table.Validate(); err != nil {
+		return models.RateTable{}, err
+	}
+	return table.Rebase(base)
+}
+
+// readRatesCSV lê as linhas "base,currency,rate" após o cabeçalho.
+func readRatesCSV(file *os.File) (models.RateTable, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return models.RateTable{}, err
+	}
+	if len(records) == 0 || !strings.EqualFold(records[0][0], "base") {
+		return models.RateTable{}, fmt.Errorf("missing header base,currency,rate")
+	}
+
+	table := models.RateTable{Rates: map[string]float64{}}
+	for line, record := range records[1:] {
+		if table.Base == "" {
+			table.Base = record[0]
+		} else if record[0] != table.Base {
+			return models.RateTable{}, fmt.Errorf("line %d: base %s differs from %s", line+2, record[0], table.Base)
+		}
+		rate, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return models.RateTable{}, fmt.Errorf("line %d: invalid rate %q", line+2, record[2])
+		}
+		table.Rates[record[1]] = rate
+	}
+	return table, nil
+}
@@ -0,0 +1,88 @@
+// rate_refresher.go
+// Este arquivo contém o atualizador das taxas de câmbio em segundo plano.
+// As moedas base configuradas são consultadas na inicialização e a cada intervalo, antes que o TTL do cache expire,
+// de modo que as conversões não aguardem a resposta dos provedores. O resultado de cada consulta (horário e erro)
+// fica disponível em RateStatuses.
+
+// O arquivo inclui:
+// 1. RateRefresher: Atualizador em segundo plano, iniciado por StartRateRefresher e encerrado por Stop.
+// 2. RefreshRates: Consulta imediatamente a tabela de uma moeda base, mesmo que ela ainda esteja em cache.
+
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// RateRefresher atualiza periodicamente as tabelas de taxas das moedas base configuradas.
+type RateRefresher struct {
+	Bases    []string
+	Interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// StartRateRefresher consulta as tabelas das moedas base informadas e continua consultando-as a cada intervalo.
+// Se não houver moedas base ou o intervalo não for positivo, nenhum atualizador é iniciado e nil é retornado.
+func StartRateRefresher(bases []string, interval time.Duration) *RateRefresher {
+	if len(bases) == 0 || interval <= 0 {
+		return nil
+	}
+
+	r := &RateRefresher{
+		Bases:    bases,
+		Interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Stop encerra o atualizador e aguarda a consulta em andamento terminar. Pode ser chamado em um atualizador nil.
+func (r *RateRefresher) Stop() {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+}
+
+func (r *RateRefresher) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.refresh()
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh consulta as moedas base em paralelo; erros ficam registrados em RateStatuses.
+func (r *RateRefresher) refresh() {
+	var wg sync.WaitGroup
+	for _, base := range r.Bases {
+		wg.Add(1)
+		go func(base string) {
+			defer wg.Done()
+			RefreshRates(base)
+		}(base)
+	}
+	wg.Wait()
+}
+
+// RefreshRates consulta imediatamente a tabela de taxas da moeda base e atualiza o cache.
+// Em caso de erro, a tabela em cache é mantida e o erro é registrado em RateStatuses.
+func RefreshRates(base string) error {
+	_, err := cache.load(base, getRateProvider(), true)
+	return err
+}
@@ -0,0 +1,226 @@
+// refund.go
+// Este arquivo coordena os reembolsos totais e parciais das transações registradas.
+// O reembolso é encaminhado ao gateway que processou a transação e registrado com um ID próprio no repositório de reembolsos.
+
+// O valor reembolsado nunca pode ultrapassar o valor recebido: o valor capturado ou, nas transações em requires_review
+// que não foram capturadas (e.g. um Pix pago depois da expiração), o valor da transação (veja Transaction.ReceivedAmount).
+// Para que reembolsos simultâneos não ultrapassem esse limite, o valor é reservado na transação antes de acionar
+// o gateway e liberado caso o gateway falhe.
+
+// O arquivo inclui:
+// 1. SetRefundRepository: Define o repositório de reembolsos utilizado (em memória por padrão).
+// 2. RefundPayment: Reembolsa total ou parcialmente uma transação capturada.
+// 3. GetRefund / ListRefunds: Consulta os reembolsos registrados.
+
+package services
+
+import (
+	"crypto/rand"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrRefundAmountExceeded é retornado quando o reembolso ultrapassaria o valor capturado da transação.
+var ErrRefundAmountExceeded = errors.New("refund amount exceeds captured amount")
+
+// ErrRefundNotFound é retornado quando o reembolso não existe ou não pertence à transação informada.
+var ErrRefundNotFound = fmt.Errorf("refund %w", repository.ErrNotFound)
+
+var (
+	refundRepository     repository.RefundRepository = repository.NewMemoryRefundRepository()
+	refundRepositoryLock sync.RWMutex
+)
+
+// SetRefundRepository define o repositório de reembolsos utilizado pelos serviços.
+func SetRefundRepository(repo repository.RefundRepository) {
+	refundRepositoryLock.Lock()
+	defer refundRepositoryLock.Unlock()
+
+	refundRepository = repo
+}
+
+// refunds retorna o repositório de reembolsos em uso.
+func refunds() repository.RefundRepository {
+	refundRepositoryLock.RLock()
+	defer refundRepositoryLock.RUnlock()
+
+	return refundRepository
+}
+
+// RefundPayment reembolsa uma transação capturada. Um valor igual a zero reembolsa todo o saldo ainda não reembolsado.
+func RefundPayment(transactionID string, request models.RefundRequest) (models.RefundResponse, error) {
+	transaction, err := transactions().Get(transactionID)
+	if err != nil {
+		return models.RefundResponse{}, err
+	}
+	gateway, err := GetGateway(transaction.Gateway)
+	if err != nil {
+		return models.RefundResponse{}, err
+	}
+	capabilities := gateway.Capabilities()
+	if !capabilities.Refunds {
+		return models.RefundResponse{}, ErrOperationNotSupported
+	}
+
+	// O valor do reembolso está na moeda de liquidação da transação
+	amount, err := models.NewMoney(request.Amount, transaction.Currency)
+	if err != nil {
+		return models.RefundResponse{}, err
+	}
+
+	// Reserva o valor na transação, validando o status e o saldo disponível
+	_, err = transactions().Update(transactionID, func(transaction *models.Transaction) error {
+		if !models.CanTransition(transaction.Status, models.StatusRefunded) {
+			return &models.InvalidTransitionError{From: transaction.Status, To: models.StatusRefunded}
+		}
+
+		refunded, err := transaction.RefundedAmount.Money(transaction.Currency)
+		if err != nil {
+			return err
+		}
+		received, err := transaction.ReceivedAmount()
+		if err != nil {
+			return err
+		}
+		remaining := received.Sub(refunded)
+		if amount.IsZero() {
+			amount = remaining
+		}
+		if amount.Minor > remaining.Minor {
+			return ErrRefundAmountExceeded
+		}
+		if amount.Minor < remaining.Minor && !capabilities.PartialRefunds {
+			return ErrOperationNotSupported
+		}
+
+		transaction.RefundedAmount = refunded.Add(amount).Amount()
+		return nil
+	})
+	if err != nil {
+		return models.RefundResponse{}, err
+	}
+
+	response, err := gateway.RefundPayment(transactionID, amount)
+	if err != nil || response.Status == models.RefundStatusFailed {
+		releaseRefund(transactionID, amount)
+	}
+	if err != nil {
+		return models.RefundResponse{}, err
+	}
+
+	now := time.Now().UTC()
+	refund := models.Refund{
+		ID:              newRefundID(),
+		TransactionID:   transactionID,
+		Gateway:         gateway.Name(),
+		GatewayRefundID: response.GatewayRefundID,
+		Amount:          amount.Amount(),
+		Currency:        amount.Currency,
+		Status:          response.Status,
+		Reason:          request.Reason,
+		CreatedAt:       now,
+	}
+
+	// Reembolsos aceitos pelo gateway (concluídos ou pendentes) alteram o status da transação
+	if refund.Status != models.RefundStatusFailed {
+		transaction, err = transactions().Update(transactionID, func(transaction *models.Transaction) error {
+			received, err := transaction.ReceivedAmount()
+			if err != nil {
+				return err
+			}
+			status := models.StatusPartiallyRefunded
+			if transaction.RefundedAmount.Cmp(received.Amount()) >= 0 {
+				status = models.StatusRefunded
+			}
+			return transaction.Transition(status, "refund "+refund.ID, now)
+		})
+		if err != nil {
+			return models.RefundResponse{}, fmt.Errorf("update transaction %s after refund %s: %w", transactionID, refund.ID, err)
+		}
+	}
+
+	if err := refunds().Create(refund); err != nil {
+		return models.RefundResponse{}, fmt.Errorf("store refund %s: %w", refund.ID, err)
+	}
+
+	result := refundResponse(refund)
+	result.Message = response.Message
+	result.TransactionStatus = transaction.Status
+	return result, nil
+}
+
+// GetRefund retorna um reembolso registrado da transação informada.
+func GetRefund(transactionID, refundID string) (models.RefundResponse, error) {
+	refund, err := refunds().Get(refundID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && refund.TransactionID != transactionID) {
+		return models.RefundResponse{}, ErrRefundNotFound
+	}
+	if err != nil {
+		return models.RefundResponse{}, err
+	}
+
+	response := refundResponse(refund)
+	response.Message = fmt.Sprintf("Refund ID: %s found", refundID)
+	return response, nil
+}
+
+// ListRefunds retorna os reembolsos registrados da transação informada.
+func ListRefunds(transactionID string) ([]models.RefundResponse, error) {
+	if _, err := transactions().Get(transactionID); err != nil {
+		return nil, err
+	}
+
+	list, err := refunds().ListByTransaction(transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.RefundResponse, 0, len(list))
+	for _, refund := range list {
+		response := refundResponse(refund)
+		response.Message = fmt.Sprintf("Refund ID: %s found", refund.ID)
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// releaseRefund devolve ao saldo reembolsável um valor reservado cujo reembolso não foi concluído pelo gateway.
+func releaseRefund(transactionID string, amount models.Money) {
+	_, err := transactions().Update(transactionID, func(transaction *models.Transaction) error {
+		refunded, err := transaction.RefundedAmount.Money(amount.Currency)
+		if err != nil {
+			return err
+		}
+		transaction.RefundedAmount = refunded.Sub(amount).Amount()
+		return nil
+	})
+	if err != nil {
+		log.Printf("could not release refund reservation of %s for transaction %s: %v", amount, transactionID, err)
+	}
+}
+
+// refundResponse monta a resposta a partir de um reembolso registrado.
+func refundResponse(refund models.Refund) models.RefundResponse {
+	return models.RefundResponse{
+		RefundID:        refund.ID,
+		GatewayRefundID: refund.GatewayRefundID,
+		TransactionID:   refund.TransactionID,
+		Amount:          refund.Amount,
+		Currency:        refund.Currency,
+		Status:          refund.Status,
+		Reason:          refund.Reason,
+	}
+}
+
+// newRefundID gera um ID único para um reembolso.
+func newRefundID() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return "rf_" + hex.EncodeToString(buf)
+}
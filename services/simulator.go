@@ -0,0 +1,119 @@
+// simulator.go
+// Este módulo simula de maneira simplificada uma transação em um gateway de pagamento.
+// Ele gera um ID de transação e um status de pagamento de maneira aleatória.
+// O armazenamento da transação e a consulta do status são feitos pelo repositório de transações (payment.go).
+
+// O simulador era a implementação original do gateway PayPal. Com o adaptador real (paypal.go),
+// ele continua disponível, mas precisa ser selecionado explicitamente pelo gateway "simulator".
+
+package services
+
+import (
+	"desafiogolang-payment/models"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	rng     = rand.New(rand.NewSource(time.Now().UnixNano()))
+	rngLock sync.Mutex
+)
+
+func init() {
+	RegisterGateway(simulatorGateway{})
+}
+
+// simulatorGateway adapta as funções de simulação à interface PaymentGateway.
+type simulatorGateway struct{}
+
+func (simulatorGateway) Name() string {
+	return "simulator"
+}
+
+func (simulatorGateway) ProcessPayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	return ProcessSimulatorPayment(request), nil
+}
+
+// GetPaymentStatus não é suportado: o status simulado é definido na criação e mantido no repositório.
+func (simulatorGateway) GetPaymentStatus(transactionID string) (models.TransactionResponse, error) {
+	return models.TransactionResponse{}, ErrOperationNotSupported
+}
+
+func (simulatorGateway) RefundPayment(transactionID string, amount models.Money) (models.RefundResponse, error) {
+	return models.RefundResponse{
+		Message:         "Payment refunded with success",
+		GatewayRefundID: generateID("RF"),
+		TransactionID:   transactionID,
+		Amount:          amount.Amount(),
+		Currency:        amount.Currency,
+		Status:          models.RefundStatusSucceeded,
+	}, nil
+}
+
+func (simulatorGateway) Capabilities() GatewayCapabilities {
+	return GatewayCapabilities{
+		Refunds:              true,
+		PartialRefunds:       true,
+		Authorization:        true,
+		BillingAgreements:    true,
+		PaymentMethods:       []string{models.PaymentMethodCreditCard, models.PaymentMethodPayPal},
+		SettlementCurrencies: []string{"USD"},
+	}
+}
+
+// AuthorizePayment simula uma autorização, que é sempre aprovada.
+func (simulatorGateway) AuthorizePayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	return models.PaymentResponse{
+		Message:        "Payment authorized with success",
+		Transaction_ID: generateTransactionID(),
+		Status:         models.StatusAuthorized,
+	}, nil
+}
+
+// CapturePayment simula a captura de um pagamento autorizado.
+func (simulatorGateway) CapturePayment(transactionID string, amount models.Money) (models.TransactionResponse, error) {
+	return models.TransactionResponse{
+		Message: "Payment captured with success",
+		Status:  models.StatusCaptured,
+	}, nil
+}
+
+// VoidPayment simula o cancelamento de um pagamento autorizado.
+func (simulatorGateway) VoidPayment(transactionID string) (models.TransactionResponse, error) {
+	return models.TransactionResponse{
+		Message: "Payment voided with success",
+		Status:  models.StatusVoided,
+	}, nil
+}
+
+// generateTransactionID gera um ID de transação único
+func generateTransactionID() string {
+	return generateID("PAY")
+}
+
+// generateID gera um ID aleatório com o prefixo informado
+func generateID(prefix string) string {
+	rngLock.Lock()
+	defer rngLock.Unlock()
+
+	return fmt.Sprintf("%s-%d", prefix, rng.Intn(1000000000))
+}
+
+// ProcessSimulatorPayment simula o processamento de um pagamento
+func ProcessSimulatorPayment(request models.PaymentRequest) models.PaymentResponse {
+	transactionID := generateTransactionID()
+
+	// Simulando diferentes resultados com base em valores aleatórios
+	statuses := []string{models.StatusCaptured, models.StatusPending, models.StatusFailed}
+	rngLock.Lock()
+	status := statuses[rng.Intn(len(statuses))]
+	rngLock.Unlock()
+
+	return models.PaymentResponse{
+		Message:        "Payment processed with success",
+		Transaction_ID: transactionID,
+		Status:         status,
+	}
+}
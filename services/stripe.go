@@ -1,14 +1,319 @@
-// handlers.go
-// Este arquivo é uma ideia inicial para implementação de outro gateway de pagamento.
-// Mas a ideia seria a mesma do arquivo paypal.go. Deverá ser validado a API do Stripe e implementado aqui a comunicação com a mesma.
+// stripe.go
+// Este arquivo implementa o adaptador do gateway Stripe utilizando a API de PaymentIntents.
+// As requisições são enviadas no formato form-encoded, como a API do Stripe exige, para uma URL base configurável,
+// permitindo apontar para a API real ou para o servidor local que a simula (mocks/stripemock) durante os testes.
+// https://docs.stripe.com/api/payment_intents/create
+// https://docs.stripe.com/api/payment_intents/retrieve
+// https://docs.stripe.com/api/refunds/create
+
+// Os status do Stripe são traduzidos para o vocabulário da máquina de estados (models.Status*)
+// e os erros retornados pela API são traduzidos para models.GatewayError.
+// Autorizações usam capture_method=manual e são capturadas ou canceladas posteriormente.
+// https://docs.stripe.com/api/payment_intents/capture
+// https://docs.stripe.com/api/payment_intents/cancel
 
 package services
 
-import "desafiogolang-payment/models"
+import (
+	"desafiogolang-payment/config"
+	"desafiogolang-payment/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	cfg := config.Load()
+	gateway := NewStripeGateway(cfg.StripeBaseURL, cfg.StripeSecretKey)
+	gateway.SettlementCurrencies = cfg.StripeSettlementCurrencies
+	RegisterGateway(gateway)
+}
+
+// StripeGateway é o adaptador para a API de PaymentIntents do Stripe.
+type StripeGateway struct {
+	BaseURL   string
+	SecretKey string
+	Client    *http.Client
+
+	// SettlementCurrencies são as moedas em que a conta liquida pagamentos (padrão: USD).
+	SettlementCurrencies []string
+}
+
+// NewStripeGateway cria um adaptador do Stripe que se comunica com a URL base informada.
+func NewStripeGateway(baseURL, secretKey string) *StripeGateway {
+	return &StripeGateway{
+		BaseURL:   strings.TrimRight(baseURL, "/"),
+		SecretKey: secretKey,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+
+		SettlementCurrencies: []string{"USD"},
+	}
+}
+
+// stripePaymentIntent representa os campos utilizados de um PaymentIntent do Stripe.
+type stripePaymentIntent struct {
+	ID               string `json:"id"`
+	Amount           int64  `json:"amount"`
+	Currency         string `json:"currency"`
+	Status           string `json:"status"`
+	LastPaymentError *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"last_payment_error"`
+}
+
+// stripeRefund representa os campos utilizados de um Refund do Stripe.
+type stripeRefund struct {
+	ID            string `json:"id"`
+	Amount        int64  `json:"amount"`
+	PaymentIntent string `json:"payment_intent"`
+	Status        string `json:"status"`
+}
+
+// stripeErrorResponse representa o corpo de erro retornado pela API do Stripe.
+type stripeErrorResponse struct {
+	Error struct {
+		Type        string `json:"type"`
+		Code        string `json:"code"`
+		DeclineCode string `json:"decline_code"`
+		Message     string `json:"message"`
+	} `json:"error"`
+}
+
+func (g *StripeGateway) Name() string {
+	return "Stripe"
+}
+
+func (g *StripeGateway) Capabilities() GatewayCapabilities {
+	return GatewayCapabilities{
+		StatusLookup:         true,
+		Refunds:              true,
+		PartialRefunds:       true,
+		Authorization:        true,
+		PaymentMethods:       []string{models.PaymentMethodCreditCard},
+		SettlementCurrencies: g.SettlementCurrencies,
+	}
+}
+
+// ProcessPayment cria e confirma um PaymentIntent com os dados do cartão, capturando o valor imediatamente.
+func (g *StripeGateway) ProcessPayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	return g.createPaymentIntent(request, false)
+}
+
+// AuthorizePayment cria e confirma um PaymentIntent com captura manual, apenas reservando o valor.
+func (g *StripeGateway) AuthorizePayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	return g.createPaymentIntent(request, true)
+}
+
+// CapturePayment captura um PaymentIntent autorizado. Um valor igual a zero captura o valor total.
+func (g *StripeGateway) CapturePayment(transactionID string, amount models.Money) (models.TransactionResponse, error) {
+	form := url.Values{}
+	if !amount.IsZero() {
+		form.Set("amount_to_capture", strconv.FormatInt(amount.Minor, 10))
+	}
+
+	var intent stripePaymentIntent
+	if err := g.do(http.MethodPost, "/v1/payment_intents/"+url.PathEscape(transactionID)+"/capture", form, &intent); err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	return models.TransactionResponse{
+		Message: "Payment captured with success",
+		Status:  stripeStatus(intent),
+	}, nil
+}
+
+// VoidPayment cancela um PaymentIntent autorizado.
+func (g *StripeGateway) VoidPayment(transactionID string) (models.TransactionResponse, error) {
+	var intent stripePaymentIntent
+	if err := g.do(http.MethodPost, "/v1/payment_intents/"+url.PathEscape(transactionID)+"/cancel", url.Values{}, &intent); err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	return models.TransactionResponse{
+		Message: "Payment voided with success",
+		Status:  stripeStatus(intent),
+	}, nil
+}
+
+// createPaymentIntent cria e confirma um PaymentIntent. Com manualCapture o valor é apenas autorizado.
+func (g *StripeGateway) createPaymentIntent(request models.PaymentRequest, manualCapture bool) (models.PaymentResponse, error) {
+	expMonth, expYear, err := models.ParseCardExpiry(request.CardDetails.Expiry)
+	if err != nil {
+		return models.PaymentResponse{}, &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorInvalidRequest, Message: err.Error()}
+	}
+
+	amount, err := request.Amount.Money(request.Currency)
+	if err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(amount.Minor, 10))
+	form.Set("currency", strings.ToLower(request.Currency))
+	form.Set("confirm", "true")
+	if manualCapture {
+		form.Set("capture_method", "manual")
+	}
+	form.Set("payment_method_data[type]", "card")
+	form.Set("payment_method_data[card][number]", request.CardDetails.Number)
+	form.Set("payment_method_data[card][exp_month]", strconv.Itoa(expMonth))
+	form.Set("payment_method_data[card][exp_year]", strconv.Itoa(expYear))
+	// Pagamentos com card_token não têm CVV depois que o CVV guardado no cofre é usado ou expira
+	if request.CardDetails.CVV != "" {
+		form.Set("payment_method_data[card][cvc]", request.CardDetails.CVV)
+	}
+
+	var intent stripePaymentIntent
+	if err := g.do(http.MethodPost, "/v1/payment_intents", form, &intent); err != nil {
+		return models.PaymentResponse{}, err
+	}
+
+	status := stripeStatus(intent)
+	message := "Payment processed with success"
+	switch status {
+	case models.StatusAuthorized:
+		message = "Payment authorized with success"
+	case models.StatusPending:
+		message = "Payment requires additional action"
+	case models.StatusFailed:
+		message = "Payment failed"
+	}
 
-func ProcessStripePayment(request models.PaymentRequest) models.PaymentResponse {
 	return models.PaymentResponse{
-		Transaction_ID: "success",
-		Message:        "Payment processed successfully",
+		Message:        message,
+		Transaction_ID: intent.ID,
+		Status:         status,
+	}, nil
+}
+
+// GetPaymentStatus consulta o PaymentIntent no Stripe.
+func (g *StripeGateway) GetPaymentStatus(transactionID string) (models.TransactionResponse, error) {
+	var intent stripePaymentIntent
+	if err := g.do(http.MethodGet, "/v1/payment_intents/"+url.PathEscape(transactionID), nil, &intent); err != nil {
+		return models.TransactionResponse{}, err
+	}
+
+	return models.TransactionResponse{
+		Message: fmt.Sprintf("Transaction ID: %s found", transactionID),
+		Status:  stripeStatus(intent),
+	}, nil
+}
+
+// RefundPayment cria um reembolso para o PaymentIntent. Um valor igual a zero reembolsa o valor total.
+func (g *StripeGateway) RefundPayment(transactionID string, amount models.Money) (models.RefundResponse, error) {
+	form := url.Values{}
+	form.Set("payment_intent", transactionID)
+	if !amount.IsZero() {
+		form.Set("amount", strconv.FormatInt(amount.Minor, 10))
+	}
+
+	var refund stripeRefund
+	if err := g.do(http.MethodPost, "/v1/refunds", form, &refund); err != nil {
+		return models.RefundResponse{}, err
+	}
+
+	status := models.RefundStatusPending
+	switch refund.Status {
+	case "succeeded":
+		status = models.RefundStatusSucceeded
+	case "failed", "canceled":
+		status = models.RefundStatusFailed
+	}
+
+	return models.RefundResponse{
+		Message:         "Refund " + refund.ID + " created",
+		GatewayRefundID: refund.ID,
+		TransactionID:   transactionID,
+		Amount:          models.Money{Minor: refund.Amount, Currency: amount.Currency}.Amount(),
+		Currency:        amount.Currency,
+		Status:          status,
+	}, nil
+}
+
+// do executa uma requisição na API do Stripe e decodifica a resposta em out.
+// Respostas de erro são traduzidas para *models.GatewayError.
+func (g *StripeGateway) do(method, path string, form url.Values, out interface{}) error {
+	var body *strings.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	} else {
+		body = strings.NewReader("")
+	}
+
+	req, err := http.NewRequest(method, g.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.SecretKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return &models.GatewayError{Gateway: g.Name(), Type: models.GatewayErrorUnavailable, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return stripeError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stripeError traduz uma resposta de erro do Stripe para models.GatewayError.
+func stripeError(resp *http.Response) error {
+	var body stripeErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+
+	gatewayErr := &models.GatewayError{
+		Gateway:     "Stripe",
+		StatusCode:  resp.StatusCode,
+		Code:        body.Error.Code,
+		DeclineCode: body.Error.DeclineCode,
+		Message:     body.Error.Message,
+	}
+	if gatewayErr.Message == "" {
+		gatewayErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	switch {
+	case body.Error.Type == "card_error":
+		gatewayErr.Type = models.GatewayErrorCard
+	case resp.StatusCode == http.StatusUnauthorized:
+		gatewayErr.Type = models.GatewayErrorAuthentication
+	case resp.StatusCode == http.StatusNotFound || body.Error.Code == "resource_missing":
+		gatewayErr.Type = models.GatewayErrorNotFound
+	case resp.StatusCode >= 500 || body.Error.Type == "api_error":
+		gatewayErr.Type = models.GatewayErrorUnavailable
+	default:
+		gatewayErr.Type = models.GatewayErrorInvalidRequest
+	}
+	return gatewayErr
+}
+
+// stripeStatus traduz o status de um PaymentIntent para o vocabulário da máquina de estados.
+func stripeStatus(intent stripePaymentIntent) string {
+	switch intent.Status {
+	case "succeeded":
+		return models.StatusCaptured
+	case "requires_capture":
+		return models.StatusAuthorized
+	case "canceled":
+		return models.StatusVoided
+	case "requires_payment_method":
+		// Após uma tentativa recusada o PaymentIntent volta para requires_payment_method
+		if intent.LastPaymentError != nil {
+			return models.StatusFailed
+		}
+		return models.StatusPending
+	default:
+		// requires_action, requires_confirmation e processing
+		return models.StatusPending
 	}
 }
@@ -0,0 +1,251 @@
+// vault.go
+// Este arquivo contém o cofre de cartões (vault), que troca os dados do cartão por um token opaco.
+// O número do cartão é cifrado com AES-256-GCM antes de ser gravado no repositório, usando o token como dado
+// associado, de modo que o texto cifrado de um token não pode ser reaproveitado em outro. O CVV nunca é gravado:
+// ele fica apenas em memória, até o primeiro pagamento com o token ou até expirar.
+
+// Somente o cofre decifra o número do cartão, e apenas no momento de enviar o pagamento ao gateway (withCardDetails);
+// os handlers, o repositório de transações e as respostas recebem apenas o token, a bandeira e os últimos dígitos.
+
+// O arquivo inclui:
+// 1. NewVault / ParseVaultKey / SetVault: Criação do cofre com a chave configurada e definição do cofre utilizado.
+// 2. TokenizeCard: Guarda o cartão no cofre e retorna o token.
+// 3. checkCardExpiry: Recusa o pagamento com um card_token cuja validade já passou, antes da cotação e do gateway.
+// 4. withCardDetails: Substitui o card_token de um pagamento pelos dados do cartão, antes da chamada ao gateway.
+
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// vaultKeySize é o tamanho da chave do cofre (AES-256).
+const vaultKeySize = 32
+
+// Erros do cofre de cartões.
+var (
+	ErrCardTokenNotFound = fmt.Errorf("card token %w", repository.ErrNotFound)
+	ErrCardExpired       = errors.New("card expired")
+	ErrInvalidVaultKey   = fmt.Errorf("vault key must be %d bytes encoded in hex or base64", vaultKeySize)
+)
+
+// Vault guarda os cartões cifrados no repositório e os CVVs em memória.
+type Vault struct {
+	aead   cipher.AEAD
+	repo   repository.CardTokenRepository
+	cvvTTL time.Duration
+
+	mu   sync.Mutex
+	cvvs map[string]transientCVV
+}
+
+// transientCVV é um CVV mantido em memória até o primeiro uso ou até a expiração.
+type transientCVV struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewVault cria um cofre com a chave AES-256 informada. Os CVVs são mantidos em memória por cvvTTL.
+func NewVault(key []byte, repo repository.CardTokenRepository, cvvTTL time.Duration) (*Vault, error) {
+	if len(key) != vaultKeySize {
+		return nil, ErrInvalidVaultKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{aead: aead, repo: repo, cvvTTL: cvvTTL, cvvs: make(map[string]transientCVV)}, nil
+}
+
+// ParseVaultKey lê a chave do cofre em hexadecimal (64 caracteres) ou base64 (32 bytes).
+func ParseVaultKey(encoded string) ([]byte, error) {
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == vaultKeySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == vaultKeySize {
+		return key, nil
+	}
+	return nil, ErrInvalidVaultKey
+}
+
+// NewVaultKey gera uma chave aleatória para o cofre.
+func NewVaultKey() []byte {
+	key := make([]byte, vaultKeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("generate vault key: %v", err))
+	}
+	return key
+}
+
+var (
+	vault     = mustNewVault(NewVaultKey(), repository.NewMemoryCardTokenRepository(), 10*time.Minute)
+	vaultLock sync.RWMutex
+)
+
+// mustNewVault cria o cofre padrão, em memória e com uma chave aleatória.
+func mustNewVault(key []byte, repo repository.CardTokenRepository, cvvTTL time.Duration) *Vault {
+	v, err := NewVault(key, repo, cvvTTL)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// SetVault define o cofre de cartões utilizado pelos serviços.
+func SetVault(v *Vault) {
+	vaultLock.Lock()
+	defer vaultLock.Unlock()
+
+	vault = v
+}
+
+// cardVault retorna o cofre de cartões em uso.
+func cardVault() *Vault {
+	vaultLock.RLock()
+	defer vaultLock.RUnlock()
+
+	return vault
+}
+
+// TokenizeCard guarda o cartão no cofre e retorna o token, a bandeira, os últimos dígitos e a validade.
+func TokenizeCard(card models.CardDetails) (models.CardTokenResponse, error) {
+	return cardVault().Tokenize(card)
+}
+
+// Tokenize cifra o número do cartão, grava-o no repositório e mantém o CVV em memória.
+func (v *Vault) Tokenize(card models.CardDetails) (models.CardTokenResponse, error) {
+	token := newCardToken()
+
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return models.CardTokenResponse{}, fmt.Errorf("tokenize card: %w", err)
+	}
+	now := time.Now().UTC()
+	stored := models.CardToken{
+		Token:           token,
+		Brand:           card.Brand(),
+		Last4:           card.Last4(),
+		Expiry:          card.Expiry,
+		CreatedAt:       now,
+		EncryptedNumber: v.aead.Seal(nonce, nonce, []byte(card.Number), []byte(token)),
+	}
+	if err := v.repo.Create(stored); err != nil {
+		return models.CardTokenResponse{}, fmt.Errorf("tokenize card: %w", err)
+	}
+
+	response := models.CardTokenResponse{CardToken: stored}
+	if card.CVV != "" && v.cvvTTL > 0 {
+		expiresAt := now.Add(v.cvvTTL)
+		v.mu.Lock()
+		v.removeExpiredCVVs(now)
+		v.cvvs[token] = transientCVV{value: card.CVV, expiresAt: expiresAt}
+		v.mu.Unlock()
+		response.CVVExpiresAt = &expiresAt
+	}
+	return response, nil
+}
+
+// lookup retorna o cartão guardado com o token informado, sem o número cifrado.
+func (v *Vault) lookup(token string) (models.CardToken, error) {
+	stored, err := v.repo.Get(token)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.CardToken{}, ErrCardTokenNotFound
+	}
+	if err != nil {
+		return models.CardToken{}, err
+	}
+	stored.EncryptedNumber = nil
+	return stored, nil
+}
+
+// detokenize decifra o número do cartão do token e retorna o CVV ainda em memória, que é descartado em seguida.
+// Depois do primeiro uso ou da expiração do CVV, o cartão é retornado sem CVV.
+func (v *Vault) detokenize(token string) (models.CardDetails, error) {
+	stored, err := v.repo.Get(token)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.CardDetails{}, ErrCardTokenNotFound
+	}
+	if err != nil {
+		return models.CardDetails{}, err
+	}
+
+	nonceSize := v.aead.NonceSize()
+	if len(stored.EncryptedNumber) < nonceSize {
+		return models.CardDetails{}, fmt.Errorf("decrypt card token %s: ciphertext too short", token)
+	}
+	number, err := v.aead.Open(nil, stored.EncryptedNumber[:nonceSize], stored.EncryptedNumber[nonceSize:], []byte(token))
+	if err != nil {
+		return models.CardDetails{}, fmt.Errorf("decrypt card token %s: %w", token, err)
+	}
+
+	card := models.CardDetails{Number: string(number), Expiry: stored.Expiry}
+	v.mu.Lock()
+	if cvv, ok := v.cvvs[token]; ok {
+		delete(v.cvvs, token)
+		if time.Now().Before(cvv.expiresAt) {
+			card.CVV = cvv.value
+		}
+	}
+	v.mu.Unlock()
+	return card, nil
+}
+
+// removeExpiredCVVs descarta os CVVs expirados. Deve ser chamada com v.mu bloqueado.
+func (v *Vault) removeExpiredCVVs(now time.Time) {
+	for token, cvv := range v.cvvs {
+		if !now.Before(cvv.expiresAt) {
+			delete(v.cvvs, token)
+		}
+	}
+}
+
+// checkCardExpiry verifica a validade do cartão guardado no card_token da requisição, com a mesma regra da validação
+// not_expired de card_details, que não alcança os tokens nem os cartões salvos dos clientes.
+func checkCardExpiry(request models.PaymentRequest) error {
+	if request.CardToken == "" {
+		return nil
+	}
+	card, err := cardVault().lookup(request.CardToken)
+	if err != nil {
+		return err
+	}
+	if models.CardExpired(card.Expiry, time.Now()) {
+		return fmt.Errorf("%w: card ending in %s expired in %s", ErrCardExpired, card.Last4, card.Expiry)
+	}
+	return nil
+}
+
+// withCardDetails substitui o card_token da requisição pelos dados do cartão guardados no cofre.
+// Deve ser chamada imediatamente antes do envio ao gateway, pois consome o CVV mantido em memória.
+func withCardDetails(request models.PaymentRequest) (models.PaymentRequest, error) {
+	if request.CardToken == "" {
+		return request, nil
+	}
+	card, err := cardVault().detokenize(request.CardToken)
+	if err != nil {
+		return models.PaymentRequest{}, err
+	}
+	request.CardDetails = &card
+	return request, nil
+}
+
+// newCardToken gera um token único para um cartão.
+func newCardToken() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return "tok_" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,152 @@
+// card_test.go
+// Este arquivo contém testes para a validação dos cartões: bandeira pelas faixas de IIN, tamanho do número e do CVV
+// de cada bandeira, dígito verificador (Luhn) e validade no formato MM/YY.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui quatro testes principais:
+// 1. TestDetectCardBrand: Verifica a bandeira identificada para números de teste de cada bandeira, inclusive Elo em faixas de Visa e Discover.
+// 2. TestCardExpired: Verifica se o cartão é válido até o último dia do mês da validade.
+// 3. TestProcessPayment_InvalidCard: Verifica se cartões inválidos resultam em um erro 400 com o campo e a regra que falhou.
+// 4. TestProcessPayment_CardBrand: Verifica se um cartão Amex com CID de 4 dígitos é aceito e se a bandeira é registrada na transação.
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// processCardPayment envia ao simulador um pagamento com os dados do cartão informados.
+func processCardPayment(t *testing.T, number, expiry, cvv string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"gateway": "simulator", "amount": 10.00, "currency": "USD", "payment_method": "credit_card",
+		"card_details": {"number": %q, "expiry": %q, "cvv": %q}}`, number, expiry, cvv)
+	return sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment", body)
+}
+
+// validExpiry retorna uma validade (MM/YY) dois anos à frente, para que os cartões dos testes não vençam.
+func validExpiry() string {
+	return time.Now().UTC().AddDate(2, 0, 0).Format("01/06")
+}
+
+func TestDetectCardBrand(t *testing.T) {
+	tests := map[string]models.CardBrand{
+		"4111111111111111":    models.CardBrandVisa,
+		"4000000000006":       models.CardBrandVisa,
+		"5555555555554444":    models.CardBrandMastercard,
+		"2223003122003222":    models.CardBrandMastercard,
+		"378282246310005":     models.CardBrandAmex,
+		"371449635398431":     models.CardBrandAmex,
+		"6362970000457013":    models.CardBrandElo,
+		"5066991111111118":    models.CardBrandElo,
+		"4389350000000002":    models.CardBrandElo,
+		"6062825624254001":    models.CardBrandHipercard,
+		"6011111111111117":    models.CardBrandDiscover,
+		"6500000000000002":    models.CardBrandDiscover,
+		"3530111333300000":    "",
+		"0000000000000000":    "",
+		"":                    "",
+		"6221260000000000000": models.CardBrandDiscover,
+	}
+	for number, brand := range tests {
+		assert.Equal(t, brand, models.DetectCardBrand(number), number)
+	}
+
+	assert.Equal(t, 4, models.CardBrandAmex.CVVLength())
+	assert.Equal(t, 3, models.CardBrandElo.CVVLength())
+	assert.True(t, models.ValidCardLength("4000000000006"))
+	assert.False(t, models.ValidCardLength("411111111111111"))
+	assert.False(t, models.ValidCardLength("3782822463100050"))
+}
+
+func TestCardExpired(t *testing.T) {
+	now := time.Now().UTC()
+	firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	thisMonth := firstDay.Format("01/06")
+
+	assert.False(t, models.CardExpired(thisMonth, now))
+	assert.False(t, models.CardExpired(thisMonth, firstDay.AddDate(0, 1, 0).Add(-time.Second)))
+	assert.True(t, models.CardExpired(thisMonth, firstDay.AddDate(0, 1, 0)))
+	assert.True(t, models.CardExpired(firstDay.AddDate(0, -1, 0).Format("01/06"), now))
+	assert.False(t, models.CardExpired(firstDay.AddDate(0, 1, 0).Format("01/06"), now))
+	assert.False(t, models.CardExpired("12/99", now))
+
+	for _, expiry := range []string{"13/30", "00/30", "1/30", "12/2030", "+1/30", "12-30", ""} {
+		_, _, err := models.ParseCardExpiry(expiry)
+		assert.Error(t, err, expiry)
+		assert.True(t, models.CardExpired(expiry, now), expiry)
+	}
+	month, year, err := models.ParseCardExpiry("02/31")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 2031}, []int{month, year})
+}
+
+func TestProcessPayment_InvalidCard(t *testing.T) {
+	setupTransactions(t)
+	now := time.Now().UTC()
+	lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Format("01/06")
+
+	tests := []struct {
+		number, expiry, cvv string
+		field               models.FieldError
+	}{
+		{"0000000000000000", validExpiry(), "123", models.FieldError{Field: "card_details.number", Rule: "card_brand"}},
+		{"3530111333300000", validExpiry(), "123", models.FieldError{Field: "card_details.number", Rule: "card_brand"}},
+		{"4111111111111112", validExpiry(), "123", models.FieldError{Field: "card_details.number", Rule: "luhn_checksum"}},
+		{"411111111111111", validExpiry(), "123", models.FieldError{Field: "card_details.number", Rule: "card_length"}},
+		{"4111 1111 1111 1111", validExpiry(), "123", models.FieldError{Field: "card_details.number", Rule: "numeric"}},
+		{"4111111111111111", "99/99", "123", models.FieldError{Field: "card_details.expiry", Rule: "card_expiry"}},
+		{"4111111111111111", lastMonth, "123", models.FieldError{Field: "card_details.expiry", Rule: "not_expired"}},
+		{"4111111111111111", validExpiry(), "1234", models.FieldError{Field: "card_details.cvv", Rule: "len=3"}},
+		{"378282246310005", validExpiry(), "123", models.FieldError{Field: "card_details.cvv", Rule: "len=4"}},
+		{"4111111111111111", validExpiry(), "12a", models.FieldError{Field: "card_details.cvv", Rule: "numeric"}},
+	}
+	for _, tt := range tests {
+		rr := processCardPayment(t, tt.number, tt.expiry, tt.cvv)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, tt.number)
+		problem := assertProblem(t, rr, "validation_failed", "Invalid request data: "+tt.field.String())
+		assert.Equal(t, []models.FieldError{tt.field}, problem.Errors, tt.number)
+	}
+}
+
+func TestProcessPayment_CardBrand(t *testing.T) {
+	repo := repository.NewMemoryTransactionRepository()
+	services.SetTransactionRepository(repo)
+	t.Cleanup(func() { services.SetTransactionRepository(repository.NewMemoryTransactionRepository()) })
+
+	// O cartão vence no último dia do mês atual
+	thisMonth := time.Now().UTC().Format("01/06")
+	tests := map[string]struct {
+		cvv   string
+		brand models.CardBrand
+	}{
+		"378282246310005":  {"1234", models.CardBrandAmex},
+		"5555555555554444": {"123", models.CardBrandMastercard},
+		"6362970000457013": {"123", models.CardBrandElo},
+		"6062825624254001": {"123", models.CardBrandHipercard},
+		"6011111111111117": {"123", models.CardBrandDiscover},
+	}
+	for number, tt := range tests {
+		rr := processCardPayment(t, number, thisMonth, tt.cvv)
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var response models.PaymentResponse
+		json.NewDecoder(rr.Body).Decode(&response)
+		assert.Equal(t, string(tt.brand), response.CardBrand, number)
+
+		transaction, err := repo.Get(response.Transaction_ID)
+		if assert.NoError(t, err, number) {
+			assert.Equal(t, string(tt.brand), transaction.CardBrand, number)
+		}
+	}
+}
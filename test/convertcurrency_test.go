@@ -29,7 +29,7 @@ import (
 func mockConvertCurrency(request models.CurrencyConversionRequest) (models.CurrencyConversionResponse, error) {
 	if request.FromCurrency == "USD" && request.ToCurrency == "EUR" {
 		return models.CurrencyConversionResponse{
-			ConvertedAmount: 85.00,
+			ConvertedAmount: models.MustParseDecimal("85.00"),
 			FromCurrency:    "USD",
 			ToCurrency:      "EUR",
 			Rate:            0.85,
@@ -46,7 +46,7 @@ func TestConvertCurrency_ValidRequest(t *testing.T) {
 
 	// Cria uma solicitação válida
 	conversionRequest := models.CurrencyConversionRequest{
-		Amount:       100.00,
+		Amount:       models.MustParseDecimal("100.00"),
 		FromCurrency: "USD",
 		ToCurrency:   "EUR",
 	}
@@ -72,7 +72,7 @@ func TestConvertCurrency_ValidRequest(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, 85.00, response.ConvertedAmount)
+	assert.Equal(t, "85.00", response.ConvertedAmount.String())
 	assert.Equal(t, "USD", response.FromCurrency)
 	assert.Equal(t, "EUR", response.ToCurrency)
 	assert.Equal(t, 0.85, response.Rate)
@@ -95,7 +95,7 @@ func TestConvertCurrency_InvalidRequest(t *testing.T) {
 
 	// Verifica o status da resposta
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
-	assert.Equal(t, "Invalid request data\n", rr.Body.String())
+	assertProblem(t, rr, "validation_failed", "Invalid request data: amount: required, from_currency: required, to_currency: required")
 }
 
 func TestConvertCurrency_ExternalAPIError(t *testing.T) {
@@ -106,7 +106,7 @@ func TestConvertCurrency_ExternalAPIError(t *testing.T) {
 
 	// Cria uma solicitação que resultará em um erro de conversão
 	conversionRequest := models.CurrencyConversionRequest{
-		Amount:       100.00,
+		Amount:       models.MustParseDecimal("100.00"),
 		FromCurrency: "USD",
 		ToCurrency:   "XXX",
 	}
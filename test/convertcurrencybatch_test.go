@@ -0,0 +1,232 @@
+// convertcurrencybatch_test.go
+// Este arquivo contém testes para o handler ConvertCurrencyBatch, que converte um lote de valores.
+// A taxa de câmbio é substituída por GetExchangeRateFunc, que também conta as consultas para verificar que cada taxa distinta é obtida uma única vez.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui cinco testes principais:
+// 1. TestConvertCurrencyBatch_Items: Verifica se uma lista de conversões retorna o resultado ou o erro de cada item, consultando cada taxa uma única vez.
+// 2. TestConvertCurrencyBatch_SingleSource: Verifica se um único valor de origem é convertido para várias moedas de destino.
+// 3. TestConvertCurrencyBatch_NDJSON: Verifica se os resultados são escritos em NDJSON, um por linha, inclusive em lotes grandes.
+// 4. TestConvertCurrencyBatch_InvalidBody: Verifica se um corpo que não pode ser lido resulta em um erro 400.
+// 5. TestConvertCurrencyBatch_Limits: Verifica os limites de itens e de tamanho do corpo e se as respostas, inclusive em NDJSON, são mascaradas.
+
+package handlers_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRates substitui a taxa de câmbio pelas taxas informadas e retorna uma função que informa quantas consultas foram feitas.
+func countingRates(t *testing.T, rates map[string]float64) func() int {
+	var mu sync.Mutex
+	lookups := 0
+	fixed := fixedRates(rates)
+	setupExchangeRate(t, func(fromCurrency, toCurrency string) (float64, error) {
+		mu.Lock()
+		lookups++
+		mu.Unlock()
+		return fixed(fromCurrency, toCurrency)
+	})
+	return func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return lookups
+	}
+}
+
+// convertCurrencyBatch envia um lote de conversões ao handler com o cabeçalho Accept informado.
+func convertCurrencyBatch(t *testing.T, body, accept string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/convert-currency/batch", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ConvertCurrencyBatch).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestConvertCurrencyBatch_Items(t *testing.T) {
+	lookups := countingRates(t, map[string]float64{"USD/EUR": 0.92, "EUR/USD": 1.0845})
+
+	rr := convertCurrencyBatch(t, `[
+		{"amount": 100.00, "from_currency": "USD", "to_currency": "EUR"},
+		{"amount": 50.00, "from_currency": "USD", "to_currency": "EUR"},
+		{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD"},
+		{"amount": 0, "from_currency": "USD", "to_currency": "EUR"},
+		{"amount": 10.001, "from_currency": "USD", "to_currency": "EUR"},
+		{"amount": 10.00, "from_currency": "USD", "to_currency": "CHF"},
+		{"amount": "abc", "from_currency": "USD", "to_currency": "EUR"},
+		{"amount": 10.00, "from_currency": "USD", "to_currency": "XYZ"},
+		{"amount": 1.00, "from_currency": "USD", "to_currency": "EUR"}
+	]`, "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response models.BatchConversionResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	if !assert.Len(t, response.Results, 9) {
+		return
+	}
+
+	expected := []struct {
+		status    int
+		converted string
+		code      string
+		detail    string
+	}{
+		{http.StatusOK, "92.00", "", ""},
+		{http.StatusOK, "46.00", "", ""},
+		{http.StatusOK, "108.45", "", ""},
+		{http.StatusBadRequest, "", "validation_failed", "Invalid request data: amount: required"},
+		{http.StatusBadRequest, "", "amount_precision", "amount 10.001 has more decimal places than USD allows (2)"},
+		{http.StatusInternalServerError, "", "conversion_failed", "currency not found"},
+		{http.StatusBadRequest, "", "invalid_body", "Invalid request"},
+		{http.StatusBadRequest, "", "validation_failed", "Invalid request data: to_currency: iso4217"},
+		{http.StatusOK, "0.92", "", ""},
+	}
+	for i, want := range expected {
+		result := response.Results[i]
+		assert.Equal(t, i, result.Index)
+		assert.Equal(t, want.status, result.Status, i)
+		if want.converted != "" && assert.NotNil(t, result.CurrencyConversionResponse, i) {
+			assert.Equal(t, want.converted, result.ConvertedAmount.String(), i)
+			assert.Nil(t, result.Error, i)
+		} else if assert.NotNil(t, result.Error, i) {
+			// O erro de cada item tem o mesmo formato das respostas de erro da API
+			assert.Nil(t, result.CurrencyConversionResponse, i)
+			assert.Equal(t, want.status, result.Error.Status, i)
+			assert.Equal(t, want.code, result.Error.Code, i)
+			assert.Equal(t, want.detail, result.Error.Detail, i)
+		}
+	}
+
+	// USD/EUR, EUR/USD e USD/CHF: uma consulta por taxa distinta; XYZ é rejeitada antes da consulta
+	assert.Equal(t, 3, lookups())
+}
+
+func TestConvertCurrencyBatch_SingleSource(t *testing.T) {
+	lookups := countingRates(t, map[string]float64{"USD/EUR": 0.92, "USD/JPY": 149.837, "USD/BRL": 4.95})
+
+	rr := convertCurrencyBatch(t, `{"amount": 100.00, "from_currency": "USD", "to_currencies": ["EUR", "JPY", "BRL", "EUR"]}`, "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response models.BatchConversionResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	converted := []string{}
+	for _, result := range response.Results {
+		assert.Equal(t, "USD", result.FromCurrency)
+		converted = append(converted, result.ConvertedAmount.String()+" "+result.ToCurrency)
+	}
+	assert.Equal(t, []string{"92.00 EUR", "14984 JPY", "495.00 BRL", "92.00 EUR"}, converted)
+	assert.Equal(t, 3, lookups())
+
+	// Sem moedas de destino, o lote inteiro é inválido
+	rr = convertCurrencyBatch(t, `{"amount": 100.00, "from_currency": "USD", "to_currencies": []}`, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: to_currencies: min=1")
+}
+
+func TestConvertCurrencyBatch_NDJSON(t *testing.T) {
+	lookups := countingRates(t, map[string]float64{"USD/EUR": 0.92})
+
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"amount": %d.00, "from_currency": "USD", "to_currency": "EUR"}`, i+1)
+	}
+	rr := convertCurrencyBatch(t, "["+strings.Join(items, ",")+"]", "application/x-ndjson")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+
+	lines := 0
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var result models.BatchConversionResult
+		if assert.NoError(t, json.Unmarshal(scanner.Bytes(), &result)) {
+			assert.Equal(t, lines, result.Index)
+			assert.Equal(t, fmt.Sprintf("%.2f", float64(lines+1)*0.92), result.ConvertedAmount.String())
+		}
+		lines++
+	}
+	assert.Equal(t, 1000, lines)
+	assert.Equal(t, 1, lookups())
+
+	// Um corpo truncado interrompe o lote após os itens já convertidos
+	rr = convertCurrencyBatch(t, `[{"amount": 1.00, "from_currency": "USD", "to_currency": "EUR"}, {"amount": 2`, "application/x-ndjson")
+	truncated := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if assert.Len(t, truncated, 2) {
+		assert.Contains(t, truncated[0], `"converted_amount":0.92`)
+		assert.JSONEq(t, `{"index": 1, "status": 400, "error": {"type": "about:blank", "title": "Bad Request", "status": 400, "detail": "Invalid request", "code": "invalid_body"}}`, truncated[1])
+	}
+}
+
+func TestConvertCurrencyBatch_InvalidBody(t *testing.T) {
+	for _, body := range []string{``, `abc`, `"USD"`, `{"amount": }`} {
+		rr := convertCurrencyBatch(t, body, "")
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+
+	// Sem streaming, um corpo truncado rejeita o lote inteiro
+	rr := convertCurrencyBatch(t, `[{"amount": 1.00, "from_currency": "USD", "to_currency": "EUR"}, {"amount": 2`, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "invalid_body", "Invalid request")
+}
+
+func TestConvertCurrencyBatch_Limits(t *testing.T) {
+	countingRates(t, map[string]float64{"USD/EUR": 0.92})
+
+	items := make([]string, 1001)
+	for i := range items {
+		items[i] = `{"amount": 1.00, "from_currency": "USD", "to_currency": "EUR"}`
+	}
+	body := "[" + strings.Join(items, ",") + "]"
+
+	// Lotes com mais de 1000 itens são rejeitados
+	rr := convertCurrencyBatch(t, body, "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assertProblem(t, rr, "batch_too_large", "Batch exceeds 1000 items")
+
+	// Em NDJSON, os itens já convertidos são enviados e o lote é interrompido no item excedente
+	rr = convertCurrencyBatch(t, body, "application/x-ndjson")
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if assert.Len(t, lines, 1001) {
+		var result models.BatchConversionResult
+		assert.NoError(t, json.Unmarshal([]byte(lines[1000]), &result))
+		assert.Equal(t, 1000, result.Index)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, result.Status)
+		if assert.NotNil(t, result.Error) {
+			assert.Equal(t, "batch_too_large", result.Error.Code)
+		}
+	}
+
+	// O corpo tem o mesmo limite de tamanho das demais solicitações
+	large := `[{"amount": 1.00, "from_currency": "USD", "to_currency": "EUR", "merchant_id": "` + strings.Repeat("m", 2<<20) + `"}]`
+	rr = convertCurrencyBatch(t, large, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "invalid_body", "Invalid request")
+
+	// As respostas do lote passam pelo mascaramento dos dados de cartão, como as demais
+	setupExchangeRate(t, func(fromCurrency, toCurrency string) (float64, error) {
+		return 0, fmt.Errorf("no rate for card 4111111111111111")
+	})
+	for _, accept := range []string{"", "application/x-ndjson"} {
+		rr = convertCurrencyBatch(t, `[{"amount": 1.00, "from_currency": "USD", "to_currency": "EUR"}]`, accept)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "4111111111111111", accept)
+		assert.Contains(t, rr.Body.String(), "411111******1111", accept)
+	}
+}
@@ -0,0 +1,145 @@
+// currencies_test.go
+// Este arquivo contém testes para o catálogo de moedas (GET /currencies), a consulta da tabela de taxas em cache (GET /rates)
+// e a rejeição de códigos de moeda desconhecidos antes de qualquer consulta aos provedores de taxas.
+// O provedor de taxas consulta o servidor local que simula a open.er-api (mocks/ratesmock).
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui quatro testes principais:
+// 1. TestListCurrencies: Verifica o nome, as casas decimais e o indicador de pagamento das moedas do catálogo, que contém os códigos aceitos na validação.
+// 2. TestConvertCurrency_UnknownCurrency: Verifica se códigos de moeda desconhecidos resultam em um erro 400 sem consultar a taxa de câmbio.
+// 3. TestGetRates: Verifica se a tabela de uma moeda base é retornada com o provedor e o horário da consulta, usando o cache.
+// 4. TestMultiCurrency_NonPaymentCurrency: Verifica se uma moeda que não é aceita em pagamentos (e.g. ouro) resulta em um erro 400.
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+// getRates consulta a tabela de taxas pelo handler com a query informada.
+func getRates(t *testing.T, query string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", "/rates"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.GetRates).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestListCurrencies(t *testing.T) {
+	req, err := http.NewRequest("GET", "/currencies", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ListCurrencies).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var currencies []models.Currency
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&currencies))
+	byCode := map[string]models.Currency{}
+	for _, currency := range currencies {
+		byCode[currency.Code] = currency
+	}
+	assert.Len(t, byCode, len(currencies))
+
+	assert.Equal(t, models.Currency{Code: "BRL", Name: "Brazilian Real", MinorUnits: 2, SupportedForPayment: true}, byCode["BRL"])
+	assert.Equal(t, models.Currency{Code: "JPY", Name: "Yen", MinorUnits: 0, SupportedForPayment: true}, byCode["JPY"])
+	assert.Equal(t, models.Currency{Code: "KWD", Name: "Kuwaiti Dinar", MinorUnits: 3, SupportedForPayment: true}, byCode["KWD"])
+	assert.Equal(t, models.Currency{Code: "XAU", Name: "Gold", MinorUnits: 2, SupportedForPayment: false}, byCode["XAU"])
+	assert.False(t, byCode["XTS"].SupportedForPayment)
+
+	// Todos os códigos do catálogo são aceitos na validação das requisições
+	validate := validator.New()
+	for _, currency := range currencies {
+		assert.NoError(t, validate.Var(currency.Code, "iso4217"), currency.Code)
+	}
+	assert.Equal(t, "AED", currencies[0].Code)
+}
+
+func TestConvertCurrency_UnknownCurrency(t *testing.T) {
+	setupExchangeRate(t, func(fromCurrency, toCurrency string) (float64, error) {
+		t.Errorf("unexpected exchange rate lookup %s/%s", fromCurrency, toCurrency)
+		return 0, nil
+	})
+
+	for body, field := range map[string]string{
+		`{"amount": 100.00, "from_currency": "USD", "to_currency": "XYZ"}`: "to_currency",
+		`{"amount": 100.00, "from_currency": "ABC", "to_currency": "EUR"}`: "from_currency",
+		`{"amount": 100.00, "from_currency": "usd", "to_currency": "EUR"}`: "from_currency",
+	} {
+		rr := convertCurrency(t, body)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+		assertProblem(t, rr, "validation_failed", "Invalid request data: "+field+": iso4217")
+	}
+}
+
+func TestGetRates(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, time.Second))
+
+	before := time.Now()
+	rr := getRates(t, "?base=EUR")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var rates models.RatesResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&rates))
+	assert.Equal(t, "EUR", rates.Base)
+	assert.Equal(t, "erapi", rates.Provider)
+	assert.Equal(t, "2024-01-02", rates.RateDate)
+	assert.False(t, rates.FetchedAt.Before(before))
+	assert.False(t, rates.Stale)
+	assert.Equal(t, 1.0845, rates.Rates["USD"])
+	assert.Equal(t, 5.3712, rates.Rates["BRL"])
+
+	// A segunda consulta usa a tabela em cache
+	requests := server.Requests()
+	rr = getRates(t, "?base=EUR")
+	var cached models.RatesResponse
+	json.NewDecoder(rr.Body).Decode(&cached)
+	assert.Equal(t, requests, server.Requests())
+	assert.True(t, cached.FetchedAt.Equal(rates.FetchedAt))
+
+	// Sem moeda base, é usada a moeda base configurada
+	rr = getRates(t, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	json.NewDecoder(rr.Body).Decode(&rates)
+	assert.Equal(t, "USD", rates.Base)
+
+	// Códigos desconhecidos são rejeitados sem consultar o provedor
+	requests = server.Requests()
+	rr = getRates(t, "?base=XYZ")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "invalid_currency", "Invalid currency code")
+	assert.Equal(t, requests, server.Requests())
+
+	// Sem tabela em cache e com o provedor falhando, as taxas estão indisponíveis
+	server.SetFailing(true)
+	rr = getRates(t, "?base=GBP")
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.Contains(t, rr.Body.String(), "exchange rate unavailable: GBP")
+}
+
+func TestMultiCurrency_NonPaymentCurrency(t *testing.T) {
+	setupExchangeRate(t, fixedRates(map[string]float64{"XAU/USD": 2050.00}))
+
+	rr := processPaymentIn(t, "simulator", "1.00", "XAU")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: currency: payment_currency")
+
+	// A mesma moeda pode ser convertida
+	rr = convertCurrency(t, `{"amount": 1.00, "from_currency": "XAU", "to_currency": "USD"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
@@ -0,0 +1,350 @@
+// customer_test.go
+// Este arquivo contém testes para o cadastro de clientes (/customers) e dos seus métodos de pagamento salvos,
+// e para os pagamentos com customer_id e payment_method_id no lugar dos dados do cartão.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui quatro testes principais:
+// 1. TestCustomerCRUD: Verifica criação, consulta, listagem, alteração e remoção de clientes, com os erros de validação e de cliente inexistente.
+// 2. TestCustomerPaymentMethods: Verifica o cadastro de cartões do cofre e de acordos de cobrança do PayPal e a escolha do método padrão.
+// 3. TestProcessPayment_SavedPaymentMethod: Verifica se os pagamentos usam o cartão do método salvo ou o método padrão do cliente.
+// 4. TestProcessPayment_BillingAgreement: Verifica os pagamentos com acordo de cobrança no PayPal e nos gateways que não os suportam.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/mocks/paypalmock"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupCustomers define repositórios de clientes e de métodos de pagamento em memória vazios.
+func setupCustomers(t *testing.T) {
+	services.SetCustomerRepository(repository.NewMemoryCustomerRepository())
+	services.SetPaymentMethodRepository(repository.NewMemoryPaymentMethodRepository())
+	t.Cleanup(func() {
+		services.SetCustomerRepository(repository.NewMemoryCustomerRepository())
+		services.SetPaymentMethodRepository(repository.NewMemoryPaymentMethodRepository())
+	})
+}
+
+// sendCustomerRequest envia uma requisição ao handler com as variáveis de rota informadas.
+func sendCustomerRequest(t *testing.T, handler http.HandlerFunc, method string, vars map[string]string, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, "/customers", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, vars)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// createCustomer cadastra um cliente e retorna a resposta decodificada.
+func createCustomer(t *testing.T, name, email string) models.Customer {
+	rr := sendRequest(t, handlers.CreateCustomer, "POST", "/customers", fmt.Sprintf(`{"name": %q, "email": %q}`, name, email))
+	if !assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String()) {
+		t.FailNow()
+	}
+	var customer models.Customer
+	json.NewDecoder(rr.Body).Decode(&customer)
+	return customer
+}
+
+// addPaymentMethod envia o método de pagamento para POST /customers/{id}/payment-methods.
+func addPaymentMethod(t *testing.T, customerID, body string) *httptest.ResponseRecorder {
+	return sendCustomerRequest(t, handlers.CreatePaymentMethod, "POST", map[string]string{"id": customerID}, body)
+}
+
+// saveCard guarda o cartão no cofre, salva-o como método de pagamento do cliente e retorna o método criado.
+func saveCard(t *testing.T, customerID, number, cvv string) models.PaymentMethod {
+	token := tokenizeCard(t, number, cvv)
+	rr := addPaymentMethod(t, customerID, fmt.Sprintf(`{"type": "card", "card_token": %q}`, token.Token))
+	if !assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String()) {
+		t.FailNow()
+	}
+	var method models.PaymentMethod
+	json.NewDecoder(rr.Body).Decode(&method)
+	return method
+}
+
+// processCustomerPayment envia ao gateway informado um pagamento com o cliente e o método de pagamento salvo.
+func processCustomerPayment(t *testing.T, gateway, customerID, paymentMethodID string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"gateway": %q, "amount": 10.00, "currency": "USD", "payment_method": "credit_card", "customer_id": %q`,
+		gateway, customerID)
+	if paymentMethodID != "" {
+		body += fmt.Sprintf(`, "payment_method_id": %q`, paymentMethodID)
+	}
+	return sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment", body+"}")
+}
+
+func TestCustomerCRUD(t *testing.T) {
+	setupCustomers(t)
+
+	customer := createCustomer(t, "Maria Silva", "maria@example.com")
+	assert.Regexp(t, "^cus_[0-9a-f]{24}$", customer.ID)
+	assert.Equal(t, "Maria Silva", customer.Name)
+	assert.Empty(t, customer.DefaultPaymentMethodID)
+	assert.WithinDuration(t, time.Now(), customer.CreatedAt, 5*time.Second)
+	other := createCustomer(t, "João Souza", "joao@example.com")
+
+	rr := sendCustomerRequest(t, handlers.GetCustomer, "GET", map[string]string{"id": customer.ID}, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var stored models.Customer
+	json.NewDecoder(rr.Body).Decode(&stored)
+	assert.Equal(t, customer.Email, stored.Email)
+
+	rr = sendRequest(t, handlers.ListCustomers, "GET", "/customers", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list []models.Customer
+	json.NewDecoder(rr.Body).Decode(&list)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, []string{customer.ID, other.ID}, []string{list[0].ID, list[1].ID})
+	}
+
+	rr = sendCustomerRequest(t, handlers.UpdateCustomer, "PUT", map[string]string{"id": customer.ID},
+		`{"name": "Maria S. Oliveira", "email": "maria.oliveira@example.com"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.NewDecoder(rr.Body).Decode(&stored)
+	assert.Equal(t, []string{"Maria S. Oliveira", "maria.oliveira@example.com"}, []string{stored.Name, stored.Email})
+
+	// Erros de validação
+	rr = sendRequest(t, handlers.CreateCustomer, "POST", "/customers", `{"name": "", "email": "maria"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: name: required, email: email")
+
+	// O método padrão só pode ser um método salvo do cliente
+	rr = sendRequest(t, handlers.CreateCustomer, "POST", "/customers",
+		`{"name": "Ana", "email": "ana@example.com", "default_payment_method_id": "pm_unknown"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "payment_method_not_found", "Payment method ID not found")
+
+	// Remoção
+	rr = sendCustomerRequest(t, handlers.DeleteCustomer, "DELETE", map[string]string{"id": customer.ID}, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	for _, rr := range []*httptest.ResponseRecorder{
+		sendCustomerRequest(t, handlers.GetCustomer, "GET", map[string]string{"id": customer.ID}, ""),
+		sendCustomerRequest(t, handlers.DeleteCustomer, "DELETE", map[string]string{"id": customer.ID}, ""),
+		sendCustomerRequest(t, handlers.UpdateCustomer, "PUT", map[string]string{"id": customer.ID}, `{"name": "Maria", "email": "maria@example.com"}`),
+		sendCustomerRequest(t, handlers.ListPaymentMethods, "GET", map[string]string{"id": customer.ID}, ""),
+	} {
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assertProblem(t, rr, "customer_not_found", "Customer ID not found")
+	}
+}
+
+func TestCustomerPaymentMethods(t *testing.T) {
+	setupCustomers(t)
+	methodRepo := repository.NewMemoryPaymentMethodRepository()
+	services.SetPaymentMethodRepository(methodRepo)
+	setupVault(t, repository.NewMemoryCardTokenRepository(), time.Minute)
+	customer := createCustomer(t, "Maria Silva", "maria@example.com")
+
+	// O primeiro método salvo se torna o padrão, com os dados do cartão copiados do cofre
+	card := saveCard(t, customer.ID, "4111111111111111", "123")
+	assert.Regexp(t, "^pm_[0-9a-f]{24}$", card.ID)
+	assert.Equal(t, models.PaymentMethodCard, card.Type)
+	assert.Equal(t, models.CardBrandVisa, card.CardBrand)
+	assert.Equal(t, []string{"1111", validExpiry()}, []string{card.CardLast4, card.CardExpiry})
+	assert.True(t, card.Default)
+
+	rr := addPaymentMethod(t, customer.ID, `{"type": "paypal_billing_agreement", "billing_agreement_id": "B-7XK12345", "payer_email": "maria@example.com"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), "4111111111111111")
+	var agreement models.PaymentMethod
+	json.NewDecoder(rr.Body).Decode(&agreement)
+	assert.Equal(t, "B-7XK12345", agreement.BillingAgreementID)
+	assert.False(t, agreement.Default)
+
+	// Default torna o novo método o padrão
+	another := tokenizeCard(t, "5555555555554444", "123")
+	rr = addPaymentMethod(t, customer.ID, fmt.Sprintf(`{"type": "card", "card_token": %q, "default": true}`, another.Token))
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var mastercard models.PaymentMethod
+	json.NewDecoder(rr.Body).Decode(&mastercard)
+	assert.True(t, mastercard.Default)
+
+	rr = sendCustomerRequest(t, handlers.ListPaymentMethods, "GET", map[string]string{"id": customer.ID}, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var methods []models.PaymentMethod
+	json.NewDecoder(rr.Body).Decode(&methods)
+	if assert.Len(t, methods, 3) {
+		assert.Equal(t, []string{card.ID, agreement.ID, mastercard.ID}, []string{methods[0].ID, methods[1].ID, methods[2].ID})
+		assert.Equal(t, []bool{false, false, true}, []bool{methods[0].Default, methods[1].Default, methods[2].Default})
+	}
+
+	// O método padrão pode ser alterado pelo cliente
+	rr = sendCustomerRequest(t, handlers.UpdateCustomer, "PUT", map[string]string{"id": customer.ID},
+		fmt.Sprintf(`{"name": "Maria Silva", "email": "maria@example.com", "default_payment_method_id": %q}`, agreement.ID))
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = sendCustomerRequest(t, handlers.GetPaymentMethod, "GET", map[string]string{"id": customer.ID, "payment_method_id": agreement.ID}, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	json.NewDecoder(rr.Body).Decode(&agreement)
+	assert.True(t, agreement.Default)
+
+	// Ao remover o método padrão, o cliente fica sem método padrão
+	rr = sendCustomerRequest(t, handlers.DeletePaymentMethod, "DELETE", map[string]string{"id": customer.ID, "payment_method_id": agreement.ID}, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	stored, _ := services.GetCustomer(customer.ID)
+	assert.Empty(t, stored.DefaultPaymentMethodID)
+
+	// Os métodos de um cliente não são visíveis por outro
+	other := createCustomer(t, "João Souza", "joao@example.com")
+	for _, rr := range []*httptest.ResponseRecorder{
+		sendCustomerRequest(t, handlers.GetPaymentMethod, "GET", map[string]string{"id": other.ID, "payment_method_id": card.ID}, ""),
+		sendCustomerRequest(t, handlers.DeletePaymentMethod, "DELETE", map[string]string{"id": other.ID, "payment_method_id": card.ID}, ""),
+		sendCustomerRequest(t, handlers.GetPaymentMethod, "GET", map[string]string{"id": customer.ID, "payment_method_id": agreement.ID}, ""),
+		sendCustomerRequest(t, handlers.UpdateCustomer, "PUT", map[string]string{"id": other.ID},
+			fmt.Sprintf(`{"name": "João Souza", "email": "joao@example.com", "default_payment_method_id": %q}`, card.ID)),
+	} {
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assertProblem(t, rr, "payment_method_not_found", "Payment method ID not found")
+	}
+
+	// Erros de cadastro
+	rr = addPaymentMethod(t, customer.ID, `{"type": "card", "card_token": "tok_unknown"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "card_token_not_found", "Card token not found")
+
+	rr = addPaymentMethod(t, customer.ID, `{"type": "card", "billing_agreement_id": "B-7XK12345"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed",
+		"Invalid request data: card_token: required_if=Type card, billing_agreement_id: excluded_unless=Type paypal_billing_agreement")
+
+	rr = addPaymentMethod(t, customer.ID, `{"type": "pix"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: type: oneof=card paypal_billing_agreement")
+
+	rr = addPaymentMethod(t, "cus_unknown", `{"type": "paypal_billing_agreement", "billing_agreement_id": "B-7XK12345"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "customer_not_found", "Customer ID not found")
+
+	// A remoção do cliente remove os seus métodos de pagamento
+	rr = sendCustomerRequest(t, handlers.DeleteCustomer, "DELETE", map[string]string{"id": customer.ID}, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	methods, _ = methodRepo.ListByCustomer(customer.ID)
+	assert.Empty(t, methods)
+}
+
+func TestProcessPayment_SavedPaymentMethod(t *testing.T) {
+	setupTransactions(t)
+	transactions := repository.NewMemoryTransactionRepository()
+	services.SetTransactionRepository(transactions)
+	setupCustomers(t)
+	setupVault(t, repository.NewMemoryCardTokenRepository(), time.Minute)
+	gateway := setupRecordingGateway(t)
+	customer := createCustomer(t, "Maria Silva", "maria@example.com")
+
+	// Sem método de pagamento salvo
+	rr := processCustomerPayment(t, "simulator", customer.ID, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assertProblem(t, rr, "no_default_payment_method", "customer has no default payment method")
+
+	visa := saveCard(t, customer.ID, "4111111111111111", "123")
+	amex := saveCard(t, customer.ID, "378282246310005", "1234")
+
+	// Sem payment_method_id, é usado o método padrão (o primeiro salvo)
+	rr = processCustomerPayment(t, "simulator", customer.ID, "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var response models.PaymentResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, customer.ID, response.CustomerID)
+	assert.Equal(t, visa.ID, response.PaymentMethodID)
+	assert.Equal(t, []string{"visa", "1111"}, []string{response.CardBrand, response.CardLast4})
+
+	transaction, err := transactions.Get(response.Transaction_ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{customer.ID, visa.ID}, []string{transaction.CustomerID, transaction.PaymentMethodID})
+	}
+
+	rr = processCustomerPayment(t, "simulator", customer.ID, amex.ID)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, amex.ID, response.PaymentMethodID)
+	assert.Equal(t, []models.CardDetails{
+		{Number: "4111111111111111", Expiry: validExpiry(), CVV: "123"},
+		{Number: "378282246310005", Expiry: validExpiry(), CVV: "1234"},
+	}, gateway.cards)
+
+	// Erros de cliente e de método de pagamento
+	rr = processCustomerPayment(t, "simulator", "cus_unknown", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "customer_not_found", "Customer ID not found")
+
+	other := createCustomer(t, "João Souza", "joao@example.com")
+	rr = processCustomerPayment(t, "simulator", other.ID, visa.ID)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "payment_method_not_found", "Payment method ID not found")
+
+	// customer_id substitui card_details e card_token; payment_method_id exige customer_id
+	rr = sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		fmt.Sprintf(`{"gateway": "simulator", "amount": 10.00, "currency": "USD", "payment_method": "credit_card",
+			"customer_id": %q, "card_token": "tok_1", "card_details": {"number": "4111111111111111", "expiry": "`+validExpiry()+`", "cvv": "123"}}`, customer.ID))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed",
+		"Invalid request data: card_details: excluded_with=CardToken CustomerID, card_token: excluded_with=CustomerID")
+
+	rr = sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		fmt.Sprintf(`{"gateway": "simulator", "amount": 10.00, "currency": "USD", "payment_method": "credit_card", "payment_method_id": %q}`, visa.ID))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed",
+		"Invalid request data: payment_method_id: excluded_without=CustomerID, card_details: required_without_all=card_token customer_id")
+}
+
+func TestProcessPayment_BillingAgreement(t *testing.T) {
+	setupTransactions(t)
+	setupCustomers(t)
+	server := setupPayPal(t)
+	setupStripe(t)
+	customer := createCustomer(t, "Maria Silva", "maria@example.com")
+
+	rr := addPaymentMethod(t, customer.ID, `{"type": "paypal_billing_agreement", "billing_agreement_id": "B-7XK12345"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var agreement models.PaymentMethod
+	json.NewDecoder(rr.Body).Decode(&agreement)
+
+	rr = processCustomerPayment(t, "PayPal", customer.ID, "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var response models.PaymentResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, models.StatusCaptured, response.Status)
+	assert.Equal(t, agreement.ID, response.PaymentMethodID)
+	assert.Empty(t, response.CardBrand)
+	assert.Equal(t, "B-7XK12345", server.LastBillingAgreement())
+
+	// Autorização com o acordo de cobrança
+	body := fmt.Sprintf(`{"gateway": "PayPal", "amount": 10.00, "currency": "USD", "payment_method": "paypal", "customer_id": %q}`, customer.ID)
+	rr = sendRequest(t, handlers.AuthorizePayment, "POST", "/payments/authorize", body)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, models.StatusAuthorized, response.Status)
+
+	// Acordo cancelado pelo pagador
+	rr = addPaymentMethod(t, customer.ID, fmt.Sprintf(`{"type": "paypal_billing_agreement", "billing_agreement_id": %q}`,
+		paypalmock.BillingAgreementCancelled))
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var cancelled models.PaymentMethod
+	json.NewDecoder(rr.Body).Decode(&cancelled)
+	rr = processCustomerPayment(t, "PayPal", customer.ID, cancelled.ID)
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	assertProblem(t, rr, "card_error", "PayPal: The requested agreement is already canceled (AGREEMENT_ALREADY_CANCELLED)")
+
+	// O Stripe não aceita acordos de cobrança do PayPal
+	rr = processCustomerPayment(t, "Stripe", customer.ID, agreement.ID)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "operation_not_supported", services.ErrOperationNotSupported.Error())
+}
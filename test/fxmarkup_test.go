@@ -0,0 +1,161 @@
+// fxmarkup_test.go
+// Este arquivo contém testes para as regras de markup (margem) aplicadas às conversões de moeda.
+// A taxa de câmbio é substituída por GetExchangeRateFunc, e as regras são definidas diretamente em cada teste.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui quatro testes principais:
+// 1. TestConvertCurrency_Markup: Verifica a taxa de mercado, a taxa do cliente e o detalhamento das tarifas, inclusive por faixa de valor.
+// 2. TestConvertCurrency_MarkupSpecificity: Verifica se a regra mais específica (merchant, par de moedas, padrão) é aplicada.
+// 3. TestConvertCurrency_AmountBelowFees: Verifica se um valor que não cobre as tarifas resulta em um erro 400.
+// 4. TestLoadMarkupRules: Verifica a leitura das regras de um arquivo JSON e a rejeição de regras inválidas.
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupMarkupRules define as regras de markup durante o teste.
+func setupMarkupRules(t *testing.T, rules []models.MarkupRule) {
+	if err := services.SetMarkupRules(rules); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { services.SetMarkupRules(nil) })
+}
+
+// convertWithMarkup envia uma solicitação de conversão ao handler e decodifica a resposta.
+func convertWithMarkup(t *testing.T, body string) models.CurrencyConversionResponse {
+	rr := convertCurrency(t, body)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response models.CurrencyConversionResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	return response
+}
+
+func TestConvertCurrency_Markup(t *testing.T) {
+	setupExchangeRate(t, fixedRates(map[string]float64{"USD/BRL": 5.00, "EUR/USD": 1.10}))
+	setupMarkupRules(t, []models.MarkupRule{{
+		FromCurrency: "USD",
+		ToCurrency:   "BRL",
+		Percent:      models.MustParseDecimal("2"),
+		FixedFee:     models.MustParseDecimal("0.30"),
+		Tiers:        []models.MarkupTier{{MinAmount: models.MustParseDecimal("1000"), Percent: models.MustParseDecimal("1.5")}},
+	}})
+
+	// 100 USD: tarifa fixa de 0.30 e 2% de 99.70 (1.99); 97.71 USD convertidos a 5.00
+	response := convertWithMarkup(t, `{"amount": 100.00, "from_currency": "USD", "to_currency": "BRL"}`)
+	assert.Equal(t, "488.55", response.ConvertedAmount.String())
+	assert.Equal(t, 5.00, response.MidMarketRate)
+	assert.InDelta(t, 4.90, response.Rate, 1e-9)
+	if assert.NotNil(t, response.Fees) {
+		assert.Equal(t, "USD", response.Fees.Currency)
+		assert.Equal(t, "2", response.Fees.Percent.String())
+		assert.Equal(t, "1.99", response.Fees.PercentageFee.String())
+		assert.Equal(t, "0.30", response.Fees.FixedFee.String())
+		assert.Equal(t, "2.29", response.Fees.Total.String())
+	}
+
+	// A partir de 1000 USD: 1,5% sem tarifa fixa
+	response = convertWithMarkup(t, `{"amount": 2000.00, "from_currency": "USD", "to_currency": "BRL"}`)
+	assert.Equal(t, "9850.00", response.ConvertedAmount.String())
+	assert.InDelta(t, 4.925, response.Rate, 1e-9)
+	assert.Equal(t, "30.00", response.Fees.Total.String())
+	assert.Equal(t, "0.00", response.Fees.FixedFee.String())
+
+	// Sem regra para o par, a conversão usa a taxa de mercado sem tarifas
+	rr := convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD"}`)
+	assert.NotContains(t, rr.Body.String(), `"fees"`)
+	var plain models.CurrencyConversionResponse
+	json.NewDecoder(rr.Body).Decode(&plain)
+	assert.Equal(t, "110.00", plain.ConvertedAmount.String())
+	assert.Equal(t, 1.10, plain.Rate)
+	assert.Equal(t, 1.10, plain.MidMarketRate)
+}
+
+func TestConvertCurrency_MarkupSpecificity(t *testing.T) {
+	setupExchangeRate(t, fixedRates(map[string]float64{"USD/BRL": 5.00, "USD/EUR": 0.90, "EUR/BRL": 5.50}))
+	setupMarkupRules(t, []models.MarkupRule{
+		{MerchantID: "merchant-123", Percent: models.MustParseDecimal("0.5")},
+		{FromCurrency: "USD", ToCurrency: "BRL", Percent: models.MustParseDecimal("2")},
+		{Percent: models.MustParseDecimal("1")},
+		{ToCurrency: "BRL", Percent: models.MustParseDecimal("3")},
+	})
+
+	tests := []struct {
+		body, converted, percent string
+	}{
+		{`{"amount": 100.00, "from_currency": "USD", "to_currency": "BRL", "merchant_id": "merchant-123"}`, "497.50", "0.5"},
+		{`{"amount": 100.00, "from_currency": "USD", "to_currency": "BRL", "merchant_id": "merchant-999"}`, "490.00", "2"},
+		{`{"amount": 100.00, "from_currency": "USD", "to_currency": "EUR"}`, "89.10", "1"},
+		{`{"amount": 100.00, "from_currency": "EUR", "to_currency": "BRL"}`, "533.50", "3"},
+	}
+	for _, tt := range tests {
+		response := convertWithMarkup(t, tt.body)
+		assert.Equal(t, tt.converted, response.ConvertedAmount.String(), tt.body)
+		if assert.NotNil(t, response.Fees, tt.body) {
+			assert.Equal(t, tt.percent, response.Fees.Percent.String(), tt.body)
+		}
+	}
+}
+
+func TestConvertCurrency_AmountBelowFees(t *testing.T) {
+	setupExchangeRate(t, fixedRates(map[string]float64{"USD/JPY": 150.00}))
+	setupMarkupRules(t, []models.MarkupRule{{FromCurrency: "USD", FixedFee: models.MustParseDecimal("5")}})
+
+	rr := convertCurrency(t, `{"amount": 4.00, "from_currency": "USD", "to_currency": "JPY"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "amount does not cover the conversion fees: 4.00 USD (fees: 5.00 USD)")
+
+	response := convertWithMarkup(t, `{"amount": 10.00, "from_currency": "USD", "to_currency": "JPY"}`)
+	assert.Equal(t, "750", response.ConvertedAmount.String())
+}
+
+func TestLoadMarkupRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "markup.json")
+	os.WriteFile(path, []byte(`[
+		{"percent": 1},
+		{"merchant_id": "merchant-123", "from_currency": "USD", "percent": "0.5", "fixed_fee": 0.30,
+		 "tiers": [{"min_amount": 1000, "percent": 0.25}]}
+	]`), 0o644)
+
+	rules, err := services.LoadMarkupRules(path)
+	assert.NoError(t, err)
+	if assert.Len(t, rules, 2) {
+		assert.Equal(t, "merchant-123", rules[1].MerchantID)
+		assert.Equal(t, "0.30", rules[1].FixedFee.String())
+		assert.Equal(t, "0.25", rules[1].Tier(models.MustParseDecimal("1500")).Percent.String())
+		assert.Equal(t, "0.5", rules[1].Tier(models.MustParseDecimal("999.99")).Percent.String())
+	}
+	assert.NoError(t, services.SetMarkupRules(rules))
+	services.SetMarkupRules(nil)
+
+	// Sem arquivo, não há regras
+	rules, err = services.LoadMarkupRules("")
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	os.WriteFile(path, []byte(`{"percent": 1}`), 0o644)
+	_, err = services.LoadMarkupRules(path)
+	assert.ErrorContains(t, err, "invalid markup rules file")
+
+	invalid := []models.MarkupRule{
+		{Percent: models.MustParseDecimal("100")},
+		{Percent: models.MustParseDecimal("-1")},
+		{FixedFee: models.MustParseDecimal("-0.30")},
+		{FromCurrency: "XYZ"},
+		{Tiers: []models.MarkupTier{{MinAmount: models.MustParseDecimal("-1")}}},
+	}
+	for _, rule := range invalid {
+		assert.ErrorIs(t, services.SetMarkupRules([]models.MarkupRule{rule}), services.ErrInvalidMarkupRule)
+	}
+}
@@ -0,0 +1,209 @@
+// fxquote_test.go
+// Este arquivo contém testes para as cotações de câmbio com taxa travada e seu uso em pagamentos.
+// A taxa de câmbio é substituída por GetExchangeRateFunc, portanto os testes não dependem da API externa.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui seis testes principais:
+// 1. TestFXQuote_LockedRateIsUsed: Verifica se o pagamento usa a taxa travada mesmo após a taxa de mercado mudar.
+// 2. TestFXQuote_SingleUse: Verifica se uma cotação já usada resulta em um erro 409.
+// 3. TestFXQuote_Expired: Verifica se uma cotação expirada resulta em um erro 422.
+// 4. TestFXQuote_Mismatch: Verifica se uma cotação de outra moeda ou valor resulta em um erro 422 sem consumir a cotação.
+// 5. TestFXQuote_ReleasedOnGatewayFailure: Verifica se a cotação volta a ficar disponível quando o gateway recusa o pagamento.
+// 6. TestFXQuote_UnknownQuote: Verifica se uma cotação inexistente resulta em um erro 404.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/mocks/stripemock"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupFXQuotes substitui o repositório de cotações por um repositório em memória com a validade informada.
+func setupFXQuotes(t *testing.T, ttl time.Duration) {
+	services.SetFXQuoteRepository(repository.NewMemoryFXQuoteRepository())
+	services.SetFXQuoteTTL(ttl)
+	t.Cleanup(func() {
+		services.SetFXQuoteRepository(repository.NewMemoryFXQuoteRepository())
+		services.SetFXQuoteTTL(10 * time.Minute)
+	})
+}
+
+// createFXQuote envia uma solicitação de cotação ao handler.
+func createFXQuote(t *testing.T, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/fx/quotes", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.CreateFXQuote).ServeHTTP(rr, req)
+	return rr
+}
+
+// newFXQuote cria uma cotação válida e retorna o quote_id.
+func newFXQuote(t *testing.T, body string) models.FXQuote {
+	rr := createFXQuote(t, body)
+	if !assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String()) {
+		t.FailNow()
+	}
+
+	var quote models.FXQuote
+	if err := json.NewDecoder(rr.Body).Decode(&quote); err != nil {
+		t.Fatal(err)
+	}
+	return quote
+}
+
+// processQuotedPayment envia uma solicitação de pagamento com a cotação informada.
+func processQuotedPayment(t *testing.T, gateway, cardNumber string, amount, currency, quoteID string) *httptest.ResponseRecorder {
+	paymentRequest := models.PaymentRequest{
+		Gateway:       gateway,
+		Amount:        models.MustParseDecimal(amount),
+		Currency:      currency,
+		PaymentMethod: "credit_card",
+		CardDetails: &models.CardDetails{
+			Number: cardNumber,
+			Expiry: validExpiry(),
+			CVV:    "123",
+		},
+		QuoteID: quoteID,
+	}
+	reqBody, _ := json.Marshal(paymentRequest)
+	req, err := http.NewRequest("POST", "/process-payment", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ProcessPayment).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestFXQuote_LockedRateIsUsed(t *testing.T) {
+	setupFXQuotes(t, time.Minute)
+	setupTransactions(t)
+	setupExchangeRate(t, fixedRates(map[string]float64{"EUR/USD": 1.10}))
+
+	quote := newFXQuote(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD"}`)
+	assert.NotEmpty(t, quote.ID)
+	assert.Equal(t, 1.10, quote.Rate)
+	assert.Equal(t, "110.00", quote.ConvertedAmount.String())
+	assert.True(t, quote.ExpiresAt.After(time.Now()))
+
+	// A taxa de mercado muda, mas o pagamento usa a taxa travada
+	setupExchangeRate(t, fixedRates(map[string]float64{"EUR/USD": 1.25}))
+	rr := processQuotedPayment(t, "simulator", "4242424242424242", "100.00", "EUR", quote.ID)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response models.PaymentResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, 1.10, response.ExchangeRate)
+	assert.Equal(t, "110.00", response.SettledAmount.String())
+	assert.Equal(t, quote.ID, response.QuoteID)
+
+	// A cotação fica vinculada à transação
+	req, _ := http.NewRequest("GET", "/fx/quotes/"+quote.ID, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": quote.ID})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handlers.GetFXQuote).ServeHTTP(rr, req)
+	var stored models.FXQuote
+	json.NewDecoder(rr.Body).Decode(&stored)
+	assert.NotNil(t, stored.UsedAt)
+	assert.Equal(t, response.Transaction_ID, stored.TransactionID)
+}
+
+func TestFXQuote_SingleUse(t *testing.T) {
+	setupFXQuotes(t, time.Minute)
+	setupTransactions(t)
+	setupExchangeRate(t, fixedRates(map[string]float64{"EUR/USD": 1.10}))
+
+	quote := newFXQuote(t, `{"from_currency": "EUR", "to_currency": "USD"}`)
+	rr := processQuotedPayment(t, "simulator", "4242424242424242", "50.00", "EUR", quote.ID)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = processQuotedPayment(t, "simulator", "4242424242424242", "50.00", "EUR", quote.ID)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assertProblem(t, rr, "quote_already_used", "fx quote already used")
+}
+
+func TestFXQuote_Expired(t *testing.T) {
+	setupFXQuotes(t, 0)
+	setupTransactions(t)
+	setupExchangeRate(t, fixedRates(map[string]float64{"EUR/USD": 1.10}))
+
+	quote := newFXQuote(t, `{"from_currency": "EUR", "to_currency": "USD"}`)
+	rr := processQuotedPayment(t, "simulator", "4242424242424242", "50.00", "EUR", quote.ID)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assertProblem(t, rr, "quote_expired", "fx quote expired")
+}
+
+func TestFXQuote_Mismatch(t *testing.T) {
+	setupFXQuotes(t, time.Minute)
+	setupTransactions(t)
+	setupExchangeRate(t, fixedRates(map[string]float64{"EUR/USD": 1.10, "EUR/JPY": 160}))
+
+	// Moeda do pagamento diferente da cotação
+	quote := newFXQuote(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD"}`)
+	rr := processQuotedPayment(t, "simulator", "4242424242424242", "100.00", "GBP", quote.ID)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	// Valor diferente do cotado
+	rr = processQuotedPayment(t, "simulator", "4242424242424242", "90.00", "EUR", quote.ID)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "quote is for 100.00 EUR")
+
+	// Moeda de destino que o gateway não liquida
+	jpy := newFXQuote(t, `{"from_currency": "EUR", "to_currency": "JPY"}`)
+	rr = processQuotedPayment(t, "simulator", "4242424242424242", "100.00", "EUR", jpy.ID)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "gateway does not settle in JPY")
+
+	// As tentativas rejeitadas não consomem a cotação
+	rr = processQuotedPayment(t, "simulator", "4242424242424242", "100.00", "EUR", quote.ID)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Moedas iguais ou inválidas na cotação
+	assert.Equal(t, http.StatusBadRequest, createFXQuote(t, `{"from_currency": "EUR", "to_currency": "EUR"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, createFXQuote(t, `{"from_currency": "EUR", "to_currency": "ABC"}`).Code)
+}
+
+func TestFXQuote_ReleasedOnGatewayFailure(t *testing.T) {
+	setupFXQuotes(t, time.Minute)
+	setupTransactions(t)
+	setupStripe(t)
+	setupExchangeRate(t, fixedRates(map[string]float64{"JPY/USD": 0.0067}))
+
+	quote := newFXQuote(t, `{"from_currency": "JPY", "to_currency": "USD"}`)
+	rr := processQuotedPayment(t, "Stripe", stripemock.CardDeclined, "15000", "JPY", quote.ID)
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+
+	rr = processQuotedPayment(t, "Stripe", "4242424242424242", "15000", "JPY", quote.ID)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response models.PaymentResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, "100.50", response.SettledAmount.String())
+	assert.Equal(t, "USD", response.SettledCurrency)
+}
+
+func TestFXQuote_UnknownQuote(t *testing.T) {
+	setupFXQuotes(t, time.Minute)
+	setupTransactions(t)
+
+	rr := processQuotedPayment(t, "simulator", "4242424242424242", "100.00", "EUR", "qt_missing")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "quote_not_found", "Quote ID not found")
+}
@@ -3,10 +3,11 @@
 // Ele verifica se o status de uma transação é corretamente retornado com base no ID da transação e no gateway de pagamento fornecido.
 // Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
 
-// O arquivo inclui três testes principais:
+// O arquivo inclui quatro testes principais:
 // 1. TestGetPaymentStatus_ValidRequest: Verifica se uma solicitação válida retorna o status correto da transação.
 // 2. TestGetPaymentStatus_InvalidRequest_MissingTransactionID: Verifica se a ausência do transaction_id na solicitação resulta em um erro adequado.
 // 3. TestGetPaymentStatus_InvalidRequest_UnsupportedGateway: Verifica se o uso de um gateway não suportado resulta em um erro adequado.
+// 4. TestGetPaymentStatus_UnknownTransaction: Verifica se a consulta de uma transação inexistente resulta em um erro 404.
 
 package handlers_test
 
@@ -18,33 +19,42 @@ import (
 
 	"desafiogolang-payment/handlers"
 	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
 	"desafiogolang-payment/services"
 
 	"github.com/stretchr/testify/assert"
 )
 
-// Mock service response for GetPayPalPaymentStatus
-func mockGetPayPalPaymentStatus(transactionID string) models.TransactionResponse {
-	if transactionID == "valid-id" {
-		return models.TransactionResponse{
-			Message: "Transaction found",
-			Status:  "completed",
+// setupTransactions substitui o repositório de transações por um repositório em memória com as transações informadas.
+// O repositório de reembolsos também é substituído por um repositório em memória vazio.
+func setupTransactions(t *testing.T, transactions ...models.Transaction) {
+	repo := repository.NewMemoryTransactionRepository()
+	for _, transaction := range transactions {
+		if err := repo.Create(transaction); err != nil {
+			t.Fatal(err)
 		}
 	}
-	return models.TransactionResponse{
-		Message: "Transaction ID not found",
-		Status:  "unknown",
-	}
+
+	services.SetTransactionRepository(repo)
+	services.SetRefundRepository(repository.NewMemoryRefundRepository())
+	t.Cleanup(func() {
+		services.SetTransactionRepository(repository.NewMemoryTransactionRepository())
+		services.SetRefundRepository(repository.NewMemoryRefundRepository())
+	})
 }
 
 func TestGetPaymentStatus_ValidRequest(t *testing.T) {
-	// Injeção de dependência da função mock
-	originalFunc := services.GetPayPalPaymentStatusFunc
-	services.GetPayPalPaymentStatusFunc = mockGetPayPalPaymentStatus
-	defer func() { services.GetPayPalPaymentStatusFunc = originalFunc }()
+	// Registra a transação consultada no repositório
+	setupTransactions(t, models.Transaction{
+		Transaction_ID: "valid-id",
+		Gateway:        "simulator",
+		Status:         "captured",
+		Amount:         models.MustParseDecimal("100.00"),
+		Currency:       "USD",
+	})
 
 	// Cria uma solicitação válida
-	req, err := http.NewRequest("GET", "/payment-status?transaction_id=valid-id&gateway=PayPal", nil)
+	req, err := http.NewRequest("GET", "/payment-status?transaction_id=valid-id&gateway=simulator", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -65,8 +75,8 @@ func TestGetPaymentStatus_ValidRequest(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	assert.Equal(t, "Transaction found", response.Message)
-	assert.Equal(t, "completed", response.Status)
+	assert.Equal(t, "Transaction ID: valid-id found", response.Message)
+	assert.Equal(t, "captured", response.Status)
 }
 
 func TestGetPaymentStatus_InvalidRequest_MissingTransactionID(t *testing.T) {
@@ -85,7 +95,7 @@ func TestGetPaymentStatus_InvalidRequest_MissingTransactionID(t *testing.T) {
 
 	// Verifica o status da resposta
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
-	assert.Equal(t, "Transaction ID is required\n", rr.Body.String())
+	assertProblem(t, rr, "transaction_id_required", "Transaction ID is required")
 }
 
 func TestGetPaymentStatus_InvalidRequest_UnsupportedGateway(t *testing.T) {
@@ -104,5 +114,26 @@ func TestGetPaymentStatus_InvalidRequest_UnsupportedGateway(t *testing.T) {
 
 	// Verifica o status da resposta
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
-	assert.Equal(t, "Unsupported gateway\n", rr.Body.String())
+	assertProblem(t, rr, "unsupported_gateway", "Unsupported gateway \"Unknown\", supported gateways: PayPal, Pix, Stripe, simulator")
+}
+
+func TestGetPaymentStatus_UnknownTransaction(t *testing.T) {
+	setupTransactions(t)
+
+	// Cria uma solicitação para uma transação que não existe
+	req, err := http.NewRequest("GET", "/payment-status?transaction_id=unknown-id&gateway=simulator", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cria um ResponseRecorder para capturar a resposta
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(handlers.GetPaymentStatus)
+
+	// Chama o handler
+	handler.ServeHTTP(rr, req)
+
+	// Verifica o status da resposta
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "transaction_not_found", "Transaction ID not found")
 }
@@ -0,0 +1,153 @@
+// idempotency_test.go
+// Este arquivo contém testes para o suporte ao cabeçalho Idempotency-Key no endpoint de pagamentos.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui seis testes principais:
+// 1. TestIdempotency_ReplaysResponse: Verifica se a repetição de uma requisição retorna a mesma resposta sem criar outra transação.
+// 2. TestIdempotency_DifferentBodyIsRejected: Verifica se reutilizar a chave com outro corpo resulta em um erro 422.
+// 3. TestIdempotency_ConcurrentRequestsWait: Verifica se requisições simultâneas com a mesma chave executam o handler uma única vez.
+// 4. TestIdempotency_KeyExpires: Verifica se a chave pode ser reutilizada após o período de retenção.
+// 5. TestIdempotency_UnrecordedPaymentIsReplayed: Verifica se o erro de um pagamento processado pelo gateway, mas não registrado, é reenviado sem nova cobrança.
+// 6. TestIdempotency_BodyTooLarge: Verifica se corpos maiores que o limite são rejeitados.
+
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var simulatorPaymentBody = `{"gateway":"simulator","amount":100,"currency":"USD","payment_method":"credit_card",` +
+	`"card_details":{"number":"4111111111111111","expiry":"` + validExpiry() + `","cvv":"123"}}`
+
+// sendIdempotent envia uma requisição POST ao handler com o Idempotency-Key informado.
+func sendIdempotent(t *testing.T, handler http.HandlerFunc, key, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/process-payment", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Idempotency-Key", key)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	setupTransactions(t)
+	handler := handlers.NewIdempotencyStore(time.Hour).Middleware(handlers.ProcessPayment)
+
+	first := sendIdempotent(t, handler, "key-1", simulatorPaymentBody)
+	second := sendIdempotent(t, handler, "key-1", simulatorPaymentBody)
+
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, first.Code, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+
+	// Uma chave diferente gera uma nova transação
+	third := sendIdempotent(t, handler, "key-2", simulatorPaymentBody)
+	assert.NotEqual(t, first.Body.String(), third.Body.String())
+}
+
+func TestIdempotency_DifferentBodyIsRejected(t *testing.T) {
+	setupTransactions(t)
+	handler := handlers.NewIdempotencyStore(time.Hour).Middleware(handlers.ProcessPayment)
+
+	sendIdempotent(t, handler, "key-1", simulatorPaymentBody)
+	rr := sendIdempotent(t, handler, "key-1", `{"gateway":"simulator","amount":200}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
+
+func TestIdempotency_ConcurrentRequestsWait(t *testing.T) {
+	var calls int32
+	slowHandler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("charged"))
+	}
+	handler := handlers.NewIdempotencyStore(time.Hour).Middleware(slowHandler)
+
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 10)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = sendIdempotent(t, handler, "key-1", "{}")
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, rr := range responses {
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "charged", rr.Body.String())
+	}
+}
+
+func TestIdempotency_KeyExpires(t *testing.T) {
+	var calls int32
+	handler := handlers.NewIdempotencyStore(10 * time.Millisecond).Middleware(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	})
+
+	sendIdempotent(t, handler, "key-1", "{}")
+	time.Sleep(20 * time.Millisecond)
+	sendIdempotent(t, handler, "key-1", `{"other":true}`)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+// failingTransactionRepository simula um repositório indisponível para a criação de transações.
+type failingTransactionRepository struct {
+	*repository.MemoryTransactionRepository
+}
+
+func (r failingTransactionRepository) Create(transaction models.Transaction) error {
+	return errors.New("database is locked")
+}
+
+func TestIdempotency_UnrecordedPaymentIsReplayed(t *testing.T) {
+	setupTransactions(t)
+	services.SetTransactionRepository(failingTransactionRepository{repository.NewMemoryTransactionRepository()})
+	handler := handlers.NewIdempotencyStore(time.Hour).Middleware(handlers.ProcessPayment)
+
+	// O gateway processou o pagamento, mas a transação não pôde ser registrada
+	first := sendIdempotent(t, handler, "key-1", simulatorPaymentBody)
+	assert.Equal(t, http.StatusInternalServerError, first.Code)
+	assert.Contains(t, first.Body.String(), `"code":"transaction_not_recorded"`)
+
+	// A repetição recebe o mesmo erro, com o mesmo ID de transação, sem cobrar novamente
+	second := sendIdempotent(t, handler, "key-1", simulatorPaymentBody)
+	assert.Equal(t, http.StatusInternalServerError, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	var calls int32
+	handler := handlers.NewIdempotencyStore(time.Hour).Middleware(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	})
+
+	rr := sendIdempotent(t, handler, "key-1", `{"padding":"`+strings.Repeat("x", 2<<20)+`"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
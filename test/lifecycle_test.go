@@ -0,0 +1,176 @@
+// lifecycle_test.go
+// Este arquivo contém testes para o ciclo de vida do pagamento: autorização, captura, cancelamento (void) e histórico de status.
+// Os cenários são executados contra o simulador e contra os servidores locais que simulam o Stripe e o PayPal.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui cinco testes principais:
+// 1. TestLifecycle_AuthorizeAndCapture: Verifica se um pagamento autorizado pode ser capturado e se o histórico é retornado pelo /payment-status.
+// 2. TestLifecycle_AuthorizeAndVoid: Verifica se um pagamento autorizado pode ser cancelado.
+// 3. TestLifecycle_IllegalTransition: Verifica se capturar um pagamento cancelado (ou cancelar um capturado) resulta em um erro 409.
+// 4. TestLifecycle_PartialCapture: Verifica a captura parcial e a rejeição de valores acima do autorizado.
+// 5. TestLifecycle_UnknownTransaction: Verifica se capturar uma transação inexistente resulta em um erro 404.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// authorizePayment envia uma solicitação de autorização ao handler para o gateway informado.
+func authorizePayment(t *testing.T, gateway string) models.PaymentResponse {
+	paymentRequest := models.PaymentRequest{
+		Gateway:       gateway,
+		Amount:        models.MustParseDecimal("100.00"),
+		Currency:      "USD",
+		PaymentMethod: "credit_card",
+		CardDetails: &models.CardDetails{
+			Number: "4242424242424242",
+			Expiry: validExpiry(),
+			CVV:    "123",
+		},
+	}
+	reqBody, _ := json.Marshal(paymentRequest)
+	req, err := http.NewRequest("POST", "/payments/authorize", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.AuthorizePayment).ServeHTTP(rr, req)
+	if !assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String()) {
+		t.FailNow()
+	}
+
+	var response models.PaymentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// sendLifecycleAction envia uma solicitação de captura ou cancelamento para a transação informada.
+func sendLifecycleAction(t *testing.T, handler http.HandlerFunc, transactionID, action, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/payments/"+transactionID+"/"+action, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": transactionID})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// lifecycleGateways prepara os gateways que suportam autorização e retorna seus nomes.
+func lifecycleGateways(t *testing.T) []string {
+	setupTransactions(t)
+	setupStripe(t)
+	setupPayPal(t)
+	return []string{"simulator", "Stripe", "PayPal"}
+}
+
+func TestLifecycle_AuthorizeAndCapture(t *testing.T) {
+	for _, gateway := range lifecycleGateways(t) {
+		t.Run(gateway, func(t *testing.T) {
+			authorized := authorizePayment(t, gateway)
+			assert.Equal(t, "authorized", authorized.Status)
+
+			rr := sendLifecycleAction(t, handlers.CapturePayment, authorized.Transaction_ID, "capture", "")
+			assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			var captured models.TransactionResponse
+			json.NewDecoder(rr.Body).Decode(&captured)
+			assert.Equal(t, "captured", captured.Status)
+
+			// O histórico completo é retornado pela consulta de status
+			req, _ := http.NewRequest("GET", "/payment-status?transaction_id="+authorized.Transaction_ID+"&gateway="+gateway, nil)
+			rr = httptest.NewRecorder()
+			http.HandlerFunc(handlers.GetPaymentStatus).ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			var status models.TransactionResponse
+			json.NewDecoder(rr.Body).Decode(&status)
+			assert.Equal(t, "captured", status.Status)
+			assert.Equal(t, authorized.Transaction_ID, status.TransactionID)
+			assert.Equal(t, gateway, status.Gateway)
+			if assert.Len(t, status.History, 3) {
+				assert.Equal(t, "created", status.History[0].To)
+				assert.Equal(t, "authorized", status.History[1].To)
+				assert.Equal(t, "authorized", status.History[2].From)
+				assert.Equal(t, "captured", status.History[2].To)
+			}
+		})
+	}
+}
+
+func TestLifecycle_AuthorizeAndVoid(t *testing.T) {
+	for _, gateway := range lifecycleGateways(t) {
+		t.Run(gateway, func(t *testing.T) {
+			authorized := authorizePayment(t, gateway)
+
+			rr := sendLifecycleAction(t, handlers.VoidPayment, authorized.Transaction_ID, "void", "")
+			assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			var voided models.TransactionResponse
+			json.NewDecoder(rr.Body).Decode(&voided)
+			assert.Equal(t, "voided", voided.Status)
+			if assert.NotEmpty(t, voided.History) {
+				assert.Equal(t, "voided", voided.History[len(voided.History)-1].To)
+			}
+		})
+	}
+}
+
+func TestLifecycle_IllegalTransition(t *testing.T) {
+	for _, gateway := range lifecycleGateways(t) {
+		t.Run(gateway, func(t *testing.T) {
+			// Capturar um pagamento cancelado não é permitido
+			voided := authorizePayment(t, gateway)
+			sendLifecycleAction(t, handlers.VoidPayment, voided.Transaction_ID, "void", "")
+			rr := sendLifecycleAction(t, handlers.CapturePayment, voided.Transaction_ID, "capture", "")
+			assert.Equal(t, http.StatusConflict, rr.Code)
+			assertProblem(t, rr, "invalid_transition", "invalid status transition from voided to captured")
+
+			// Cancelar um pagamento capturado também não é permitido
+			captured := authorizePayment(t, gateway)
+			sendLifecycleAction(t, handlers.CapturePayment, captured.Transaction_ID, "capture", "")
+			rr = sendLifecycleAction(t, handlers.VoidPayment, captured.Transaction_ID, "void", "")
+			assert.Equal(t, http.StatusConflict, rr.Code)
+			assertProblem(t, rr, "invalid_transition", "invalid status transition from captured to voided")
+		})
+	}
+}
+
+func TestLifecycle_PartialCapture(t *testing.T) {
+	for _, gateway := range lifecycleGateways(t) {
+		t.Run(gateway, func(t *testing.T) {
+			authorized := authorizePayment(t, gateway)
+
+			rr := sendLifecycleAction(t, handlers.CapturePayment, authorized.Transaction_ID, "capture", `{"amount": 150.00}`)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			rr = sendLifecycleAction(t, handlers.CapturePayment, authorized.Transaction_ID, "capture", `{"amount": 40.00}`)
+			assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			var captured models.TransactionResponse
+			json.NewDecoder(rr.Body).Decode(&captured)
+			assert.Equal(t, "captured", captured.Status)
+		})
+	}
+}
+
+func TestLifecycle_UnknownTransaction(t *testing.T) {
+	setupTransactions(t)
+
+	rr := sendLifecycleAction(t, handlers.CapturePayment, "missing", "capture", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "transaction_not_found", "Transaction ID not found")
+}
@@ -0,0 +1,206 @@
+// money_test.go
+// Este arquivo contém testes para os valores monetários exatos (models.Decimal e models.Money),
+// incluindo as casas decimais de cada moeda, os modos de arredondamento e a leitura e escrita em JSON.
+// A taxa de câmbio é substituída por GetExchangeRateFunc, portanto os testes não dependem da API externa.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui seis testes principais:
+// 1. TestMoney_MinorUnits: Verifica as casas decimais das moedas (JPY 0, USD 2, KWD 3) e a rejeição de casas decimais a mais.
+// 2. TestMoney_RoundingModes: Verifica o arredondamento das conversões em cada modo de arredondamento.
+// 3. TestDecimal_JSON: Verifica se os valores são lidos e escritos em JSON sem passar por float64.
+// 4. TestConvertCurrency_ExactAmounts: Verifica se a conversão não produz valores como 18.330000000000002 e respeita as casas decimais da moeda de destino.
+// 5. TestProcessPayment_AmountPrecision: Verifica se um pagamento com mais casas decimais do que a moeda permite resulta em um erro 400.
+// 6. TestMoney_ConvertedAmountOutOfRange: Verifica se conversões cujo resultado não cabe na unidade menor da moeda resultam em erro (422 na API).
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMoney_MinorUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		minor    int64
+		display  string
+	}{
+		{"1500", "JPY", 1500, "1500 JPY"},
+		{"18.3", "USD", 1830, "18.30 USD"},
+		{"10.500", "USD", 1050, "10.50 USD"},
+		{"1.234", "KWD", 1234, "1.234 KWD"},
+		{"2", "KWD", 2000, "2.000 KWD"},
+	}
+	for _, tt := range tests {
+		money, err := models.NewMoney(models.MustParseDecimal(tt.amount), tt.currency)
+		if assert.NoError(t, err, tt.amount+" "+tt.currency) {
+			assert.Equal(t, tt.minor, money.Minor)
+			assert.Equal(t, tt.display, money.String())
+		}
+	}
+
+	// Mais casas decimais do que a moeda permite
+	for _, invalid := range []struct{ amount, currency string }{{"10.5", "JPY"}, {"10.001", "USD"}, {"1.2345", "KWD"}} {
+		_, err := models.NewMoney(models.MustParseDecimal(invalid.amount), invalid.currency)
+		var precisionErr *models.AmountPrecisionError
+		assert.ErrorAs(t, err, &precisionErr, invalid.amount+" "+invalid.currency)
+	}
+}
+
+func TestMoney_RoundingModes(t *testing.T) {
+	// 1.00 USD * 0.125 = 0.125 USD e 1.00 USD * 0.135 = 0.135 USD: empates entre dois centavos
+	one := models.Money{Minor: 100, Currency: "USD"}
+	negative := models.Money{Minor: -100, Currency: "USD"}
+
+	tests := []struct {
+		mode     models.RoundingMode
+		even     string // 0.125
+		odd      string // 0.135
+		negative string // -0.125
+		above    string // 0.1251
+	}{
+		{models.RoundHalfEven, "0.12", "0.14", "-0.12", "0.13"},
+		{models.RoundHalfUp, "0.13", "0.14", "-0.13", "0.13"},
+		{models.RoundHalfDown, "0.12", "0.13", "-0.12", "0.13"},
+		{models.RoundUp, "0.13", "0.14", "-0.13", "0.13"},
+		{models.RoundDown, "0.12", "0.13", "-0.12", "0.12"},
+		{models.RoundCeiling, "0.13", "0.14", "-0.12", "0.13"},
+		{models.RoundFloor, "0.12", "0.13", "-0.13", "0.12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			for rate, expected := range map[float64]string{0.125: tt.even, 0.135: tt.odd, 0.1251: tt.above} {
+				converted, err := one.Convert(rate, "USD", tt.mode)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, converted.Amount().String())
+			}
+			converted, err := negative.Convert(0.125, "USD", tt.mode)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.negative, converted.Amount().String())
+
+			parsed, err := models.ParseRoundingMode(tt.mode.String())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.mode, parsed)
+		})
+	}
+
+	// O valor zero é o arredondamento bancário
+	var mode models.RoundingMode
+	assert.Equal(t, models.RoundHalfEven, mode)
+	_, err := models.ParseRoundingMode("nearest")
+	assert.Error(t, err)
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	var request models.CaptureRequest
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": 18.33}`), &request))
+	assert.Equal(t, "18.33", request.Amount.String())
+
+	// Números entre aspas e com expoente também são aceitos
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": "0.10"}`), &request))
+	assert.Equal(t, "0.10", request.Amount.String())
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": 1.5e2}`), &request))
+	assert.Equal(t, "150", request.Amount.String())
+
+	for _, invalid := range []string{`{"amount": "abc"}`, `{"amount": "1/3"}`, `{"amount": 99999999999999999999}`, `{"amount": true}`} {
+		assert.Error(t, json.Unmarshal([]byte(invalid), &request), invalid)
+	}
+
+	// A escrita mantém as casas decimais, sem aspas
+	body, err := json.Marshal(models.CurrencyConversionResponse{ConvertedAmount: models.MustParseDecimal("18.30")})
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"converted_amount":18.30`)
+}
+
+func TestConvertCurrency_ExactAmounts(t *testing.T) {
+	// 100 * 0.1833 em float64 resulta em 18.330000000000002
+	setupExchangeRate(t, fixedRates(map[string]float64{"BRL/USD": 0.1833, "USD/JPY": 149.837, "USD/KWD": 0.30745}))
+
+	convert := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/convert-currency", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(handlers.ConvertCurrency).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := convert(`{"amount": 100.00, "from_currency": "BRL", "to_currency": "USD"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"converted_amount":18.33,`)
+
+	// JPY não tem casas decimais e KWD tem três
+	rr = convert(`{"amount": 10.01, "from_currency": "USD", "to_currency": "JPY"}`)
+	assert.Contains(t, rr.Body.String(), `"converted_amount":1500,`)
+	rr = convert(`{"amount": 10.01, "from_currency": "USD", "to_currency": "KWD"}`)
+	assert.Contains(t, rr.Body.String(), `"converted_amount":3.078,`)
+
+	// O modo de arredondamento é configurável
+	services.SetRoundingMode(models.RoundDown)
+	t.Cleanup(func() { services.SetRoundingMode(models.RoundHalfEven) })
+	rr = convert(`{"amount": 10.01, "from_currency": "USD", "to_currency": "JPY"}`)
+	assert.Contains(t, rr.Body.String(), `"converted_amount":1499,`)
+
+	// Mais casas decimais do que a moeda de origem permite
+	rr = convert(`{"amount": 10.001, "from_currency": "USD", "to_currency": "JPY"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "amount_precision", "amount 10.001 has more decimal places than USD allows (2)")
+}
+
+func TestProcessPayment_AmountPrecision(t *testing.T) {
+	setupTransactions(t)
+	setupExchangeRate(t, fixedRates(map[string]float64{"JPY/USD": 0.0067}))
+
+	rr := processPaymentIn(t, "simulator", "1500.5", "JPY")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "more decimal places than JPY allows (0)")
+
+	// Sem casas decimais o pagamento é convertido e liquidado em centavos exatos
+	rr = processPaymentIn(t, "simulator", "1500", "JPY")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"original_amount":1500,`)
+	assert.Contains(t, rr.Body.String(), `"settled_amount":10.05,`)
+}
+
+func TestMoney_ConvertedAmountOutOfRange(t *testing.T) {
+	setupTransactions(t)
+	setupFXQuotes(t, 10*time.Minute)
+	// A taxa EUR/USD é fictícia, apenas para que o valor liquidado em USD ultrapasse o limite
+	setupExchangeRate(t, fixedRates(map[string]float64{"USD/IDR": 16250, "EUR/USD": 1000000}))
+
+	// 10^15 USD cabem em centavos (int64), mas não em IDR
+	amount := models.Money{Minor: 100000000000000000, Currency: "USD"}
+	_, err := amount.Convert(16250, "IDR", models.RoundHalfEven)
+	assert.ErrorIs(t, err, models.ErrAmountOutOfRange)
+	assert.ErrorIs(t, err, models.ErrConvertedAmountOutOfRange)
+	_, err = amount.Percentage(models.MustParseDecimal("10000"), models.RoundHalfEven)
+	assert.ErrorIs(t, err, models.ErrConvertedAmountOutOfRange)
+	_, err = models.MustParseDecimal("100000000000000000").Money("USD")
+	assert.ErrorIs(t, err, models.ErrConvertedAmountOutOfRange)
+
+	req, _ := http.NewRequest("POST", "/convert-currency",
+		bytes.NewBufferString(`{"amount": 1000000000000000, "from_currency": "USD", "to_currency": "IDR"}`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ConvertCurrency).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	assertProblem(t, rr, "amount_out_of_range", "converted amount out of range: 16250000000000000000.00 IDR")
+
+	rr = createFXQuote(t, `{"amount": 1000000000000000, "from_currency": "USD", "to_currency": "IDR"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+
+	rr = processPaymentIn(t, "simulator", "100000000000000", "EUR")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"code":"amount_out_of_range"`)
+}
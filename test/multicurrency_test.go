@@ -0,0 +1,131 @@
+// multicurrency_test.go
+// Este arquivo contém testes para os pagamentos multimoeda, com conversão automática para a moeda de liquidação do gateway.
+// A taxa de câmbio é substituída por GetExchangeRateFunc, portanto os testes não dependem da API externa.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui quatro testes principais:
+// 1. TestMultiCurrency_ConvertedToSettlementCurrency: Verifica se um pagamento em moeda não liquidada pelo gateway é convertido e registrado com os valores original e liquidado.
+// 2. TestMultiCurrency_SettledInPresentmentCurrency: Verifica se um pagamento em moeda liquidada pelo gateway não é convertido.
+// 3. TestMultiCurrency_InvalidCurrency: Verifica se um código de moeda fora da ISO 4217 resulta em um erro 400.
+// 4. TestMultiCurrency_ExchangeRateUnavailable: Verifica se uma falha na consulta da taxa de câmbio resulta em um erro 502.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupExchangeRate substitui a consulta da taxa de câmbio pela função informada.
+func setupExchangeRate(t *testing.T, rate func(fromCurrency, toCurrency string) (float64, error)) {
+	original := services.GetExchangeRateFunc
+	services.GetExchangeRateFunc = rate
+	t.Cleanup(func() { services.GetExchangeRateFunc = original })
+}
+
+// fixedRates retorna uma função de taxa de câmbio com as taxas informadas no formato "EUR/USD".
+func fixedRates(rates map[string]float64) func(fromCurrency, toCurrency string) (float64, error) {
+	return func(fromCurrency, toCurrency string) (float64, error) {
+		if rate, ok := rates[fromCurrency+"/"+toCurrency]; ok {
+			return rate, nil
+		}
+		return 0, fmt.Errorf("currency not found")
+	}
+}
+
+// processPaymentIn envia uma solicitação de pagamento ao handler no gateway e na moeda informados.
+func processPaymentIn(t *testing.T, gateway, amount, currency string) *httptest.ResponseRecorder {
+	paymentRequest := models.PaymentRequest{
+		Gateway:       gateway,
+		Amount:        models.MustParseDecimal(amount),
+		Currency:      currency,
+		PaymentMethod: "credit_card",
+		CardDetails: &models.CardDetails{
+			Number: "4242424242424242",
+			Expiry: validExpiry(),
+			CVV:    "123",
+		},
+	}
+	reqBody, _ := json.Marshal(paymentRequest)
+	req, err := http.NewRequest("POST", "/process-payment", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ProcessPayment).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestMultiCurrency_ConvertedToSettlementCurrency(t *testing.T) {
+	setupExchangeRate(t, fixedRates(map[string]float64{"EUR/USD": 1.0845}))
+	repo := repository.NewMemoryTransactionRepository()
+	services.SetTransactionRepository(repo)
+	t.Cleanup(func() { services.SetTransactionRepository(repository.NewMemoryTransactionRepository()) })
+
+	// O simulador liquida apenas em USD
+	rr := processPaymentIn(t, "simulator", "100.00", "EUR")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response models.PaymentResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, "100.00", response.OriginalAmount.String())
+	assert.Equal(t, "EUR", response.OriginalCurrency)
+	assert.Equal(t, "108.45", response.SettledAmount.String())
+	assert.Equal(t, "USD", response.SettledCurrency)
+	assert.Equal(t, 1.0845, response.ExchangeRate)
+
+	stored, err := repo.Get(response.Transaction_ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "108.45", stored.Amount.String())
+	assert.Equal(t, "USD", stored.Currency)
+	assert.Equal(t, "100.00", stored.OriginalAmount.String())
+	assert.Equal(t, "EUR", stored.OriginalCurrency)
+	assert.Equal(t, 1.0845, stored.ExchangeRate)
+}
+
+func TestMultiCurrency_SettledInPresentmentCurrency(t *testing.T) {
+	setupExchangeRate(t, func(fromCurrency, toCurrency string) (float64, error) {
+		t.Errorf("unexpected exchange rate lookup %s/%s", fromCurrency, toCurrency)
+		return 0, fmt.Errorf("unexpected lookup")
+	})
+	setupTransactions(t)
+	setupStripe(t)
+	gateway, _ := services.GetGateway("Stripe")
+	gateway.(*services.StripeGateway).SettlementCurrencies = []string{"USD", "EUR"}
+
+	rr := processPaymentIn(t, "Stripe", "100.00", "EUR")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response models.PaymentResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, "100.00", response.SettledAmount.String())
+	assert.Equal(t, "EUR", response.SettledCurrency)
+	assert.Equal(t, 1.00, response.ExchangeRate)
+}
+
+func TestMultiCurrency_InvalidCurrency(t *testing.T) {
+	rr := processPaymentIn(t, "simulator", "100.00", "ABC")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: currency: iso4217")
+}
+
+func TestMultiCurrency_ExchangeRateUnavailable(t *testing.T) {
+	setupExchangeRate(t, fixedRates(nil))
+	setupTransactions(t)
+
+	rr := processPaymentIn(t, "simulator", "100.00", "JPY")
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.Contains(t, rr.Body.String(), "exchange rate unavailable: JPY to USD")
+}
@@ -0,0 +1,175 @@
+// paypal_test.go
+// Este arquivo contém testes para o gateway PayPal, executados contra o servidor local que simula a API do PayPal (mocks/paypalmock).
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui cinco testes principais:
+// 1. TestPayPal_ProcessPaymentAndStatus: Verifica se um pagamento aprovado pode ser consultado e se o token OAuth2 é reutilizado.
+// 2. TestPayPal_RefusedCard: Verifica se um cartão recusado resulta em um erro 402.
+// 3. TestPayPal_PendingPaymentIsPolled: Verifica se um pagamento pendente é consultado novamente até ser aprovado.
+// 4. TestPayPal_ExpiredTokenIsRenewed: Verifica se um token rejeitado é renovado automaticamente.
+// 5. TestPayPal_CardType: Verifica se a bandeira enviada ao PayPal é a mesma registrada na transação e se Elo e Hipercard são recusados.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/mocks/paypalmock"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupPayPal inicia o servidor que simula o PayPal e registra o gateway apontando para ele.
+func setupPayPal(t *testing.T) *paypalmock.Server {
+	server := paypalmock.NewServer("client-id", "client-secret")
+	original, _ := services.GetGateway("PayPal")
+
+	gateway := services.NewPayPalGateway(server.URL, "client-id", "client-secret")
+	gateway.PollInterval = 0
+	services.RegisterGateway(gateway)
+
+	t.Cleanup(func() {
+		services.RegisterGateway(original)
+		server.Close()
+	})
+	return server
+}
+
+// processPayPalPayment envia uma solicitação de pagamento ao handler com o número de cartão informado.
+func processPayPalPayment(t *testing.T, cardNumber string) *httptest.ResponseRecorder {
+	paymentRequest := models.PaymentRequest{
+		Gateway:       "PayPal",
+		Amount:        models.MustParseDecimal("100.00"),
+		Currency:      "USD",
+		PaymentMethod: "credit_card",
+		CardDetails: &models.CardDetails{
+			Number: cardNumber,
+			Expiry: validExpiry(),
+			CVV:    "123",
+		},
+	}
+	reqBody, _ := json.Marshal(paymentRequest)
+	req, err := http.NewRequest("POST", "/process-payment", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ProcessPayment).ServeHTTP(rr, req)
+	return rr
+}
+
+// getPayPalStatus consulta o status de uma transação do PayPal pelo handler.
+func getPayPalStatus(t *testing.T, transactionID string) models.TransactionResponse {
+	req, err := http.NewRequest("GET", "/payment-status?transaction_id="+transactionID+"&gateway=PayPal", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.GetPaymentStatus).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response models.TransactionResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestPayPal_ProcessPaymentAndStatus(t *testing.T) {
+	server := setupPayPal(t)
+
+	rr := processPayPalPayment(t, "4111111111111111")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response models.PaymentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Regexp(t, "^PAY-", response.Transaction_ID)
+	assert.Equal(t, "captured", response.Status)
+
+	status := getPayPalStatus(t, response.Transaction_ID)
+	assert.Equal(t, "captured", status.Status)
+
+	// O token obtido no pagamento é reutilizado na consulta
+	assert.Equal(t, 1, server.TokenRequests())
+}
+
+func TestPayPal_RefusedCard(t *testing.T) {
+	setupPayPal(t)
+
+	rr := processPayPalPayment(t, paypalmock.CardRefused)
+
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	assertProblem(t, rr, "card_error", "PayPal: Credit card was refused (CREDIT_CARD_REFUSED)")
+}
+
+func TestPayPal_PendingPaymentIsPolled(t *testing.T) {
+	server := setupPayPal(t)
+	server.PendingLookups = 2
+
+	rr := processPayPalPayment(t, paypalmock.CardPending)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response models.PaymentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "captured", response.Status)
+}
+
+func TestPayPal_ExpiredTokenIsRenewed(t *testing.T) {
+	server := setupPayPal(t)
+
+	rr := processPayPalPayment(t, "4111111111111111")
+	var response models.PaymentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalida o token em cache no gateway
+	server.RevokeTokens()
+
+	status := getPayPalStatus(t, response.Transaction_ID)
+	assert.Equal(t, "captured", status.Status)
+	assert.Equal(t, 2, server.TokenRequests())
+}
+
+func TestPayPal_CardType(t *testing.T) {
+	server := setupPayPal(t)
+	setupTransactions(t)
+
+	cards := []struct{ number, cardType string }{
+		{"4111111111111111", "visa"},
+		{"2223003122003222", "mastercard"},
+		{"6011111111111117", "discover"},
+	}
+	for _, card := range cards {
+		rr := processPayPalPayment(t, card.number)
+		if !assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String()) {
+			continue
+		}
+		assert.Equal(t, card.cardType, server.LastCardType(), card.number)
+
+		var response models.PaymentResponse
+		json.NewDecoder(rr.Body).Decode(&response)
+		assert.Equal(t, card.cardType, response.CardBrand, card.number)
+	}
+
+	// Elo (inclusive na faixa de Visa) e Hipercard não são aceitos pelo PayPal
+	for _, number := range []string{"4389350000000002", "6362970000457013", "6062825624254001"} {
+		rr := processPayPalPayment(t, number)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, number)
+		assert.Contains(t, rr.Body.String(), `"code":"operation_not_supported"`, number)
+	}
+	assert.Equal(t, "discover", server.LastCardType())
+}
@@ -0,0 +1,379 @@
+// pix_test.go
+// Este arquivo contém testes para os pagamentos Pix, executados contra o servidor local que simula o PSP (mocks/pixmock),
+// e para a geração do BR Code ("Pix copia e cola").
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui seis testes principais:
+// 1. TestBRCode_Payload: Verifica o BR Code estático do exemplo do Manual do Pix, o CRC16, a decodificação dos campos
+//    e a conversão para ASCII do nome e da cidade do recebedor.
+// 2. TestPix_ProcessPaymentAndWebhook: Verifica se o pagamento Pix fica pendente com o QR Code e é concluído pela notificação do PSP.
+// 3. TestPix_Validation: Verifica a validação do método de pagamento e os gateways que não aceitam Pix.
+// 4. TestPix_WebhookRejections: Verifica a recusa de notificações sem assinatura válida ou com valor divergente.
+// 5. TestPix_LatePayment: Verifica se um Pix pago depois da expiração é confirmado e leva a transação para requires_review,
+//    inclusive após a consulta de status marcá-la como failed, e se a notificação repetida é ignorada.
+// 6. TestPix_DuplicatePayment: Verifica se outro Pix para uma cobrança já paga é registrado e leva a transação para requires_review.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/mocks/pixmock"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pixWebhookSecret é o segredo compartilhado entre o PSP simulado e o webhook nos testes.
+const pixWebhookSecret = "pix_webhook_secret"
+
+// setupPix inicia o servidor que simula o PSP, registra o gateway Pix apontando para ele
+// e expõe o webhook, para onde o PSP envia as notificações de pagamento.
+func setupPix(t *testing.T) *pixmock.Server {
+	setupTransactions(t)
+
+	server := pixmock.NewServer("pix_token", "pix@example.com")
+	original, _ := services.GetGateway("Pix")
+	services.RegisterGateway(services.NewPixGateway(server.URL, "pix_token", "pix@example.com"))
+	services.SetPixWebhookSecret(pixWebhookSecret)
+
+	webhook := httptest.NewServer(http.HandlerFunc(handlers.PixWebhook))
+	server.WebhookURL = webhook.URL
+	server.WebhookSecret = pixWebhookSecret
+
+	t.Cleanup(func() {
+		services.RegisterGateway(original)
+		services.SetPixWebhookSecret("")
+		webhook.Close()
+		server.Close()
+	})
+	return server
+}
+
+// processPixPayment envia uma solicitação de pagamento Pix ao handler.
+func processPixPayment(t *testing.T, amount string) models.PaymentResponse {
+	rr := sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		fmt.Sprintf(`{"gateway": "Pix", "amount": %s, "currency": "BRL", "payment_method": "pix"}`, amount))
+	if !assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String()) {
+		t.FailNow()
+	}
+
+	var response models.PaymentResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// getPixStatus consulta o status de uma transação Pix pelo handler.
+func getPixStatus(t *testing.T, transactionID string) models.TransactionResponse {
+	rr := sendRequest(t, handlers.GetPaymentStatus, "GET", "/payment-status?transaction_id="+transactionID+"&gateway=Pix", "")
+	if !assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String()) {
+		t.FailNow()
+	}
+
+	var response models.TransactionResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// sendPixNotification envia ao webhook uma notificação assinada com o segredo informado.
+func sendPixNotification(t *testing.T, secret string, payment pixmock.Pix) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(pixmock.Notification{Pix: []pixmock.Pix{payment}})
+	req, err := http.NewRequest("POST", "/webhooks/pix", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Pix-Signature", pixmock.Sign(secret, body))
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.PixWebhook).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestBRCode_Payload(t *testing.T) {
+	// Exemplo de QR Code estático do Manual de Padrões para Iniciação do Pix
+	payload, err := models.BRCode{
+		PixKey:       "123e4567-e12b-12d1-a456-426655440000",
+		MerchantName: "Fulano de Tal",
+		MerchantCity: "BRASILIA",
+	}.Payload()
+	assert.NoError(t, err)
+	assert.Equal(t, "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR"+
+		"5913Fulano de Tal6008BRASILIA62070503***63041D3D", payload)
+	assert.Equal(t, "1D3D", models.CRC16(strings.TrimSuffix(payload, "1D3D")))
+
+	// QR Code dinâmico: a URL de payload substitui a chave e o nome e a cidade são truncados
+	payload, err = models.BRCode{
+		Location:     "pix.example.com/qr/v2/9d36b84fc70b478fb95c12729b90ca25",
+		MerchantName: "Desafio Golang Payment Comércio Eletrônico",
+		MerchantCity: "Sao Jose dos Campos",
+		Amount:       models.MustParseDecimal("10.5"),
+		TxID:         "ignored",
+	}.Payload()
+	assert.NoError(t, err)
+
+	fields, err := models.ParseBRCode(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "12", fields["01"])
+	assert.Equal(t, "986", fields["53"])
+	assert.Equal(t, "10.50", fields["54"])
+	assert.Equal(t, "Desafio Golang Payment Co", fields["59"])
+	assert.Equal(t, "Sao Jose dos Ca", fields["60"])
+
+	// Os acentos são removidos, e os tamanhos dos campos, contados em bytes, respeitam os limites do padrão
+	accented, err := models.BRCode{
+		Location:     "pix.example.com/qr/v2/9d36b84fc70b478fb95c12729b90ca25",
+		MerchantName: "São João Padaria e Confeitaria",
+		MerchantCity: "São João del-Rei",
+	}.Payload()
+	assert.NoError(t, err)
+	merchant, err := models.ParseBRCode(accented)
+	assert.NoError(t, err)
+	assert.Equal(t, "Sao Joao Padaria e Confei", merchant["59"])
+	assert.Equal(t, "Sao Joao del-Re", merchant["60"])
+	account, _ := models.ParseTLV(fields["26"])
+	assert.Equal(t, map[string]string{"00": "br.gov.bcb.pix", "25": "pix.example.com/qr/v2/9d36b84fc70b478fb95c12729b90ca25"}, account)
+	additional, _ := models.ParseTLV(fields["62"])
+	assert.Equal(t, "***", additional["05"])
+
+	// Um payload alterado não passa na verificação do CRC16
+	_, err = models.ParseBRCode(strings.Replace(payload, "10.50", "10.00", 1))
+	assert.Error(t, err)
+
+	_, err = models.BRCode{MerchantName: "Fulano de Tal", MerchantCity: "BRASILIA"}.Payload()
+	assert.EqualError(t, err, "BR Code requires either a Pix key or a location")
+}
+
+func TestPix_ProcessPaymentAndWebhook(t *testing.T) {
+	server := setupPix(t)
+
+	response := processPixPayment(t, "100")
+	assert.Equal(t, models.StatusPending, response.Status)
+	assert.Equal(t, "Pix charge created, waiting for payment", response.Message)
+	assert.Equal(t, "100.00", response.SettledAmount.String())
+	assert.Equal(t, "BRL", response.SettledCurrency)
+	if !assert.NotNil(t, response.Pix) {
+		return
+	}
+	assert.Equal(t, response.Transaction_ID, response.Pix.TxID)
+	assert.WithinDuration(t, time.Now().Add(30*time.Minute), response.Pix.ExpiresAt, time.Minute)
+
+	// O BR Code é dinâmico, com a URL de payload da cobrança e o valor
+	fields, err := models.ParseBRCode(response.Pix.QRCode)
+	assert.NoError(t, err)
+	assert.Equal(t, "100.00", fields["54"])
+	account, _ := models.ParseTLV(fields["26"])
+	assert.Contains(t, account["25"], "/qr/v2/")
+
+	image, err := base64.StdEncoding.DecodeString(response.Pix.QRCodeImage)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(image, []byte("\x89PNG\r\n\x1a\n")), "QR code image must be a PNG")
+
+	status := getPixStatus(t, response.Transaction_ID)
+	assert.Equal(t, models.StatusPending, status.Status)
+
+	// O pagamento pelo pagador gera a notificação do PSP, que conclui a transação
+	pix, err := server.Pay(response.Transaction_ID)
+	assert.NoError(t, err)
+
+	status = getPixStatus(t, response.Transaction_ID)
+	assert.Equal(t, models.StatusCaptured, status.Status)
+	last := status.History[len(status.History)-1]
+	assert.Equal(t, "Pix payment received ("+pix.EndToEndID+")", last.Reason)
+
+	// Notificações repetidas são ignoradas
+	rr := sendPixNotification(t, pixWebhookSecret, pix)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Len(t, getPixStatus(t, response.Transaction_ID).History, len(status.History))
+}
+
+func TestPix_Validation(t *testing.T) {
+	setupPix(t)
+
+	rr := sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		`{"gateway": "Pix", "amount": 10, "currency": "BRL", "payment_method": "boleto"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: payment_method: oneof=credit_card paypal pix, card_details: required_without_all=card_token customer_id")
+
+	rr = sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		`{"gateway": "Pix", "amount": 10, "currency": "BRL", "payment_method": "pix",
+		  "card_details": {"number": "4111111111111111", "expiry": "`+validExpiry()+`", "cvv": "123"}}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: card_details: excluded_if=PaymentMethod pix")
+
+	// Os gateways de cartão não aceitam Pix, e o gateway Pix não aceita cartões
+	rr = sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		`{"gateway": "simulator", "amount": 10, "currency": "USD", "payment_method": "pix"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "unsupported_payment_method",
+		`Gateway simulator does not support payment method "pix", supported payment methods: credit_card, paypal`)
+
+	rr = sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		`{"gateway": "Pix", "amount": 10, "currency": "BRL", "payment_method": "credit_card",
+		  "card_details": {"number": "4111111111111111", "expiry": "`+validExpiry()+`", "cvv": "123"}}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "unsupported_payment_method",
+		`Gateway Pix does not support payment method "credit_card", supported payment methods: pix`)
+
+	// Pix não tem autorização e captura em etapas
+	rr = sendRequest(t, handlers.AuthorizePayment, "POST", "/payments/authorize",
+		`{"gateway": "Pix", "amount": 10, "currency": "BRL", "payment_method": "pix"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "operation_not_supported", "operation not supported by gateway")
+}
+
+func TestPix_WebhookRejections(t *testing.T) {
+	server := setupPix(t)
+	response := processPixPayment(t, "25.90")
+
+	payment := pixmock.Pix{
+		EndToEndID: "E12345678202610181200abcdefghijk",
+		TxID:       response.Transaction_ID,
+		Amount:     "25.90",
+		PaidAt:     time.Now().UTC(),
+	}
+
+	// Assinatura inválida
+	rr := sendPixNotification(t, "wrong_secret", payment)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assertProblem(t, rr, "invalid_signature", "invalid Pix webhook signature")
+
+	// Valor divergente
+	wrongAmount := payment
+	wrongAmount.Amount = "25.00"
+	rr = sendPixNotification(t, pixWebhookSecret, wrongAmount)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assertProblem(t, rr, "pix_amount_mismatch", "Pix amount does not match the charge amount: received 25.00, expected 25.90")
+
+	// Cobrança inexistente
+	unknown := payment
+	unknown.TxID = "pix0000000000000000000000000000"
+	rr = sendPixNotification(t, pixWebhookSecret, unknown)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "transaction_not_found", "Transaction ID not found")
+	assert.Equal(t, models.StatusPending, getPixStatus(t, response.Transaction_ID).Status)
+
+	// Cobrança expirada no PSP: a consulta de status leva a transação para failed e ela não pode mais ser paga
+	expired := processPixPayment(t, "10")
+	server.Expire(expired.Transaction_ID)
+	assert.Equal(t, models.StatusFailed, getPixStatus(t, expired.Transaction_ID).Status)
+	_, err := server.Pay(expired.Transaction_ID)
+	assert.Error(t, err)
+}
+
+func TestPix_LatePayment(t *testing.T) {
+	server := setupPix(t)
+	repo := repository.NewMemoryTransactionRepository()
+	services.SetTransactionRepository(repo)
+	response := processPixPayment(t, "25.90")
+
+	late := pixmock.Pix{
+		EndToEndID: "E12345678202610181200latepayment",
+		TxID:       response.Transaction_ID,
+		Amount:     "25.90",
+		PaidAt:     response.Pix.ExpiresAt.Add(time.Second),
+	}
+
+	// O valor foi recebido: a notificação é confirmada e a transação aguarda a devolução
+	rr := sendPixNotification(t, pixWebhookSecret, late)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var responses []models.TransactionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &responses))
+	if assert.Len(t, responses, 1) {
+		assert.Equal(t, models.StatusRequiresReview, responses[0].Status)
+		assert.Equal(t, "Pix payment received after the charge expired, refund required", responses[0].Message)
+	}
+
+	transaction, err := repo.Get(response.Transaction_ID)
+	assert.NoError(t, err)
+	assert.Equal(t, late.EndToEndID, transaction.PixEndToEndID)
+	history := getPixStatus(t, response.Transaction_ID).History
+	last := history[len(history)-1]
+	assert.Equal(t, models.StatusRequiresReview, last.To)
+	assert.Contains(t, last.Reason, late.EndToEndID)
+
+	// O PSP reenvia a mesma notificação: ela é confirmada de novo sem alterar a transação
+	rr = sendPixNotification(t, pixWebhookSecret, late)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	status := getPixStatus(t, response.Transaction_ID)
+	assert.Equal(t, models.StatusRequiresReview, status.Status)
+	assert.Equal(t, history, status.History)
+
+	// A consulta de status já levou a transação para failed quando o Pix chega
+	expired := processPixPayment(t, "10")
+	server.Expire(expired.Transaction_ID)
+	assert.Equal(t, models.StatusFailed, getPixStatus(t, expired.Transaction_ID).Status)
+
+	rr = sendPixNotification(t, pixWebhookSecret, pixmock.Pix{
+		EndToEndID: "E12345678202610181200expiredpaid",
+		TxID:       expired.Transaction_ID,
+		Amount:     "10.00",
+		PaidAt:     time.Now().UTC(),
+	})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, models.StatusRequiresReview, getPixStatus(t, expired.Transaction_ID).Status)
+}
+
+func TestPix_DuplicatePayment(t *testing.T) {
+	server := setupPix(t)
+	repo := repository.NewMemoryTransactionRepository()
+	services.SetTransactionRepository(repo)
+	response := processPixPayment(t, "25.90")
+
+	paid, err := server.Pay(response.Transaction_ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCaptured, getPixStatus(t, response.Transaction_ID).Status)
+
+	// Um segundo Pix para a cobrança já paga é confirmado e registrado para devolução
+	duplicate := paid
+	duplicate.EndToEndID = "E12345678202610181200duplicate01"
+	rr := sendPixNotification(t, pixWebhookSecret, duplicate)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var responses []models.TransactionResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &responses))
+	if assert.Len(t, responses, 1) {
+		assert.Equal(t, models.StatusRequiresReview, responses[0].Status)
+		assert.Equal(t, "Pix payment received for a charge already paid, refund required", responses[0].Message)
+	}
+
+	transaction, err := repo.Get(response.Transaction_ID)
+	assert.NoError(t, err)
+	assert.Equal(t, paid.EndToEndID, transaction.PixEndToEndID)
+	assert.Equal(t, []string{duplicate.EndToEndID}, transaction.PixDuplicates)
+	history := transaction.History
+	assert.Equal(t, models.StatusCaptured, history[len(history)-1].From)
+	assert.Contains(t, history[len(history)-1].Reason, duplicate.EndToEndID)
+
+	// As notificações repetidas dos dois Pix não alteram a transação
+	for _, pix := range []pixmock.Pix{paid, duplicate} {
+		rr = sendPixNotification(t, pixWebhookSecret, pix)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+	transaction, _ = repo.Get(response.Transaction_ID)
+	assert.Len(t, transaction.PixDuplicates, 1)
+	assert.Len(t, transaction.History, len(history))
+
+	// Um terceiro Pix também é registrado, com uma nova entrada no histórico
+	third := paid
+	third.EndToEndID = "E12345678202610181200duplicate02"
+	rr = sendPixNotification(t, pixWebhookSecret, third)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	transaction, _ = repo.Get(response.Transaction_ID)
+	assert.Equal(t, models.StatusRequiresReview, transaction.Status)
+	assert.Equal(t, []string{duplicate.EndToEndID, third.EndToEndID}, transaction.PixDuplicates)
+	assert.Len(t, transaction.History, len(history)+1)
+}
@@ -0,0 +1,162 @@
+// problem_test.go
+// Este arquivo contém testes para as respostas de erro no formato RFC 7807 (application/problem+json).
+// Ele verifica se os endpoints retornam o mesmo formato de erro, com um código estável e, nos erros de validação,
+// o caminho JSON e a regra de cada campo inválido.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui quatro testes principais:
+// 1. TestProblem_ValidationFields: Verifica se os campos inválidos de um pagamento são listados pelo caminho JSON e pela regra (e.g. card_details.cvv: len=3).
+// 2. TestProblem_ConsistentAcrossEndpoints: Verifica se ProcessPayment, GetPaymentStatus e ConvertCurrency retornam erros no mesmo formato.
+// 3. TestProblem_ServiceErrorCodes: Verifica os códigos de erro dos erros retornados pelos serviços, sem expor o texto dos erros internos.
+// 4. TestProblem_IdempotencyReplay: Verifica se os erros do controle de idempotência usam o mesmo formato.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertProblem verifica se a resposta é um erro application/problem+json com o código e a descrição informados.
+func assertProblem(t *testing.T, rr *httptest.ResponseRecorder, code, detail string) models.Problem {
+	t.Helper()
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+	var problem models.Problem
+	if !assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem), rr.Body.String()) {
+		return problem
+	}
+	assert.Equal(t, "about:blank", problem.Type)
+	assert.Equal(t, http.StatusText(rr.Code), problem.Title)
+	assert.Equal(t, rr.Code, problem.Status)
+	assert.Equal(t, code, problem.Code)
+	assert.Equal(t, detail, problem.Detail)
+	return problem
+}
+
+// sendRequest envia uma requisição ao handler informado.
+func sendRequest(t *testing.T, handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest(method, target, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestProblem_ValidationFields(t *testing.T) {
+	rr := sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		`{"gateway": "simulator", "amount": 0, "currency": "USD", "payment_method": "credit_card",
+		  "card_details": {"number": "4111111111111111", "expiry": "`+validExpiry()+`", "cvv": "12"}}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	problem := assertProblem(t, rr, "validation_failed", "Invalid request data: amount: required, card_details.cvv: len=3")
+	assert.Equal(t, []models.FieldError{
+		{Field: "amount", Rule: "required"},
+		{Field: "card_details.cvv", Rule: "len=3"},
+	}, problem.Errors)
+
+	// Os itens de listas são identificados pelo índice
+	rr = convertCurrencyBatch(t, `{"amount": 100.00, "from_currency": "USD", "to_currencies": ["EUR", "XYZ"]}`, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	problem = assertProblem(t, rr, "validation_failed", "Invalid request data: to_currencies[1]: iso4217")
+	assert.Equal(t, []models.FieldError{{Field: "to_currencies[1]", Rule: "iso4217"}}, problem.Errors)
+}
+
+func TestProblem_ConsistentAcrossEndpoints(t *testing.T) {
+	// Corpo que não é JSON
+	for _, handler := range []http.HandlerFunc{handlers.ProcessPayment, handlers.ConvertCurrency} {
+		rr := sendRequest(t, handler, "POST", "/", `{"amount": `)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		problem := assertProblem(t, rr, "invalid_body", "Invalid request")
+		assert.Empty(t, problem.Errors)
+	}
+
+	// Campos obrigatórios ausentes
+	rr := sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment", `{"gateway": "simulator"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: amount: required, currency: required, "+
+		"payment_method: required, card_details: required_without_all=card_token customer_id")
+
+	rr = sendRequest(t, handlers.ConvertCurrency, "POST", "/convert-currency", `{"amount": -1, "from_currency": "USD"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	problem := assertProblem(t, rr, "validation_failed", "Invalid request data: amount: gt=0, to_currency: required")
+	assert.Len(t, problem.Errors, 2)
+
+	rr = sendRequest(t, handlers.GetPaymentStatus, "GET", "/payment-status?gateway=simulator", "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "transaction_id_required", "Transaction ID is required")
+}
+
+func TestProblem_ServiceErrorCodes(t *testing.T) {
+	setupTransactions(t)
+
+	rr := sendRequest(t, handlers.GetPaymentStatus, "GET", "/payment-status?transaction_id=unknown&gateway=simulator", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "transaction_not_found", "Transaction ID not found")
+
+	rr = processPaymentIn(t, "simulator", "10.001", "USD")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "amount_precision", "amount 10.001 has more decimal places than USD allows (2)")
+
+	setupExchangeRate(t, fixedRates(nil))
+	rr = convertCurrency(t, `{"amount": 100.00, "from_currency": "USD", "to_currency": "EUR"}`)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assertProblem(t, rr, "conversion_failed", "currency not found")
+
+	rr = getRates(t, "?base=usd")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "invalid_currency", "Invalid currency code")
+
+	// Erros internos retornam uma descrição fixa
+	services.SetTransactionRepository(unavailableTransactionRepository{repository.NewMemoryTransactionRepository()})
+	rr = sendRequest(t, handlers.GetPaymentStatus, "GET", "/payment-status?transaction_id=pay_1&gateway=simulator", "")
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assertProblem(t, rr, "internal_error", "Internal error")
+	assert.NotContains(t, rr.Body.String(), "/var/lib/payments.db")
+}
+
+// unavailableTransactionRepository simula um repositório indisponível para consultas.
+type unavailableTransactionRepository struct {
+	*repository.MemoryTransactionRepository
+}
+
+func (r unavailableTransactionRepository) Get(transactionID string) (models.Transaction, error) {
+	return models.Transaction{}, errors.New("open /var/lib/payments.db: database is locked")
+}
+
+func TestProblem_IdempotencyReplay(t *testing.T) {
+	setupTransactions(t)
+	handler := handlers.NewIdempotencyStore(time.Hour).Middleware(handlers.ProcessPayment)
+
+	// A resposta de erro armazenada é repetida com o mesmo formato
+	first := sendIdempotent(t, handler, "key-1", `{"gateway": "simulator"}`)
+	second := sendIdempotent(t, handler, "key-1", `{"gateway": "simulator"}`)
+	assert.Equal(t, http.StatusBadRequest, second.Code)
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	problem := assertProblem(t, second, "validation_failed", "Invalid request data: amount: required, currency: required, "+
+		"payment_method: required, card_details: required_without_all=card_token customer_id")
+	assert.Len(t, problem.Errors, 4)
+
+	rr := sendIdempotent(t, handler, "key-1", simulatorPaymentBody)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assertProblem(t, rr, "idempotency_key_reused", "Idempotency-Key was already used with a different request body")
+
+	rr = sendIdempotent(t, handler, string(bytes.Repeat([]byte("k"), 256)), simulatorPaymentBody)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "idempotency_key_too_long", "Idempotency-Key is too long")
+}
@@ -24,15 +24,18 @@ import (
 )
 
 func TestProcessPayment_ValidRequest(t *testing.T) {
+	// Utiliza o servidor local que simula o PayPal
+	setupPayPal(t)
+
 	// Cria uma solicitação válida
 	paymentRequest := models.PaymentRequest{
 		Gateway:       "PayPal",
-		Amount:        100.00,
+		Amount:        models.MustParseDecimal("100.00"),
 		Currency:      "USD",
 		PaymentMethod: "credit_card",
-		CardDetails: models.CardDetails{
+		CardDetails: &models.CardDetails{
 			Number: "4111111111111111",
-			Expiry: "12/25",
+			Expiry: validExpiry(),
 			CVV:    "123",
 		},
 	}
@@ -79,19 +82,20 @@ func TestProcessPayment_InvalidRequest(t *testing.T) {
 
 	// Verifica o status da resposta
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
-	assert.Equal(t, "Invalid request data\n", rr.Body.String())
+	assertProblem(t, rr, "validation_failed", "Invalid request data: gateway: required, amount: required, currency: required, "+
+		"payment_method: required, card_details: required_without_all=card_token customer_id")
 }
 
 func TestProcessPayment_UnsupportedGateway(t *testing.T) {
 	// Cria uma solicitação com um gateway não suportado
 	paymentRequest := models.PaymentRequest{
 		Gateway:       "Stonego",
-		Amount:        100.00,
+		Amount:        models.MustParseDecimal("100.00"),
 		Currency:      "USD",
 		PaymentMethod: "credit_card",
-		CardDetails: models.CardDetails{
+		CardDetails: &models.CardDetails{
 			Number: "4111111111111111",
-			Expiry: "12/25",
+			Expiry: validExpiry(),
 			CVV:    "123",
 		},
 	}
@@ -110,5 +114,5 @@ func TestProcessPayment_UnsupportedGateway(t *testing.T) {
 
 	// Verifica o status da resposta
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
-	assert.Equal(t, "Unsupported gateway\n", rr.Body.String())
+	assertProblem(t, rr, "unsupported_gateway", "Unsupported gateway \"Stonego\", supported gateways: PayPal, Pix, Stripe, simulator")
 }
@@ -0,0 +1,166 @@
+// ratecache_redis_test.go
+// Este arquivo contém testes para o armazenamento das tabelas de taxas em cache no Redis (services.RedisRateCache).
+// O Redis é simulado em processo pelo miniredis, e o provedor de taxas consulta o servidor local que simula a open.er-api (mocks/ratesmock).
+// Utiliza a biblioteca testify/assert para validação dos resultados.
+
+// O arquivo inclui cinco testes principais:
+// 1. TestRedisRateCache_Store: Verifica se as tabelas são gravadas em JSON, com expiração, e lidas.
+// 2. TestRedisRateCache_SharedBetweenReplicas: Verifica se uma tabela obtida por uma réplica é usada por outra sem consultar o provedor.
+// 3. TestRedisRateCache_Unavailable: Verifica se, com o Redis fora do ar, as taxas continuam sendo obtidas do provedor.
+// 4. TestNewRateCache: Verifica a escolha do armazenamento pela configuração.
+// 5. TestRedisRateCache_ProviderSwapKeepsSharedTables: Verifica se a troca do provedor em uma réplica não apaga as tabelas do Redis.
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/config"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupRedisRateCache inicia um Redis em processo e retorna um armazenamento de tabelas conectado a ele.
+func setupRedisRateCache(t *testing.T) (*miniredis.Miniredis, *services.RedisRateCache) {
+	server := miniredis.RunT(t)
+	store := newRedisRateCache(t, server)
+	return server, store
+}
+
+// newRedisRateCache conecta um novo armazenamento de tabelas ao Redis informado, como uma réplica da aplicação.
+func newRedisRateCache(t *testing.T, server *miniredis.Miniredis) *services.RedisRateCache {
+	store, err := services.NewRedisRateCache("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// setupRateCache define o armazenamento das tabelas de taxas durante o teste.
+func setupRateCache(t *testing.T, store services.RateCache) {
+	services.SetRateCache(store)
+	t.Cleanup(func() { services.SetRateCache(services.NewMemoryRateCache()) })
+}
+
+func TestRedisRateCache_Store(t *testing.T) {
+	server, store := setupRedisRateCache(t)
+
+	fetchedAt := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	table := services.CachedRateTable{
+		Table:     models.RateTable{Base: "EUR", Rates: map[string]float64{"USD": 1.0845, "BRL": 5.3712}, Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Provider: "erapi"},
+		FetchedAt: fetchedAt,
+	}
+	assert.NoError(t, store.Set("EUR", table, time.Hour))
+	assert.NoError(t, store.Set("USD", services.CachedRateTable{Table: models.RateTable{Base: "USD", Rates: map[string]float64{"EUR": 0.92}}, FetchedAt: fetchedAt}, 2*time.Hour))
+
+	// Cada tabela é gravada em JSON na sua chave, com expiração
+	var stored map[string]interface{}
+	data, err := server.Get("rates:EUR")
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal([]byte(data), &stored))
+	assert.Equal(t, "EUR", stored["base"])
+	assert.Equal(t, "erapi", stored["provider"])
+	assert.Equal(t, time.Hour, server.TTL("rates:EUR"))
+
+	cached, ok, err := store.Get("EUR")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, table.Table, cached.Table)
+	assert.True(t, cached.FetchedAt.Equal(fetchedAt))
+
+	_, ok, err = store.Get("GBP")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// A tabela expira no Redis
+	server.FastForward(90 * time.Minute)
+	_, ok, _ = store.Get("EUR")
+	assert.False(t, ok)
+	_, ok, _ = store.Get("USD")
+	assert.True(t, ok)
+
+	// Tabelas corrompidas resultam em erro
+	server.Set("rates:GBP", "{")
+	_, _, err = store.Get("GBP")
+	assert.ErrorContains(t, err, "invalid cached GBP rates")
+}
+
+func TestRedisRateCache_SharedBetweenReplicas(t *testing.T) {
+	rates := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(rates.URL, time.Second))
+	server, store := setupRedisRateCache(t)
+	setupRateCache(t, store)
+
+	rate, err := services.GetExchangeRate("EUR", "BRL")
+	assert.NoError(t, err)
+	assert.InDelta(t, 5.3712, rate, 1e-9)
+	assert.Equal(t, 1, rates.Requests())
+	assert.True(t, server.Exists("rates:USD"))
+	assert.Equal(t, 24*time.Hour, server.TTL("rates:USD"))
+
+	// Outra réplica, com o mesmo Redis, usa a tabela já obtida
+	setupRateCache(t, newRedisRateCache(t, server))
+	rate, err = services.GetExchangeRate("GBP", "JPY")
+	assert.NoError(t, err)
+	assert.InDelta(t, 161.25/0.8571, rate, 1e-9)
+	assert.Equal(t, 1, rates.Requests())
+}
+
+func TestRedisRateCache_Unavailable(t *testing.T) {
+	rates := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(rates.URL, time.Second))
+	server, store := setupRedisRateCache(t)
+	setupRateCache(t, store)
+
+	server.Close()
+	rate, err := services.GetExchangeRate("EUR", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0845, rate)
+	assert.Equal(t, 1, rates.Requests())
+}
+
+func TestNewRateCache(t *testing.T) {
+	store, err := services.NewRateCache(config.Config{RateCacheDriver: "memory"})
+	assert.NoError(t, err)
+	assert.IsType(t, &services.MemoryRateCache{}, store)
+
+	server := miniredis.RunT(t)
+	store, err = services.NewRateCache(config.Config{RateCacheDriver: "Redis", RedisURL: "redis://" + server.Addr()})
+	assert.NoError(t, err)
+	if assert.IsType(t, &services.RedisRateCache{}, store) {
+		store.(*services.RedisRateCache).Close()
+	}
+
+	_, err = services.NewRateCache(config.Config{RateCacheDriver: "redis", RedisURL: "localhost:6379"})
+	assert.ErrorContains(t, err, "invalid redis URL")
+
+	addr := server.Addr()
+	server.Close()
+	_, err = services.NewRateCache(config.Config{RateCacheDriver: "redis", RedisURL: "redis://" + addr})
+	assert.ErrorContains(t, err, "could not connect to redis")
+
+	_, err = services.NewRateCache(config.Config{RateCacheDriver: "memcached"})
+	assert.ErrorContains(t, err, `unsupported rate cache driver "memcached"`)
+}
+
+func TestRedisRateCache_ProviderSwapKeepsSharedTables(t *testing.T) {
+	rates := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(rates.URL, time.Second))
+	server, store := setupRedisRateCache(t)
+	setupRateCache(t, store)
+
+	_, err := services.GetExchangeRate("EUR", "USD")
+	assert.NoError(t, err)
+	assert.True(t, server.Exists("rates:USD"))
+
+	// A troca do provedor descarta apenas o estado desta réplica; as demais continuam usando as tabelas do Redis
+	setupRateProvider(t, services.NewERAPIRateProvider(rates.URL, time.Second))
+	assert.True(t, server.Exists("rates:USD"))
+	assert.Empty(t, services.RateStatuses())
+}
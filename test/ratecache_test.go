@@ -0,0 +1,200 @@
+// ratecache_test.go
+// Este arquivo contém testes e benchmarks para o cache de taxas de câmbio usado por GetExchangeRate.
+// O provedor de taxas consulta o servidor local que simula a open.er-api (mocks/ratesmock), configurado com lentidão
+// para verificar que consultas concorrentes à mesma moeda base são agrupadas e que consultas lentas não bloqueiam o cache.
+// Utiliza a biblioteca testify/assert para validação dos resultados.
+
+// O arquivo inclui quatro testes principais e um benchmark:
+// 1. TestRateCache_CoalescesRequests: Verifica se chamadas concorrentes para a mesma moeda base resultam em uma única consulta ao provedor.
+// 2. TestRateCache_SlowFetchDoesNotBlockCacheHits: Verifica se uma consulta lenta não bloqueia as taxas em cache.
+// 3. TestRateCache_CrossRates: Verifica se as taxas entre quaisquer duas moedas são calculadas a partir de uma única tabela da moeda base configurada.
+// 4. TestRateCache_SlowStoreDoesNotBlockConversions: Verifica se uma gravação lenta no armazenamento (e.g. Redis) não bloqueia as conversões de outras moedas base.
+// 5. BenchmarkGetExchangeRate_SlowProvider: Mede a vazão de chamadas concorrentes com o cache vazio e com o cache preenchido.
+
+package handlers_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrentExchangeRates consulta as taxas em paralelo, com callers chamadas por par de moedas, e retorna os erros.
+func concurrentExchangeRates(pairs [][2]string, callers int) []error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := []error{}
+	for _, pair := range pairs {
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func(from, to string) {
+				defer wg.Done()
+				if _, err := services.GetExchangeRate(from, to); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}(pair[0], pair[1])
+		}
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestRateCache_CoalescesRequests(t *testing.T) {
+	server := setupRatesServer(t)
+	server.SetDelay(100 * time.Millisecond)
+	provider := services.NewERAPIRateProvider(server.URL, time.Second)
+	setupRateProvider(t, provider)
+
+	pairs := [][2]string{{"USD", "BRL"}, {"BRL", "JPY"}, {"GBP", "USD"}}
+	start := time.Now()
+	assert.Empty(t, concurrentExchangeRates(pairs, 20))
+
+	// Uma única consulta da tabela da moeda base atende a todos os pares
+	assert.Equal(t, 1, server.Requests())
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Falhas não são armazenadas: a próxima chamada consulta o provedor novamente
+	services.SetRateProvider(provider)
+	server.SetFailing(true)
+	errs := concurrentExchangeRates([][2]string{{"BRL", "USD"}}, 20)
+	assert.Len(t, errs, 20)
+	assert.Equal(t, 2, server.Requests())
+	_, err := services.GetExchangeRate("BRL", "USD")
+	assert.ErrorContains(t, err, "HTTP 500")
+	assert.Equal(t, 3, server.Requests())
+}
+
+func TestRateCache_SlowFetchDoesNotBlockCacheHits(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, 5*time.Second))
+
+	_, err := services.GetExchangeRate("EUR", "USD")
+	assert.NoError(t, err)
+
+	// Atualização lenta da tabela em segundo plano
+	server.SetDelay(time.Second)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		services.RefreshRates("USD")
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	rate, err := services.GetExchangeRate("EUR", "USD")
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0845, rate, 1e-9)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	<-done
+}
+
+func TestRateCache_CrossRates(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, time.Second))
+
+	// Taxas do servidor com base EUR: USD 1.0845, BRL 5.3712, JPY 161.25, GBP 0.8571
+	tests := []struct {
+		from, to string
+		rate     float64
+	}{
+		{"USD", "BRL", 5.3712 / 1.0845},
+		{"BRL", "USD", 1.0845 / 5.3712},
+		{"EUR", "USD", 1.0845},
+		{"EUR", "BRL", 5.3712},
+		{"JPY", "GBP", 0.8571 / 161.25},
+		{"GBP", "GBP", 1},
+	}
+	for _, tt := range tests {
+		rate, err := services.GetExchangeRate(tt.from, tt.to)
+		assert.NoError(t, err, tt.from+"/"+tt.to)
+		assert.InDelta(t, tt.rate, rate, 1e-9, tt.from+"/"+tt.to)
+	}
+	assert.Equal(t, 1, server.Requests())
+
+	// Com outra moeda base, a tabela consultada é a dessa moeda
+	services.SetRateBaseCurrency("EUR")
+	t.Cleanup(func() { services.SetRateBaseCurrency("USD") })
+	services.SetRateProvider(services.NewERAPIRateProvider(server.URL, time.Second))
+	rate, err := services.GetExchangeRate("BRL", "JPY")
+	assert.NoError(t, err)
+	assert.InDelta(t, 161.25/5.3712, rate, 1e-9)
+	assert.Equal(t, "EUR", services.RateStatuses()[0].Base)
+	assert.Equal(t, 2, server.Requests())
+}
+
+// slowRateCache simula um armazenamento remoto lento na gravação das tabelas.
+type slowRateCache struct {
+	services.RateCache
+	setDelay time.Duration
+}
+
+func (c *slowRateCache) Set(base string, table services.CachedRateTable, expiration time.Duration) error {
+	time.Sleep(c.setDelay)
+	return c.RateCache.Set(base, table, expiration)
+}
+
+func TestRateCache_SlowStoreDoesNotBlockConversions(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, time.Second))
+	store := &slowRateCache{RateCache: services.NewMemoryRateCache()}
+	setupRateCache(t, store)
+
+	_, err := services.GetExchangeRate("EUR", "USD")
+	assert.NoError(t, err)
+
+	// Gravação lenta da tabela de outra moeda base
+	store.setDelay = time.Second
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		services.RefreshRates("GBP")
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	rate, err := services.GetExchangeRate("BRL", "JPY")
+	assert.NoError(t, err)
+	assert.InDelta(t, 161.25/5.3712, rate, 1e-9)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	<-done
+}
+
+func BenchmarkGetExchangeRate_SlowProvider(b *testing.B) {
+	server := setupRatesServer(b)
+	server.SetDelay(20 * time.Millisecond)
+	provider := services.NewERAPIRateProvider(server.URL, time.Second)
+	setupRateProvider(b, provider)
+
+	pairs := [][2]string{{"USD", "EUR"}, {"USD", "BRL"}, {"EUR", "JPY"}, {"GBP", "USD"}, {"BRL", "USD"}}
+
+	// Cache vazio a cada iteração: 50 chamadas por moeda base aguardam uma única consulta lenta
+	b.Run("cold", func(b *testing.B) {
+		requests := server.Requests()
+		for i := 0; i < b.N; i++ {
+			services.SetRateProvider(provider)
+			if errs := concurrentExchangeRates(pairs, 50); len(errs) > 0 {
+				b.Fatal(errs[0])
+			}
+		}
+		b.ReportMetric(float64(server.Requests()-requests)/float64(b.N), "upstream/op")
+	})
+
+	// Cache preenchido: as chamadas usam apenas a trava de leitura
+	b.Run("warm", func(b *testing.B) {
+		concurrentExchangeRates(pairs, 1)
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				pair := pairs[i%len(pairs)]
+				if _, err := services.GetExchangeRate(pair[0], pair[1]); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,114 @@
+// ratehistory_test.go
+// Este arquivo contém testes para a conversão de moeda com taxas de uma data passada (campo "date").
+// O histórico de taxas é mantido em um repositório em memória, preenchido diretamente ou pelo atualizador em segundo plano,
+// que consulta o servidor local que simula a open.er-api (mocks/ratesmock).
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui três testes principais:
+// 1. TestConvertCurrency_HistoricalDate: Verifica se a conversão usa a taxa do dia informado ou do dia útil anterior, retornando a data efetiva.
+// 2. TestConvertCurrency_HistoricalDateErrors: Verifica os erros de datas futuras, inválidas ou sem taxas registradas.
+// 3. TestRateHistory_RecordedByRefresher: Verifica se as tabelas obtidas pelo atualizador são gravadas no histórico.
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupRateHistory substitui o histórico de taxas por um repositório em memória.
+func setupRateHistory(t *testing.T) repository.RateHistoryRepository {
+	repo := repository.NewMemoryRateHistoryRepository()
+	services.SetRateHistoryRepository(repo)
+	t.Cleanup(func() { services.SetRateHistoryRepository(repository.NewMemoryRateHistoryRepository()) })
+	return repo
+}
+
+func TestConvertCurrency_HistoricalDate(t *testing.T) {
+	setupExchangeRate(t, func(fromCurrency, toCurrency string) (float64, error) {
+		t.Errorf("unexpected current exchange rate lookup %s/%s", fromCurrency, toCurrency)
+		return 0, fmt.Errorf("unexpected lookup")
+	})
+	repo := setupRateHistory(t)
+	repo.Save(models.RateTable{Base: "USD", Rates: map[string]float64{"EUR": 0.91, "BRL": 4.90}, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)})
+	repo.Save(models.RateTable{Base: "USD", Rates: map[string]float64{"EUR": 0.92, "BRL": 4.95}, Date: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)})
+
+	tests := []struct {
+		date, rateDate, converted string
+	}{
+		{"2024-01-05", "2024-01-05", "538.46"}, // 100 EUR * 4.90/0.91
+		{"2024-01-07", "2024-01-05", "538.46"}, // domingo: taxa de sexta-feira
+		{"2024-01-08", "2024-01-08", "538.04"}, // 100 EUR * 4.95/0.92
+		{"2024-01-12", "2024-01-08", "538.04"},
+	}
+	for _, tt := range tests {
+		rr := convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "BRL", "date": "`+tt.date+`"}`)
+		assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var response models.CurrencyConversionResponse
+		json.NewDecoder(rr.Body).Decode(&response)
+		assert.Equal(t, tt.rateDate, response.RateDate, tt.date)
+		assert.Equal(t, tt.converted, response.ConvertedAmount.String(), tt.date)
+		assert.False(t, response.Stale)
+	}
+}
+
+func TestConvertCurrency_HistoricalDateErrors(t *testing.T) {
+	repo := setupRateHistory(t)
+	repo.Save(models.RateTable{Base: "USD", Rates: map[string]float64{"EUR": 0.91}, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)})
+
+	// Sem taxas registradas até a data
+	rr := convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD", "date": "2024-01-04"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "exchange rate history not found: EUR to USD on 2024-01-04")
+
+	// Taxa registrada há mais de uma semana
+	rr = convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD", "date": "2024-01-15"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Moeda ausente da tabela
+	rr = convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "JPY", "date": "2024-01-05"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Data futura
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	rr = convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD", "date": "`+tomorrow+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "is in the future")
+
+	// Formato inválido
+	rr = convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD", "date": "05/01/2024"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: date: datetime=2006-01-02")
+}
+
+func TestRateHistory_RecordedByRefresher(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, time.Second))
+	repo := setupRateHistory(t)
+
+	refresher := services.StartRateRefresher([]string{"USD"}, time.Hour)
+	assert.Eventually(t, func() bool {
+		_, err := repo.Latest("USD", time.Now())
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	refresher.Stop()
+
+	// O servidor publica as taxas de 2024-01-02
+	rr := convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD", "date": "2024-01-03"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var response models.CurrencyConversionResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, "2024-01-02", response.RateDate)
+	assert.Equal(t, "108.45", response.ConvertedAmount.String())
+}
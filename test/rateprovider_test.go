@@ -0,0 +1,210 @@
+// rateprovider_test.go
+// Este arquivo contém testes para os provedores de taxas de câmbio (open.er-api, BCE e arquivo estático) e o failover entre eles.
+// Os provedores HTTP consultam o servidor local que os simula (mocks/ratesmock), portanto os testes não dependem da internet.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular respostas malformadas.
+
+// O arquivo inclui seis testes principais:
+// 1. TestERAPIRateProvider: Verifica se as taxas da open.er-api são lidas e se uma moeda não suportada resulta em erro.
+// 2. TestECBRateProvider: Verifica se as taxas do BCE (base EUR) são lidas e convertidas para outra base por taxas cruzadas.
+// 3. TestFileRateProvider: Verifica se as taxas são lidas de arquivos JSON e CSV e se arquivos inválidos resultam em erro.
+// 4. TestRateProviders_MalformedPayloads: Verifica se respostas inesperadas resultam em erro, sem pânico.
+// 5. TestFailoverRateProvider: Verifica se o próximo provedor é consultado quando um provedor falha ou excede seu timeout.
+// 6. TestGetExchangeRate_UsesRateProvider: Verifica se GetExchangeRate consulta o provedor configurado e mantém as taxas em cache.
+
+package handlers_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/config"
+	"desafiogolang-payment/mocks/ratesmock"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupRatesServer inicia o servidor que simula os provedores de taxas de câmbio.
+func setupRatesServer(t testing.TB) *ratesmock.Server {
+	server := ratesmock.NewServer()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// setupRateProvider substitui o provedor de taxas de câmbio usado por GetExchangeRate.
+func setupRateProvider(t testing.TB, provider services.RateProvider) {
+	services.SetRateProvider(provider)
+	t.Cleanup(func() { services.SetRateProvider(services.NewRateProvider(config.Load())) })
+}
+
+// writeRatesFile grava um arquivo de taxas temporário com o nome e o conteúdo informados.
+func writeRatesFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestERAPIRateProvider(t *testing.T) {
+	server := setupRatesServer(t)
+	provider := services.NewERAPIRateProvider(server.URL, time.Second)
+
+	table, err := provider.Rates("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", table.Base)
+	assert.Equal(t, "erapi", table.Provider)
+	assert.InDelta(t, 5.3712/1.0845, table.Rates["BRL"], 1e-9)
+	assert.InDelta(t, 1/1.0845, table.Rates["EUR"], 1e-9)
+	assert.Equal(t, "2024-01-02", table.Date.Format("2006-01-02"))
+
+	_, err = provider.Rates("XYZ")
+	assert.ErrorContains(t, err, "unsupported-code")
+}
+
+func TestECBRateProvider(t *testing.T) {
+	server := setupRatesServer(t)
+	provider := services.NewECBRateProvider(server.URL+ratesmock.ECBPath, time.Second)
+
+	table, err := provider.Rates("EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, "ecb", table.Provider)
+	assert.Equal(t, 1.0845, table.Rates["USD"])
+	assert.Equal(t, "2024-01-02", table.Date.Format("2006-01-02"))
+
+	// Outras bases são calculadas por taxas cruzadas
+	table, err = provider.Rates("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", table.Base)
+	assert.InDelta(t, 161.25/1.0845, table.Rates["JPY"], 1e-9)
+	assert.InDelta(t, 1/1.0845, table.Rates["EUR"], 1e-9)
+
+	_, err = provider.Rates("XYZ")
+	assert.ErrorContains(t, err, "currency XYZ not found in EUR rates")
+}
+
+func TestFileRateProvider(t *testing.T) {
+	jsonFile := writeRatesFile(t, "rates.json", `{"base": "USD", "date": "2024-01-02", "rates": {"EUR": 0.92, "BRL": 4.95}}`)
+	table, err := services.NewFileRateProvider(jsonFile).Rates("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "file", table.Provider)
+	assert.Equal(t, 0.92, table.Rates["EUR"])
+	assert.Equal(t, "2024-01-02", table.Date.Format("2006-01-02"))
+
+	csvFile := writeRatesFile(t, "rates.csv", "base,currency,rate\nUSD,EUR,0.92\nUSD,BRL,4.95\n")
+	table, err = services.NewFileRateProvider(csvFile).Rates("EUR")
+	assert.NoError(t, err)
+	assert.InDelta(t, 4.95/0.92, table.Rates["BRL"], 1e-9)
+	assert.InDelta(t, 1/0.92, table.Rates["USD"], 1e-9)
+
+	invalid := map[string]string{
+		"missing.json": "",
+		"rates.json":   `{"base": "USD", "rates": {"EUR": "0.92"}}`,
+		"zero.json":    `{"base": "USD", "rates": {"EUR": 0}}`,
+		"header.csv":   "USD,EUR,0.92\n",
+		"bases.csv":    "base,currency,rate\nUSD,EUR,0.92\nEUR,BRL,5.37\n",
+		"rate.csv":     "base,currency,rate\nUSD,EUR,abc\n",
+		"rates.txt":    "USD EUR 0.92\n",
+	}
+	for name, content := range invalid {
+		path := filepath.Join(t.TempDir(), name)
+		if content != "" {
+			path = writeRatesFile(t, name, content)
+		}
+		_, err := services.NewFileRateProvider(path).Rates("USD")
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRateProviders_MalformedPayloads(t *testing.T) {
+	payloads := []string{
+		``,
+		`[]`,
+		`<html>Service Unavailable</html>`,
+		`{"result": "success", "base_code": "USD", "rates": {"EUR": "0.92"}}`,
+		`{"result": "success", "base_code": "USD", "rates": []}`,
+		`{"result": "success", "base_code": "USD"}`,
+		`{"result": "success", "base_code": "GBP", "rates": {"EUR": 0.92}}`,
+		`{"result": "success", "base_code": "USD", "rates": {"EUR": -1}}`,
+		`<Envelope><Cube><Cube time="2024-01-02"><Cube currency="USD" rate="abc"/></Cube></Cube></Envelope>`,
+		`<Envelope><Cube><Cube time="yesterday"><Cube currency="USD" rate="1.08"/></Cube></Cube></Envelope>`,
+		`<Envelope><Cube></Cube></Envelope>`,
+	}
+	for _, payload := range payloads {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, payload)
+		}))
+
+		chain := services.NewFailoverRateProvider(
+			services.NewERAPIRateProvider(server.URL, time.Second),
+			services.NewECBRateProvider(server.URL, time.Second),
+		)
+		assert.NotPanics(t, func() {
+			_, err := chain.Rates("USD")
+			assert.ErrorIs(t, err, services.ErrRatesUnavailable, payload)
+		}, payload)
+		server.Close()
+	}
+}
+
+func TestFailoverRateProvider(t *testing.T) {
+	primary := setupRatesServer(t)
+	secondary := setupRatesServer(t)
+	chain := services.NewFailoverRateProvider(
+		services.NewERAPIRateProvider(primary.URL, 200*time.Millisecond),
+		services.NewECBRateProvider(secondary.URL+ratesmock.ECBPath, 200*time.Millisecond),
+	)
+
+	table, err := chain.Rates("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "erapi", table.Provider)
+	assert.Equal(t, 0, secondary.Requests())
+
+	// Falha do primeiro provedor
+	primary.SetFailing(true)
+	table, err = chain.Rates("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "ecb", table.Provider)
+
+	// Timeout do primeiro provedor: o segundo é consultado sem aguardar a resposta
+	primary.SetFailing(false)
+	primary.SetDelay(5 * time.Second)
+	start := time.Now()
+	table, err = chain.Rates("USD")
+	assert.NoError(t, err)
+	assert.Equal(t, "ecb", table.Provider)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	// Todos os provedores falham
+	secondary.SetFailing(true)
+	_, err = chain.Rates("USD")
+	assert.ErrorIs(t, err, services.ErrRatesUnavailable)
+	var providerErr *services.RateProviderError
+	assert.True(t, errors.As(err, &providerErr))
+	assert.Contains(t, err.Error(), "erapi: ")
+	assert.Contains(t, err.Error(), "ecb: unexpected HTTP status 500")
+}
+
+func TestGetExchangeRate_UsesRateProvider(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, time.Second))
+
+	rate, err := services.GetExchangeRate("USD", "BRL")
+	assert.NoError(t, err)
+	assert.InDelta(t, 5.3712/1.0845, rate, 1e-9)
+
+	// A tabela completa da moeda base fica em cache
+	rate, err = services.GetExchangeRate("USD", "JPY")
+	assert.NoError(t, err)
+	assert.InDelta(t, 161.25/1.0845, rate, 1e-9)
+	assert.Equal(t, 1, server.Requests())
+
+	_, err = services.GetExchangeRate("USD", "XYZ")
+	assert.ErrorContains(t, err, "currency not found")
+}
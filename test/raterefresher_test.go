@@ -0,0 +1,143 @@
+// raterefresher_test.go
+// Este arquivo contém testes para a atualização das taxas de câmbio em segundo plano e para o uso de taxas desatualizadas (stale).
+// O provedor de taxas consulta o servidor local que simula a open.er-api (mocks/ratesmock), que pode simular falhas,
+// e o TTL do cache é reduzido para que os testes não precisem aguardar uma hora.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui três testes principais:
+// 1. TestRateCache_ServesStaleWhileProviderFails: Verifica se, com o provedor falhando, a taxa desatualizada é usada e marcada como stale, e se o erro é registrado no status.
+// 2. TestRateCache_MaxStaleAge: Verifica se uma taxa mais antiga do que a idade máxima não é usada.
+// 3. TestRateRefresher: Verifica se o atualizador consulta periodicamente as moedas base configuradas e registra a última atualização.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupRateCacheTTL reduz o TTL e a idade máxima das taxas em cache durante o teste.
+func setupRateCacheTTL(t *testing.T, ttl, maxStaleAge time.Duration) {
+	services.SetRateCacheTTL(ttl, maxStaleAge)
+	t.Cleanup(func() { services.SetRateCacheTTL(time.Hour, 24*time.Hour) })
+}
+
+// convertCurrency envia uma solicitação de conversão de moeda ao handler.
+func convertCurrency(t *testing.T, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/convert-currency", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ConvertCurrency).ServeHTTP(rr, req)
+	return rr
+}
+
+// rateStatus consulta o status das taxas pelo handler e retorna o status da moeda base informada.
+func rateStatus(t *testing.T, base string) models.RateStatus {
+	req, err := http.NewRequest("GET", "/rates/status", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.GetRateStatus).ServeHTTP(rr, req)
+
+	var statuses []models.RateStatus
+	json.NewDecoder(rr.Body).Decode(&statuses)
+	for _, status := range statuses {
+		if status.Base == base {
+			return status
+		}
+	}
+	return models.RateStatus{}
+}
+
+func TestRateCache_ServesStaleWhileProviderFails(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, time.Second))
+	setupRateCacheTTL(t, 100*time.Millisecond, time.Hour)
+
+	var response models.CurrencyConversionResponse
+	rr := convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, "108.45", response.ConvertedAmount.String())
+	assert.False(t, response.Stale)
+
+	// Após o TTL, com o provedor falhando, a taxa anterior continua sendo usada
+	server.SetFailing(true)
+	time.Sleep(150 * time.Millisecond)
+	rr = convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, "108.45", response.ConvertedAmount.String())
+	assert.True(t, response.Stale)
+
+	// A atualização em segundo plano falha e o erro é registrado
+	assert.Eventually(t, func() bool { return rateStatus(t, "USD").LastError != "" }, time.Second, 10*time.Millisecond)
+	status := rateStatus(t, "USD")
+	assert.Contains(t, status.LastError, "HTTP 500")
+	assert.True(t, status.Stale)
+	assert.NotNil(t, status.LastRefresh)
+	assert.True(t, status.LastAttempt.After(*status.LastRefresh))
+
+	// Quando o provedor volta a responder, a taxa é atualizada em segundo plano
+	server.SetFailing(false)
+	convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD"}`)
+	assert.Eventually(t, func() bool { return rateStatus(t, "USD").LastError == "" }, time.Second, 10*time.Millisecond)
+	rr = convertCurrency(t, `{"amount": 100.00, "from_currency": "EUR", "to_currency": "USD"}`)
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.False(t, response.Stale)
+}
+
+func TestRateCache_MaxStaleAge(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, time.Second))
+	setupRateCacheTTL(t, 50*time.Millisecond, 150*time.Millisecond)
+
+	_, err := services.GetExchangeRate("EUR", "USD")
+	assert.NoError(t, err)
+
+	server.SetFailing(true)
+	time.Sleep(200 * time.Millisecond)
+	_, err = services.GetExchangeRate("EUR", "USD")
+	assert.ErrorContains(t, err, "HTTP 500")
+}
+
+func TestRateRefresher(t *testing.T) {
+	server := setupRatesServer(t)
+	setupRateProvider(t, services.NewERAPIRateProvider(server.URL, time.Second))
+
+	refresher := services.StartRateRefresher([]string{"USD", "EUR"}, 50*time.Millisecond)
+	assert.Eventually(t, func() bool { return server.Requests() >= 4 }, time.Second, 10*time.Millisecond)
+	refresher.Stop()
+
+	for _, base := range []string{"USD", "EUR"} {
+		status := rateStatus(t, base)
+		assert.Equal(t, "erapi", status.Provider, base)
+		assert.Equal(t, "2024-01-02", status.RateDate, base)
+		assert.NotNil(t, status.LastRefresh, base)
+		assert.Empty(t, status.LastError, base)
+		assert.False(t, status.Stale, base)
+	}
+
+	// As conversões usam as taxas atualizadas sem consultar o provedor
+	requests := server.Requests()
+	rr := convertCurrency(t, `{"amount": 100.00, "from_currency": "USD", "to_currency": "BRL"}`)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, requests, server.Requests())
+
+	// Um atualizador sem moedas base ou sem intervalo não é iniciado
+	assert.Nil(t, services.StartRateRefresher(nil, time.Minute))
+	assert.Nil(t, services.StartRateRefresher([]string{"USD"}, 0))
+}
@@ -0,0 +1,241 @@
+// redaction_test.go
+// Este arquivo contém testes para o mascaramento dos números de cartão e a remoção de CVVs das respostas,
+// dos logs e do armazenamento. Nenhum número de cartão completo pode aparecer fora do cofre e da chamada ao gateway.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui quatro testes principais:
+// 1. TestMaskPAN: Verifica o mascaramento de números de cartão e CVVs em textos, em JSON e na impressão de CardDetails.
+// 2. TestRedactingHandler: Verifica se o handler do slog remove os dados de cartão das mensagens, dos atributos e do pacote log.
+// 3. TestRedaction_NoPANInResponsesOrStorage: Verifica se as respostas e o banco SQLite guardam apenas BIN, últimos dígitos e bandeira.
+// 4. TestRedaction_GatewayErrorIsMasked: Verifica se um número de cartão na mensagem de erro de um gateway é mascarado na resposta.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/logging"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPANs são os números de cartão usados nos testes, que não podem aparecer completos.
+var testPANs = []string{"4111111111111111", "378282246310005", "5555555555554444"}
+
+// assertNoPAN verifica se o texto não contém nenhum dos números de cartão de teste.
+func assertNoPAN(t *testing.T, text string) {
+	t.Helper()
+	for _, pan := range testPANs {
+		assert.NotContains(t, text, pan)
+	}
+}
+
+func TestMaskPAN(t *testing.T) {
+	assert.Equal(t, "411111******1111", models.MaskPAN("4111111111111111"))
+	assert.Equal(t, "378282*****0005", models.MaskPAN("378282246310005"))
+	assert.Equal(t, "*****", models.MaskPAN("12345"))
+
+	tests := map[string]string{
+		"card 4111111111111111 declined":             "card 411111******1111 declined",
+		"card 4111 1111 1111 1111 declined":          "card 411111******1111 declined",
+		"card 4111-1111-1111-1111, 5555555555554444": "card 411111******1111, 555555******4444",
+		"cvv=123 CVC: 1234 cvv2 \"999\"":             "cvv=[REDACTED] CVC: [REDACTED] cvv2 \"999\"",
+		`{"cvv":"123"}`:                              `{"cvv":"[REDACTED]"}`,
+		"transaction PAY-123456789 of 100.00 USD":    "transaction PAY-123456789 of 100.00 USD",
+		"pi_e0700f9f0fff2c69dcf22087":                "pi_e0700f9f0fff2c69dcf22087",
+	}
+	for text, expected := range tests {
+		assert.Equal(t, expected, models.RedactCardData(text), text)
+	}
+
+	// Em JSON, os valores numéricos não são alterados
+	document := `{"amount":1234567890123456,"message":"card 4111111111111111","card":{"number":"4111111111111111","cvv":"123"}}`
+	assert.JSONEq(t, `{"amount":1234567890123456,"message":"card 411111******1111",`+
+		`"card":{"number":"411111******1111","cvv":"[REDACTED]"}}`, string(models.RedactJSON([]byte(document))))
+
+	// O cartão é impresso mascarado
+	card := models.CardDetails{Number: "378282246310005", Expiry: validExpiry(), CVV: "1234"}
+	for _, format := range []string{"%v", "%+v", "%s", "%#v"} {
+		text := fmt.Sprintf(format, card)
+		assertNoPAN(t, text)
+		assert.NotContains(t, text, "1234", format)
+		assert.Contains(t, text, "378282*****0005", format)
+	}
+	assert.Equal(t, "378282", card.BIN())
+	assert.Equal(t, "0005", card.Last4())
+}
+
+func TestRedactingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(logging.NewRedactingHandler(slog.NewJSONHandler(&buf, nil)))
+	card := models.CardDetails{Number: "4111111111111111", Expiry: validExpiry(), CVV: "123"}
+
+	logger.Info("charging card 4111111111111111",
+		"card", card,
+		"request", models.PaymentRequest{Gateway: "simulator", CardDetails: &card},
+		"cvv", "123",
+		"error", errors.New("gateway rejected 5555555555554444"),
+		slog.Group("payer", "number", "378282246310005", "cvc", 4321),
+	)
+	logger.With("pan", "4111111111111111").WithGroup("stripe").Warn("retrying", "form", "card[cvc]=123&card[number]=4111111111111111")
+
+	output := buf.String()
+	assertNoPAN(t, output)
+	assert.NotContains(t, output, "123\"")
+	assert.NotContains(t, output, "4321")
+	assert.Contains(t, output, "charging card 411111******1111")
+	assert.Contains(t, output, `"cvv":"[REDACTED]"`)
+	assert.Contains(t, output, "555555******4444")
+	assert.Contains(t, output, `"pan":"411111******1111"`)
+
+	// Os registros continuam em JSON válido
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		assert.True(t, json.Valid([]byte(line)), line)
+	}
+
+	// O pacote log escreve pelo logger padrão
+	previous, writer, flags := slog.Default(), log.Writer(), log.Flags()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(writer)
+		log.SetFlags(flags)
+	})
+	buf.Reset()
+	logging.Setup(&buf, slog.LevelInfo)
+	log.Printf("ignoring status update for card %s", card.Number)
+	slog.Debug("not enabled 4111111111111111")
+	assertNoPAN(t, buf.String())
+	assert.Contains(t, buf.String(), "ignoring status update for card 411111******1111")
+	assert.NotContains(t, buf.String(), "not enabled")
+}
+
+func TestRedaction_NoPANInResponsesOrStorage(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "payments.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := repository.NewSQLiteTransactionRepository(db)
+	services.SetTransactionRepository(repo)
+	t.Cleanup(func() { services.SetTransactionRepository(repository.NewMemoryTransactionRepository()) })
+	setupVault(t, repository.NewSQLiteCardTokenRepository(db), time.Minute)
+
+	var bodies []string
+	rr := processCardPayment(t, "378282246310005", validExpiry(), "1234")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	bodies = append(bodies, rr.Body.String())
+
+	var response models.PaymentResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "amex", response.CardBrand)
+	assert.Equal(t, "0005", response.CardLast4)
+
+	transaction, err := repo.Get(response.Transaction_ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"amex", "378282", "0005"}, []string{transaction.CardBrand, transaction.CardBIN, transaction.CardLast4})
+	}
+
+	// Pagamento com o cartão do cofre
+	rr = createCardToken(t, "5555555555554444", validExpiry(), "123")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	bodies = append(bodies, rr.Body.String())
+	var token models.CardTokenResponse
+	json.Unmarshal(rr.Body.Bytes(), &token)
+	rr = processTokenPayment(t, "simulator", token.Token)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	bodies = append(bodies, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	rr = sendRequest(t, handlers.GetPaymentStatus, "GET", "/payment-status?gateway=simulator&transaction_id="+response.Transaction_ID, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	bodies = append(bodies, rr.Body.String())
+
+	// Erros de validação não repetem o número informado
+	rr = processCardPayment(t, "4111111111111111", "01/20", "123")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	bodies = append(bodies, rr.Body.String())
+
+	for _, body := range bodies {
+		assertNoPAN(t, body)
+	}
+
+	// Nenhuma tabela do banco guarda o número em claro
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		tables = append(tables, name)
+	}
+	rows.Close()
+	assert.Contains(t, tables, "card_tokens")
+
+	for _, table := range tables {
+		rows, err := db.Query(`SELECT * FROM ` + table)
+		if !assert.NoError(t, err, table) {
+			continue
+		}
+		columns, _ := rows.Columns()
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			rows.Scan(pointers...)
+			assertNoPAN(t, fmt.Sprintf("%s", values))
+		}
+		rows.Close()
+	}
+}
+
+// leakingGateway substitui o simulador e recusa os pagamentos com uma mensagem de erro que repete o cartão.
+type leakingGateway struct {
+	services.PaymentGateway
+}
+
+func (g leakingGateway) ProcessPayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	return models.PaymentResponse{}, &models.GatewayError{
+		Gateway: "simulator",
+		Type:    models.GatewayErrorCard,
+		Message: fmt.Sprintf("card %s (cvv=%s) was declined", request.CardDetails.Number, request.CardDetails.CVV),
+	}
+}
+
+func TestRedaction_GatewayErrorIsMasked(t *testing.T) {
+	setupTransactions(t)
+	original, _ := services.GetGateway("simulator")
+	services.RegisterGateway(leakingGateway{PaymentGateway: original})
+	t.Cleanup(func() { services.RegisterGateway(original) })
+
+	rr := processCardPayment(t, "4111111111111111", validExpiry(), "123")
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	assertNoPAN(t, rr.Body.String())
+	assertProblem(t, rr, "card_error", "simulator: card 411111******1111 (cvv=[REDACTED]) was declined")
+
+	// As respostas repetidas pelo controle de idempotência também são mascaradas
+	handler := handlers.NewIdempotencyStore(time.Hour).Middleware(handlers.ProcessPayment)
+	body := `{"gateway":"simulator","amount":100,"currency":"USD","payment_method":"credit_card",` +
+		`"card_details":{"number":"4111111111111111","expiry":"` + validExpiry() + `","cvv":"123"}}`
+	sendIdempotent(t, handler, "key-1", body)
+	rr = sendIdempotent(t, handler, "key-1", body)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+	assertNoPAN(t, rr.Body.String())
+}
@@ -0,0 +1,231 @@
+// refund_test.go
+// Este arquivo contém testes para os reembolsos totais e parciais, executados contra o simulador e contra os servidores
+// locais que simulam o Stripe e o PayPal.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui seis testes principais:
+// 1. TestRefund_Full: Verifica se um reembolso sem valor reembolsa todo o valor capturado e altera o status para refunded.
+// 2. TestRefund_MultiplePartial: Verifica reembolsos parciais sucessivos e a rejeição de um reembolso acima do saldo.
+// 3. TestRefund_NotCaptured: Verifica se reembolsar um pagamento apenas autorizado resulta em um erro 409.
+// 4. TestRefund_Lookup: Verifica a consulta de um reembolso pelo ID e a listagem dos reembolsos da transação.
+// 5. TestRefund_UnknownTransaction: Verifica se reembolsar uma transação inexistente resulta em um erro 404.
+// 6. TestRefund_RequiresReview: Verifica se o reembolso de uma transação em revisão tem como base o valor efetivamente recebido.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// capturedPayment autoriza e captura um pagamento de 100.00 no gateway informado e retorna o ID da transação.
+func capturedPayment(t *testing.T, gateway string) string {
+	authorized := authorizePayment(t, gateway)
+	rr := sendLifecycleAction(t, handlers.CapturePayment, authorized.Transaction_ID, "capture", "")
+	if !assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String()) {
+		t.FailNow()
+	}
+	return authorized.Transaction_ID
+}
+
+// refundPayment envia uma solicitação de reembolso ao handler.
+func refundPayment(t *testing.T, transactionID, body string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("POST", "/payments/"+transactionID+"/refunds", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = mux.SetURLVars(req, map[string]string{"id": transactionID})
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.RefundPayment).ServeHTTP(rr, req)
+	return rr
+}
+
+// decodeRefund decodifica a resposta de um reembolso.
+func decodeRefund(t *testing.T, rr *httptest.ResponseRecorder) models.RefundResponse {
+	var response models.RefundResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestRefund_Full(t *testing.T) {
+	for _, gateway := range lifecycleGateways(t) {
+		t.Run(gateway, func(t *testing.T) {
+			transactionID := capturedPayment(t, gateway)
+
+			rr := refundPayment(t, transactionID, "")
+			assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+			refund := decodeRefund(t, rr)
+			assert.NotEmpty(t, refund.RefundID)
+			assert.NotEmpty(t, refund.GatewayRefundID)
+			assert.Equal(t, transactionID, refund.TransactionID)
+			assert.Equal(t, "100.00", refund.Amount.String())
+			assert.Equal(t, "succeeded", refund.Status)
+			assert.Equal(t, "refunded", refund.TransactionStatus)
+
+			// Nada mais pode ser reembolsado
+			rr = refundPayment(t, transactionID, `{"amount": 1.00}`)
+			assert.Equal(t, http.StatusConflict, rr.Code)
+		})
+	}
+}
+
+func TestRefund_MultiplePartial(t *testing.T) {
+	for _, gateway := range lifecycleGateways(t) {
+		t.Run(gateway, func(t *testing.T) {
+			transactionID := capturedPayment(t, gateway)
+
+			rr := refundPayment(t, transactionID, `{"amount": 30.00, "reason": "item returned"}`)
+			assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+			first := decodeRefund(t, rr)
+			assert.Equal(t, "partially_refunded", first.TransactionStatus)
+			assert.Equal(t, "item returned", first.Reason)
+
+			rr = refundPayment(t, transactionID, `{"amount": 50.00}`)
+			assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+			second := decodeRefund(t, rr)
+			assert.Equal(t, "partially_refunded", second.TransactionStatus)
+			assert.NotEqual(t, first.RefundID, second.RefundID)
+
+			// O total reembolsado não pode ultrapassar o valor capturado
+			rr = refundPayment(t, transactionID, `{"amount": 20.01}`)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assertProblem(t, rr, "refund_amount_exceeded", "refund amount exceeds captured amount")
+
+			// O saldo restante é reembolsado sem informar o valor
+			rr = refundPayment(t, transactionID, "")
+			assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+			last := decodeRefund(t, rr)
+			assert.Equal(t, "20.00", last.Amount.String())
+			assert.Equal(t, "refunded", last.TransactionStatus)
+
+			// O histórico registra cada reembolso
+			req, _ := http.NewRequest("GET", "/payment-status?transaction_id="+transactionID+"&gateway="+gateway, nil)
+			rr = httptest.NewRecorder()
+			http.HandlerFunc(handlers.GetPaymentStatus).ServeHTTP(rr, req)
+			var status models.TransactionResponse
+			json.NewDecoder(rr.Body).Decode(&status)
+			assert.Equal(t, "refunded", status.Status)
+			if assert.Len(t, status.History, 6) {
+				assert.Equal(t, "partially_refunded", status.History[3].To)
+				assert.Equal(t, "refund "+first.RefundID, status.History[3].Reason)
+				assert.Equal(t, "refunded", status.History[5].To)
+			}
+		})
+	}
+}
+
+func TestRefund_NotCaptured(t *testing.T) {
+	for _, gateway := range lifecycleGateways(t) {
+		t.Run(gateway, func(t *testing.T) {
+			authorized := authorizePayment(t, gateway)
+
+			rr := refundPayment(t, authorized.Transaction_ID, "")
+			assert.Equal(t, http.StatusConflict, rr.Code)
+			assertProblem(t, rr, "invalid_transition", "invalid status transition from authorized to refunded")
+		})
+	}
+}
+
+func TestRefund_Lookup(t *testing.T) {
+	lifecycleGateways(t)
+	transactionID := capturedPayment(t, "simulator")
+	refund := decodeRefund(t, refundPayment(t, transactionID, `{"amount": 10.00}`))
+
+	// Consulta pelo ID do reembolso
+	req, _ := http.NewRequest("GET", "/payments/"+transactionID+"/refunds/"+refund.RefundID, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": transactionID, "refund_id": refund.RefundID})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.GetRefund).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	found := decodeRefund(t, rr)
+	assert.Equal(t, refund.RefundID, found.RefundID)
+	assert.Equal(t, "10.00", found.Amount.String())
+	assert.Equal(t, "succeeded", found.Status)
+
+	// O reembolso não é encontrado a partir de outra transação
+	otherID := capturedPayment(t, "simulator")
+	req, _ = http.NewRequest("GET", "/payments/"+otherID+"/refunds/"+refund.RefundID, nil)
+	req = mux.SetURLVars(req, map[string]string{"id": otherID, "refund_id": refund.RefundID})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handlers.GetRefund).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "refund_not_found", "Refund ID not found")
+
+	// Listagem dos reembolsos da transação
+	req, _ = http.NewRequest("GET", "/payments/"+transactionID+"/refunds", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": transactionID})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(handlers.ListRefunds).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list []models.RefundResponse
+	json.NewDecoder(rr.Body).Decode(&list)
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, refund.RefundID, list[0].RefundID)
+	}
+}
+
+func TestRefund_UnknownTransaction(t *testing.T) {
+	setupTransactions(t)
+
+	rr := refundPayment(t, "missing", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "transaction_not_found", "Transaction ID not found")
+}
+
+// reviewTransaction cria uma transação de 50.00 no simulador que chegou a requires_review pelos status informados.
+func reviewTransaction(t *testing.T, transactionID string, path ...string) models.Transaction {
+	now := time.Now().UTC()
+	transaction := models.NewTransaction(transactionID, "simulator", models.MustParseDecimal("50.00"), "USD", now)
+	for _, status := range path {
+		if err := transaction.Transition(status, "test", now); err != nil {
+			t.Fatal(err)
+		}
+		if status == models.StatusCaptured {
+			transaction.CapturedAmount = transaction.Amount
+		}
+	}
+	return transaction
+}
+
+func TestRefund_RequiresReview(t *testing.T) {
+	// Pix pago depois da expiração: o valor foi recebido, mas a transação não foi capturada
+	late := reviewTransaction(t, "late", models.StatusPending, models.StatusFailed, models.StatusRequiresReview)
+	// Pix recebido duas vezes para a mesma cobrança
+	duplicate := reviewTransaction(t, "duplicate", models.StatusPending, models.StatusAuthorized, models.StatusCaptured, models.StatusRequiresReview)
+	duplicate.PixDuplicates = []string{"E12345678202610181200duplicate01"}
+	setupTransactions(t, late, duplicate)
+
+	rr := refundPayment(t, "late", `{"amount": 20.00}`)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, "partially_refunded", decodeRefund(t, rr).TransactionStatus)
+
+	rr = refundPayment(t, "late", `{"amount": 30.01}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "refund_amount_exceeded", "refund amount exceeds captured amount")
+
+	rr = refundPayment(t, "late", "")
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	refund := decodeRefund(t, rr)
+	assert.Equal(t, "30.00", refund.Amount.String())
+	assert.Equal(t, "refunded", refund.TransactionStatus)
+
+	// Os dois pagamentos recebidos podem ser devolvidos
+	rr = refundPayment(t, "duplicate", "")
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	refund = decodeRefund(t, rr)
+	assert.Equal(t, "100.00", refund.Amount.String())
+	assert.Equal(t, "refunded", refund.TransactionStatus)
+}
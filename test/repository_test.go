@@ -0,0 +1,460 @@
+// repository_test.go
+// Este arquivo contém testes para as implementações do repositório de transações (em memória e SQLite).
+// Os mesmos cenários são executados contra as duas implementações, garantindo que elas se comportem da mesma forma.
+// Utiliza a biblioteca testify/assert para validação dos resultados.
+
+// O arquivo inclui sete testes principais:
+// 1. TestTransactionRepository: Verifica criação, consulta, transições de status com histórico e listagem de transações.
+// 2. TestRefundRepository: Verifica criação, consulta e listagem dos reembolsos de uma transação.
+// 3. TestFXQuoteRepository: Verifica criação, consulta e marcação de uso das cotações de câmbio.
+// 4. TestRateHistoryRepository: Verifica a gravação do histórico diário de taxas e a consulta da tabela mais recente até uma data.
+// 5. TestCardTokenRepository: Verifica criação e consulta dos cartões do cofre, com o número cifrado.
+// 6. TestCustomerRepository: Verifica criação, alteração, listagem e remoção de clientes e dos seus métodos de pagamento salvos.
+// 7. TestSQLiteRepository_PersistsAcrossReopen: Verifica se as transações sobrevivem ao fechar e reabrir o banco, com as migrações reaplicadas sem erro.
+
+package handlers_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// transactionRepositories retorna as implementações do repositório de transações a serem testadas.
+func transactionRepositories(t *testing.T) map[string]repository.TransactionRepository {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return map[string]repository.TransactionRepository{
+		"memory": repository.NewMemoryTransactionRepository(),
+		"sqlite": repository.NewSQLiteTransactionRepository(db),
+	}
+}
+
+func TestTransactionRepository(t *testing.T) {
+	for name, repo := range transactionRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			first := models.NewTransaction("PAY-1", "PayPal", models.MustParseDecimal("100.50"), "USD", time.Now().Add(-time.Minute))
+			assert.NoError(t, first.Transition(models.StatusPending, "payment processed", time.Now().Add(-time.Minute)))
+			expiresAt := time.Now().Add(30 * time.Minute).UTC().Truncate(time.Millisecond)
+			first.PaymentMethod, first.ExpiresAt = models.PaymentMethodPix, &expiresAt
+			second := models.Transaction{
+				Transaction_ID: "pi_2",
+				Gateway:        "Stripe",
+				Status:         "captured",
+				Amount:         models.MustParseDecimal("12345678901234567.89"),
+				Currency:       "USD",
+				CardBrand:      "amex",
+				CardBIN:        "378282",
+				CardLast4:      "0005",
+			}
+
+			assert.NoError(t, repo.Create(first))
+			assert.NoError(t, repo.Create(second))
+			assert.ErrorIs(t, repo.Create(first), repository.ErrAlreadyExists)
+
+			// Consulta
+			stored, err := repo.Get("PAY-1")
+			assert.NoError(t, err)
+			assert.Equal(t, "PayPal", stored.Gateway)
+			assert.Equal(t, "pending", stored.Status)
+			assert.Equal(t, "100.50", stored.Amount.String())
+			assert.False(t, stored.UpdatedAt.IsZero())
+			assert.Len(t, stored.History, 2)
+			assert.Equal(t, models.PaymentMethodPix, stored.PaymentMethod)
+			if assert.NotNil(t, stored.ExpiresAt) {
+				assert.True(t, expiresAt.Equal(*stored.ExpiresAt))
+			}
+
+			// Valores sem representação exata em float64 são preservados
+			stored, _ = repo.Get("pi_2")
+			assert.Equal(t, "12345678901234567.89", stored.Amount.String())
+			assert.Nil(t, stored.ExpiresAt)
+			assert.Equal(t, []string{"amex", "378282", "0005"}, []string{stored.CardBrand, stored.CardBIN, stored.CardLast4})
+
+			_, err = repo.Get("missing")
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+
+			// Transição de status registrada no histórico
+			_, err = repository.UpdateStatus(repo, "PAY-1", models.StatusAuthorized, "gateway status update")
+			assert.NoError(t, err)
+			stored, _ = repo.Get("PAY-1")
+			assert.Equal(t, "authorized", stored.Status)
+			if assert.Len(t, stored.History, 3) {
+				assert.Equal(t, "pending", stored.History[2].From)
+				assert.Equal(t, "authorized", stored.History[2].To)
+				assert.Equal(t, "gateway status update", stored.History[2].Reason)
+			}
+
+			// Transição inválida não altera a transação
+			_, err = repository.UpdateStatus(repo, "PAY-1", models.StatusRefunded, "refund")
+			var transitionErr *models.InvalidTransitionError
+			assert.ErrorAs(t, err, &transitionErr)
+			stored, _ = repo.Get("PAY-1")
+			assert.Equal(t, "authorized", stored.Status)
+			assert.Len(t, stored.History, 3)
+
+			_, err = repository.UpdateStatus(repo, "missing", models.StatusCaptured, "capture")
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+
+			// Campos alterados na atualização
+			_, err = repo.Update("PAY-1", func(transaction *models.Transaction) error {
+				transaction.PixEndToEndID = "E12345678202610181200abcdefghijk"
+				transaction.PixDuplicates = []string{"E12345678202610181200duplicate01", "E12345678202610181200duplicate02"}
+				return nil
+			})
+			assert.NoError(t, err)
+			stored, _ = repo.Get("PAY-1")
+			assert.Equal(t, "E12345678202610181200abcdefghijk", stored.PixEndToEndID)
+			assert.Equal(t, []string{"E12345678202610181200duplicate01", "E12345678202610181200duplicate02"}, stored.PixDuplicates)
+
+			// Listagem em ordem de criação
+			list, err := repo.List()
+			assert.NoError(t, err)
+			if assert.Len(t, list, 2) {
+				assert.Equal(t, "PAY-1", list[0].Transaction_ID)
+				assert.Equal(t, "pi_2", list[1].Transaction_ID)
+			}
+		})
+	}
+}
+
+// refundRepositories retorna as implementações do repositório de reembolsos a serem testadas,
+// junto com o repositório de transações correspondente.
+func refundRepositories(t *testing.T) map[string]struct {
+	transactions repository.TransactionRepository
+	refunds      repository.RefundRepository
+} {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return map[string]struct {
+		transactions repository.TransactionRepository
+		refunds      repository.RefundRepository
+	}{
+		"memory": {repository.NewMemoryTransactionRepository(), repository.NewMemoryRefundRepository()},
+		"sqlite": {repository.NewSQLiteTransactionRepository(db), repository.NewSQLiteRefundRepository(db)},
+	}
+}
+
+func TestRefundRepository(t *testing.T) {
+	for name, repos := range refundRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, id := range []string{"PAY-1", "PAY-2"} {
+				assert.NoError(t, repos.transactions.Create(models.NewTransaction(id, "Stripe", models.MustParseDecimal("100.00"), "USD", time.Now())))
+			}
+
+			first := models.Refund{
+				ID:              "rf_1",
+				TransactionID:   "PAY-1",
+				Gateway:         "Stripe",
+				GatewayRefundID: "re_1",
+				Amount:          models.MustParseDecimal("30.00"),
+				Currency:        "USD",
+				Status:          models.RefundStatusSucceeded,
+				Reason:          "item returned",
+				CreatedAt:       time.Now().Add(-time.Minute),
+			}
+			second := first
+			second.ID, second.GatewayRefundID, second.Amount, second.CreatedAt = "rf_2", "re_2", models.MustParseDecimal("70000000000000000.07"), time.Now()
+			other := first
+			other.ID, other.TransactionID = "rf_3", "PAY-2"
+
+			assert.NoError(t, repos.refunds.Create(second))
+			assert.NoError(t, repos.refunds.Create(first))
+			assert.NoError(t, repos.refunds.Create(other))
+			assert.ErrorIs(t, repos.refunds.Create(first), repository.ErrAlreadyExists)
+
+			// Consulta
+			stored, err := repos.refunds.Get("rf_1")
+			assert.NoError(t, err)
+			assert.Equal(t, "PAY-1", stored.TransactionID)
+			assert.Equal(t, "re_1", stored.GatewayRefundID)
+			assert.Equal(t, "30.00", stored.Amount.String())
+			assert.Equal(t, "succeeded", stored.Status)
+			stored, _ = repos.refunds.Get("rf_2")
+			assert.Equal(t, "70000000000000000.07", stored.Amount.String())
+			assert.Equal(t, "item returned", stored.Reason)
+
+			_, err = repos.refunds.Get("missing")
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+
+			// Listagem por transação em ordem de criação
+			list, err := repos.refunds.ListByTransaction("PAY-1")
+			assert.NoError(t, err)
+			if assert.Len(t, list, 2) {
+				assert.Equal(t, "rf_1", list[0].ID)
+				assert.Equal(t, "rf_2", list[1].ID)
+			}
+		})
+	}
+}
+
+func TestFXQuoteRepository(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repos := map[string]repository.FXQuoteRepository{
+		"memory": repository.NewMemoryFXQuoteRepository(),
+		"sqlite": repository.NewSQLiteFXQuoteRepository(db),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now().UTC().Truncate(time.Second)
+			amount, converted := models.MustParseDecimal("100.00"), models.MustParseDecimal("108.75")
+			quote := models.FXQuote{
+				ID:              "qt_1",
+				FromCurrency:    "EUR",
+				ToCurrency:      "USD",
+				Rate:            1.0875,
+				Amount:          &amount,
+				ConvertedAmount: &converted,
+				CreatedAt:       now,
+				ExpiresAt:       now.Add(10 * time.Minute),
+			}
+
+			assert.NoError(t, repo.Create(quote))
+			assert.ErrorIs(t, repo.Create(quote), repository.ErrAlreadyExists)
+
+			// Consulta
+			stored, err := repo.Get("qt_1")
+			assert.NoError(t, err)
+			assert.Equal(t, 1.0875, stored.Rate)
+			assert.Equal(t, "108.75", stored.ConvertedAmount.String())
+			assert.True(t, stored.ExpiresAt.Equal(quote.ExpiresAt))
+			assert.Nil(t, stored.UsedAt)
+
+			_, err = repo.Get("missing")
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+
+			// Marcação de uso
+			_, err = repo.Update("qt_1", func(q *models.FXQuote) error {
+				q.UsedAt = &now
+				q.TransactionID = "PAY-1"
+				return nil
+			})
+			assert.NoError(t, err)
+			stored, _ = repo.Get("qt_1")
+			if assert.NotNil(t, stored.UsedAt) {
+				assert.True(t, stored.UsedAt.Equal(now))
+			}
+			assert.Equal(t, "PAY-1", stored.TransactionID)
+
+			// Erro na função de atualização não altera a cotação
+			_, err = repo.Update("qt_1", func(q *models.FXQuote) error {
+				q.UsedAt = nil
+				return assert.AnError
+			})
+			assert.ErrorIs(t, err, assert.AnError)
+			stored, _ = repo.Get("qt_1")
+			assert.NotNil(t, stored.UsedAt)
+
+			_, err = repo.Update("missing", func(q *models.FXQuote) error { return nil })
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+		})
+	}
+}
+
+func TestRateHistoryRepository(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repos := map[string]repository.RateHistoryRepository{
+		"memory": repository.NewMemoryRateHistoryRepository(),
+		"sqlite": repository.NewSQLiteRateHistoryRepository(db),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			tuesday := time.Date(2024, 1, 2, 16, 0, 0, 0, time.UTC)
+			friday := time.Date(2024, 1, 5, 0, 0, 1, 0, time.UTC)
+
+			assert.NoError(t, repo.Save(models.RateTable{Base: "USD", Rates: map[string]float64{"EUR": 0.90}, Date: tuesday, Provider: "ecb"}))
+			assert.NoError(t, repo.Save(models.RateTable{Base: "USD", Rates: map[string]float64{"EUR": 0.91}, Date: friday, Provider: "ecb"}))
+			// A última tabela do dia substitui a anterior
+			assert.NoError(t, repo.Save(models.RateTable{Base: "USD", Rates: map[string]float64{"EUR": 0.92}, Date: tuesday.Add(time.Hour), Provider: "erapi"}))
+
+			// Domingo: tabela de sexta-feira
+			table, err := repo.Latest("USD", time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC))
+			assert.NoError(t, err)
+			assert.Equal(t, "2024-01-05", table.Date.Format("2006-01-02"))
+			assert.Equal(t, 0.91, table.Rates["EUR"])
+			assert.Equal(t, "USD", table.Base)
+
+			table, err = repo.Latest("USD", time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC))
+			assert.NoError(t, err)
+			assert.Equal(t, "2024-01-02", table.Date.Format("2006-01-02"))
+			assert.Equal(t, 0.92, table.Rates["EUR"])
+			assert.Equal(t, "erapi", table.Provider)
+
+			_, err = repo.Latest("USD", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+			_, err = repo.Latest("EUR", friday)
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+		})
+	}
+}
+
+func TestCardTokenRepository(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repos := map[string]repository.CardTokenRepository{
+		"memory": repository.NewMemoryCardTokenRepository(),
+		"sqlite": repository.NewSQLiteCardTokenRepository(db),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			card := models.CardToken{
+				Token:           "tok_1",
+				Brand:           models.CardBrandAmex,
+				Last4:           "0005",
+				Expiry:          validExpiry(),
+				CreatedAt:       time.Now().UTC().Truncate(time.Second),
+				EncryptedNumber: []byte{0x01, 0x02, 0x03},
+			}
+
+			assert.NoError(t, repo.Create(card))
+			assert.ErrorIs(t, repo.Create(card), repository.ErrAlreadyExists)
+
+			stored, err := repo.Get("tok_1")
+			assert.NoError(t, err)
+			assert.Equal(t, models.CardBrandAmex, stored.Brand)
+			assert.Equal(t, []string{"0005", validExpiry()}, []string{stored.Last4, stored.Expiry})
+			assert.Equal(t, card.EncryptedNumber, stored.EncryptedNumber)
+			assert.True(t, stored.CreatedAt.Equal(card.CreatedAt))
+
+			_, err = repo.Get("missing")
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+		})
+	}
+}
+
+func TestCustomerRepository(t *testing.T) {
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	type repos struct {
+		customers repository.CustomerRepository
+		methods   repository.PaymentMethodRepository
+	}
+	implementations := map[string]repos{
+		"memory": {repository.NewMemoryCustomerRepository(), repository.NewMemoryPaymentMethodRepository()},
+		"sqlite": {repository.NewSQLiteCustomerRepository(db), repository.NewSQLitePaymentMethodRepository(db)},
+	}
+	for name, repo := range implementations {
+		t.Run(name, func(t *testing.T) {
+			customer := models.Customer{ID: "cus_1", Name: "Maria Silva", Email: "maria@example.com"}
+			assert.NoError(t, repo.customers.Create(customer))
+			assert.ErrorIs(t, repo.customers.Create(customer), repository.ErrAlreadyExists)
+			assert.NoError(t, repo.customers.Create(models.Customer{ID: "cus_2", Name: "João", Email: "joao@example.com"}))
+
+			card := models.PaymentMethod{
+				ID: "pm_1", CustomerID: "cus_1", Type: models.PaymentMethodCard,
+				CardToken: "tok_1", CardBrand: models.CardBrandVisa, CardLast4: "1111", CardExpiry: validExpiry(),
+			}
+			agreement := models.PaymentMethod{
+				ID: "pm_2", CustomerID: "cus_1", Type: models.PaymentMethodPayPalBillingAgreement,
+				BillingAgreementID: "B-1234", PayerEmail: "maria@example.com", CreatedAt: time.Now().UTC().Add(time.Second),
+			}
+			assert.NoError(t, repo.methods.Create(card))
+			assert.NoError(t, repo.methods.Create(agreement))
+			assert.ErrorIs(t, repo.methods.Create(card), repository.ErrAlreadyExists)
+
+			updated, err := repo.customers.Update("cus_1", func(customer *models.Customer) error {
+				customer.DefaultPaymentMethodID = "pm_2"
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "pm_2", updated.DefaultPaymentMethodID)
+
+			stored, err := repo.customers.Get("cus_1")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"Maria Silva", "maria@example.com", "pm_2"}, []string{stored.Name, stored.Email, stored.DefaultPaymentMethodID})
+			assert.False(t, stored.CreatedAt.IsZero())
+
+			list, err := repo.customers.List()
+			assert.NoError(t, err)
+			assert.Len(t, list, 2)
+
+			method, err := repo.methods.Get("pm_1")
+			assert.NoError(t, err)
+			assert.Equal(t, models.CardBrandVisa, method.CardBrand)
+			assert.Equal(t, []string{"tok_1", "1111", validExpiry()}, []string{method.CardToken, method.CardLast4, method.CardExpiry})
+
+			methods, err := repo.methods.ListByCustomer("cus_1")
+			assert.NoError(t, err)
+			if assert.Len(t, methods, 2) {
+				assert.Equal(t, "pm_1", methods[0].ID)
+				assert.Equal(t, "B-1234", methods[1].BillingAgreementID)
+			}
+			methods, _ = repo.methods.ListByCustomer("cus_2")
+			assert.Empty(t, methods)
+
+			assert.NoError(t, repo.methods.Delete("pm_1"))
+			assert.ErrorIs(t, repo.methods.Delete("pm_1"), repository.ErrNotFound)
+			_, err = repo.methods.Get("pm_1")
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+
+			assert.NoError(t, repo.methods.Delete("pm_2"))
+			assert.NoError(t, repo.customers.Delete("cus_1"))
+			assert.ErrorIs(t, repo.customers.Delete("cus_1"), repository.ErrNotFound)
+			_, err = repo.customers.Get("cus_1")
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+			_, err = repo.customers.Update("cus_1", func(customer *models.Customer) error { return nil })
+			assert.ErrorIs(t, err, repository.ErrNotFound)
+		})
+	}
+}
+
+func TestSQLiteRepository_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payments.db")
+
+	db, err := repository.OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repository.NewSQLiteTransactionRepository(db).Create(models.Transaction{
+		Transaction_ID: "PAY-1",
+		Gateway:        "PayPal",
+		Status:         "captured",
+		Amount:         models.MustParseDecimal("42.00"),
+		Currency:       "USD",
+	})
+	assert.NoError(t, err)
+	db.Close()
+
+	// Reabre o banco: as migrações já aplicadas não devem ser executadas novamente
+	db, err = repository.OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stored, err := repository.NewSQLiteTransactionRepository(db).Get("PAY-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "captured", stored.Status)
+	assert.Equal(t, "42.00", stored.Amount.String())
+}
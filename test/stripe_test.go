@@ -0,0 +1,138 @@
+// stripe_test.go
+// Este arquivo contém testes para o gateway Stripe, executados contra o servidor local que simula a API do Stripe (mocks/stripemock).
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui quatro testes principais:
+// 1. TestStripe_ProcessPaymentAndStatus: Verifica se um pagamento aprovado retorna um ID pi_ e se o status pode ser consultado.
+// 2. TestStripe_DeclinedCard: Verifica se um cartão recusado resulta em um erro 402 com a mensagem do Stripe.
+// 3. TestStripe_RequiresAction: Verifica se um pagamento que exige autenticação fica pendente até ser concluído.
+// 4. TestStripe_UnknownTransaction: Verifica se a consulta de um ID inexistente resulta em um erro 404.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/mocks/stripemock"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupStripe inicia o servidor que simula o Stripe e registra o gateway apontando para ele.
+func setupStripe(t *testing.T) *stripemock.Server {
+	server := stripemock.NewServer("sk_test_123")
+	original, _ := services.GetGateway("Stripe")
+	services.RegisterGateway(services.NewStripeGateway(server.URL, "sk_test_123"))
+
+	t.Cleanup(func() {
+		services.RegisterGateway(original)
+		server.Close()
+	})
+	return server
+}
+
+// processStripePayment envia uma solicitação de pagamento ao handler com o número de cartão informado.
+func processStripePayment(t *testing.T, cardNumber string) *httptest.ResponseRecorder {
+	paymentRequest := models.PaymentRequest{
+		Gateway:       "Stripe",
+		Amount:        models.MustParseDecimal("100.00"),
+		Currency:      "USD",
+		PaymentMethod: "credit_card",
+		CardDetails: &models.CardDetails{
+			Number: cardNumber,
+			Expiry: validExpiry(),
+			CVV:    "123",
+		},
+	}
+	reqBody, _ := json.Marshal(paymentRequest)
+	req, err := http.NewRequest("POST", "/process-payment", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.ProcessPayment).ServeHTTP(rr, req)
+	return rr
+}
+
+// getStripeStatus consulta o status de uma transação do Stripe pelo handler.
+func getStripeStatus(t *testing.T, transactionID string) *httptest.ResponseRecorder {
+	req, err := http.NewRequest("GET", "/payment-status?transaction_id="+transactionID+"&gateway=Stripe", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(handlers.GetPaymentStatus).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestStripe_ProcessPaymentAndStatus(t *testing.T) {
+	setupStripe(t)
+
+	rr := processStripePayment(t, "4242424242424242")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response models.PaymentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Regexp(t, "^pi_", response.Transaction_ID)
+	assert.Equal(t, "captured", response.Status)
+
+	rr = getStripeStatus(t, response.Transaction_ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var status models.TransactionResponse
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "captured", status.Status)
+}
+
+func TestStripe_DeclinedCard(t *testing.T) {
+	setupStripe(t)
+
+	rr := processStripePayment(t, stripemock.CardDeclined)
+
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	assertProblem(t, rr, "card_error", "Stripe: Your card was declined. (card_declined)")
+}
+
+func TestStripe_RequiresAction(t *testing.T) {
+	server := setupStripe(t)
+
+	rr := processStripePayment(t, stripemock.CardRequiresAction)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response models.PaymentResponse
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "pending", response.Status)
+
+	// Simula a conclusão da autenticação pelo cliente
+	server.SetStatus(response.Transaction_ID, "succeeded")
+
+	rr = getStripeStatus(t, response.Transaction_ID)
+	var status models.TransactionResponse
+	if err := json.NewDecoder(rr.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "captured", status.Status)
+}
+
+func TestStripe_UnknownTransaction(t *testing.T) {
+	setupStripe(t)
+
+	rr := getStripeStatus(t, "pi_unknown")
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
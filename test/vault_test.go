@@ -0,0 +1,276 @@
+// vault_test.go
+// Este arquivo contém testes para o cofre de cartões (vault): a troca dos dados do cartão por um token em POST /tokens,
+// os pagamentos com card_token e a cifragem do número do cartão no armazenamento.
+// Utiliza a biblioteca testify/assert para validação dos resultados e net/http/httptest para simular requisições HTTP.
+
+// O arquivo inclui cinco testes principais:
+// 1. TestCreateCardToken: Verifica se o token é criado com a bandeira, os últimos dígitos e a validade, sem expor o número nem o CVV.
+// 2. TestProcessPayment_CardToken: Verifica se o gateway recebe os dados do cartão do cofre e se o CVV é usado apenas no primeiro pagamento.
+// 3. TestProcessPayment_CardTokenErrors: Verifica os erros de token desconhecido e de card_token informado junto com card_details.
+// 4. TestProcessPayment_ExpiredCardToken: Verifica se o token e o cartão salvo vencidos são recusados antes do gateway.
+// 5. TestVault_Encryption: Verifica se o SQLite guarda apenas o número cifrado e se outra chave não consegue decifrá-lo.
+
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"desafiogolang-payment/handlers"
+	"desafiogolang-payment/models"
+	"desafiogolang-payment/repository"
+	"desafiogolang-payment/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupVault define um cofre com uma chave aleatória sobre o repositório informado.
+func setupVault(t *testing.T, repo repository.CardTokenRepository, cvvTTL time.Duration) {
+	vault, err := services.NewVault(services.NewVaultKey(), repo, cvvTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	services.SetVault(vault)
+	t.Cleanup(func() { setupDefaultVault() })
+}
+
+// setupDefaultVault restaura um cofre em memória.
+func setupDefaultVault() {
+	vault, _ := services.NewVault(services.NewVaultKey(), repository.NewMemoryCardTokenRepository(), 10*time.Minute)
+	services.SetVault(vault)
+}
+
+// recordingGateway substitui o simulador e registra os dados do cartão recebidos pelo gateway.
+type recordingGateway struct {
+	services.PaymentGateway
+
+	mu    sync.Mutex
+	cards []models.CardDetails
+}
+
+func (g *recordingGateway) ProcessPayment(request models.PaymentRequest) (models.PaymentResponse, error) {
+	g.mu.Lock()
+	g.cards = append(g.cards, *request.CardDetails)
+	g.mu.Unlock()
+	return g.PaymentGateway.ProcessPayment(request)
+}
+
+// setupRecordingGateway registra o recordingGateway no lugar do simulador.
+func setupRecordingGateway(t *testing.T) *recordingGateway {
+	original, _ := services.GetGateway("simulator")
+	gateway := &recordingGateway{PaymentGateway: original}
+	services.RegisterGateway(gateway)
+	t.Cleanup(func() { services.RegisterGateway(original) })
+	return gateway
+}
+
+// createCardToken envia os dados do cartão para POST /tokens.
+func createCardToken(t *testing.T, number, expiry, cvv string) *httptest.ResponseRecorder {
+	return sendRequest(t, handlers.CreateCardToken, "POST", "/tokens",
+		fmt.Sprintf(`{"number": %q, "expiry": %q, "cvv": %q}`, number, expiry, cvv))
+}
+
+// tokenizeCard cria um token para o cartão informado e retorna a resposta decodificada.
+func tokenizeCard(t *testing.T, number, cvv string) models.CardTokenResponse {
+	rr := createCardToken(t, number, validExpiry(), cvv)
+	if !assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String()) {
+		t.FailNow()
+	}
+	var token models.CardTokenResponse
+	json.NewDecoder(rr.Body).Decode(&token)
+	return token
+}
+
+// processTokenPayment envia ao gateway informado um pagamento com o card_token.
+func processTokenPayment(t *testing.T, gateway, token string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"gateway": %q, "amount": 10.00, "currency": "USD", "payment_method": "credit_card", "card_token": %q}`,
+		gateway, token)
+	return sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment", body)
+}
+
+func TestCreateCardToken(t *testing.T) {
+	repo := repository.NewMemoryCardTokenRepository()
+	setupVault(t, repo, time.Minute)
+
+	rr := createCardToken(t, "4111111111111111", validExpiry(), "123")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "4111111111111111")
+	assert.NotContains(t, rr.Body.String(), `"cvv"`)
+
+	var token models.CardTokenResponse
+	json.Unmarshal(rr.Body.Bytes(), &token)
+	assert.True(t, strings.HasPrefix(token.Token, "tok_"), token.Token)
+	assert.Equal(t, models.CardBrandVisa, token.Brand)
+	assert.Equal(t, "1111", token.Last4)
+	assert.Equal(t, validExpiry(), token.Expiry)
+	if assert.NotNil(t, token.CVVExpiresAt) {
+		assert.WithinDuration(t, time.Now().Add(time.Minute), *token.CVVExpiresAt, 5*time.Second)
+	}
+
+	// O repositório recebe apenas o número cifrado
+	stored, err := repo.Get(token.Token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, stored.EncryptedNumber)
+	assert.False(t, bytes.Contains(stored.EncryptedNumber, []byte("4111111111111111")))
+
+	// Tokens diferentes para o mesmo cartão
+	assert.NotEqual(t, token.Token, tokenizeCard(t, "4111111111111111", "123").Token)
+
+	// Os dados do cartão passam pelas mesmas validações dos pagamentos
+	rr = createCardToken(t, "4111111111111112", validExpiry(), "123")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	problem := assertProblem(t, rr, "validation_failed", "Invalid request data: number: luhn_checksum")
+	assert.Equal(t, []models.FieldError{{Field: "number", Rule: "luhn_checksum"}}, problem.Errors)
+
+	rr = createCardToken(t, "378282246310005", validExpiry(), "123")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: cvv: len=4")
+}
+
+func TestProcessPayment_CardToken(t *testing.T) {
+	setupTransactions(t)
+	setupVault(t, repository.NewMemoryCardTokenRepository(), time.Minute)
+	gateway := setupRecordingGateway(t)
+
+	token := tokenizeCard(t, "5555555555554444", "123")
+	rr := processTokenPayment(t, "simulator", token.Token)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.NotContains(t, rr.Body.String(), "5555555555554444")
+
+	var response models.PaymentResponse
+	json.NewDecoder(rr.Body).Decode(&response)
+	assert.Equal(t, "mastercard", response.CardBrand)
+
+	// O CVV é enviado apenas no primeiro pagamento com o token
+	rr = processTokenPayment(t, "simulator", token.Token)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, []models.CardDetails{
+		{Number: "5555555555554444", Expiry: validExpiry(), CVV: "123"},
+		{Number: "5555555555554444", Expiry: validExpiry()},
+	}, gateway.cards)
+
+	// O CVV expirado não é enviado
+	setupVault(t, repository.NewMemoryCardTokenRepository(), time.Millisecond)
+	token = tokenizeCard(t, "4111111111111111", "123")
+	time.Sleep(5 * time.Millisecond)
+	rr = processTokenPayment(t, "simulator", token.Token)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, models.CardDetails{Number: "4111111111111111", Expiry: validExpiry()}, gateway.cards[2])
+
+	// Os gateways reais recebem o número decifrado (o cartão recusado pelo Stripe)
+	setupStripe(t)
+	token = tokenizeCard(t, "4000000000000002", "123")
+	rr = processTokenPayment(t, "Stripe", token.Token)
+	assert.Equal(t, http.StatusPaymentRequired, rr.Code)
+	assertProblem(t, rr, "card_error", "Stripe: Your card was declined. (card_declined)")
+}
+
+func TestProcessPayment_CardTokenErrors(t *testing.T) {
+	setupTransactions(t)
+	setupVault(t, repository.NewMemoryCardTokenRepository(), time.Minute)
+
+	rr := processTokenPayment(t, "simulator", "tok_unknown")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assertProblem(t, rr, "card_token_not_found", "Card token not found")
+
+	token := tokenizeCard(t, "4111111111111111", "123")
+	rr = sendRequest(t, handlers.ProcessPayment, "POST", "/process-payment",
+		fmt.Sprintf(`{"gateway": "simulator", "amount": 10.00, "currency": "USD", "payment_method": "credit_card",
+			"card_token": %q, "card_details": {"number": "4111111111111111", "expiry": "`+validExpiry()+`", "cvv": "123"}}`, token.Token))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assertProblem(t, rr, "validation_failed", "Invalid request data: card_details: excluded_with=CardToken CustomerID")
+}
+
+func TestProcessPayment_ExpiredCardToken(t *testing.T) {
+	setupTransactions(t)
+	setupCustomers(t)
+	setupVault(t, repository.NewMemoryCardTokenRepository(), time.Minute)
+
+	// O cofre aceita o cartão, que vence antes do pagamento
+	now := time.Now().UTC()
+	lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0).Format("01/06")
+	token, err := services.TokenizeCard(models.CardDetails{Number: "4111111111111111", Expiry: lastMonth, CVV: "123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detail := "card expired: card ending in 1111 expired in " + lastMonth
+
+	rr := sendRequest(t, handlers.AuthorizePayment, "POST", "/payments/authorize",
+		fmt.Sprintf(`{"gateway": "simulator", "amount": 10.00, "currency": "USD", "payment_method": "credit_card", "card_token": %q}`, token.Token))
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assertProblem(t, rr, "card_expired", detail)
+
+	gateway := setupRecordingGateway(t)
+	rr = processTokenPayment(t, "simulator", token.Token)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assertProblem(t, rr, "card_expired", detail)
+
+	// O cartão salvo do cliente usa o mesmo token
+	customer := createCustomer(t, "Maria Silva", "maria@example.com")
+	rr = addPaymentMethod(t, customer.ID, fmt.Sprintf(`{"type": "card", "card_token": %q}`, token.Token))
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	rr = processCustomerPayment(t, "simulator", customer.ID, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assertProblem(t, rr, "card_expired", detail)
+
+	// O cartão vence no último dia do mês da validade
+	token, err = services.TokenizeCard(models.CardDetails{Number: "4111111111111111", Expiry: now.Format("01/06"), CVV: "123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = processTokenPayment(t, "simulator", token.Token)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Len(t, gateway.cards, 1)
+}
+
+func TestVault_Encryption(t *testing.T) {
+	setupTransactions(t)
+	db, err := repository.OpenSQLite(filepath.Join(t.TempDir(), "vault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := repository.NewSQLiteCardTokenRepository(db)
+	setupVault(t, repo, time.Minute)
+
+	token := tokenizeCard(t, "6362970000457013", "123")
+
+	// O banco guarda apenas o número cifrado, a bandeira e os últimos dígitos
+	var encrypted []byte
+	var brand, last4 string
+	err = db.QueryRow(`SELECT encrypted_number, brand, last4 FROM card_tokens WHERE token = ?`, token.Token).Scan(&encrypted, &brand, &last4)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"elo", "7013"}, []string{brand, last4})
+	assert.False(t, bytes.Contains(encrypted, []byte("6362970000457013")))
+	assert.False(t, bytes.Contains(encrypted, []byte("123")))
+
+	// Outra chave não decifra o número
+	setupVault(t, repo, time.Minute)
+	rr := processTokenPayment(t, "simulator", token.Token)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "6362970000457013")
+
+	// Chaves em hexadecimal ou base64 com 32 bytes
+	key := services.NewVaultKey()
+	for _, encoded := range []string{hex.EncodeToString(key), base64.StdEncoding.EncodeToString(key)} {
+		parsed, err := services.ParseVaultKey(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, key, parsed)
+	}
+	for _, encoded := range []string{"", "secret", hex.EncodeToString(key[:16])} {
+		_, err := services.ParseVaultKey(encoded)
+		assert.ErrorIs(t, err, services.ErrInvalidVaultKey, encoded)
+	}
+	_, err = services.NewVault(key[:16], repo, time.Minute)
+	assert.ErrorIs(t, err, services.ErrInvalidVaultKey)
+}